@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io/fs"
+	"regexp"
+	"strings"
+	"time"
+
+	fs2 "github.com/gopi-frame/contract/filesystem"
+)
+
+var atomicTempFile = regexp.MustCompile(`^\..+\.[0-9a-f]{16}\.tmp$`)
+
+// AtomicTempPath returns the path of the temporary file of an atomic write to path.
+// It is a hidden file in the same directory, so that the final rename does not cross file systems:
+// ".<name>.<random>.tmp".
+func AtomicTempPath(path string) string {
+	token := make([]byte, 8)
+	_, _ = rand.Read(token)
+	i := strings.LastIndexAny(path, `/\`)
+	return path[:i+1] + "." + path[i+1:] + "." + hex.EncodeToString(token) + ".tmp"
+}
+
+// IsAtomicTempFile reports whether the name is the name of a temporary file of an atomic write.
+func IsAtomicTempFile(name string) bool {
+	return atomicTempFile.MatchString(name)
+}
+
+// CleanAtomicTempFiles deletes the temporary files of atomic writes under dir which were not modified for olderThan,
+// i.e. left behind by a process which crashed before renaming them, and returns their paths.
+func CleanAtomicTempFiles(f fs2.FileSystem, dir string, olderThan time.Duration) ([]string, error) {
+	var deleted []string
+	err := WalkDirRelative(f, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !IsAtomicTempFile(d.Name()) {
+			return nil
+		}
+		path = JoinWalked(dir, path, false)
+		modified, err := f.LastModified(path)
+		if err != nil {
+			return err
+		}
+		if time.Since(modified) < olderThan {
+			return nil
+		}
+		if err := f.Delete(path); err != nil {
+			return err
+		}
+		deleted = append(deleted, path)
+		return nil
+	})
+	return deleted, err
+}
@@ -10,20 +10,49 @@ const (
 	DirVisibilityKey  = "dir_visibility"
 	FileVisibilityKey = "file_visibility"
 	FileWriteFlagKey  = "file_write_flag"
+	// AtomicKey enables atomic writes: the content is written to a temporary file, see [AtomicTempPath],
+	// renamed over the target once complete, so that readers never see a partially written file.
+	AtomicKey = "atomic"
+	// IfAbsentKey makes a write fail with a [PreconditionFailed] error if the file exists.
+	IfAbsentKey = "if_absent"
+	// IfMatchKey makes a write fail with a [PreconditionFailed] error
+	// unless the file exists and its current version is the given one, see [Versioner].
+	IfMatchKey = "if_match"
+	// ProgressKey sets the [ProgressFunc] receiving the progress of the transfer.
+	ProgressKey = "progress"
+	// ContextKey passes a [context.Context] to the operation, e.g. carrying a progress callback, see [WithProgress].
+	ContextKey = "context"
+	// ContentLengthKey gives the size of the written stream, used as the total of the reported progress.
+	ContentLengthKey = "content_length"
+	// SymlinksKey sets the [SymlinkMode] of a walk, see [SymlinkWalker].
+	SymlinksKey = "symlinks"
+	// MetadataKey sets the custom metadata of the written file, a map[string]string, see [MetadataStore].
+	MetadataKey = "metadata"
+	// TagsKey sets the tags of the written file, a map[string]string, see [MetadataStore].
+	TagsKey = "tags"
 )
 
 type Config struct {
 	DirVisibility  *string
 	FileVisibility *string
 	FileWriteFlag  *int
-	remain         map[string]any `mapstructure:",remain"`
+	Atomic         *bool
+	IfAbsent       *bool
+	IfMatch        *string
+	ContentLength  *int64
+	Symlinks       *string
+	Metadata       map[string]string
+	Tags           map[string]string
+	remain         map[string]any `mapstructure:"-"`
 }
 
 func NewConfig(configMap map[string]any) (*Config, error) {
 	var cfg Config
+	var metadata mapstructure.Metadata
 	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		WeaklyTypedInput: true,
 		Result:           &cfg,
+		Metadata:         &metadata,
 		MatchName: func(mapKey, fieldName string) bool {
 			return strings.EqualFold(mapKey, fieldName) ||
 				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
@@ -38,6 +67,10 @@ func NewConfig(configMap map[string]any) (*Config, error) {
 	if err := decoder.Decode(configMap); err != nil {
 		return nil, err
 	}
+	// the keys which are not fields of the config are kept as is, e.g. callbacks and driver specific keys
+	for _, key := range metadata.Unused {
+		cfg.Set(key, configMap[key])
+	}
 	return &cfg, nil
 }
 
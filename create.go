@@ -0,0 +1,113 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+var ErrWriteAborted = errors.New("write aborted")
+
+// FileWriter is a stream writing a file, returned by [Creator.Create].
+// The file is committed by Close, which returns the error of the write if any.
+type FileWriter interface {
+	io.WriteCloser
+	// Abort discards the written content instead of committing it.
+	Abort() error
+}
+
+// Creator is implemented by the file systems which can write a file by handing out a writer,
+// for producers like [encoding/csv.Writer] or [archive/tar.Writer] which write into a destination.
+type Creator interface {
+	// Create returns a writer to the file, with the same config as WriteStream.
+	// With an atomic write, Abort leaves the file as it was,
+	// otherwise the partially written file is deleted if the writer created it.
+	// The drivers which can write atomically do so unless [AtomicKey] is set to false, see [AtomicByDefault].
+	Create(path string, config map[string]any) (FileWriter, error)
+}
+
+// NewPipeWriter returns a [FileWriter] piping its content to write, which runs in its own goroutine
+// until the writer is closed or aborted.
+// When aborted, the stream read by write fails with [ErrWriteAborted],
+// and discard, if not nil, is called once write has returned with this error.
+func NewPipeWriter(write func(stream io.Reader) error, discard func() error) FileWriter {
+	pr, pw := io.Pipe()
+	w := &pipeWriter{pw: pw, discard: discard, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		w.err = write(pr)
+		// unblocks the writes if write returned before reading everything
+		_ = pr.CloseWithError(w.err)
+	}()
+	return w
+}
+
+type pipeWriter struct {
+	pw      *io.PipeWriter
+	discard func() error
+	done    chan struct{}
+	err     error
+	once    sync.Once
+	result  error
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	n, err := w.pw.Write(p)
+	if err != nil {
+		<-w.done
+		if w.err != nil {
+			return n, w.err
+		}
+	}
+	return n, err
+}
+
+func (w *pipeWriter) Close() error {
+	w.once.Do(func() {
+		_ = w.pw.Close()
+		<-w.done
+		w.result = w.err
+	})
+	return w.result
+}
+
+func (w *pipeWriter) Abort() error {
+	w.once.Do(func() {
+		_ = w.pw.CloseWithError(ErrWriteAborted)
+		<-w.done
+		// the file is only touched once the stream is read, a write failing before, e.g. on a precondition, left it as it was
+		if w.discard != nil && errors.Is(w.err, ErrWriteAborted) {
+			w.result = w.discard()
+		}
+	})
+	return w.result
+}
+
+// DiscardFunc returns the discard function of [NewPipeWriter] for a write with the config:
+// del, deleting the partially written file, if the file did not exist when the writer was created,
+// or nil, since an atomic write keeps the previous content when aborted,
+// and deleting an existing file would lose more than the aborted write.
+func DiscardFunc(cfg *Config, existed bool, del func() error) func() error {
+	if existed {
+		return nil
+	}
+	if cfg != nil && cfg.Atomic != nil && *cfg.Atomic {
+		return nil
+	}
+	return del
+}
+
+// AtomicByDefault returns the config with [AtomicKey] set to true unless it is set,
+// for the drivers whose Create writes atomically by default, so that Abort restores an existing file.
+// The config is copied, the map of the caller is left unchanged.
+func AtomicByDefault(config map[string]any) map[string]any {
+	if _, ok := config[AtomicKey]; ok {
+		return config
+	}
+	atomic := make(map[string]any, len(config)+1)
+	for key, value := range config {
+		atomic[key] = value
+	}
+	atomic[AtomicKey] = true
+	return atomic
+}
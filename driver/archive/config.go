@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Config struct {
+	// FileSystem stores the archive, it is read from the local file system if nil.
+	FileSystem fs.FileSystem
+	// Path is the path of the archive.
+	Path string
+	// Format is the format of the archive, detected from its extension or its content if empty.
+	Format Format
+	// TempDir is the local directory a zip archive is spooled to
+	// when its file system can't read ranges, default is os.TempDir().
+	TempDir string
+}
+
+func (c *Config) Apply(f *ArchiveFileSystem) error {
+	if c.Format != "" {
+		f.format = c.Format
+	}
+	if c.TempDir != "" {
+		f.tempDir = c.TempDir
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
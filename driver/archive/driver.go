@@ -0,0 +1,30 @@
+package archive
+
+import (
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+var driverName = "archive"
+
+func init() {
+	//goland:noinspection GoBoolExpressions
+	if driverName != "" {
+		filesystem.Register(driverName, &Driver{})
+	}
+}
+
+type Driver struct{}
+
+// Open opens the archive at the path of the file system of the options,
+// or at the local path if no file system is given.
+func (d *Driver) Open(options map[string]any) (fs.FileSystem, error) {
+	cfg, err := ConfigFromMap(options)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.FileSystem == nil {
+		return OpenLocal(cfg.Path, cfg)
+	}
+	return NewArchiveFileSystem(cfg.FileSystem, cfg.Path, cfg)
+}
@@ -0,0 +1,372 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gopi-frame/contract"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/visibility/unix"
+	"github.com/klauspost/compress/zstd"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+// ExportConfig configures how [Archive] exports a directory.
+type ExportConfig struct {
+	// Context cancels the export, default is context.Background().
+	Context context.Context
+	// Include restricts the exported files to the ones matching one of the patterns.
+	Include []string
+	// Exclude skips the files and directories matching one of the patterns.
+	Exclude []string
+	// VisibilityConvertor converts the visibility of the files to the modes stored in the archive.
+	VisibilityConvertor unix.VisibilityConvertor
+}
+
+func (c *ExportConfig) Apply(cfg *ExportConfig) error {
+	if c.Context != nil {
+		cfg.Context = c.Context
+	}
+	cfg.Include = append(cfg.Include, c.Include...)
+	cfg.Exclude = append(cfg.Exclude, c.Exclude...)
+	if c.VisibilityConvertor != nil {
+		cfg.VisibilityConvertor = c.VisibilityConvertor
+	}
+	return nil
+}
+
+type ExportOption = contract.Option[*ExportConfig]
+
+type ExportOptionFunc func(cfg *ExportConfig) error
+
+func (o ExportOptionFunc) Apply(cfg *ExportConfig) error {
+	return o(cfg)
+}
+
+// WithContext cancels the export when the context is done.
+func WithContext(ctx context.Context) ExportOption {
+	return ExportOptionFunc(func(cfg *ExportConfig) error {
+		cfg.Context = ctx
+		return nil
+	})
+}
+
+// WithInclude exports only the files matching one of the patterns.
+//
+// A pattern without a slash is matched against the name of the file,
+// otherwise against its path relative to the exported directory; "**" matches any number of directories.
+func WithInclude(patterns ...string) ExportOption {
+	return ExportOptionFunc(func(cfg *ExportConfig) error {
+		cfg.Include = append(cfg.Include, patterns...)
+		return nil
+	})
+}
+
+// WithExclude skips the files and directories matching one of the patterns, matched like [WithInclude].
+func WithExclude(patterns ...string) ExportOption {
+	return ExportOptionFunc(func(cfg *ExportConfig) error {
+		cfg.Exclude = append(cfg.Exclude, patterns...)
+		return nil
+	})
+}
+
+// WithExportVisibilityConvertor sets the convertor of the visibility of the files to the modes stored in the archive.
+func WithExportVisibilityConvertor(convertor unix.VisibilityConvertor) ExportOption {
+	return ExportOptionFunc(func(cfg *ExportConfig) error {
+		cfg.VisibilityConvertor = convertor
+		return nil
+	})
+}
+
+// archiveWriter writes the entries of an archive of one format.
+type archiveWriter interface {
+	writeDir(name string, mode gofs.FileMode, modTime time.Time) error
+	writeFile(name string, mode gofs.FileMode, modTime time.Time, size int64) (io.Writer, error)
+	writeSymlink(name, target string, modTime time.Time) error
+	Close() error
+}
+
+// Archive walks the directory of the file system and streams it as an archive of the format to the writer.
+//
+// Nothing is spooled: the content of every file is copied from the file system straight into the archive.
+// The entries are named after their paths relative to the directory, keep the modification times of the files,
+// and their modes are converted from their visibilities. Symbolic links are stored as links
+// when the file system implements [filesystem.Symlinker].
+// When include patterns are set, only the matching files are exported and the directories are left implicit.
+//
+// The archive is incomplete if an error is returned, the writer is not closed.
+func Archive(source fs.FileSystem, dir string, format Format, w io.Writer, opts ...ExportOption) error {
+	cfg := &ExportConfig{Context: context.Background()}
+	for _, opt := range opts {
+		if err := opt.Apply(cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.VisibilityConvertor == nil {
+		cfg.VisibilityConvertor = unix.New()
+	}
+	for _, pattern := range append(cfg.Include, cfg.Exclude...) {
+		if !validGlob(pattern) {
+			return fmt.Errorf("%w: %s", path.ErrBadPattern, pattern)
+		}
+	}
+	aw, err := newArchiveWriter(format, w)
+	if err != nil {
+		return err
+	}
+	err = filesystem.WalkDirRelative(source, dir, func(name string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := cfg.Context.Err(); err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		p := filesystem.JoinWalked(dir, name, d.IsDir())
+		if matchAny(cfg.Exclude, name) {
+			if d.IsDir() {
+				return gofs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if len(cfg.Include) > 0 {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			mode := cfg.VisibilityConvertor.DefaultForDir()
+			if visibility, err := source.Visibility(p); err == nil {
+				mode = cfg.VisibilityConvertor.ForDir(visibility)
+			}
+			return aw.writeDir(name, mode, info.ModTime())
+		}
+		if len(cfg.Include) > 0 && !matchAny(cfg.Include, name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.Type()&gofs.ModeSymlink != 0 {
+			if linker, ok := source.(filesystem.Symlinker); ok {
+				target, err := linker.Readlink(p)
+				if err == nil {
+					return aw.writeSymlink(name, target, info.ModTime())
+				}
+				if !errors.Is(err, filesystem.ErrSymlinkUnsupported) {
+					return err
+				}
+			}
+		}
+		return exportFile(cfg, source, aw, p, name, info)
+	})
+	if err != nil {
+		_ = aw.Close()
+		return err
+	}
+	return aw.Close()
+}
+
+func exportFile(cfg *ExportConfig, source fs.FileSystem, aw archiveWriter, p, name string, info gofs.FileInfo) error {
+	stream, err := source.ReadStream(p)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	size := info.Size()
+	if info.Mode()&gofs.ModeSymlink != 0 {
+		// the info of a link followed as a file may describe the link itself.
+		if size, err = source.FileSize(p); err != nil {
+			return err
+		}
+	}
+	mode := cfg.VisibilityConvertor.DefaultForFile()
+	if visibility, err := source.Visibility(p); err == nil {
+		mode = cfg.VisibilityConvertor.ForFile(visibility)
+	}
+	fw, err := aw.writeFile(name, mode, info.ModTime(), size)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(fw, &contextReader{ctx: cfg.Context, r: stream})
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return filesystem.NewUnableToReadFile(p, fmt.Errorf("read %d bytes, expected %d: %w", n, size, io.ErrUnexpectedEOF))
+	}
+	return nil
+}
+
+// contextReader stops reading once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// matchAny reports whether the slash-separated name matches one of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(name)); ok {
+				return true
+			}
+			continue
+		}
+		if matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func validGlob(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func newArchiveWriter(format Format, w io.Writer) (archiveWriter, error) {
+	switch format {
+	case FormatZip:
+		return &zipWriter{w: zip.NewWriter(w)}, nil
+	case FormatTar:
+		return &tarWriter{w: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		compressed := gzip.NewWriter(w)
+		return &tarWriter{w: tar.NewWriter(compressed), compressed: compressed}, nil
+	case FormatTarZst:
+		compressed, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &tarWriter{w: tar.NewWriter(compressed), compressed: compressed}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type zipWriter struct {
+	w *zip.Writer
+}
+
+func (z *zipWriter) writeDir(name string, mode gofs.FileMode, modTime time.Time) error {
+	header := &zip.FileHeader{Name: name + "/", Modified: modTime}
+	header.SetMode(gofs.ModeDir | mode.Perm())
+	_, err := z.w.CreateHeader(header)
+	return err
+}
+
+func (z *zipWriter) writeFile(name string, mode gofs.FileMode, modTime time.Time, size int64) (io.Writer, error) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+	header.SetMode(mode.Perm())
+	return z.w.CreateHeader(header)
+}
+
+func (z *zipWriter) writeSymlink(name, target string, modTime time.Time) error {
+	header := &zip.FileHeader{Name: name, Modified: modTime}
+	header.SetMode(gofs.ModeSymlink | 0777)
+	fw, err := z.w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(fw, target)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.w.Close()
+}
+
+type tarWriter struct {
+	w          *tar.Writer
+	compressed io.WriteCloser
+}
+
+func (t *tarWriter) writeDir(name string, mode gofs.FileMode, modTime time.Time) error {
+	return t.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     int64(mode.Perm()),
+		ModTime:  modTime,
+	})
+}
+
+func (t *tarWriter) writeFile(name string, mode gofs.FileMode, modTime time.Time, size int64) (io.Writer, error) {
+	err := t.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		ModTime:  modTime,
+		Size:     size,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.w, nil
+}
+
+func (t *tarWriter) writeSymlink(name, target string, modTime time.Time) error {
+	return t.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: target,
+		Mode:     0777,
+		ModTime:  modTime,
+	})
+}
+
+func (t *tarWriter) Close() error {
+	err := t.w.Close()
+	if t.compressed != nil {
+		if closeErr := t.compressed.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
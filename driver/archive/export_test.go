@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	gofs "io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/memory"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTree(t *testing.T) fs.FileSystem {
+	tree := memory.NewMemoryFileSystem("public", nil)
+	files := map[string]string{
+		"project/readme.md":         "# readme",
+		"project/src/main.go":       "package main",
+		"project/src/lib/lib.go":    "package lib",
+		"project/node_modules/x.js": "x",
+		"project/secret.env":        "TOKEN=1",
+	}
+	for name, content := range files {
+		if err := tree.Write(name, []byte(content), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if err := tree.SetVisibility("project/secret.env", "private"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return tree
+}
+
+// exported lists the files of an archive with their contents, read back with the archive driver.
+func exported(t *testing.T, content []byte, format Format) (*ArchiveFileSystem, map[string]string) {
+	store := memory.NewMemoryFileSystem("public", nil)
+	f := openFrom(t, store, "export"+format.Extension(), content)
+	files := map[string]string{}
+	err := f.WalkDir(".", func(path string, d gofs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := f.Read(path)
+		files[path] = string(content)
+		return err
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return f, files
+}
+
+func TestArchive(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz, FormatTarZst} {
+		t.Run(string(format), func(t *testing.T) {
+			tree := newTree(t)
+			var buf bytes.Buffer
+			if err := Archive(tree, "project", format, &buf); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			f, files := exported(t, buf.Bytes(), format)
+			assert.Equal(t, map[string]string{
+				"readme.md":         "# readme",
+				"src/main.go":       "package main",
+				"src/lib/lib.go":    "package lib",
+				"node_modules/x.js": "x",
+				"secret.env":        "TOKEN=1",
+			}, files)
+			visibility, _ := f.Visibility("secret.env")
+			assert.Equal(t, "private", visibility)
+			visibility, _ = f.Visibility("readme.md")
+			assert.Equal(t, "public", visibility)
+			expected, _ := tree.LastModified("project/readme.md")
+			lastModified, _ := f.LastModified("readme.md")
+			assert.WithinDuration(t, expected, lastModified, 2*time.Second)
+		})
+	}
+}
+
+// objectStore walks like the object stores: a flat listing of the keys under the prefix,
+// with a trailing slash for the directories.
+type objectStore struct {
+	fs.FileSystem
+}
+
+func (s *objectStore) WalkDir(dir string, walkFn gofs.WalkDirFunc) error {
+	if !strings.HasSuffix(dir, "/") {
+		return filesystem.NewUnableToReadDirectory(dir, filesystem.ErrIsNotDirectory)
+	}
+	return s.FileSystem.WalkDir(strings.TrimSuffix(dir, "/"), func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			p += "/"
+		}
+		return walkFn(p, d, nil)
+	})
+}
+
+// rootedStore walks like the local driver, the paths are joined with its root.
+type rootedStore struct {
+	fs.FileSystem
+	root string
+}
+
+func (s *rootedStore) WalkDir(dir string, walkFn gofs.WalkDirFunc) error {
+	return s.FileSystem.WalkDir(dir, func(p string, d gofs.DirEntry, err error) error {
+		return walkFn(path.Join(s.root, p), d, err)
+	})
+}
+
+func TestArchive_WalkedPaths(t *testing.T) {
+	expected := map[string]string{
+		"readme.md":         "# readme",
+		"src/main.go":       "package main",
+		"src/lib/lib.go":    "package lib",
+		"node_modules/x.js": "x",
+		"secret.env":        "TOKEN=1",
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		for _, dir := range []string{"project", "project/", "./project"} {
+			var buf bytes.Buffer
+			if err := Archive(newTree(t), dir, FormatTar, &buf); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			_, files := exported(t, buf.Bytes(), FormatTar)
+			assert.Equal(t, expected, files, dir)
+		}
+	})
+
+	t.Run("object store", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Archive(&objectStore{newTree(t)}, "project/", FormatZip, &buf); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		f, files := exported(t, buf.Bytes(), FormatZip)
+		assert.Equal(t, expected, files)
+		exists, err := f.DirExists("src/lib")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+	})
+
+	t.Run("rooted", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Archive(&rootedStore{FileSystem: newTree(t), root: "/srv/files"}, "project", FormatZip, &buf); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, files := exported(t, buf.Bytes(), FormatZip)
+		assert.Equal(t, expected, files)
+	})
+
+	t.Run("outside", func(t *testing.T) {
+		// a path which is not under the walked directory is an error, not a misnamed entry
+		store := &rootedStore{FileSystem: newTree(t), root: "/srv/files"}
+		err := Archive(&outsideStore{store}, "project", FormatZip, &bytes.Buffer{})
+		assert.ErrorIs(t, err, filesystem.ErrOutsideWalk)
+	})
+}
+
+// outsideStore yields a path out of the walked directory after its root.
+type outsideStore struct {
+	*rootedStore
+}
+
+func (s *outsideStore) WalkDir(dir string, walkFn gofs.WalkDirFunc) error {
+	first := true
+	return s.rootedStore.WalkDir(dir, func(p string, d gofs.DirEntry, err error) error {
+		if first {
+			first = false
+			if err := walkFn(p, d, err); err != nil {
+				return err
+			}
+			return walkFn("/srv/other/file.txt", d, nil)
+		}
+		return walkFn(p, d, err)
+	})
+}
+
+func TestArchive_Filters(t *testing.T) {
+	t.Run("exclude", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Archive(newTree(t), "project", FormatZip, &buf, WithExclude("node_modules", "*.env"))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, files := exported(t, buf.Bytes(), FormatZip)
+		var names []string
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		assert.Equal(t, []string{"readme.md", "src/lib/lib.go", "src/main.go"}, names)
+	})
+
+	t.Run("include", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Archive(newTree(t), "project", FormatTar, &buf, WithInclude("src/**/*.go"), WithExclude("lib"))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, files := exported(t, buf.Bytes(), FormatTar)
+		assert.Equal(t, map[string]string{"src/main.go": "package main"}, files)
+	})
+
+	t.Run("bad pattern", func(t *testing.T) {
+		err := Archive(newTree(t), "project", FormatZip, &bytes.Buffer{}, WithInclude("[a-"))
+		assert.Error(t, err)
+	})
+}
+
+func TestArchive_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Archive(newTree(t), "project", FormatZip, &bytes.Buffer{}, WithContext(ctx))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestServeArchive(t *testing.T) {
+	tree := newTree(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = ServeArchive(w, r, tree, "project/src", FormatTarGz)
+	})
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download", nil))
+	assert.Equal(t, "application/gzip", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=src.tar.gz`, recorder.Header().Get("Content-Disposition"))
+	_, files := exported(t, recorder.Body.Bytes(), FormatTarGz)
+	assert.Equal(t, map[string]string{"main.go": "package main", "lib/lib.go": "package lib"}, files)
+}
@@ -0,0 +1,579 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopi-frame/contract"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/visibility/unix"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+// ConflictPolicy decides what [Extract] does with an entry whose destination file exists.
+type ConflictPolicy string
+
+const (
+	// ConflictFail aborts the extraction with a [filesystem.PreconditionFailed] error. It is the default.
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictSkip keeps the existing file and skips the entry.
+	ConflictSkip ConflictPolicy = "skip"
+)
+
+const (
+	// DefaultMaxEntries is the default maximum number of entries extracted from an archive.
+	DefaultMaxEntries = 10000
+	// DefaultMaxSize is the default maximum number of bytes extracted from an archive.
+	DefaultMaxSize = 1 << 30
+)
+
+// ErrUnsafePath is returned for an entry whose path or link target leads outside of the destination directory.
+var ErrUnsafePath = errors.New("archive entry outside of the destination")
+
+// ErrTooManyEntries is returned when an archive has more entries than allowed.
+var ErrTooManyEntries = errors.New("archive has too many entries")
+
+// ErrTooLarge is returned when the extracted content of an archive or of an entry is larger than allowed.
+var ErrTooLarge = errors.New("archive content is too large")
+
+// ExtractConfig configures how [Extract] unpacks an archive.
+type ExtractConfig struct {
+	// Context cancels the extraction, default is context.Background().
+	Context context.Context
+	// Conflict is the policy applied to the entries whose destination exists, default is [ConflictFail].
+	Conflict ConflictPolicy
+	// MaxEntries is the maximum number of entries, default is [DefaultMaxEntries], negative for no limit.
+	MaxEntries int
+	// MaxSize is the maximum number of bytes extracted over all entries,
+	// default is [DefaultMaxSize], negative for no limit.
+	MaxSize int64
+	// MaxFileSize is the maximum number of bytes extracted from one entry, zero or negative for no limit.
+	MaxFileSize int64
+	// Progress receives the progress of every extracted file, with the overall progress of the archive.
+	Progress filesystem.ProgressFunc
+	// TempDir is the local directory a zip stream is spooled to, default is os.TempDir().
+	TempDir string
+	// VisibilityConvertor converts the modes stored in the archive to the visibilities of the files.
+	VisibilityConvertor unix.VisibilityConvertor
+}
+
+func (c *ExtractConfig) Apply(cfg *ExtractConfig) error {
+	if c.Context != nil {
+		cfg.Context = c.Context
+	}
+	if c.Conflict != "" {
+		cfg.Conflict = c.Conflict
+	}
+	if c.MaxEntries != 0 {
+		cfg.MaxEntries = c.MaxEntries
+	}
+	if c.MaxSize != 0 {
+		cfg.MaxSize = c.MaxSize
+	}
+	if c.MaxFileSize != 0 {
+		cfg.MaxFileSize = c.MaxFileSize
+	}
+	if c.Progress != nil {
+		cfg.Progress = c.Progress
+	}
+	if c.TempDir != "" {
+		cfg.TempDir = c.TempDir
+	}
+	if c.VisibilityConvertor != nil {
+		cfg.VisibilityConvertor = c.VisibilityConvertor
+	}
+	return nil
+}
+
+type ExtractOption = contract.Option[*ExtractConfig]
+
+type ExtractOptionFunc func(cfg *ExtractConfig) error
+
+func (o ExtractOptionFunc) Apply(cfg *ExtractConfig) error {
+	return o(cfg)
+}
+
+// WithExtractContext cancels the extraction when the context is done.
+func WithExtractContext(ctx context.Context) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.Context = ctx
+		return nil
+	})
+}
+
+// WithConflictPolicy sets the policy applied to the entries whose destination exists.
+func WithConflictPolicy(policy ConflictPolicy) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		switch policy {
+		case ConflictFail, ConflictOverwrite, ConflictSkip:
+			cfg.Conflict = policy
+			return nil
+		}
+		return fmt.Errorf("unknown conflict policy: %s", policy)
+	})
+}
+
+// WithMaxEntries sets the maximum number of entries of the archive, negative for no limit.
+func WithMaxEntries(n int) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.MaxEntries = n
+		return nil
+	})
+}
+
+// WithMaxSize sets the maximum number of bytes extracted over all entries, negative for no limit.
+func WithMaxSize(n int64) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.MaxSize = n
+		return nil
+	})
+}
+
+// WithMaxFileSize sets the maximum number of bytes extracted from one entry.
+func WithMaxFileSize(n int64) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.MaxFileSize = n
+		return nil
+	})
+}
+
+// WithExtractProgress sets the callback receiving the progress of every extracted file.
+func WithExtractProgress(fn filesystem.ProgressFunc) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.Progress = fn
+		return nil
+	})
+}
+
+// WithExtractTempDir sets the local directory a zip stream is spooled to.
+func WithExtractTempDir(dir string) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.TempDir = dir
+		return nil
+	})
+}
+
+// WithExtractVisibilityConvertor sets the convertor of the modes stored in the archive to visibilities.
+func WithExtractVisibilityConvertor(convertor unix.VisibilityConvertor) ExtractOption {
+	return ExtractOptionFunc(func(cfg *ExtractConfig) error {
+		cfg.VisibilityConvertor = convertor
+		return nil
+	})
+}
+
+func newExtractConfig(opts []ExtractOption) (*ExtractConfig, error) {
+	cfg := &ExtractConfig{
+		Context:    context.Background(),
+		Conflict:   ConflictFail,
+		MaxEntries: DefaultMaxEntries,
+		MaxSize:    DefaultMaxSize,
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.VisibilityConvertor == nil {
+		cfg.VisibilityConvertor = unix.New()
+	}
+	return cfg, nil
+}
+
+// Extract unpacks the archive read from the stream into the directory of the file system,
+// streaming every file into WriteStream. The format is detected from the content if empty.
+//
+// A zip archive needs random access: the stream is used as is if it implements io.ReaderAt and io.Seeker,
+// otherwise it is spooled to a local temporary file.
+// Directories are created with CreateDir, modification times are kept when the file system implements
+// [filesystem.Toucher], and the modes are converted to visibilities.
+// Symbolic links are created when the file system implements [filesystem.Symlinker], skipped otherwise;
+// hard links are extracted as copies of their targets.
+//
+// The extraction is guarded against hostile archives:
+//   - an entry whose path is absolute or leads outside of the directory, or is under an extracted link,
+//     and a link whose target leads outside of the directory, fail with [ErrUnsafePath];
+//   - more entries than [ExtractConfig.MaxEntries] fail with [ErrTooManyEntries];
+//   - more bytes than [ExtractConfig.MaxSize] or [ExtractConfig.MaxFileSize] fail with [ErrTooLarge],
+//     counting the bytes actually decompressed rather than the sizes declared by the archive.
+//
+// The entries extracted before an error are left in place.
+func Extract(r io.Reader, format Format, dst fs.FileSystem, dstDir string, opts ...ExtractOption) error {
+	cfg, err := newExtractConfig(opts)
+	if err != nil {
+		return err
+	}
+	stream := r
+	if format == "" {
+		buffered := bufio.NewReaderSize(r, sniffSize)
+		header, err := buffered.Peek(sniffSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if format = formatFromContent(header); format == "" {
+			return ErrUnknownFormat
+		}
+		stream = buffered
+	}
+	if !format.valid() {
+		return ErrUnknownFormat
+	}
+	x := newExtractor(cfg, dst, dstDir)
+	if format != FormatZip {
+		return x.tar(format, stream)
+	}
+	if ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		size, err := ra.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		return x.zip(ra, size)
+	}
+	spooled, err := spool(cfg.TempDir, stream)
+	if err != nil {
+		return err
+	}
+	defer spooled.close()
+	return x.zip(spooled.file, spooled.size)
+}
+
+// ExtractAt unpacks the archive of the given size read from r, like [Extract].
+func ExtractAt(r io.ReaderAt, size int64, format Format, dst fs.FileSystem, dstDir string, opts ...ExtractOption) error {
+	if format == "" {
+		header := make([]byte, sniffSize)
+		n, err := r.ReadAt(header, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if format = formatFromContent(header[:n]); format == "" {
+			return ErrUnknownFormat
+		}
+	}
+	if format != FormatZip {
+		return Extract(io.NewSectionReader(r, 0, size), format, dst, dstDir, opts...)
+	}
+	cfg, err := newExtractConfig(opts)
+	if err != nil {
+		return err
+	}
+	return newExtractor(cfg, dst, dstDir).zip(r, size)
+}
+
+type extractor struct {
+	cfg      *ExtractConfig
+	dst      fs.FileSystem
+	dir      string
+	entries  int
+	size     int64
+	links    map[string]bool
+	progress *filesystem.AggregateProgress
+}
+
+func newExtractor(cfg *ExtractConfig, dst fs.FileSystem, dstDir string) *extractor {
+	return &extractor{
+		cfg:   cfg,
+		dst:   dst,
+		dir:   filesystem.CleanPath(dstDir),
+		links: map[string]bool{},
+	}
+}
+
+func (x *extractor) zip(r io.ReaderAt, size int64) error {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	// the declared sizes may lie, they only reject obvious bombs early; the bytes read are counted anyway.
+	if x.cfg.MaxEntries >= 0 && len(reader.File) > x.cfg.MaxEntries {
+		return ErrTooManyEntries
+	}
+	var total uint64
+	var files int
+	for _, file := range reader.File {
+		if file.Mode().IsRegular() {
+			total += file.UncompressedSize64
+			files++
+		}
+	}
+	if x.cfg.MaxSize >= 0 && total > uint64(x.cfg.MaxSize) {
+		return ErrTooLarge
+	}
+	x.progress = filesystem.NewAggregateProgress(files, int64(total), x.cfg.Progress)
+	for _, file := range reader.File {
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.extractDir(file.Name, mode, file.Modified)
+		case mode&gofs.ModeSymlink != 0:
+			var target []byte
+			if target, err = readZipFile(file, maxLinkSize); err == nil {
+				err = x.extractSymlink(file.Name, string(target))
+			}
+		case mode.IsRegular():
+			err = x.extractFile(file.Name, mode, file.Modified, int64(file.UncompressedSize64), file.Open)
+		default:
+			err = x.count()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *extractor) tar(format Format, r io.Reader) error {
+	decompressed, err := decompress(format, r)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+	x.progress = filesystem.NewAggregateProgress(-1, -1, x.cfg.Progress)
+	reader := tar.NewReader(decompressed)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.extractDir(header.Name, mode, header.ModTime)
+		case tar.TypeSymlink:
+			err = x.extractSymlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = x.extractHardLink(header.Name, header.Linkname, mode, header.ModTime)
+		case tar.TypeReg, tar.TypeGNUSparse:
+			err = x.extractFile(header.Name, mode, header.ModTime, header.Size, func() (io.ReadCloser, error) {
+				return io.NopCloser(reader), nil
+			})
+		default:
+			err = x.count()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// count counts an entry against the limit, and checks whether the extraction is cancelled.
+func (x *extractor) count() error {
+	if err := x.cfg.Context.Err(); err != nil {
+		return err
+	}
+	x.entries++
+	if x.cfg.MaxEntries >= 0 && x.entries > x.cfg.MaxEntries {
+		return ErrTooManyEntries
+	}
+	return nil
+}
+
+// target returns the destination of an entry, it fails if the entry would be written outside of the directory
+// or through a link extracted before.
+func (x *extractor) target(name string) (string, error) {
+	rel, err := safeRelative(name)
+	if err != nil {
+		return "", err
+	}
+	for parent := path.Dir(rel); parent != "."; parent = path.Dir(parent) {
+		if x.links[parent] {
+			return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+	if x.dir == "." {
+		return rel, nil
+	}
+	return path.Join(x.dir, rel), nil
+}
+
+// safeRelative cleans the name of an entry, it fails if the name is absolute or leads outside of the root.
+func safeRelative(name string) (string, error) {
+	name = filepath.ToSlash(name)
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") ||
+		len(clean) >= 2 && clean[1] == ':' || clean == "." || name == "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return clean, nil
+}
+
+// conflict applies the conflict policy to an existing destination, and reports whether it exists.
+func (x *extractor) conflict(p string) (skip bool, exists bool, err error) {
+	exists, err = x.dst.Exists(p)
+	if err != nil || !exists {
+		return false, false, err
+	}
+	switch x.cfg.Conflict {
+	case ConflictOverwrite:
+		return false, true, nil
+	case ConflictSkip:
+		return true, true, nil
+	default:
+		return false, true, filesystem.NewPreconditionFailed(p, os.ErrExist)
+	}
+}
+
+func (x *extractor) extractDir(name string, mode gofs.FileMode, modTime time.Time) error {
+	if err := x.count(); err != nil {
+		return err
+	}
+	if clean := path.Clean(filepath.ToSlash(name)); clean == "." || clean == "/" {
+		return nil
+	}
+	p, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	config := map[string]any{}
+	if mode.Perm() != 0 {
+		config[filesystem.DirVisibilityKey] = x.cfg.VisibilityConvertor.InverseForDir(mode.Perm())
+	}
+	if err := x.dst.CreateDir(p, config); err != nil {
+		return err
+	}
+	return x.touch(p, modTime)
+}
+
+func (x *extractor) extractFile(name string, mode gofs.FileMode, modTime time.Time, size int64, open func() (io.ReadCloser, error)) error {
+	if err := x.count(); err != nil {
+		return err
+	}
+	p, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	skip, exists, err := x.conflict(p)
+	if err != nil || skip {
+		return err
+	}
+	if x.cfg.MaxFileSize > 0 && size > x.cfg.MaxFileSize {
+		return filesystem.NewUnableToWriteFile(p, ErrTooLarge)
+	}
+	rc, err := open()
+	if err != nil {
+		return filesystem.NewUnableToReadFile(name, err)
+	}
+	defer rc.Close()
+	progress, finish := x.progress.File(p, size)
+	tracker := filesystem.NewProgressTracker(p, size, progress)
+	limited := &limitReader{x: x, r: &contextReader{ctx: x.cfg.Context, r: rc}}
+	config := map[string]any{}
+	if mode.Perm() != 0 {
+		config[filesystem.FileVisibilityKey] = x.cfg.VisibilityConvertor.InverseForFile(mode.Perm())
+	}
+	if exists {
+		// an overwritten file is only replaced once the entry is complete, so a rejected entry leaves it as it was
+		config[filesystem.AtomicKey] = true
+	}
+	if err := x.dst.WriteStream(p, tracker.ReadCloser(io.NopCloser(limited)), config); err != nil {
+		if limited.err != nil {
+			// do not leave the truncated content of a rejected entry behind, only the files extracted are removed.
+			if !exists {
+				_ = x.dst.Delete(p)
+			}
+			return filesystem.NewUnableToWriteFile(p, limited.err)
+		}
+		return err
+	}
+	tracker.Done()
+	finish()
+	return x.touch(p, modTime)
+}
+
+func (x *extractor) extractSymlink(name, target string) error {
+	if err := x.count(); err != nil {
+		return err
+	}
+	p, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	rel, _ := safeRelative(name)
+	if path.IsAbs(filepath.ToSlash(target)) {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafePath, name, target)
+	}
+	if _, err := safeRelative(path.Join(path.Dir(rel), filepath.ToSlash(target))); err != nil {
+		return fmt.Errorf("%w: %s -> %s", ErrUnsafePath, name, target)
+	}
+	linker, ok := x.dst.(filesystem.Symlinker)
+	if !ok {
+		return nil
+	}
+	if skip, _, err := x.conflict(p); err != nil || skip {
+		return err
+	}
+	if err := linker.Symlink(target, p); err != nil {
+		if errors.Is(err, filesystem.ErrSymlinkUnsupported) {
+			return nil
+		}
+		return err
+	}
+	x.links[rel] = true
+	return nil
+}
+
+// extractHardLink extracts a hard link as a copy of its target, read back from the destination.
+func (x *extractor) extractHardLink(name, target string, mode gofs.FileMode, modTime time.Time) error {
+	src, err := x.target(target)
+	if err != nil {
+		return err
+	}
+	size, err := x.dst.FileSize(src)
+	if err != nil {
+		return err
+	}
+	return x.extractFile(name, mode, modTime, size, func() (io.ReadCloser, error) {
+		return x.dst.ReadStream(src)
+	})
+}
+
+func (x *extractor) touch(p string, modTime time.Time) error {
+	toucher, ok := x.dst.(filesystem.Toucher)
+	if !ok || modTime.IsZero() {
+		return nil
+	}
+	if err := toucher.SetLastModified(p, modTime); err != nil && !errors.Is(err, filesystem.ErrSetLastModifiedUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// limitReader fails once more bytes than allowed are extracted.
+type limitReader struct {
+	x     *extractor
+	r     io.Reader
+	bytes int64
+	err   error
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.bytes += int64(n)
+	l.x.size += int64(n)
+	if l.x.cfg.MaxFileSize > 0 && l.bytes > l.x.cfg.MaxFileSize || l.x.cfg.MaxSize >= 0 && l.x.size > l.x.cfg.MaxSize {
+		l.err = ErrTooLarge
+		return n, l.err
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		l.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,279 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	gofs "io/fs"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tarEntry struct {
+	header  *tar.Header
+	content string
+}
+
+func tarOf(t *testing.T, entries ...tarEntry) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.content))
+		if entry.header.Mode == 0 {
+			entry.header.Mode = 0644
+		}
+		if err := w.WriteHeader(entry.header); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := io.WriteString(w, entry.content); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return buf.Bytes()
+}
+
+type zipEntry struct {
+	name    string
+	mode    gofs.FileMode
+	content string
+}
+
+func zipOf(t *testing.T, entries ...zipEntry) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name}
+		if entry.mode == 0 {
+			entry.mode = 0644
+		}
+		header.SetMode(entry.mode)
+		file, err := w.CreateHeader(header)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := io.WriteString(file, entry.content); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz, FormatTarZst} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Archive(newTree(t), "project", format, &buf); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			dst := memory.NewMemoryFileSystem("public", nil)
+			// a plain stream, the zip archive is spooled and the format detected from the content.
+			err := Extract(struct{ io.Reader }{&buf}, "", dst, "out", WithExtractTempDir(t.TempDir()))
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			content, err := dst.Read("out/src/lib/lib.go")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "package lib", string(content))
+			visibility, _ := dst.Visibility("out/secret.env")
+			assert.Equal(t, "private", visibility)
+		})
+	}
+
+	t.Run("reader at", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Archive(newTree(t), "project", FormatZip, &buf); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		dst := memory.NewMemoryFileSystem("public", nil)
+		if err := ExtractAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "", dst, "."); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, _ := dst.FileExists("src/main.go")
+		assert.True(t, exists)
+	})
+
+	t.Run("links", func(t *testing.T) {
+		archive := tarOf(t,
+			tarEntry{header: &tar.Header{Name: "docs/a.txt", Typeflag: tar.TypeReg}, content: "a"},
+			tarEntry{header: &tar.Header{Name: "docs/b.txt", Typeflag: tar.TypeLink, Linkname: "docs/a.txt"}},
+			tarEntry{header: &tar.Header{Name: "latest", Typeflag: tar.TypeSymlink, Linkname: "docs/a.txt"}},
+		)
+		dst := memory.NewMemoryFileSystem("public", nil)
+		if err := Extract(bytes.NewReader(archive), FormatTar, dst, "out"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := dst.Read("out/docs/b.txt")
+		assert.Equal(t, "a", string(content))
+		target, err := dst.Readlink("out/latest")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "docs/a.txt", target)
+	})
+}
+
+func TestExtract_Unsafe(t *testing.T) {
+	cases := map[string][]byte{
+		"parent":   tarOf(t, tarEntry{header: &tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg}, content: "x"}),
+		"absolute": tarOf(t, tarEntry{header: &tar.Header{Name: "/etc/passwd", Typeflag: tar.TypeReg}, content: "x"}),
+		"nested":   tarOf(t, tarEntry{header: &tar.Header{Name: "a/../../evil.txt", Typeflag: tar.TypeReg}, content: "x"}),
+		"link target": tarOf(t,
+			tarEntry{header: &tar.Header{Name: "a/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}}),
+		"absolute link target": tarOf(t,
+			tarEntry{header: &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}}),
+		"through link": tarOf(t,
+			tarEntry{header: &tar.Header{Name: "sub", Typeflag: tar.TypeDir}},
+			tarEntry{header: &tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "sub"}},
+			tarEntry{header: &tar.Header{Name: "link/x.txt", Typeflag: tar.TypeReg}, content: "x"}),
+		"hard link": tarOf(t,
+			tarEntry{header: &tar.Header{Name: "x.txt", Typeflag: tar.TypeLink, Linkname: "../secret"}}),
+	}
+	for name, archive := range cases {
+		t.Run(name, func(t *testing.T) {
+			dst := memory.NewMemoryFileSystem("public", nil)
+			err := Extract(bytes.NewReader(archive), FormatTar, dst, "out")
+			assert.ErrorIs(t, err, ErrUnsafePath)
+			exists, _ := dst.Exists("evil.txt")
+			assert.False(t, exists)
+		})
+	}
+
+	zipCases := map[string][]byte{
+		"zip parent":   zipOf(t, zipEntry{name: "../evil.txt", content: "x"}),
+		"zip absolute": zipOf(t, zipEntry{name: "/etc/passwd", content: "x"}),
+		"zip nested":   zipOf(t, zipEntry{name: "a/../../evil.txt", content: "x"}),
+		"zip link target": zipOf(t,
+			zipEntry{name: "a/link", mode: gofs.ModeSymlink | 0777, content: "../../etc"}),
+		"zip absolute link target": zipOf(t,
+			zipEntry{name: "link", mode: gofs.ModeSymlink | 0777, content: "/etc"}),
+		"zip through link": zipOf(t,
+			zipEntry{name: "sub/", mode: gofs.ModeDir | 0755},
+			zipEntry{name: "link", mode: gofs.ModeSymlink | 0777, content: "sub"},
+			zipEntry{name: "link/x.txt", content: "x"}),
+	}
+	for name, archive := range zipCases {
+		t.Run(name, func(t *testing.T) {
+			dst := memory.NewMemoryFileSystem("public", nil)
+			err := ExtractAt(bytes.NewReader(archive), int64(len(archive)), FormatZip, dst, "out")
+			assert.ErrorIs(t, err, ErrUnsafePath)
+			exists, _ := dst.Exists("evil.txt")
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestExtract_Limits(t *testing.T) {
+	big := strings.Repeat("0", 4096)
+	archive := tarOf(t,
+		tarEntry{header: &tar.Header{Name: "a.txt", Typeflag: tar.TypeReg}, content: big},
+		tarEntry{header: &tar.Header{Name: "b.txt", Typeflag: tar.TypeReg}, content: big},
+	)
+
+	t.Run("size", func(t *testing.T) {
+		dst := memory.NewMemoryFileSystem("public", nil)
+		err := Extract(bytes.NewReader(gzipped(t, archive)), FormatTarGz, dst, ".", WithMaxSize(6000))
+		assert.ErrorIs(t, err, ErrTooLarge)
+		exists, _ := dst.FileExists("a.txt")
+		assert.True(t, exists)
+		exists, _ = dst.FileExists("b.txt")
+		assert.False(t, exists)
+	})
+
+	t.Run("file size", func(t *testing.T) {
+		err := Extract(bytes.NewReader(archive), FormatTar, memory.NewMemoryFileSystem("public", nil), ".", WithMaxFileSize(1000))
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("entries", func(t *testing.T) {
+		err := Extract(bytes.NewReader(archive), FormatTar, memory.NewMemoryFileSystem("public", nil), ".", WithMaxEntries(1))
+		assert.ErrorIs(t, err, ErrTooManyEntries)
+	})
+
+	t.Run("overwritten file kept", func(t *testing.T) {
+		dst := memory.NewMemoryFileSystem("public", nil)
+		if err := dst.Write("b.txt", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		err := Extract(bytes.NewReader(archive), FormatTar, dst, ".", WithMaxSize(6000), WithConflictPolicy(ConflictOverwrite))
+		assert.ErrorIs(t, err, ErrTooLarge)
+		content, err := dst.Read("b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "old", string(content))
+	})
+}
+
+func TestExtract_Conflict(t *testing.T) {
+	archive := tarOf(t, tarEntry{header: &tar.Header{Name: "a.txt", Typeflag: tar.TypeReg}, content: "new"})
+	cases := map[ConflictPolicy]string{
+		ConflictOverwrite: "new",
+		ConflictSkip:      "old",
+	}
+	for policy, expected := range cases {
+		t.Run(string(policy), func(t *testing.T) {
+			dst := memory.NewMemoryFileSystem("public", nil)
+			if err := dst.Write("a.txt", []byte("old"), nil); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			if err := Extract(bytes.NewReader(archive), FormatTar, dst, ".", WithConflictPolicy(policy)); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			content, _ := dst.Read("a.txt")
+			assert.Equal(t, expected, string(content))
+		})
+	}
+
+	t.Run(string(ConflictFail), func(t *testing.T) {
+		dst := memory.NewMemoryFileSystem("public", nil)
+		if err := dst.Write("a.txt", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		err := Extract(bytes.NewReader(archive), FormatTar, dst, ".")
+		var precondition *filesystem.PreconditionFailed
+		assert.ErrorAs(t, err, &precondition)
+	})
+}
+
+func TestExtract_Progress(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Archive(newTree(t), "project", FormatZip, &buf); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	var mu sync.Mutex
+	var last filesystem.Progress
+	done := map[string]bool{}
+	err := ExtractAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()), FormatZip, memory.NewMemoryFileSystem("public", nil), "out",
+		WithExtractProgress(func(p filesystem.Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			if p.Done {
+				done[p.Path] = true
+			}
+			last = p
+		}))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Len(t, done, 5)
+	assert.True(t, done["out/src/main.go"])
+	if assert.NotNil(t, last.Overall) {
+		assert.Equal(t, 5, last.Overall.Files)
+		assert.Equal(t, 5, last.Overall.TotalFiles)
+	}
+}
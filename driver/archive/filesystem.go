@@ -0,0 +1,389 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+	"github.com/gopi-frame/filesystem/visibility/unix"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+// maxLinkHops bounds the links followed to resolve an entry.
+const maxLinkHops = 40
+
+var ErrNoArchive = errors.New("archive path is required")
+
+var ErrTooManyLinks = errors.New("too many levels of links")
+
+// ArchiveFileSystem is a read-only file system over the entries of a zip, tar, tar.gz or tar.zst archive.
+//
+// A zip archive is read through its central directory. It is accessed as ranges of the archive
+// when the file system storing it implements [filesystem.RangeReader], or is a local file,
+// otherwise it is first spooled to a local temporary file.
+//
+// A tar archive is indexed in one pass over its stream. The content of a plain tar is then read as ranges
+// when the archive supports random access, otherwise the archive is decompressed to a local temporary file
+// while it is indexed, and the entries are read as ranges of this file.
+//
+// The ranges of a remote archive are read by blocks, the last blocks read are kept in memory.
+//
+// Every write fails with an error wrapping [readonly.ErrReadOnly].
+//
+// Directories missing from the archive are implied by the paths of their entries.
+// Symbolic and hard links are listed as they are, and followed by the read methods;
+// links are not followed in the middle of a path.
+type ArchiveFileSystem struct {
+	source  source
+	format  Format
+	tempDir string
+
+	index    *index
+	readerAt io.ReaderAt
+	size     int64
+
+	mimetypeDetector    fs.MimeTypeDetector
+	visibilityConvertor unix.VisibilityConvertor
+}
+
+// NewArchiveFileSystem opens the archive at the path of the given file system.
+func NewArchiveFileSystem(archive fs.FileSystem, path string, opts ...Option) (*ArchiveFileSystem, error) {
+	if path == "" {
+		return nil, ErrNoArchive
+	}
+	return newArchiveFileSystem(&fsSource{fs: archive, path: path}, path, opts)
+}
+
+// OpenLocal opens the archive at the path of the local file system.
+func OpenLocal(path string, opts ...Option) (*ArchiveFileSystem, error) {
+	if path == "" {
+		return nil, ErrNoArchive
+	}
+	src, err := openFileSource(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newArchiveFileSystem(src, path, opts)
+	if err != nil {
+		_ = src.close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func newArchiveFileSystem(src source, name string, opts []Option) (*ArchiveFileSystem, error) {
+	f := &ArchiveFileSystem{
+		source: src,
+		index:  newIndex(),
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if f.mimetypeDetector == nil {
+		f.mimetypeDetector = filesystem.NewMimeTypeDetector()
+	}
+	if f.visibilityConvertor == nil {
+		f.visibilityConvertor = unix.New()
+	}
+	if f.format == "" {
+		f.format = formatFromPath(name)
+	}
+	if f.format == "" {
+		format, err := f.sniff()
+		if err != nil {
+			return nil, err
+		}
+		f.format = format
+	}
+	var err error
+	switch f.format {
+	case FormatZip:
+		err = f.openZip()
+	case FormatTar, FormatTarGz, FormatTarZst:
+		err = f.openTar()
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *ArchiveFileSystem) sniff() (Format, error) {
+	rc, err := f.source.open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	header := make([]byte, sniffSize)
+	n, err := io.ReadFull(rc, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if format := formatFromContent(header[:n]); format != "" {
+		return format, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f *ArchiveFileSystem) openZip() error {
+	readerAt, size, err := f.source.readerAt()
+	if err != nil {
+		return err
+	}
+	if readerAt == nil {
+		rc, err := f.source.open()
+		if err != nil {
+			return err
+		}
+		spooled, err := spool(f.tempDir, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+		f.source = spooled
+		readerAt, size, _ = spooled.readerAt()
+	}
+	f.readerAt, f.size = readerAt, size
+	return f.indexZip()
+}
+
+func (f *ArchiveFileSystem) openTar() error {
+	if f.format == FormatTar {
+		readerAt, size, err := f.source.readerAt()
+		if err != nil {
+			return err
+		}
+		f.readerAt, f.size = readerAt, size
+	}
+	return f.indexTar()
+}
+
+// Format returns the format of the archive.
+func (f *ArchiveFileSystem) Format() Format {
+	return f.format
+}
+
+// Close releases the local file of the archive, and removes it if it was spooled.
+func (f *ArchiveFileSystem) Close() error {
+	return f.source.close()
+}
+
+// Stat returns the information of an entry, links are not followed.
+func (f *ArchiveFileSystem) Stat(path string) (gofs.FileInfo, error) {
+	e, ok := f.index.lookup(path)
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+	return e, nil
+}
+
+// stat returns the entry of a path, following its links.
+func (f *ArchiveFileSystem) stat(p string) (*entry, error) {
+	e, ok := f.index.lookup(p)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	for hops := 0; e.link != ""; hops++ {
+		if hops == maxLinkHops {
+			return nil, ErrTooManyLinks
+		}
+		target := e.link
+		if !e.hard && !path.IsAbs(target) {
+			target = path.Join(path.Dir(e.path), target)
+		}
+		if e, ok = f.index.lookup(target); !ok {
+			return nil, os.ErrNotExist
+		}
+	}
+	return e, nil
+}
+
+func (f *ArchiveFileSystem) open(e *entry) (io.ReadCloser, error) {
+	if e.zipFile != nil {
+		return e.zipFile.Open()
+	}
+	return f.openTarEntry(e)
+}
+
+func (f *ArchiveFileSystem) Exists(path string) (bool, error) {
+	_, ok := f.index.lookup(path)
+	return ok, nil
+}
+
+func (f *ArchiveFileSystem) FileExists(path string) (bool, error) {
+	e, err := f.stat(path)
+	return err == nil && !e.IsDir(), nil
+}
+
+func (f *ArchiveFileSystem) DirExists(path string) (bool, error) {
+	e, err := f.stat(path)
+	return err == nil && e.IsDir(), nil
+}
+
+func (f *ArchiveFileSystem) Read(path string) ([]byte, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return content, nil
+}
+
+func (f *ArchiveFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	e, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if e.IsDir() {
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	stream, err := f.open(e)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return stream, nil
+}
+
+func (f *ArchiveFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	e, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if !e.IsDir() {
+		return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	var entries []os.DirEntry
+	for _, child := range e.sortedChildren() {
+		entries = append(entries, child)
+	}
+	return entries, nil
+}
+
+// WalkDir walks the entries under the path in lexical order, the links are not followed.
+func (f *ArchiveFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	root, err := f.stat(path)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if !root.IsDir() {
+		return filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	var walkDir func(p string, e *entry) error
+	walkDir = func(p string, e *entry) error {
+		if err := walkFn(p, e, nil); err != nil || !e.IsDir() {
+			if errors.Is(err, filepath.SkipDir) && e.IsDir() {
+				err = nil
+			}
+			return err
+		}
+		for _, child := range e.sortedChildren() {
+			if err := walkDir(filepath.ToSlash(filepath.Join(p, child.Name())), child); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					break
+				}
+				return err
+			}
+		}
+		return nil
+	}
+	err = walkDir(path, root)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (f *ArchiveFileSystem) LastModified(path string) (time.Time, error) {
+	e, err := f.stat(path)
+	if err != nil {
+		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return e.modTime, nil
+}
+
+func (f *ArchiveFileSystem) FileSize(path string) (int64, error) {
+	e, err := f.stat(path)
+	if err != nil {
+		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return e.size, nil
+}
+
+// MimeType detects the mime type from the extension of the path and the beginning of the content.
+func (f *ArchiveFileSystem) MimeType(path string) (string, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	defer stream.Close()
+	header, err := io.ReadAll(io.LimitReader(stream, sniffSize))
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return f.mimetypeDetector.Detect(path, header), nil
+}
+
+// Visibility converts the permissions of the entry stored in the archive.
+func (f *ArchiveFileSystem) Visibility(path string) (string, error) {
+	e, err := f.stat(path)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if e.IsDir() {
+		return f.visibilityConvertor.InverseForDir(e.mode.Perm()), nil
+	}
+	return f.visibilityConvertor.InverseForFile(e.mode.Perm()), nil
+}
+
+func (f *ArchiveFileSystem) Write(location string, content []byte, config map[string]any) error {
+	return filesystem.NewUnableToWriteFile(location, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) WriteStream(location string, stream io.Reader, config map[string]any) error {
+	return filesystem.NewUnableToWriteFile(location, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) SetVisibility(location string, visibility string) error {
+	return filesystem.NewUnableToSetPermission(location, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) Delete(location string) error {
+	return filesystem.NewUnableToDeleteFile(location, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) DeleteDir(location string) error {
+	return filesystem.NewUnableToDeleteDirectory(location, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) CreateDir(location string, config map[string]any) error {
+	return filesystem.NewUnableToCreateDirectory(location, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) Move(src string, dst string, config map[string]any) error {
+	return filesystem.NewUnableToMove(src, dst, readonly.ErrReadOnly)
+}
+
+func (f *ArchiveFileSystem) Copy(src string, dst string, config map[string]any) error {
+	return filesystem.NewUnableToCopyFile(src, dst, readonly.ErrReadOnly)
+}
+
+// ReadOnly reports that the file system can't be written.
+func (f *ArchiveFileSystem) ReadOnly() bool {
+	return true
+}
@@ -0,0 +1,282 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/memory"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+	"github.com/klauspost/compress/zstd"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+var modTime = time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+func buildZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	add := func(name string, mode os.FileMode, content string) {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+		header.SetMode(mode)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	add("docs/", os.ModeDir|0755, "")
+	add("docs/readme.txt", 0644, "hello zip")
+	add("src/pkg/main.go", 0600, "package main")
+	add("link.txt", os.ModeSymlink|0777, "docs/readme.txt")
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return buf.Bytes()
+}
+
+func buildTar(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	add := func(header *tar.Header, content string) {
+		header.ModTime = modTime
+		header.Size = int64(len(content))
+		if err := w.WriteHeader(header); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	add(&tar.Header{Name: "docs/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	add(&tar.Header{Name: "docs/readme.txt", Typeflag: tar.TypeReg, Mode: 0644}, "hello tar")
+	add(&tar.Header{Name: "./src/pkg/main.go", Typeflag: tar.TypeReg, Mode: 0600}, "package main")
+	add(&tar.Header{Name: "link.txt", Typeflag: tar.TypeSymlink, Linkname: "docs/readme.txt", Mode: 0777}, "")
+	add(&tar.Header{Name: "hard.txt", Typeflag: tar.TypeLink, Linkname: "docs/readme.txt", Mode: 0644}, "")
+	add(&tar.Header{Name: "../escape.txt", Typeflag: tar.TypeReg, Mode: 0644}, "kept inside")
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, content []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, content []byte) []byte {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(content, nil)
+}
+
+// streamOnly hides the optional interfaces of a file system, so that the archive can't be read as ranges.
+type streamOnly struct {
+	fs.FileSystem
+}
+
+func openFrom(t *testing.T, store fs.FileSystem, path string, content []byte, opts ...Option) *ArchiveFileSystem {
+	if err := store.Write(path, content, nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	f, err := NewArchiveFileSystem(store, path, append([]Option{WithTempDir(t.TempDir())}, opts...)...)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestArchiveFileSystem_Zip(t *testing.T) {
+	cases := map[string]func(t *testing.T) *ArchiveFileSystem{
+		"range": func(t *testing.T) *ArchiveFileSystem {
+			return openFrom(t, memory.NewMemoryFileSystem("public", nil), "a.zip", buildZip(t))
+		},
+		"spooled": func(t *testing.T) *ArchiveFileSystem {
+			return openFrom(t, streamOnly{memory.NewMemoryFileSystem("public", nil)}, "a.bin", buildZip(t))
+		},
+		"local": func(t *testing.T) *ArchiveFileSystem {
+			name := filepath.Join(t.TempDir(), "a.zip")
+			if err := os.WriteFile(name, buildZip(t), 0644); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			f, err := OpenLocal(name)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			t.Cleanup(func() { _ = f.Close() })
+			return f
+		},
+	}
+	for name, open := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := open(t)
+			assert.Equal(t, FormatZip, f.Format())
+			content, err := f.Read("docs/readme.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "hello zip", string(content))
+			content, err = f.Read("/link.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "hello zip", string(content))
+			exists, _ := f.DirExists("src/pkg")
+			assert.True(t, exists)
+			visibility, err := f.Visibility("src/pkg/main.go")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "private", visibility)
+			lastModified, err := f.LastModified("docs/readme.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.True(t, modTime.Equal(lastModified))
+		})
+	}
+}
+
+func TestArchiveFileSystem_Tar(t *testing.T) {
+	cases := map[string][]byte{
+		"a.tar":     buildTar(t),
+		"a.tar.gz":  gzipped(t, buildTar(t)),
+		"a.tar.zst": zstded(t, buildTar(t)),
+		"a.tgz.bin": gzipped(t, buildTar(t)),
+	}
+	for name, archive := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := openFrom(t, memory.NewMemoryFileSystem("public", nil), name, archive)
+			content, err := f.Read("docs/readme.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "hello tar", string(content))
+			for _, link := range []string{"link.txt", "hard.txt"} {
+				content, err = f.Read(link)
+				if err != nil {
+					assert.FailNow(t, err.Error())
+				}
+				assert.Equal(t, "hello tar", string(content))
+			}
+			content, err = f.Read("escape.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "kept inside", string(content))
+			size, err := f.FileSize("src/pkg/main.go")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, int64(len("package main")), size)
+			info, err := f.Stat("link.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, gofs.ModeSymlink, info.Mode().Type())
+		})
+	}
+}
+
+func TestArchiveFileSystem_ReadDir(t *testing.T) {
+	f := openFrom(t, memory.NewMemoryFileSystem("public", nil), "a.tar", buildTar(t))
+	entries, err := f.ReadDir(".")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{"docs", "escape.txt", "hard.txt", "link.txt", "src"}, names)
+
+	t.Run("walk", func(t *testing.T) {
+		var paths []string
+		err := f.WalkDir("src", func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{"src", "src/pkg", "src/pkg/main.go"}, paths)
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		_, err := f.ReadDir("docs/readme.txt")
+		assert.ErrorIs(t, err, filesystem.ErrIsNotDirectory)
+	})
+}
+
+func TestArchiveFileSystem_ReadOnly(t *testing.T) {
+	f := openFrom(t, memory.NewMemoryFileSystem("public", nil), "a.zip", buildZip(t))
+	assert.True(t, f.ReadOnly())
+	assert.ErrorIs(t, f.Write("new.txt", []byte("x"), nil), readonly.ErrReadOnly)
+	assert.ErrorIs(t, f.Delete("docs/readme.txt"), readonly.ErrReadOnly)
+	assert.ErrorIs(t, f.CreateDir("dir", nil), readonly.ErrReadOnly)
+	_, err := f.Read("missing.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+type countingReaderAt struct {
+	r     *bytes.Reader
+	reads int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+func TestBufferedReaderAt(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), blockSize/5)
+	counting := &countingReaderAt{r: bytes.NewReader(content)}
+	b := newBufferedReaderAt(counting, int64(len(content)))
+	p := make([]byte, 10)
+	for off := int64(0); off < blockSize; off += 1000 {
+		if _, err := b.ReadAt(p, off); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, content[off:off+10], p)
+	}
+	assert.Equal(t, 1, counting.reads)
+
+	// a read across two blocks
+	p = make([]byte, 100)
+	if _, err := b.ReadAt(p, blockSize-50); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, content[blockSize-50:blockSize+50], p)
+	assert.Equal(t, 2, counting.reads)
+
+	n, err := b.ReadAt(p, int64(len(content))-10)
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, 10, n)
+}
@@ -0,0 +1,82 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is the format of an archive.
+type Format string
+
+const (
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+)
+
+var ErrUnknownFormat = errors.New("unknown archive format")
+
+func (f Format) valid() bool {
+	switch f {
+	case FormatZip, FormatTar, FormatTarGz, FormatTarZst:
+		return true
+	}
+	return false
+}
+
+// sniffSize is the number of bytes the format is detected from.
+const sniffSize = 512
+
+// formatFromPath detects the format of an archive from its extension, it returns an empty format if unknown.
+func formatFromPath(p string) Format {
+	p = strings.ToLower(p)
+	switch {
+	case strings.HasSuffix(p, ".zip"):
+		return FormatZip
+	case strings.HasSuffix(p, ".tar"):
+		return FormatTar
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return FormatTarGz
+	case strings.HasSuffix(p, ".tar.zst"), strings.HasSuffix(p, ".tzst"):
+		return FormatTarZst
+	}
+	return ""
+}
+
+// formatFromContent detects the format of an archive from its first bytes.
+func formatFromContent(header []byte) Format {
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return FormatZip
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return FormatTarGz
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return FormatTarZst
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return FormatTar
+	}
+	return ""
+}
+
+// decompress returns the tar stream of an archive of the format.
+func decompress(format Format, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case FormatTar:
+		return io.NopCloser(r), nil
+	case FormatTarGz:
+		return gzip.NewReader(r)
+	case FormatTarZst:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, ErrUnknownFormat
+}
@@ -0,0 +1,5 @@
+module github.com/gopi-frame/filesystem/driver/archive
+
+go 1.22
+
+require github.com/klauspost/compress v1.18.0
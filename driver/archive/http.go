@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"mime"
+	"net/http"
+	"path"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Extension returns the file extension of the format, including the dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// ContentType returns the mime type of an archive of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatZip:
+		return "application/zip"
+	case FormatTar:
+		return "application/x-tar"
+	case FormatTarGz:
+		return "application/gzip"
+	case FormatTarZst:
+		return "application/zstd"
+	}
+	return "application/octet-stream"
+}
+
+// ServeArchive streams the directory of the file system as an archive download,
+// named after the directory. The export is cancelled when the client goes away.
+//
+// The response is committed once the first bytes are sent, an error happening later
+// can only be returned, and leaves the client with a truncated archive.
+func ServeArchive(w http.ResponseWriter, r *http.Request, source fs.FileSystem, dir string, format Format, opts ...ExportOption) error {
+	if !format.valid() {
+		http.Error(w, ErrUnknownFormat.Error(), http.StatusBadRequest)
+		return ErrUnknownFormat
+	}
+	name := path.Base(filesystem.CleanPath(dir))
+	if name == "." {
+		name = "archive"
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + format.Extension()}))
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	return Archive(source, dir, format, w, append([]ExportOption{WithContext(r.Context())}, opts...)...)
+}
@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"archive/zip"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// entry is a file, directory or link of an archive.
+// It implements both [fs.FileInfo] and [fs.DirEntry].
+type entry struct {
+	path    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	// link is the target of a symbolic or hard link.
+	link string
+	// hard reports whether the entry is a hard link.
+	hard     bool
+	children map[string]*entry
+
+	// zipFile is the file of a zip archive.
+	zipFile *zip.File
+	// seq is the position of the header in a tar archive.
+	seq int
+	// offset is the position of the data in a tar stream.
+	offset int64
+	// sparse reports whether the data of a tar entry can't be read as a plain range.
+	sparse bool
+}
+
+func (e *entry) Name() string {
+	return path.Base(e.path)
+}
+
+func (e *entry) Size() int64 {
+	return e.size
+}
+
+func (e *entry) Mode() fs.FileMode {
+	return e.mode
+}
+
+func (e *entry) ModTime() time.Time {
+	return e.modTime
+}
+
+func (e *entry) IsDir() bool {
+	return e.mode.IsDir()
+}
+
+func (e *entry) Sys() any {
+	return nil
+}
+
+func (e *entry) Type() fs.FileMode {
+	return e.mode.Type()
+}
+
+func (e *entry) Info() (fs.FileInfo, error) {
+	return e, nil
+}
+
+// sortedChildren returns the entries of a directory sorted by name.
+func (e *entry) sortedChildren() []*entry {
+	children := make([]*entry, 0, len(e.children))
+	for _, child := range e.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].path < children[j].path
+	})
+	return children
+}
+
+// index is the tree of the entries of an archive.
+type index struct {
+	entries map[string]*entry
+}
+
+func newIndex() *index {
+	root := &entry{path: ".", mode: fs.ModeDir | 0755, children: map[string]*entry{}}
+	return &index{entries: map[string]*entry{".": root}}
+}
+
+func (x *index) lookup(p string) (*entry, bool) {
+	e, ok := x.entries[filesystem.CleanPath(p)]
+	return e, ok
+}
+
+// add adds an entry under its name, creating the missing parent directories.
+// A later entry replaces an earlier one of the same name, as tar does on extraction.
+func (x *index) add(name string, e *entry) {
+	e.path = filesystem.CleanPath(strings.TrimSuffix(name, "/"))
+	if e.path == "." {
+		return
+	}
+	parent := x.dir(path.Dir(e.path), e.modTime)
+	if existing, ok := x.entries[e.path]; ok && existing.IsDir() {
+		if e.IsDir() {
+			existing.mode, existing.modTime = e.mode, e.modTime
+			return
+		}
+		x.remove(existing)
+	}
+	if e.IsDir() {
+		e.children = map[string]*entry{}
+	}
+	parent.children[e.Name()] = e
+	x.entries[e.path] = e
+}
+
+// dir returns the directory of the path, creating it and its parents if missing.
+func (x *index) dir(p string, modTime time.Time) *entry {
+	if d, ok := x.entries[p]; ok {
+		if !d.IsDir() {
+			x.remove(d)
+		} else {
+			return d
+		}
+	}
+	d := &entry{path: p, mode: fs.ModeDir | 0755, modTime: modTime, children: map[string]*entry{}}
+	parent := x.dir(path.Dir(p), modTime)
+	parent.children[d.Name()] = d
+	x.entries[p] = d
+	return d
+}
+
+// remove removes an entry and its descendants.
+func (x *index) remove(e *entry) {
+	for _, child := range e.children {
+		x.remove(child)
+	}
+	delete(x.entries, e.path)
+	if parent, ok := x.entries[path.Dir(e.path)]; ok {
+		delete(parent.children, e.Name())
+	}
+}
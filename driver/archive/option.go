@@ -0,0 +1,63 @@
+package archive
+
+import (
+	"github.com/gopi-frame/contract"
+	"github.com/gopi-frame/filesystem/visibility/unix"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*ArchiveFileSystem]
+
+type OptionFunc func(f *ArchiveFileSystem) error
+
+func (o OptionFunc) Apply(f *ArchiveFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *ArchiveFileSystem) error {
+	return nil
+})
+
+// WithFormat sets the format of the archive instead of detecting it.
+func WithFormat(format Format) Option {
+	if format == "" {
+		return noneOption
+	}
+	return OptionFunc(func(f *ArchiveFileSystem) error {
+		f.format = format
+		return nil
+	})
+}
+
+// WithTempDir sets the local directory a zip archive is spooled to when its file system can't read ranges.
+func WithTempDir(dir string) Option {
+	if dir == "" {
+		return noneOption
+	}
+	return OptionFunc(func(f *ArchiveFileSystem) error {
+		f.tempDir = dir
+		return nil
+	})
+}
+
+func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
+	if detector == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *ArchiveFileSystem) error {
+		f.mimetypeDetector = detector
+		return nil
+	})
+}
+
+// WithVisibilityConvertor sets the convertor giving the visibility of the entries from their modes.
+func WithVisibilityConvertor(convertor unix.VisibilityConvertor) Option {
+	if convertor == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *ArchiveFileSystem) error {
+		f.visibilityConvertor = convertor
+		return nil
+	})
+}
@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// source is where the archive is stored.
+type source interface {
+	// open returns a stream of the whole archive.
+	open() (io.ReadCloser, error)
+	// readerAt returns a random access view of the archive and its size,
+	// or a nil reader if the storage can't read ranges.
+	readerAt() (io.ReaderAt, int64, error)
+	close() error
+}
+
+// fsSource is an archive stored in a file system.
+type fsSource struct {
+	fs   fs.FileSystem
+	path string
+}
+
+func (s *fsSource) open() (io.ReadCloser, error) {
+	return s.fs.ReadStream(s.path)
+}
+
+func (s *fsSource) readerAt() (io.ReaderAt, int64, error) {
+	reader, ok := s.fs.(filesystem.RangeReader)
+	if !ok {
+		return nil, 0, nil
+	}
+	readerAt, err := filesystem.NewReaderAt(reader, s.path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return newBufferedReaderAt(readerAt, readerAt.Size()), readerAt.Size(), nil
+}
+
+func (s *fsSource) close() error {
+	return nil
+}
+
+// fileSource is an archive stored in a local file.
+type fileSource struct {
+	file *os.File
+	size int64
+	// temporary reports whether the file is a spooled copy removed on close.
+	temporary bool
+}
+
+func openFileSource(name string) (*fileSource, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return &fileSource{file: file, size: info.Size()}, nil
+}
+
+// spool copies the stream to a temporary file of the directory.
+func spool(dir string, stream io.Reader) (*fileSource, error) {
+	file, err := os.CreateTemp(dir, "archive-*")
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(file, stream)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return &fileSource{file: file, size: size, temporary: true}, nil
+}
+
+func (s *fileSource) open() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(s.file, 0, s.size)), nil
+}
+
+func (s *fileSource) readerAt() (io.ReaderAt, int64, error) {
+	return s.file, s.size, nil
+}
+
+func (s *fileSource) close() error {
+	err := s.file.Close()
+	if s.temporary {
+		if rmErr := os.Remove(s.file.Name()); err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
+const (
+	// blockSize is the size of the blocks a remote archive is read by.
+	blockSize = 64 << 10
+	// cachedBlocks is the number of blocks kept in memory.
+	cachedBlocks = 16
+)
+
+// bufferedReaderAt reads a remote archive by blocks and keeps the last blocks read,
+// so that the many small reads of the zip and tar readers don't each cost a range read.
+type bufferedReaderAt struct {
+	mu     sync.Mutex
+	r      io.ReaderAt
+	size   int64
+	blocks map[int64][]byte
+	// recent are the indexes of the cached blocks, the least recently used first
+	recent []int64
+}
+
+func newBufferedReaderAt(r io.ReaderAt, size int64) *bufferedReaderAt {
+	return &bufferedReaderAt{r: r, size: size, blocks: make(map[int64][]byte)}
+}
+
+func (b *bufferedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, filesystem.ErrInvalidRange
+	}
+	n := 0
+	for n < len(p) && off+int64(n) < b.size {
+		pos := off + int64(n)
+		block, err := b.block(pos / blockSize)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], block[pos%blockSize:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (b *bufferedReaderAt) block(i int64) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for j, k := range b.recent {
+		if k == i {
+			b.recent = append(append(b.recent[:j:j], b.recent[j+1:]...), i)
+			return b.blocks[i], nil
+		}
+	}
+	start := i * blockSize
+	block := make([]byte, min(blockSize, b.size-start))
+	if _, err := b.r.ReadAt(block, start); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(b.recent) == cachedBlocks {
+		delete(b.blocks, b.recent[0])
+		b.recent = b.recent[1:]
+	}
+	b.blocks[i] = block
+	b.recent = append(b.recent, i)
+	return block, nil
+}
@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// counter counts the bytes read from a stream, to record where the data of every tar entry starts.
+type counter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// seekCounter lets the tar reader skip the data of the entries while indexing a plain tar.
+type seekCounter struct {
+	*counter
+	s io.Seeker
+}
+
+func (c *seekCounter) Seek(offset int64, whence int) (int64, error) {
+	n, err := c.s.Seek(offset, whence)
+	if err == nil {
+		c.counter.n = n
+	}
+	return n, err
+}
+
+// indexTar builds the index of a tar archive in one pass over its stream.
+// Unless the archive is a plain tar with random access, it is decompressed to a local temporary file
+// as it is indexed, so that its entries are then read as ranges of this file.
+func (f *ArchiveFileSystem) indexTar() error {
+	c := &counter{}
+	var stream io.Reader = c
+	var spooled *fileSource
+	if f.readerAt != nil {
+		section := io.NewSectionReader(f.readerAt, 0, f.size)
+		c.r = section
+		stream = &seekCounter{counter: c, s: section}
+	} else {
+		rc, err := f.source.open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		decompressed, err := decompress(f.format, rc)
+		if err != nil {
+			return err
+		}
+		defer decompressed.Close()
+		file, err := os.CreateTemp(f.tempDir, "archive-*")
+		if err != nil {
+			return err
+		}
+		spooled = &fileSource{file: file, temporary: true}
+		c.r = io.TeeReader(decompressed, file)
+	}
+	if err := f.readTar(tar.NewReader(stream), c); err != nil {
+		if spooled != nil {
+			_ = spooled.close()
+		}
+		return err
+	}
+	if spooled != nil {
+		spooled.size = c.n
+		_ = f.source.close()
+		f.source = spooled
+		f.readerAt, f.size = spooled.file, spooled.size
+	}
+	return nil
+}
+
+func (f *ArchiveFileSystem) readTar(reader *tar.Reader, c *counter) error {
+	for seq := 0; ; seq++ {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := &entry{
+			mode:    header.FileInfo().Mode(),
+			size:    header.Size,
+			modTime: header.ModTime,
+			seq:     seq,
+			offset:  c.n,
+			sparse:  header.Typeflag == tar.TypeGNUSparse || isSparse(header),
+		}
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			e.link, e.size = header.Linkname, 0
+		case tar.TypeLink:
+			e.link, e.hard, e.size = header.Linkname, true, 0
+			e.mode &^= fs.ModeType
+		case tar.TypeReg, tar.TypeDir, tar.TypeGNUSparse:
+		default:
+			// character and block devices, fifos and the like have no content to read.
+			continue
+		}
+		f.index.add(header.Name, e)
+	}
+}
+
+func isSparse(header *tar.Header) bool {
+	for k := range header.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// openTarEntry returns the content of a regular file of a tar archive, read as a range of the archive.
+// The holes of a sparse file are described by its headers, the archive is read again up to the entry.
+func (f *ArchiveFileSystem) openTarEntry(e *entry) (io.ReadCloser, error) {
+	if !e.sparse {
+		return io.NopCloser(io.NewSectionReader(f.readerAt, e.offset, e.size)), nil
+	}
+	reader := tar.NewReader(io.NewSectionReader(f.readerAt, 0, f.size))
+	for seq := 0; ; seq++ {
+		_, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, &os.PathError{Op: "open", Path: path.Clean(e.path), Err: os.ErrNotExist}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seq == e.seq {
+			return io.NopCloser(reader), nil
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+)
+
+// maxLinkSize bounds the target of a symbolic link stored in a zip archive.
+const maxLinkSize = 4096
+
+// indexZip builds the index of a zip archive from its central directory.
+func (f *ArchiveFileSystem) indexZip() error {
+	reader, err := zip.NewReader(f.readerAt, f.size)
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		e := &entry{
+			mode:    file.Mode(),
+			size:    int64(file.UncompressedSize64),
+			modTime: file.Modified,
+			zipFile: file,
+		}
+		if e.mode&fs.ModeSymlink != 0 {
+			// the target of a symbolic link is stored as its content.
+			target, err := readZipFile(file, maxLinkSize)
+			if err != nil {
+				return err
+			}
+			e.link, e.size = string(target), 0
+		}
+		f.index.add(file.Name, e)
+	}
+	return nil
+}
+
+func readZipFile(file *zip.File, limit int64) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, limit))
+}
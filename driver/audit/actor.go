@@ -0,0 +1,23 @@
+package audit
+
+import "context"
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of the context carrying the actor,
+// which is the default source of the actor of the records.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by [ContextWithActor], or an empty string.
+func ActorFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// ActorResolver returns the actor of an operation, ctx is the context bound with [AuditFileSystem.WithContext].
+type ActorResolver func(ctx context.Context) string
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Config struct {
+	// FileSystem is the audited file system.
+	FileSystem fs.FileSystem
+	// LogFileSystem is the file system the audit log is appended to.
+	LogFileSystem fs.FileSystem
+	// LogPath is the path of the audit log in LogFileSystem,
+	// which must append natively, see [FileSink].
+	LogPath string
+	// LogPrefix is the prefix every record is written under as a new object in LogFileSystem, instead of LogPath,
+	// see [ObjectSink].
+	LogPrefix string
+	// LogVisibility is the visibility of the audit log, default is "private".
+	LogVisibility string
+	// Reads enables the auditing of read operations.
+	Reads bool
+	// Actor is the actor recorded when the context does not carry one, e.g. the name of the service.
+	Actor string
+}
+
+func (c *Config) Apply(f *AuditFileSystem) error {
+	if c.LogFileSystem != nil {
+		switch {
+		case c.LogPrefix != "":
+			f.sink = NewObjectSink(c.LogFileSystem, c.LogPrefix, c.LogVisibility)
+		case c.LogPath != "":
+			f.sink = NewFileSink(c.LogFileSystem, c.LogPath, c.LogVisibility)
+		default:
+			return errors.New("log path or log prefix is required")
+		}
+	}
+	f.reads = c.Reads
+	if c.Actor != "" {
+		actor := c.Actor
+		f.resolveActor = func(ctx context.Context) string {
+			if a := ActorFromContext(ctx); a != "" {
+				return a
+			}
+			return actor
+		}
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+var driverName = "audit"
+
+func init() {
+	//goland:noinspection GoBoolExpressions
+	if driverName != "" {
+		filesystem.Register(driverName, &Driver{})
+	}
+}
+
+type Driver struct{}
+
+func (d *Driver) Open(options map[string]any) (fs.FileSystem, error) {
+	cfg, err := ConfigFromMap(options)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuditFileSystem(cfg.FileSystem, cfg)
+}
@@ -0,0 +1,251 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"sync"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+var ErrNoFileSystem = errors.New("audited file system is required")
+var ErrNoSink = errors.New("audit sink is required")
+
+// AuditFileSystem is a wrapper which writes an audit record for every operation changing the file system,
+// and optionally for every read.
+//
+// Metadata calls, such as Exists or FileSize, are never audited.
+// The actor of a record is resolved from the context bound with [AuditFileSystem.WithContext],
+// by default with [ActorFromContext].
+type AuditFileSystem struct {
+	f            fs.FileSystem
+	sink         Sink
+	reads        bool
+	resolveActor ActorResolver
+	onSinkError  func(record *Record, err error)
+	ctx          context.Context
+}
+
+func NewAuditFileSystem(f fs.FileSystem, opts ...Option) (*AuditFileSystem, error) {
+	if f == nil {
+		return nil, ErrNoFileSystem
+	}
+	a := &AuditFileSystem{
+		f:            f,
+		resolveActor: ActorFromContext,
+		onSinkError:  func(*Record, error) {},
+		ctx:          context.Background(),
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(a); err != nil {
+			return nil, err
+		}
+	}
+	if a.sink == nil {
+		return nil, ErrNoSink
+	}
+	return a, nil
+}
+
+// WithContext returns a view of the file system whose records are attributed to the actor of the context.
+// The view shares the sink and the options of the file system.
+func (a *AuditFileSystem) WithContext(ctx context.Context) *AuditFileSystem {
+	view := *a
+	view.ctx = ctx
+	return &view
+}
+
+func (a *AuditFileSystem) record(op, path, dst string, bytes int64, err error) {
+	record := &Record{
+		Timestamp:   time.Now(),
+		Operation:   op,
+		Path:        path,
+		Destination: dst,
+		Bytes:       bytes,
+		Outcome:     OutcomeSuccess,
+		Actor:       a.resolveActor(a.ctx),
+	}
+	if err != nil {
+		record.Outcome = OutcomeFailure
+		record.ErrorType = fmt.Sprintf("%T", err)
+		record.Error = err.Error()
+	}
+	if err := a.sink.Write(record); err != nil {
+		a.onSinkError(record, err)
+	}
+}
+
+func (a *AuditFileSystem) Exists(path string) (bool, error) {
+	return a.f.Exists(path)
+}
+
+func (a *AuditFileSystem) FileExists(path string) (bool, error) {
+	return a.f.FileExists(path)
+}
+
+func (a *AuditFileSystem) DirExists(path string) (bool, error) {
+	return a.f.DirExists(path)
+}
+
+func (a *AuditFileSystem) Read(path string) ([]byte, error) {
+	content, err := a.f.Read(path)
+	if a.reads {
+		a.record(OpRead, path, "", int64(len(content)), err)
+	}
+	return content, err
+}
+
+// ReadStream records the read when the stream is closed, with the number of bytes actually read.
+func (a *AuditFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	stream, err := a.f.ReadStream(path)
+	if !a.reads {
+		return stream, err
+	}
+	if err != nil {
+		a.record(OpRead, path, "", 0, err)
+		return nil, err
+	}
+	return &readCloser{ReadCloser: stream, a: a, path: path}, nil
+}
+
+func (a *AuditFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	entries, err := a.f.ReadDir(path)
+	if a.reads {
+		a.record(OpList, path, "", 0, err)
+	}
+	return entries, err
+}
+
+func (a *AuditFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	err := a.f.WalkDir(path, walkFn)
+	if a.reads {
+		a.record(OpList, path, "", 0, err)
+	}
+	return err
+}
+
+func (a *AuditFileSystem) LastModified(path string) (time.Time, error) {
+	return a.f.LastModified(path)
+}
+
+func (a *AuditFileSystem) FileSize(path string) (int64, error) {
+	return a.f.FileSize(path)
+}
+
+func (a *AuditFileSystem) MimeType(path string) (string, error) {
+	return a.f.MimeType(path)
+}
+
+func (a *AuditFileSystem) Visibility(path string) (string, error) {
+	return a.f.Visibility(path)
+}
+
+func (a *AuditFileSystem) Write(path string, content []byte, config map[string]any) error {
+	err := a.f.Write(path, content, config)
+	a.record(OpWrite, path, "", int64(len(content)), err)
+	return err
+}
+
+// WriteStream records the number of bytes consumed from the stream.
+func (a *AuditFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	counter := &countingReader{r: stream}
+	err := a.f.WriteStream(path, counter, config)
+	a.record(OpWrite, path, "", counter.n, err)
+	return err
+}
+
+func (a *AuditFileSystem) SetVisibility(path string, visibility string) error {
+	err := a.f.SetVisibility(path, visibility)
+	a.record(OpSetVisibility, path, "", 0, err)
+	return err
+}
+
+func (a *AuditFileSystem) Delete(path string) error {
+	size := a.fileSize(path)
+	err := a.f.Delete(path)
+	a.record(OpDelete, path, "", size, err)
+	return err
+}
+
+func (a *AuditFileSystem) DeleteDir(path string) error {
+	err := a.f.DeleteDir(path)
+	a.record(OpDeleteDir, path, "", 0, err)
+	return err
+}
+
+func (a *AuditFileSystem) CreateDir(path string, config map[string]any) error {
+	err := a.f.CreateDir(path, config)
+	a.record(OpCreateDir, path, "", 0, err)
+	return err
+}
+
+// Move records the size of the source, or 0 for a directory.
+func (a *AuditFileSystem) Move(src string, dst string, config map[string]any) error {
+	size := a.fileSize(src)
+	err := a.f.Move(src, dst, config)
+	a.record(OpMove, src, dst, size, err)
+	return err
+}
+
+// Copy records the size of the source, or 0 for a directory.
+func (a *AuditFileSystem) Copy(src string, dst string, config map[string]any) error {
+	size := a.fileSize(src)
+	err := a.f.Copy(src, dst, config)
+	a.record(OpCopy, src, dst, size, err)
+	return err
+}
+
+func (a *AuditFileSystem) fileSize(path string) int64 {
+	if exists, err := a.f.FileExists(path); err != nil || !exists {
+		return 0
+	}
+	size, _ := a.f.FileSize(path)
+	return size
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// readCloser records the read of a stream once, when it is closed.
+type readCloser struct {
+	io.ReadCloser
+	a    *AuditFileSystem
+	path string
+	n    int64
+	err  error
+	once sync.Once
+}
+
+func (r *readCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+func (r *readCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		recordErr := r.err
+		if recordErr == nil {
+			recordErr = err
+		}
+		r.a.record(OpRead, r.path, "", r.n, recordErr)
+	})
+	return err
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem/driver/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeRecords(t *testing.T, content []byte) []*Record {
+	var records []*Record
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		records = append(records, &record)
+	}
+	return records
+}
+
+func TestAuditFileSystem(t *testing.T) {
+	t.Run("file sink", func(t *testing.T) {
+		logs := memory.NewMemoryFileSystem("public", nil)
+		f, err := NewAuditFileSystem(memory.NewMemoryFileSystem("public", nil), WithSink(NewFileSink(logs, "audit/audit.log", "")))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		alice := f.WithContext(ContextWithActor(context.Background(), "alice"))
+		if err := alice.Write("test.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := alice.Move("test.txt", "moved.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Error(t, f.Copy("missing.txt", "copy.txt", nil))
+		if _, err := f.Read("moved.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := logs.Read("audit/audit.log")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		records := decodeRecords(t, content)
+		if !assert.Len(t, records, 3) {
+			return
+		}
+		assert.Equal(t, OpWrite, records[0].Operation)
+		assert.Equal(t, "test.txt", records[0].Path)
+		assert.Equal(t, int64(5), records[0].Bytes)
+		assert.Equal(t, OutcomeSuccess, records[0].Outcome)
+		assert.Equal(t, "alice", records[0].Actor)
+		assert.Equal(t, OpMove, records[1].Operation)
+		assert.Equal(t, "moved.txt", records[1].Destination)
+		assert.Equal(t, OpCopy, records[2].Operation)
+		assert.Equal(t, OutcomeFailure, records[2].Outcome)
+		assert.NotEmpty(t, records[2].ErrorType)
+		assert.Empty(t, records[2].Actor)
+		visibility, err := logs.Visibility("audit/audit.log")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "private", visibility)
+	})
+
+	t.Run("object sink", func(t *testing.T) {
+		logs := memory.NewMemoryFileSystem("public", nil)
+		f, err := NewAuditFileSystem(memory.NewMemoryFileSystem("public", nil), WithSink(NewObjectSink(logs, "audit", "")))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("test.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Move("test.txt", "moved.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		entries, err := logs.ReadDir("audit")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if !assert.Len(t, entries, 2) {
+			return
+		}
+		var operations []string
+		for _, entry := range entries {
+			content, err := logs.Read("audit/" + entry.Name())
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			records := decodeRecords(t, content)
+			if assert.Len(t, records, 1) {
+				operations = append(operations, records[0].Operation)
+			}
+		}
+		assert.Equal(t, []string{OpWrite, OpMove}, operations)
+	})
+
+	t.Run("reads", func(t *testing.T) {
+		var buf bytes.Buffer
+		f, err := NewAuditFileSystem(memory.NewMemoryFileSystem("public", nil), WithSink(NewWriterSink(&buf)), WithReads(),
+			WithActorResolver(func(ctx context.Context) string {
+				return "service"
+			}))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.WriteStream("test.txt", strings.NewReader("hello world"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		stream, err := f.ReadStream("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := io.ReadAll(stream); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := stream.Close(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := f.ReadDir("."); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		records := decodeRecords(t, buf.Bytes())
+		if !assert.Len(t, records, 3) {
+			return
+		}
+		assert.Equal(t, int64(11), records[0].Bytes)
+		assert.Equal(t, OpRead, records[1].Operation)
+		assert.Equal(t, int64(11), records[1].Bytes)
+		assert.Equal(t, "service", records[1].Actor)
+		assert.Equal(t, OpList, records[2].Operation)
+	})
+}
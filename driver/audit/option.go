@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"github.com/gopi-frame/contract"
+)
+
+type Option = contract.Option[*AuditFileSystem]
+
+type OptionFunc func(f *AuditFileSystem) error
+
+func (o OptionFunc) Apply(f *AuditFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *AuditFileSystem) error {
+	return nil
+})
+
+// WithSink sets the sink the records are written to.
+func WithSink(sink Sink) Option {
+	if sink == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *AuditFileSystem) error {
+		f.sink = sink
+		return nil
+	})
+}
+
+// WithReads enables the auditing of Read, ReadStream, ReadDir and WalkDir.
+func WithReads() Option {
+	return OptionFunc(func(f *AuditFileSystem) error {
+		f.reads = true
+		return nil
+	})
+}
+
+// WithActorResolver sets the function returning the actor of an operation.
+func WithActorResolver(resolver ActorResolver) Option {
+	if resolver == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *AuditFileSystem) error {
+		f.resolveActor = resolver
+		return nil
+	})
+}
+
+// WithSinkErrorHandler sets the callback invoked when a record cannot be written to the sink.
+func WithSinkErrorHandler(handler func(record *Record, err error)) Option {
+	if handler == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *AuditFileSystem) error {
+		f.onSinkError = handler
+		return nil
+	})
+}
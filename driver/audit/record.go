@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Operations recorded in a [Record].
+const (
+	OpRead          = "read"
+	OpList          = "list"
+	OpWrite         = "write"
+	OpSetVisibility = "set_visibility"
+	OpDelete        = "delete"
+	OpDeleteDir     = "delete_dir"
+	OpCreateDir     = "create_dir"
+	OpMove          = "move"
+	OpCopy          = "copy"
+)
+
+// Outcomes of a [Record].
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Record is an audit log entry, it is written as a single json line.
+type Record struct {
+	Timestamp time.Time `json:"timestamp"`
+	Operation string    `json:"operation"`
+	Path      string    `json:"path"`
+	// Destination is the target path of Move and Copy.
+	Destination string `json:"destination,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	Outcome     string `json:"outcome"`
+	// ErrorType is the go type of the error, e.g. *filesystem.UnableToWriteFile.
+	ErrorType string `json:"error_type,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+}
+
+// Sink receives the audit records.
+type Sink interface {
+	Write(record *Record) error
+}
+
+// SinkFunc is a function which implements [Sink].
+type SinkFunc func(record *Record) error
+
+func (f SinkFunc) Write(record *Record) error {
+	return f(record)
+}
+
+// WriterSink writes the records as json lines to an [io.Writer].
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Write(record *Record) error {
+	line, err := encode(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// FileSink appends the records as json lines to a file of a file system,
+// which should be another one than the audited file system.
+//
+// Every record is appended with a single write opened with os.O_APPEND,
+// which leaves the earlier records untouched only on file systems appending natively, e.g. local, sftp or ftp.
+// Object stores either ignore the flag or emulate it by rewriting the whole object,
+// use an [ObjectSink] for them.
+type FileSink struct {
+	mu         sync.Mutex
+	f          fs.FileSystem
+	path       string
+	visibility string
+}
+
+// NewFileSink creates a sink appending to the file at path, the file is created with the given visibility.
+func NewFileSink(f fs.FileSystem, path string, visibility string) *FileSink {
+	if visibility == "" {
+		visibility = "private"
+	}
+	return &FileSink{f: f, path: path, visibility: visibility}
+}
+
+func (s *FileSink) Write(record *Record) error {
+	line, err := encode(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Write(s.path, line, map[string]any{
+		filesystem.FileVisibilityKey: s.visibility,
+		filesystem.FileWriteFlagKey:  os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	})
+}
+
+// ObjectSink writes every record as a new object under a prefix of a file system,
+// which should be another one than the audited file system.
+//
+// The objects are never rewritten, which suits the object stores, and are named after the time of the record,
+// so that listing the prefix returns them in order.
+type ObjectSink struct {
+	mu         sync.Mutex
+	f          fs.FileSystem
+	prefix     string
+	visibility string
+	id         string
+	seq        uint64
+}
+
+// NewObjectSink creates a sink writing the records under prefix, the objects are created with the given visibility.
+func NewObjectSink(f fs.FileSystem, prefix string, visibility string) *ObjectSink {
+	if visibility == "" {
+		visibility = "private"
+	}
+	// the id keeps apart the records of several processes written at the same time
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+	return &ObjectSink{f: f, prefix: prefix, visibility: visibility, id: hex.EncodeToString(id)}
+}
+
+func (s *ObjectSink) Write(record *Record) error {
+	line, err := encode(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.seq++
+	name := fmt.Sprintf("%s-%s-%06d.json", record.Timestamp.UTC().Format("20060102T150405.000000000Z"), s.id, s.seq)
+	s.mu.Unlock()
+	return s.f.Write(path.Join(s.prefix, name), line, map[string]any{
+		filesystem.FileVisibilityKey: s.visibility,
+		filesystem.IfAbsentKey:       true,
+	})
+}
+
+func encode(record *Record) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
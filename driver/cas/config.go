@@ -0,0 +1,75 @@
+package cas
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Config struct {
+	// FileSystem stores the blobs.
+	FileSystem fs.FileSystem
+	// Index maps the paths to the blobs and counts their references, default is a [FileSystemIndex].
+	Index Index
+	// IndexFileSystem stores the default index, default is FileSystem.
+	IndexFileSystem fs.FileSystem
+	// IndexPrefix is the directory of the default index in IndexFileSystem, default is "index".
+	IndexPrefix string
+	// Visibility is the default visibility of files and directories, default is "public".
+	Visibility string
+	// TempDir is the local directory uploads are spooled to while they are hashed, default is os.TempDir().
+	TempDir string
+	// GCGracePeriod protects blobs younger than this duration from the garbage collector,
+	// so that uploads from other processes which have not been indexed yet are kept, default is 1h.
+	GCGracePeriod time.Duration
+}
+
+func (c *Config) Apply(f *CASFileSystem) error {
+	if c.Index != nil {
+		f.index = c.Index
+	}
+	if c.IndexFileSystem != nil {
+		f.indexFileSystem = c.IndexFileSystem
+	}
+	if c.IndexPrefix != "" {
+		f.indexPrefix = c.IndexPrefix
+	}
+	if c.Visibility != "" {
+		f.visibility = c.Visibility
+	}
+	if c.TempDir != "" {
+		f.tempDir = c.TempDir
+	}
+	if c.GCGracePeriod > 0 {
+		f.gcGracePeriod = c.GCGracePeriod
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,20 @@
+package cas
+
+import (
+	"io"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Create returns a writer to the file, its content is spooled and stored like WriteStream on Close,
+// so an aborted write leaves the file as it was.
+func (f *CASFileSystem) Create(path string, config map[string]any) (filesystem.FileWriter, error) {
+	if config != nil {
+		if _, err := filesystem.NewConfig(config); err != nil {
+			return nil, err
+		}
+	}
+	return filesystem.NewPipeWriter(func(stream io.Reader) error {
+		return f.WriteStream(path, stream, config)
+	}, nil), nil
+}
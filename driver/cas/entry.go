@@ -0,0 +1,66 @@
+package cas
+
+import (
+	"io/fs"
+	"path"
+	"time"
+)
+
+// Entry is the index record of a file, it points to the blob holding the content.
+type Entry struct {
+	Hash         string    `json:"hash"`
+	Size         int64     `json:"size"`
+	MimeType     string    `json:"mime_type"`
+	Visibility   string    `json:"visibility"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// fileEntry is the [fs.DirEntry] of an indexed file,
+// its info describes the content instead of the pointer stored in the index.
+type fileEntry struct {
+	fs.DirEntry
+	f    *CASFileSystem
+	path string
+}
+
+func (e *fileEntry) Info() (fs.FileInfo, error) {
+	entry, err := e.f.Stat(e.path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := e.DirEntry.Info()
+	if err != nil {
+		return nil, err
+	}
+	return &fileInfo{name: path.Base(e.path), entry: entry, mode: info.Mode()}, nil
+}
+
+type fileInfo struct {
+	name  string
+	entry *Entry
+	mode  fs.FileMode
+}
+
+func (i *fileInfo) Name() string {
+	return i.name
+}
+
+func (i *fileInfo) Size() int64 {
+	return i.entry.Size
+}
+
+func (i *fileInfo) Mode() fs.FileMode {
+	return i.mode
+}
+
+func (i *fileInfo) ModTime() time.Time {
+	return i.entry.LastModified
+}
+
+func (i *fileInfo) IsDir() bool {
+	return false
+}
+
+func (i *fileInfo) Sys() any {
+	return i.entry
+}
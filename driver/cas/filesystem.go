@@ -0,0 +1,599 @@
+package cas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// BlobDir is the directory blobs are stored under, in the blob file system.
+const BlobDir = "sha256"
+
+// defaultGCGracePeriod protects the blobs of the uploads in progress in other processes.
+const defaultGCGracePeriod = time.Hour
+
+// mimeHeaderSize is the number of bytes the mime type is detected from.
+const mimeHeaderSize = 3072
+
+var ErrNoFileSystem = errors.New("blob file system is required")
+
+// CASFileSystem is a content-addressable wrapper which stores every distinct content once.
+//
+// The content of a file is stored as a blob named after its sha256 hash, under sha256/ab/cd/<hash>.
+// The [Index] maps every path to its blob and counts the references to every blob,
+// by default a [FileSystemIndex] stored along the blobs.
+// Copy only adds a reference, and a blob is deleted when its last reference is.
+//
+// Mutations are serialized within the process; the reference counts of a store shared by several processes
+// may drift, [CASFileSystem.GC] recomputes them from the index.
+type CASFileSystem struct {
+	mu               sync.Mutex
+	blobs            fs.FileSystem
+	index            Index
+	indexFileSystem  fs.FileSystem
+	indexPrefix      string
+	visibility       string
+	tempDir          string
+	gcGracePeriod    time.Duration
+	mimetypeDetector fs.MimeTypeDetector
+}
+
+// NewCASFileSystem creates a content-addressable file system storing its blobs in the given file system.
+func NewCASFileSystem(blobs fs.FileSystem, opts ...Option) (*CASFileSystem, error) {
+	if blobs == nil {
+		return nil, ErrNoFileSystem
+	}
+	f := &CASFileSystem{
+		blobs:         blobs,
+		indexPrefix:   "index",
+		visibility:    "public",
+		gcGracePeriod: defaultGCGracePeriod,
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if f.index == nil {
+		if f.indexFileSystem == nil {
+			f.indexFileSystem = f.blobs
+		}
+		index, err := NewFileSystemIndex(f.indexFileSystem, f.indexPrefix)
+		if err != nil {
+			return nil, err
+		}
+		f.index = index
+	}
+	if f.mimetypeDetector == nil {
+		f.mimetypeDetector = filesystem.NewMimeTypeDetector()
+	}
+	return f, nil
+}
+
+// BlobPath returns the path of a blob in the blob file system.
+func BlobPath(hash string) string {
+	return path.Join(BlobDir, hash[:2], hash[2:4], hash)
+}
+
+// Stat returns the index entry of a file.
+func (f *CASFileSystem) Stat(path string) (*Entry, error) {
+	entry, err := f.lookup(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return entry, nil
+}
+
+func (f *CASFileSystem) lookup(p string) (*Entry, error) {
+	return f.index.Lookup(p)
+}
+
+// release drops a reference to the blob, and deletes the blob if it was the last one.
+func (f *CASFileSystem) release(hash string) error {
+	count, err := f.index.Ref(hash, -1)
+	if err != nil || count > 0 {
+		return err
+	}
+	if exists, err := f.blobs.FileExists(BlobPath(hash)); err != nil || !exists {
+		return err
+	}
+	return f.blobs.Delete(BlobPath(hash))
+}
+
+func (f *CASFileSystem) Exists(path string) (bool, error) {
+	exists, err := f.index.FileExists(path)
+	if err != nil || exists {
+		return exists, err
+	}
+	return f.index.DirExists(path)
+}
+
+func (f *CASFileSystem) FileExists(path string) (bool, error) {
+	return f.index.FileExists(path)
+}
+
+func (f *CASFileSystem) DirExists(path string) (bool, error) {
+	return f.index.DirExists(path)
+}
+
+func (f *CASFileSystem) Read(path string) ([]byte, error) {
+	entry, err := f.lookup(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return f.blobs.Read(BlobPath(entry.Hash))
+}
+
+func (f *CASFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	entry, err := f.lookup(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return f.blobs.ReadStream(BlobPath(entry.Hash))
+}
+
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the blob is read.
+func (f *CASFileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := f.lookup(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	stream, err := f.blobs.ReadStream(BlobPath(entry.Hash))
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.NewProgressTracker(path, entry.Size, cfg.Progress()).ReadCloser(stream), nil
+}
+
+func (f *CASFileSystem) ReadDir(dir string) ([]os.DirEntry, error) {
+	entries, err := f.index.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if !entry.IsDir() {
+			entries[i] = &fileEntry{DirEntry: entry, f: f, path: path.Join(filesystem.CleanPath(dir), entry.Name())}
+		}
+	}
+	return entries, nil
+}
+
+func (f *CASFileSystem) WalkDir(dir string, walkFn gofs.WalkDirFunc) error {
+	return f.index.WalkDir(dir, func(p string, d gofs.DirEntry, err error) error {
+		if d != nil && !d.IsDir() {
+			d = &fileEntry{DirEntry: d, f: f, path: p}
+		}
+		return walkFn(p, d, err)
+	})
+}
+
+func (f *CASFileSystem) LastModified(path string) (time.Time, error) {
+	entry, err := f.Stat(path)
+	if err != nil {
+		if exists, _ := f.DirExists(path); exists {
+			return f.index.DirLastModified(path)
+		}
+		return time.Time{}, err
+	}
+	return entry.LastModified, nil
+}
+
+func (f *CASFileSystem) FileSize(path string) (int64, error) {
+	entry, err := f.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Size, nil
+}
+
+func (f *CASFileSystem) MimeType(path string) (string, error) {
+	entry, err := f.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return entry.MimeType, nil
+}
+
+func (f *CASFileSystem) Visibility(path string) (string, error) {
+	entry, err := f.lookup(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f.index.DirVisibility(path)
+	}
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return entry.Visibility, nil
+}
+
+func (f *CASFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return f.WriteStream(path, bytes.NewReader(content), config)
+}
+
+// WriteStream spools the stream to a local temporary file while hashing it,
+// then uploads the blob only if no file shares the same content.
+// The os.O_APPEND write flag is supported by concatenating the current content and the stream,
+// under the lock, so that a concurrent write cannot be lost.
+func (f *CASFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	visibility := ""
+	var cfg *filesystem.Config
+	appending := false
+	if config != nil {
+		var err error
+		cfg, err = filesystem.NewConfig(config)
+		if err != nil {
+			return err
+		}
+		if cfg.FileVisibility != nil {
+			visibility = *cfg.FileVisibility
+		}
+		appending = cfg.FileWriteFlag != nil && *cfg.FileWriteFlag&os.O_APPEND != 0
+	}
+	if appending {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if current, err := f.ReadStream(path); err == nil {
+			defer current.Close()
+			stream = io.MultiReader(current, stream)
+		}
+	}
+	blob, err := f.spool(path, stream)
+	if err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	defer blob.remove()
+	if !appending {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+	}
+	if err := f.upload(path, blob, cfg.Progress()); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	old, err := f.lookup(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	if visibility == "" && old != nil {
+		visibility = old.Visibility
+	}
+	if visibility == "" {
+		visibility = f.visibility
+	}
+	entry := &Entry{
+		Hash:         blob.hash,
+		Size:         blob.size,
+		MimeType:     blob.mimeType,
+		Visibility:   visibility,
+		LastModified: time.Now(),
+	}
+	if err := f.relink(path, entry, old); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	return nil
+}
+
+// relink points the path to the entry and moves the reference from the old entry, if any.
+// The new reference is taken before the pointer is written,
+// so that an interruption can only leave a count too high, which GC repairs, and never a dangling pointer.
+func (f *CASFileSystem) relink(path string, entry *Entry, old *Entry) error {
+	if old != nil && old.Hash == entry.Hash {
+		return f.index.Put(path, entry)
+	}
+	if _, err := f.index.Ref(entry.Hash, 1); err != nil {
+		return err
+	}
+	if err := f.index.Put(path, entry); err != nil {
+		return err
+	}
+	if old != nil {
+		return f.release(old.Hash)
+	}
+	return nil
+}
+
+// upload writes the blob to the blob store, unless it is already there.
+// The progress of the blob store is reported under the path of the file.
+func (f *CASFileSystem) upload(path string, blob *spooled, progress filesystem.ProgressFunc) error {
+	exists, err := f.blobs.FileExists(BlobPath(blob.hash))
+	if err != nil {
+		return err
+	}
+	if exists {
+		reportDone(path, blob.size, progress)
+		return nil
+	}
+	var config map[string]any
+	if progress != nil {
+		config = map[string]any{
+			filesystem.ProgressKey: func(p filesystem.Progress) {
+				p.Path = path
+				progress(p)
+			},
+			filesystem.ContentLengthKey: blob.size,
+		}
+	}
+	return f.blobs.WriteStream(BlobPath(blob.hash), blob.file, config)
+}
+
+// reportDone reports a file for which no content was transferred, e.g. a deduplicated blob.
+func reportDone(path string, size int64, progress filesystem.ProgressFunc) {
+	tracker := filesystem.NewProgressTracker(path, size, progress)
+	tracker.Add(size)
+	tracker.Done()
+}
+
+// SetVisibility updates the visibility recorded in the index, blobs are shared and keep their own.
+func (f *CASFileSystem) SetVisibility(path string, visibility string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, err := f.lookup(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f.index.SetDirVisibility(path, visibility)
+	}
+	if err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	entry.Visibility = visibility
+	if err := f.index.Put(path, entry); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	return nil
+}
+
+func (f *CASFileSystem) Delete(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, err := f.lookup(path)
+	if err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	if err := f.index.Remove(path); err != nil {
+		return err
+	}
+	if err := f.release(entry.Hash); err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	return nil
+}
+
+func (f *CASFileSystem) DeleteDir(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.entries(path)
+	if err != nil {
+		return filesystem.NewUnableToDeleteDirectory(path, err)
+	}
+	if err := f.index.DeleteDir(path); err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if err := f.release(entry.Hash); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return filesystem.NewUnableToDeleteDirectory(path, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (f *CASFileSystem) CreateDir(path string, config map[string]any) error {
+	return f.index.CreateDir(path, config)
+}
+
+// Move moves the pointers of a file or a directory, blobs are never touched.
+// An existing destination is replaced, and the references of its files are released,
+// but a directory is never replaced by a file.
+//
+// A replaced directory is first moved aside in the index, and moved back if the move fails,
+// so that its files are only removed, and their blobs released, once the source is in place.
+func (f *CASFileSystem) Move(src string, dst string, config map[string]any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	srcIsDir, err := f.index.DirExists(src)
+	if err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	if !srcIsDir {
+		if exists, err := f.index.FileExists(src); err != nil {
+			return filesystem.NewUnableToMove(src, dst, err)
+		} else if !exists {
+			return filesystem.NewUnableToMove(src, dst, os.ErrNotExist)
+		}
+	}
+	if filesystem.CleanPath(src) == filesystem.CleanPath(dst) {
+		return nil
+	}
+	old, err := f.lookup(dst)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	dstIsDir, err := f.index.DirExists(dst)
+	if err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	if dstIsDir && !srcIsDir {
+		return filesystem.NewUnableToMove(src, dst, filesystem.ErrIsNotFile)
+	}
+	var replaced []*Entry
+	var aside string
+	if dstIsDir {
+		if replaced, err = f.entries(dst); err != nil {
+			return filesystem.NewUnableToMove(src, dst, err)
+		}
+		aside = path.Join(path.Dir(filesystem.CleanPath(dst)), fmt.Sprintf(".%s.replaced-%d", path.Base(filesystem.CleanPath(dst)), time.Now().UnixNano()))
+		if err := f.index.Move(dst, aside, nil); err != nil {
+			return filesystem.NewUnableToMove(src, dst, err)
+		}
+	} else if old != nil && srcIsDir {
+		// the pointer of the file and the directory share the same name in the index
+		if err := f.index.Remove(dst); err != nil {
+			return filesystem.NewUnableToMove(src, dst, err)
+		}
+	}
+	if err := f.index.Move(src, dst, config); err != nil {
+		if aside != "" {
+			err = errors.Join(err, f.index.Move(aside, dst, nil))
+		} else if old != nil && srcIsDir {
+			err = errors.Join(err, f.index.Put(dst, old))
+		}
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	if aside != "" {
+		// the blobs are still referenced by the directory moved aside until it is deleted
+		if err := f.index.DeleteDir(aside); err != nil {
+			return filesystem.NewUnableToMove(src, dst, err)
+		}
+	}
+	if old != nil {
+		replaced = append(replaced, old)
+	}
+	var errs []error
+	for _, entry := range replaced {
+		if err := f.release(entry.Hash); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return filesystem.NewUnableToMove(src, dst, errors.Join(errs...))
+	}
+	return nil
+}
+
+// Copy adds a reference to the blob of every copied file, no content is transferred.
+// Every copied file is reported as done at once, with the overall progress for a directory.
+func (f *CASFileSystem) Copy(src string, dst string, config map[string]any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var visibility string
+	var cfg *filesystem.Config
+	if config != nil {
+		var err error
+		cfg, err = filesystem.NewConfig(config)
+		if err != nil {
+			return err
+		}
+		if cfg.FileVisibility != nil {
+			visibility = *cfg.FileVisibility
+		}
+	}
+	if exists, err := f.index.DirExists(src); err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	} else if !exists {
+		entry, err := f.copy(src, dst, visibility)
+		if err != nil {
+			return filesystem.NewUnableToCopyFile(src, dst, err)
+		}
+		reportDone(dst, entry.Size, cfg.Progress())
+		return nil
+	}
+	if err := f.index.CreateDir(dst, config); err != nil {
+		return err
+	}
+	var files []string
+	var total int64
+	err := f.index.Entries(src, func(p string, entry *Entry) error {
+		files = append(files, p)
+		total += entry.Size
+		return nil
+	})
+	if err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	aggregate := filesystem.NewAggregateProgress(len(files), total, cfg.Progress())
+	for _, p := range files {
+		rel := p
+		if filesystem.CleanPath(src) != "." {
+			rel = strings.TrimPrefix(p, filesystem.CleanPath(src)+"/")
+		}
+		target := path.Join(dst, rel)
+		entry, err := f.copy(p, target, visibility)
+		if err != nil {
+			return filesystem.NewUnableToCopyFile(src, dst, err)
+		}
+		_, finish := aggregate.File(target, entry.Size)
+		finish()
+	}
+	return nil
+}
+
+func (f *CASFileSystem) copy(src, dst, visibility string) (*Entry, error) {
+	entry, err := f.lookup(src)
+	if err != nil {
+		return nil, err
+	}
+	old, err := f.lookup(dst)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if visibility != "" {
+		entry.Visibility = visibility
+	}
+	entry.LastModified = time.Now()
+	return entry, f.relink(dst, entry, old)
+}
+
+// entries returns the index entries of every file under the directory.
+func (f *CASFileSystem) entries(dir string) ([]*Entry, error) {
+	var entries []*Entry
+	err := f.index.Entries(dir, func(_ string, entry *Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// spooled is an upload written to a local temporary file.
+type spooled struct {
+	file     *os.File
+	hash     string
+	size     int64
+	mimeType string
+}
+
+func (s *spooled) remove() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+func (f *CASFileSystem) spool(location string, stream io.Reader) (*spooled, error) {
+	file, err := os.CreateTemp(f.tempDir, "cas-*")
+	if err != nil {
+		return nil, err
+	}
+	blob := &spooled{file: file}
+	h := sha256.New()
+	if blob.size, err = io.Copy(io.MultiWriter(file, h), stream); err != nil {
+		blob.remove()
+		return nil, err
+	}
+	blob.hash = hex.EncodeToString(h.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		blob.remove()
+		return nil, err
+	}
+	header := make([]byte, mimeHeaderSize)
+	n, _ := io.ReadFull(file, header)
+	blob.mimeType = f.mimetypeDetector.Detect(location, header[:n])
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		blob.remove()
+		return nil, err
+	}
+	return blob, nil
+}
@@ -0,0 +1,500 @@
+package cas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	gofs "io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func newCAS(t *testing.T, opts ...Option) (*CASFileSystem, *memory.MemoryFileSystem) {
+	blobs := memory.NewMemoryFileSystem("public", nil)
+	f, err := NewCASFileSystem(blobs, append([]Option{WithTempDir(t.TempDir())}, opts...)...)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return f, blobs
+}
+
+func countBlobs(t *testing.T, f *CASFileSystem) int {
+	exists, err := f.blobs.DirExists(BlobDir)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if !exists {
+		return 0
+	}
+	count := 0
+	err = f.blobs.WalkDir(BlobDir, func(path string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return count
+}
+
+func TestCASFileSystem_Write(t *testing.T) {
+	f, blobs := newCAS(t)
+	if err := f.Write("a/report.pdf", []byte("same content"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("b/report.pdf", []byte("same content"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 1, countBlobs(t, f))
+	content, err := blobs.Read(BlobPath(hashOf("same content")))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "same content", string(content))
+	hash := hashOf("same content")
+	assert.Equal(t, "sha256/"+hash[:2]+"/"+hash[2:4]+"/"+hash, BlobPath(hash))
+
+	content, err = f.Read("b/report.pdf")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "same content", string(content))
+	size, err := f.FileSize("a/report.pdf")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, int64(12), size)
+
+	// overwriting the last reference deletes the old blob
+	if err := f.Write("a/report.pdf", []byte("new content"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("b/report.pdf", []byte("new content"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 1, countBlobs(t, f))
+	exists, err := blobs.FileExists(BlobPath(hashOf("same content")))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+}
+
+func TestCASFileSystem_Create(t *testing.T) {
+	f, _ := newCAS(t)
+	assert.Implements(t, (*filesystem.Creator)(nil), f)
+	if err := f.Write("report.pdf", []byte("previous"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	blobs := countBlobs(t, f)
+
+	w, err := f.Create("report.pdf", nil)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := w.Write([]byte("partial")); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NoError(t, w.Abort())
+	content, err := f.Read("report.pdf")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "previous", string(content))
+	assert.Equal(t, blobs, countBlobs(t, f))
+
+	w, err = f.Create("report.pdf", nil)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for _, part := range []string{"new ", "content"} {
+		if _, err := w.Write([]byte(part)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err = f.Read("report.pdf")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "new content", string(content))
+}
+
+func TestCASFileSystem_CopyDelete(t *testing.T) {
+	f, _ := newCAS(t)
+	if err := f.Write("src.txt", []byte("hello"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Copy("src.txt", "dst.txt", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	count, err := f.index.Ref(hashOf("hello"), 0)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 2, count)
+	if err := f.Delete("src.txt"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 1, countBlobs(t, f))
+	content, err := f.Read("dst.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "hello", string(content))
+	if err := f.Move("dst.txt", "dir/moved.txt", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	entries, err := f.ReadDir("dir")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if assert.Len(t, entries, 1) {
+		info, err := entries[0].Info()
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(5), info.Size())
+	}
+	if err := f.DeleteDir("dir"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 0, countBlobs(t, f))
+}
+
+func TestCASFileSystem_GC(t *testing.T) {
+	f, blobs := newCAS(t)
+	if err := f.Write("a.txt", []byte("a"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("b.txt", []byte("b"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// an orphaned blob, and a reference count which drifted
+	if err := blobs.Write(BlobPath(hashOf("orphan")), []byte("orphan"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := f.index.Ref(hashOf("a"), 3); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// a pointer whose blob was lost
+	if err := blobs.Delete(BlobPath(hashOf("b"))); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// the orphan may belong to an upload in progress until the grace period is over
+	report, err := f.GC()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 0, report.Orphans)
+	if err := blobs.SetLastModified(BlobPath(hashOf("orphan")), time.Now().Add(-2*defaultGCGracePeriod)); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := f.index.Ref(hashOf("a"), 3); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	report, err = f.GC()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 2, report.Blobs)
+	assert.Equal(t, 1, report.Orphans)
+	assert.Equal(t, 1, report.RefsRepaired)
+	assert.Equal(t, []string{"b.txt"}, report.Dangling)
+	count, err := f.index.Ref(hashOf("a"), 0)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestCASFileSystem_Index(t *testing.T) {
+	index := memory.NewMemoryFileSystem("public", nil)
+	f, blobs := newCAS(t, WithIndexFileSystem(index))
+	if err := f.Write("a.txt", []byte("a"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	exists, err := index.FileExists("index/tree/a.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, exists)
+	exists, err = blobs.DirExists("index")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+}
+
+// objectStore only knows the directories by their trailing slash, as the object stores.
+type objectStore struct {
+	*memory.MemoryFileSystem
+}
+
+func (s objectStore) DirExists(p string) (bool, error) {
+	if !strings.HasSuffix(p, "/") {
+		return false, nil
+	}
+	return s.MemoryFileSystem.DirExists(p)
+}
+
+func (s objectStore) CreateDir(p string, config map[string]any) error {
+	if !strings.HasSuffix(p, "/") {
+		return filesystem.NewUnableToCreateDirectory(p, filesystem.ErrIsNotDirectory)
+	}
+	return s.MemoryFileSystem.CreateDir(p, config)
+}
+
+func (s objectStore) ReadDir(p string) ([]gofs.DirEntry, error) {
+	if !strings.HasSuffix(p, "/") {
+		return nil, filesystem.NewUnableToReadDirectory(p, filesystem.ErrIsNotDirectory)
+	}
+	return s.MemoryFileSystem.ReadDir(p)
+}
+
+func (s objectStore) WalkDir(p string, walkFn gofs.WalkDirFunc) error {
+	if !strings.HasSuffix(p, "/") {
+		return filesystem.NewUnableToReadDirectory(p, filesystem.ErrIsNotDirectory)
+	}
+	return s.MemoryFileSystem.WalkDir(p, walkFn)
+}
+
+func (s objectStore) DeleteDir(p string) error {
+	if !strings.HasSuffix(p, "/") {
+		return filesystem.NewUnableToDeleteDirectory(p, filesystem.ErrIsNotDirectory)
+	}
+	return s.MemoryFileSystem.DeleteDir(p)
+}
+
+func TestFileSystemIndex_ObjectStore(t *testing.T) {
+	index, err := NewFileSystemIndex(objectStore{memory.NewMemoryFileSystem("public", nil)}, "index")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	f, _ := newCAS(t, WithIndex(index))
+	if err := f.Write("dir/a.txt", []byte("a"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	exists, err := f.DirExists("dir")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, exists)
+	entries, err := f.ReadDir("dir")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Len(t, entries, 1)
+	report, err := f.GC()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 1, report.Blobs)
+	assert.Equal(t, 0, report.RefsRepaired)
+	if err := f.DeleteDir("dir"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 0, countBlobs(t, f))
+}
+
+func TestCASFileSystem_MoveDir(t *testing.T) {
+	f, _ := newCAS(t)
+	if err := f.Write("src/a.txt", []byte("new"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("dst/a.txt", []byte("old a"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("dst/b.txt", []byte("old b"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Move("src", "dst", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// the destination is replaced, and the blobs of its files released
+	content, err := f.Read("dst/a.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "new", string(content))
+	exists, err := f.FileExists("dst/b.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+	assert.Equal(t, 1, countBlobs(t, f))
+}
+
+// failingMoveIndex fails every move of the given source.
+type failingMoveIndex struct {
+	Index
+	src string
+}
+
+func (i *failingMoveIndex) Move(src, dst string, config map[string]any) error {
+	if src == i.src {
+		return errors.New("move failed")
+	}
+	return i.Index.Move(src, dst, config)
+}
+
+func TestCASFileSystem_MoveReplace(t *testing.T) {
+	t.Run("failed move keeps the destination", func(t *testing.T) {
+		index, err := NewFileSystemIndex(memory.NewMemoryFileSystem("public", nil), "")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		f, _ := newCAS(t, WithIndex(&failingMoveIndex{Index: index, src: "src"}))
+		if err := f.Write("src/a.txt", []byte("new"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("dst/a.txt", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Error(t, f.Move("src", "dst", nil))
+		content, err := f.Read("dst/a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "old", string(content))
+		entries, err := f.ReadDir(".")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, entries, 2)
+		assert.Equal(t, 2, countBlobs(t, f))
+	})
+
+	t.Run("directory replaced by a file", func(t *testing.T) {
+		f, _ := newCAS(t)
+		if err := f.Write("src.txt", []byte("new"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("dst/a.txt", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.ErrorIs(t, f.Move("src.txt", "dst", nil), filesystem.ErrIsNotFile)
+		exists, err := f.FileExists("dst/a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		f, _ := newCAS(t)
+		if err := f.Write("dst/a.txt", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Error(t, f.Move("missing", "dst", nil))
+		assert.Equal(t, 1, countBlobs(t, f))
+	})
+
+	t.Run("file replaced by a directory", func(t *testing.T) {
+		f, _ := newCAS(t)
+		if err := f.Write("src/a.txt", []byte("new"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("dst", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Move("src", "dst", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err := f.FileExists("dst")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		content, err := f.Read("dst/a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "new", string(content))
+		assert.Equal(t, 1, countBlobs(t, f))
+	})
+}
+
+func TestCASFileSystem_Progress(t *testing.T) {
+	f, _ := newCAS(t)
+	var written []filesystem.Progress
+	progress := map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { written = append(written, p) },
+	}
+	if err := f.Write("dir/a.txt", []byte("hello"), progress); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// the blob is already stored, the second write is reported at once
+	if err := f.Write("dir/b.txt", []byte("hello"), progress); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("dir/sub/c.txt", []byte("world!"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	var done []string
+	for _, p := range written {
+		if p.Done {
+			done = append(done, p.Path)
+			assert.Equal(t, int64(5), p.Bytes)
+		}
+	}
+	assert.Equal(t, []string{"dir/a.txt", "dir/b.txt"}, done)
+
+	var last filesystem.Progress
+	err := f.Copy("dir", "copy", map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { last = p },
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if assert.NotNil(t, last.Overall) {
+		assert.Equal(t, 3, last.Overall.Files)
+		assert.Equal(t, 3, last.Overall.TotalFiles)
+		assert.Equal(t, int64(16), last.Overall.Bytes)
+		assert.Equal(t, int64(16), last.Overall.Total)
+	}
+}
+
+func TestCASFileSystem_SetLastModified(t *testing.T) {
+	f, _ := newCAS(t)
+	assert.Implements(t, (*filesystem.Toucher)(nil), f)
+	if err := f.Write("a.txt", []byte("hello"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := f.SetLastModified("a.txt", mtime); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	modified, err := f.LastModified("a.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, mtime.Equal(modified))
+
+	if err := f.Touch("b.txt"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := f.Read("b.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Empty(t, content)
+}
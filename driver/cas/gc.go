@@ -0,0 +1,136 @@
+package cas
+
+import (
+	"errors"
+	gofs "io/fs"
+	"path"
+	"time"
+)
+
+// GCReport is the outcome of a garbage collection pass.
+type GCReport struct {
+	// Blobs is the number of blobs found in the blob file system.
+	Blobs int
+	// Orphans is the number of deleted blobs which no file referenced.
+	Orphans int
+	// BytesFreed is the total size of the deleted blobs.
+	BytesFreed int64
+	// RefsRepaired is the number of reference counts which did not match the index and were rewritten.
+	RefsRepaired int
+	// Dangling are the paths whose blob is missing, their content is lost and they are left untouched.
+	Dangling []string
+}
+
+// GC recomputes the reference count of every blob from the index,
+// deletes the blobs which are not referenced anymore, and repairs the stored counts.
+//
+// Orphaned blobs are left behind when a process dies between an upload and the indexing of the file,
+// or when several processes update the same store; blobs younger than the grace period are kept,
+// since they may belong to an upload in progress in another process.
+func (f *CASFileSystem) GC() (*GCReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	report := new(GCReport)
+	counts := make(map[string]int)
+	err := f.index.Entries(".", func(p string, entry *Entry) error {
+		if counts[entry.Hash] == 0 {
+			if exists, err := f.blobs.FileExists(BlobPath(entry.Hash)); err != nil {
+				return err
+			} else if !exists {
+				report.Dangling = append(report.Dangling, p)
+			}
+		}
+		counts[entry.Hash]++
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	var errs []error
+	if err := f.collectBlobs(counts, report); err != nil {
+		errs = append(errs, err)
+	}
+	if err := f.repairRefs(counts, report); err != nil {
+		errs = append(errs, err)
+	}
+	return report, errors.Join(errs...)
+}
+
+func (f *CASFileSystem) collectBlobs(counts map[string]int, report *GCReport) error {
+	if exists, err := f.blobs.DirExists(BlobDir); err != nil || !exists {
+		return err
+	}
+	var orphans []string
+	err := f.blobs.WalkDir(BlobDir, func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		report.Blobs++
+		if counts[path.Base(p)] == 0 {
+			orphans = append(orphans, path.Base(p))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, hash := range orphans {
+		blobPath := BlobPath(hash)
+		if f.gcGracePeriod > 0 {
+			modified, err := f.blobs.LastModified(blobPath)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if time.Since(modified) < f.gcGracePeriod {
+				continue
+			}
+		}
+		size, err := f.blobs.FileSize(blobPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := f.blobs.Delete(blobPath); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		report.Orphans++
+		report.BytesFreed += size
+	}
+	return errors.Join(errs...)
+}
+
+func (f *CASFileSystem) repairRefs(counts map[string]int, report *GCReport) error {
+	// a corrupted count is returned as -1, so that it is always rewritten
+	stored, err := f.index.Refs()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for hash, count := range stored {
+		if counts[hash] == count {
+			continue
+		}
+		if err := f.index.SetRef(hash, counts[hash]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		report.RefsRepaired++
+	}
+	for hash, count := range counts {
+		if _, ok := stored[hash]; ok {
+			continue
+		}
+		if err := f.index.SetRef(hash, count); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		report.RefsRepaired++
+	}
+	return errors.Join(errs...)
+}
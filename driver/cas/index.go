@@ -0,0 +1,271 @@
+package cas
+
+import (
+	"encoding/json"
+	"fmt"
+	gofs "io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Index maps the paths of the files to their entries, keeps their directories,
+// and counts the references to every blob.
+//
+// The paths are the ones of the files in the [CASFileSystem], "." being its root.
+// The mutations of a [CASFileSystem] are serialized before they reach the index.
+type Index interface {
+	// Lookup returns the entry of the file, or an error wrapping os.ErrNotExist if it is not indexed.
+	Lookup(path string) (*Entry, error)
+	// Put sets the entry of the file, creating its parent directories.
+	Put(path string, entry *Entry) error
+	// Remove removes the entry of the file.
+	Remove(path string) error
+	// FileExists reports whether the file is indexed.
+	FileExists(path string) (bool, error)
+	// Entries calls fn with the path and the entry of every file under the directory.
+	Entries(dir string, fn func(path string, entry *Entry) error) error
+
+	// Ref adds delta to the reference count of the blob and returns the new count,
+	// a count which drops to 0 is removed.
+	Ref(hash string, delta int) (int, error)
+	// SetRef stores the reference count of the blob, 0 removes it.
+	SetRef(hash string, count int) error
+	// Refs returns every stored reference count, a corrupted count is returned as -1.
+	Refs() (map[string]int, error)
+
+	DirExists(path string) (bool, error)
+	CreateDir(path string, config map[string]any) error
+	// DeleteDir removes the directory and the entries under it.
+	DeleteDir(path string) error
+	// ReadDir lists the directory, the [CASFileSystem] describes its files with their entries.
+	ReadDir(path string) ([]os.DirEntry, error)
+	// WalkDir walks the directory, with the paths of the files in the [CASFileSystem].
+	WalkDir(path string, walkFn gofs.WalkDirFunc) error
+	// Move moves the entry of a file, or a directory with its entries.
+	Move(src, dst string, config map[string]any) error
+	DirVisibility(path string) (string, error)
+	SetDirVisibility(path string, visibility string) error
+	DirLastModified(path string) (time.Time, error)
+	SetDirLastModified(path string, t time.Time) error
+}
+
+// FileSystemIndex is an [Index] stored in a file system, by default the one of the blobs.
+//
+// The entry of every file is a small json pointer file under <prefix>/tree/<path>,
+// and the reference count of every blob is stored under <prefix>/refs/ab/cd/<hash>.
+// Directories are addressed with a trailing slash, which the object stores expect.
+type FileSystemIndex struct {
+	fs     fs.FileSystem
+	prefix string
+}
+
+// NewFileSystemIndex creates an index stored under the prefix of the file system.
+func NewFileSystemIndex(f fs.FileSystem, prefix string) (*FileSystemIndex, error) {
+	if f == nil {
+		return nil, ErrNoFileSystem
+	}
+	index := &FileSystemIndex{fs: f, prefix: prefix}
+	if exists, err := f.DirExists(index.treeRoot()); err != nil {
+		return nil, err
+	} else if !exists {
+		if err := f.CreateDir(index.treeRoot(), nil); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func (i *FileSystemIndex) treeRoot() string {
+	return path.Join(i.prefix, "tree") + "/"
+}
+
+// tree returns the path of the pointer file of a file.
+func (i *FileSystemIndex) tree(p string) string {
+	return path.Join(i.prefix, "tree", filesystem.CleanPath(p))
+}
+
+// treeDir returns the path of a directory, with its trailing slash.
+func (i *FileSystemIndex) treeDir(p string) string {
+	if filesystem.CleanPath(p) == "." {
+		return i.treeRoot()
+	}
+	return i.tree(p) + "/"
+}
+
+func (i *FileSystemIndex) refsRoot() string {
+	return path.Join(i.prefix, "refs") + "/"
+}
+
+func (i *FileSystemIndex) refPath(hash string) string {
+	return path.Join(i.prefix, "refs", hash[:2], hash[2:4], hash)
+}
+
+func (i *FileSystemIndex) Lookup(p string) (*Entry, error) {
+	exists, err := i.fs.FileExists(i.tree(p))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, os.ErrNotExist
+	}
+	content, err := i.fs.Read(i.tree(p))
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, fmt.Errorf("corrupted index entry %s: %w", filesystem.CleanPath(p), err)
+	}
+	return &entry, nil
+}
+
+func (i *FileSystemIndex) Put(p string, entry *Entry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return i.fs.Write(i.tree(p), content, nil)
+}
+
+func (i *FileSystemIndex) Remove(p string) error {
+	return i.fs.Delete(i.tree(p))
+}
+
+func (i *FileSystemIndex) FileExists(p string) (bool, error) {
+	return i.fs.FileExists(i.tree(p))
+}
+
+func (i *FileSystemIndex) Entries(dir string, fn func(path string, entry *Entry) error) error {
+	return i.WalkDir(dir, func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		entry, err := i.Lookup(p)
+		if err != nil {
+			return err
+		}
+		return fn(p, entry)
+	})
+}
+
+func (i *FileSystemIndex) Ref(hash string, delta int) (int, error) {
+	refPath := i.refPath(hash)
+	count := 0
+	exists, err := i.fs.FileExists(refPath)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		content, err := i.fs.Read(refPath)
+		if err != nil {
+			return 0, err
+		}
+		if count, err = strconv.Atoi(strings.TrimSpace(string(content))); err != nil {
+			return 0, err
+		}
+	}
+	count += delta
+	if count <= 0 {
+		if exists {
+			return 0, i.fs.Delete(refPath)
+		}
+		return 0, nil
+	}
+	return count, i.fs.Write(refPath, []byte(strconv.Itoa(count)), nil)
+}
+
+func (i *FileSystemIndex) SetRef(hash string, count int) error {
+	if count <= 0 {
+		return i.fs.Delete(i.refPath(hash))
+	}
+	return i.fs.Write(i.refPath(hash), []byte(strconv.Itoa(count)), nil)
+}
+
+func (i *FileSystemIndex) Refs() (map[string]int, error) {
+	refs := make(map[string]int)
+	if exists, err := i.fs.DirExists(i.refsRoot()); err != nil || !exists {
+		return refs, err
+	}
+	err := filesystem.WalkDirRelative(i.fs, i.refsRoot(), func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		p = filesystem.JoinWalked(i.refsRoot(), p, false)
+		content, err := i.fs.Read(p)
+		if err != nil {
+			return err
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			count = -1
+		}
+		refs[path.Base(p)] = count
+		return nil
+	})
+	return refs, err
+}
+
+func (i *FileSystemIndex) DirExists(p string) (bool, error) {
+	return i.fs.DirExists(i.treeDir(p))
+}
+
+func (i *FileSystemIndex) CreateDir(p string, config map[string]any) error {
+	return i.fs.CreateDir(i.treeDir(p), config)
+}
+
+func (i *FileSystemIndex) DeleteDir(p string) error {
+	return i.fs.DeleteDir(i.treeDir(p))
+}
+
+func (i *FileSystemIndex) ReadDir(p string) ([]os.DirEntry, error) {
+	return i.fs.ReadDir(i.treeDir(p))
+}
+
+func (i *FileSystemIndex) WalkDir(dir string, walkFn gofs.WalkDirFunc) error {
+	return filesystem.WalkDirRelative(i.fs, i.treeDir(dir), func(p string, d gofs.DirEntry, err error) error {
+		return walkFn(path.Join(filesystem.CleanPath(dir), p), d, err)
+	})
+}
+
+func (i *FileSystemIndex) Move(src, dst string, config map[string]any) error {
+	isDir, err := i.DirExists(src)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		return i.fs.Move(i.treeDir(src), i.treeDir(dst), config)
+	}
+	return i.fs.Move(i.tree(src), i.tree(dst), config)
+}
+
+func (i *FileSystemIndex) DirVisibility(p string) (string, error) {
+	return i.fs.Visibility(i.treeDir(p))
+}
+
+func (i *FileSystemIndex) SetDirVisibility(p string, visibility string) error {
+	return i.fs.SetVisibility(i.treeDir(p), visibility)
+}
+
+func (i *FileSystemIndex) DirLastModified(p string) (time.Time, error) {
+	return i.fs.LastModified(i.treeDir(p))
+}
+
+func (i *FileSystemIndex) SetDirLastModified(p string, t time.Time) error {
+	toucher, ok := i.fs.(filesystem.Toucher)
+	if !ok {
+		return filesystem.ErrSetLastModifiedUnsupported
+	}
+	return toucher.SetLastModified(i.treeDir(p), t)
+}
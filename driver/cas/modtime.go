@@ -0,0 +1,47 @@
+package cas
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time recorded in the index to now, writing an empty file if it is missing.
+func (f *CASFileSystem) Touch(path string) error {
+	exists, err := f.FileExists(path)
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	if !exists {
+		return f.WriteStream(path, bytes.NewReader(nil), nil)
+	}
+	return f.SetLastModified(path, time.Now())
+}
+
+// SetLastModified updates the modification time recorded in the index, the blobs are left as they are.
+// For directories, it is passed to the index.
+func (f *CASFileSystem) SetLastModified(path string, t time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, err := f.lookup(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if exists, err := f.index.DirExists(path); err != nil || !exists {
+			return filesystem.NewUnableToSetLastModified(path, os.ErrNotExist)
+		}
+		if err := f.index.SetDirLastModified(path, t); err != nil {
+			return filesystem.NewUnableToSetLastModified(path, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	entry.LastModified = t
+	if err := f.index.Put(path, entry); err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cas
+
+import (
+	"time"
+
+	"github.com/gopi-frame/contract"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*CASFileSystem]
+
+type OptionFunc func(f *CASFileSystem) error
+
+func (o OptionFunc) Apply(f *CASFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *CASFileSystem) error {
+	return nil
+})
+
+// WithIndex sets the index, instead of a [FileSystemIndex].
+func WithIndex(index Index) Option {
+	if index == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.index = index
+		return nil
+	})
+}
+
+// WithIndexFileSystem stores the [FileSystemIndex] in another file system than the blobs.
+func WithIndexFileSystem(index fs.FileSystem) Option {
+	if index == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.indexFileSystem = index
+		return nil
+	})
+}
+
+// WithIndexPrefix sets the directory of the [FileSystemIndex] in its file system.
+func WithIndexPrefix(prefix string) Option {
+	if prefix == "" {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.indexPrefix = prefix
+		return nil
+	})
+}
+
+// WithVisibility sets the default visibility of files and directories.
+func WithVisibility(visibility string) Option {
+	if visibility == "" {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.visibility = visibility
+		return nil
+	})
+}
+
+// WithTempDir sets the local directory uploads are spooled to while they are hashed.
+func WithTempDir(dir string) Option {
+	if dir == "" {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.tempDir = dir
+		return nil
+	})
+}
+
+// WithGCGracePeriod protects blobs younger than the period from the garbage collector, default is 1h.
+// A period of 0 lets the garbage collector delete every orphaned blob,
+// including those of the uploads in progress in other processes.
+func WithGCGracePeriod(period time.Duration) Option {
+	if period < 0 {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.gcGracePeriod = period
+		return nil
+	})
+}
+
+// WithMimeTypeDetector sets the detector used to compute the mime type of uploaded files.
+func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
+	if detector == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *CASFileSystem) error {
+		f.mimetypeDetector = detector
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"io"
+	gofs "io/fs"
+	"net"
+	"os"
+	"syscall"
+)
+
+// ErrorClass tells the failover file system how to react to an error.
+type ErrorClass int
+
+const (
+	// ClassOther errors are returned to the caller without trying the next backend.
+	ClassOther ErrorClass = iota
+	// ClassConnection errors mark the backend as down and the next backend is tried.
+	ClassConnection
+	// ClassNotFound errors try the next backend only when fallback on not found is enabled.
+	ClassNotFound
+)
+
+// Classifier returns the class of an error returned by a backend.
+type Classifier func(err error) ErrorClass
+
+// DefaultClassifier treats network errors, timeouts and broken connections as connection errors,
+// and [fs.ErrNotExist] as not found.
+func DefaultClassifier(err error) ErrorClass {
+	if err == nil {
+		return ClassOther
+	}
+	if errors.Is(err, gofs.ErrNotExist) {
+		return ClassNotFound
+	}
+	if IsConnectionError(err) {
+		return ClassConnection
+	}
+	return ClassOther
+}
+
+// IsConnectionError reports whether the error is caused by an unreachable or broken backend.
+func IsConnectionError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	for _, target := range []error{
+		syscall.ECONNREFUSED,
+		syscall.ECONNRESET,
+		syscall.ECONNABORTED,
+		syscall.EHOSTUNREACH,
+		syscall.ENETUNREACH,
+		syscall.EPIPE,
+		syscall.ETIMEDOUT,
+		io.ErrUnexpectedEOF,
+		os.ErrDeadlineExceeded,
+		context.DeadlineExceeded,
+		net.ErrClosed,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package failover
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Config struct {
+	// FileSystems are the backends in order of preference, the first one is the primary.
+	FileSystems []fs.FileSystem
+	// FallbackOnNotFound makes reads try the next backend when a file is not found,
+	// by default a not-found error is returned as is.
+	FallbackOnNotFound bool
+	// WritePolicy is one of "primary" or "first_healthy", default is "primary".
+	WritePolicy WritePolicy
+	// ProbeInterval is the interval between two health probes, default is 10s.
+	// A negative value disables the background probes.
+	ProbeInterval time.Duration
+	// ProbePath is the path checked by the default health probe, default is ".".
+	ProbePath string
+}
+
+func (c *Config) Apply(f *FailoverFileSystem) error {
+	f.fallbackOnNotFound = c.FallbackOnNotFound
+	if c.WritePolicy != "" {
+		f.writePolicy = c.WritePolicy
+	}
+	if c.ProbeInterval != 0 {
+		f.probeInterval = c.ProbeInterval
+	}
+	if c.ProbePath != "" {
+		f.probePath = c.ProbePath
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,25 @@
+package failover
+
+import (
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+var driverName = "failover"
+
+func init() {
+	//goland:noinspection GoBoolExpressions
+	if driverName != "" {
+		filesystem.Register(driverName, &Driver{})
+	}
+}
+
+type Driver struct{}
+
+func (d *Driver) Open(options map[string]any) (fs.FileSystem, error) {
+	cfg, err := ConfigFromMap(options)
+	if err != nil {
+		return nil, err
+	}
+	return NewFailoverFileSystem(cfg.FileSystems, cfg)
+}
@@ -0,0 +1,375 @@
+package failover
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+// WritePolicy decides which backend receives the write operations.
+type WritePolicy string
+
+const (
+	// WritePrimary always writes to the first backend, whatever its health.
+	WritePrimary WritePolicy = "primary"
+	// WriteFirstHealthy writes to the first backend which is not marked as down.
+	WriteFirstHealthy WritePolicy = "first_healthy"
+)
+
+const defaultProbeInterval = 10 * time.Second
+
+var ErrNoFileSystem = errors.New("at least one file system is required")
+var ErrUnavailable = errors.New("no file system available")
+
+// Probe checks the health of a backend.
+type Probe func(f fs.FileSystem) error
+
+// FailoverFileSystem is a wrapper which serves reads from an ordered list of file systems.
+//
+// A read is tried on every healthy backend in order until one succeeds.
+// Connection errors mark the backend as down and move on to the next one,
+// not found errors do the same only when fallback on not found is enabled,
+// any other error is returned as is.
+// Backends marked as down are skipped until a health probe or a successful call brings them back,
+// if every backend is down, all of them are tried anyway.
+//
+// Writes are not failed over, they are routed to a single backend according to the write policy.
+type FailoverFileSystem struct {
+	backends []fs.FileSystem
+	down     []atomic.Bool
+
+	fallbackOnNotFound bool
+	writePolicy        WritePolicy
+	probeInterval      time.Duration
+	probePath          string
+	probe              Probe
+	classify           Classifier
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewFailoverFileSystem creates a failover file system over the backends, the first one is the primary.
+//
+// Unless the probe interval is negative, a background worker probes the backends periodically,
+// call [FailoverFileSystem.Close] to stop it.
+func NewFailoverFileSystem(backends []fs.FileSystem, opts ...Option) (*FailoverFileSystem, error) {
+	if len(backends) == 0 {
+		return nil, ErrNoFileSystem
+	}
+	f := &FailoverFileSystem{
+		backends:      backends,
+		down:          make([]atomic.Bool, len(backends)),
+		writePolicy:   WritePrimary,
+		probeInterval: defaultProbeInterval,
+		probePath:     ".",
+		classify:      DefaultClassifier,
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	switch f.writePolicy {
+	case WritePrimary, WriteFirstHealthy:
+	default:
+		return nil, fmt.Errorf("unknown write policy: %s", f.writePolicy)
+	}
+	if f.probe == nil {
+		f.probe = func(backend fs.FileSystem) error {
+			_, err := backend.Exists(f.probePath)
+			return err
+		}
+	}
+	if f.probeInterval > 0 {
+		f.wg.Add(1)
+		go f.run()
+	}
+	return f, nil
+}
+
+// Close stops the health probes.
+func (f *FailoverFileSystem) Close() error {
+	f.closeOnce.Do(func() {
+		close(f.done)
+	})
+	f.wg.Wait()
+	return nil
+}
+
+// Healthy reports the health of every backend, in the order they were given.
+func (f *FailoverFileSystem) Healthy() []bool {
+	healthy := make([]bool, len(f.backends))
+	for i := range f.backends {
+		healthy[i] = !f.down[i].Load()
+	}
+	return healthy
+}
+
+// Probe checks the health of every backend once.
+func (f *FailoverFileSystem) Probe() {
+	var wg sync.WaitGroup
+	for i, backend := range f.backends {
+		wg.Add(1)
+		go func(i int, backend fs.FileSystem) {
+			defer wg.Done()
+			err := f.probe(backend)
+			f.down[i].Store(err != nil && f.classify(err) == ClassConnection)
+		}(i, backend)
+	}
+	wg.Wait()
+}
+
+func (f *FailoverFileSystem) run() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(f.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			f.Probe()
+		}
+	}
+}
+
+// candidates returns the indexes of the healthy backends,
+// or of all backends if none is healthy.
+func (f *FailoverFileSystem) candidates() []int {
+	var indexes []int
+	for i := range f.backends {
+		if !f.down[i].Load() {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		for i := range f.backends {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// failover runs the operation on the candidates until one succeeds or returns an error which is not failed over.
+// missing reports whether a successful result means the path was not found, e.g. false for Exists.
+func failover[T any](f *FailoverFileSystem, op func(backend fs.FileSystem) (T, error), missing func(T) bool) (T, error) {
+	var result T
+	var notFound error
+	var errs []error
+	found := false
+	for _, i := range f.candidates() {
+		value, err := op(f.backends[i])
+		if err == nil {
+			f.down[i].Store(false)
+			if missing != nil && missing(value) && f.fallbackOnNotFound {
+				if !found {
+					result, found = value, true
+				}
+				continue
+			}
+			return value, nil
+		}
+		switch f.classify(err) {
+		case ClassConnection:
+			f.down[i].Store(true)
+			errs = append(errs, err)
+			continue
+		case ClassNotFound:
+			if f.fallbackOnNotFound {
+				if notFound == nil {
+					notFound = err
+				}
+				continue
+			}
+		}
+		return value, err
+	}
+	if found {
+		return result, nil
+	}
+	if notFound != nil {
+		return result, notFound
+	}
+	return result, errors.Join(append([]error{ErrUnavailable}, errs...)...)
+}
+
+// writer returns the backend write operations are routed to.
+func (f *FailoverFileSystem) writer() (int, fs.FileSystem) {
+	if f.writePolicy == WriteFirstHealthy {
+		for i, backend := range f.backends {
+			if !f.down[i].Load() {
+				return i, backend
+			}
+		}
+	}
+	return 0, f.backends[0]
+}
+
+// write runs the write operation on the routed backend, keeping its health up to date.
+func (f *FailoverFileSystem) write(op func(backend fs.FileSystem) error) error {
+	i, backend := f.writer()
+	err := op(backend)
+	if err == nil {
+		f.down[i].Store(false)
+	} else if f.classify(err) == ClassConnection {
+		f.down[i].Store(true)
+	}
+	return err
+}
+
+func isFalse(exists bool) bool {
+	return !exists
+}
+
+func (f *FailoverFileSystem) Exists(path string) (bool, error) {
+	return failover(f, func(backend fs.FileSystem) (bool, error) {
+		return backend.Exists(path)
+	}, isFalse)
+}
+
+func (f *FailoverFileSystem) FileExists(path string) (bool, error) {
+	return failover(f, func(backend fs.FileSystem) (bool, error) {
+		return backend.FileExists(path)
+	}, isFalse)
+}
+
+func (f *FailoverFileSystem) DirExists(path string) (bool, error) {
+	return failover(f, func(backend fs.FileSystem) (bool, error) {
+		return backend.DirExists(path)
+	}, isFalse)
+}
+
+func (f *FailoverFileSystem) Read(path string) ([]byte, error) {
+	return failover(f, func(backend fs.FileSystem) ([]byte, error) {
+		return backend.Read(path)
+	}, nil)
+}
+
+// ReadStream opens the stream on the first backend which succeeds,
+// errors happening while the stream is read are not failed over.
+func (f *FailoverFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	return failover(f, func(backend fs.FileSystem) (io.ReadCloser, error) {
+		return backend.ReadStream(path)
+	}, nil)
+}
+
+func (f *FailoverFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	return failover(f, func(backend fs.FileSystem) ([]os.DirEntry, error) {
+		return backend.ReadDir(path)
+	}, nil)
+}
+
+// WalkDir walks the first backend which succeeds.
+// Once an entry has been passed to walkFn, errors are returned as is,
+// so that the same entry is never visited twice.
+func (f *FailoverFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	_, err := failover(f, func(backend fs.FileSystem) (struct{}, error) {
+		visited := false
+		err := backend.WalkDir(path, func(path string, d gofs.DirEntry, err error) error {
+			visited = true
+			return walkFn(path, d, err)
+		})
+		if err != nil && visited {
+			// failing over would replay entries which have already been visited,
+			// hide the error class from the failover loop
+			return struct{}{}, &walkError{err}
+		}
+		return struct{}{}, err
+	}, nil)
+	var walkErr *walkError
+	if errors.As(err, &walkErr) {
+		return walkErr.err
+	}
+	return err
+}
+
+func (f *FailoverFileSystem) LastModified(path string) (time.Time, error) {
+	return failover(f, func(backend fs.FileSystem) (time.Time, error) {
+		return backend.LastModified(path)
+	}, nil)
+}
+
+func (f *FailoverFileSystem) FileSize(path string) (int64, error) {
+	return failover(f, func(backend fs.FileSystem) (int64, error) {
+		return backend.FileSize(path)
+	}, nil)
+}
+
+func (f *FailoverFileSystem) MimeType(path string) (string, error) {
+	return failover(f, func(backend fs.FileSystem) (string, error) {
+		return backend.MimeType(path)
+	}, nil)
+}
+
+func (f *FailoverFileSystem) Visibility(path string) (string, error) {
+	return failover(f, func(backend fs.FileSystem) (string, error) {
+		return backend.Visibility(path)
+	}, nil)
+}
+
+func (f *FailoverFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.Write(path, content, config)
+	})
+}
+
+func (f *FailoverFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.WriteStream(path, stream, config)
+	})
+}
+
+func (f *FailoverFileSystem) SetVisibility(path string, visibility string) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.SetVisibility(path, visibility)
+	})
+}
+
+func (f *FailoverFileSystem) Delete(path string) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.Delete(path)
+	})
+}
+
+func (f *FailoverFileSystem) DeleteDir(path string) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.DeleteDir(path)
+	})
+}
+
+func (f *FailoverFileSystem) CreateDir(path string, config map[string]any) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.CreateDir(path, config)
+	})
+}
+
+func (f *FailoverFileSystem) Move(src string, dst string, config map[string]any) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.Move(src, dst, config)
+	})
+}
+
+func (f *FailoverFileSystem) Copy(src string, dst string, config map[string]any) error {
+	return f.write(func(backend fs.FileSystem) error {
+		return backend.Copy(src, dst, config)
+	})
+}
+
+// walkError wraps an error which happened after the walk started, it is never failed over.
+type walkError struct {
+	err error
+}
+
+func (e *walkError) Error() string {
+	return e.err.Error()
+}
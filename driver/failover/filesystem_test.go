@@ -0,0 +1,221 @@
+package failover
+
+import (
+	"errors"
+	"io"
+	gofs "io/fs"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem/driver/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unreachable is a memory file system which fails with a connection error while it is down.
+type unreachable struct {
+	*memory.MemoryFileSystem
+	down  atomic.Bool
+	calls atomic.Int32
+}
+
+func newUnreachable() *unreachable {
+	return &unreachable{MemoryFileSystem: memory.NewMemoryFileSystem("public", nil)}
+}
+
+func (u *unreachable) err() error {
+	u.calls.Add(1)
+	if u.down.Load() {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	return nil
+}
+
+func (u *unreachable) Exists(path string) (bool, error) {
+	if err := u.err(); err != nil {
+		return false, err
+	}
+	return u.MemoryFileSystem.Exists(path)
+}
+
+func (u *unreachable) Read(path string) ([]byte, error) {
+	if err := u.err(); err != nil {
+		return nil, err
+	}
+	return u.MemoryFileSystem.Read(path)
+}
+
+func (u *unreachable) ReadStream(path string) (io.ReadCloser, error) {
+	if err := u.err(); err != nil {
+		return nil, err
+	}
+	return u.MemoryFileSystem.ReadStream(path)
+}
+
+func (u *unreachable) Write(path string, content []byte, config map[string]any) error {
+	if err := u.err(); err != nil {
+		return err
+	}
+	return u.MemoryFileSystem.Write(path, content, config)
+}
+
+func TestFailoverFileSystem_Read(t *testing.T) {
+	t.Run("connection error", func(t *testing.T) {
+		primary := newUnreachable()
+		fallback := memory.NewMemoryFileSystem("public", nil)
+		if err := fallback.Write("test.txt", []byte("fallback"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer f.Close()
+		primary.down.Store(true)
+		content, err := f.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "fallback", string(content))
+		assert.Equal(t, []bool{false, true}, f.Healthy())
+
+		// the primary is skipped while it is marked as down
+		stream, err := f.ReadStream("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_ = stream.Close()
+		assert.Equal(t, int32(1), primary.calls.Load())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		fallback := memory.NewMemoryFileSystem("public", nil)
+		if err := fallback.Write("test.txt", []byte("fallback"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer f.Close()
+		_, err = f.Read("test.txt")
+		assert.ErrorIs(t, err, gofs.ErrNotExist)
+		exists, err := f.Exists("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("fallback on not found", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		fallback := memory.NewMemoryFileSystem("public", nil)
+		if err := fallback.Write("test.txt", []byte("fallback"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1), WithFallbackOnNotFound())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer f.Close()
+		content, err := f.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "fallback", string(content))
+		exists, err := f.Exists("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+		_, err = f.Read("missing.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("all unavailable", func(t *testing.T) {
+		primary := newUnreachable()
+		fallback := newUnreachable()
+		primary.down.Store(true)
+		fallback.down.Store(true)
+		f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer f.Close()
+		_, err = f.Read("test.txt")
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	})
+}
+
+func TestFailoverFileSystem_Probe(t *testing.T) {
+	primary := newUnreachable()
+	fallback := memory.NewMemoryFileSystem("public", nil)
+	f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer f.Close()
+	primary.down.Store(true)
+	f.Probe()
+	assert.Equal(t, []bool{false, true}, f.Healthy())
+	primary.down.Store(false)
+	f.Probe()
+	assert.Equal(t, []bool{true, true}, f.Healthy())
+}
+
+func TestFailoverFileSystem_Write(t *testing.T) {
+	t.Run("primary", func(t *testing.T) {
+		primary := newUnreachable()
+		fallback := memory.NewMemoryFileSystem("public", nil)
+		f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer f.Close()
+		primary.down.Store(true)
+		f.Probe()
+		err = f.Write("test.txt", []byte("hello"), nil)
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+		exists, err := fallback.Exists("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("first healthy", func(t *testing.T) {
+		primary := newUnreachable()
+		fallback := memory.NewMemoryFileSystem("public", nil)
+		f, err := NewFailoverFileSystem([]fs.FileSystem{primary, fallback}, WithProbeInterval(-1), WithWritePolicy(WriteFirstHealthy))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer f.Close()
+		if err := f.Write("test.txt", []byte("primary"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		primary.down.Store(true)
+		f.Probe()
+		if err := f.Write("test.txt", []byte("fallback"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := fallback.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "fallback", string(content))
+	})
+}
+
+func TestDefaultClassifier(t *testing.T) {
+	assert.Equal(t, ClassConnection, DefaultClassifier(&net.OpError{Op: "read", Err: syscall.ECONNRESET}))
+	assert.Equal(t, ClassConnection, DefaultClassifier(io.ErrUnexpectedEOF))
+	assert.Equal(t, ClassNotFound, DefaultClassifier(os.ErrNotExist))
+	assert.Equal(t, ClassOther, DefaultClassifier(errors.New("permission denied")))
+}
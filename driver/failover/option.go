@@ -0,0 +1,71 @@
+package failover
+
+import (
+	"time"
+
+	"github.com/gopi-frame/contract"
+)
+
+type Option = contract.Option[*FailoverFileSystem]
+
+type OptionFunc func(f *FailoverFileSystem) error
+
+func (o OptionFunc) Apply(f *FailoverFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *FailoverFileSystem) error {
+	return nil
+})
+
+// WithFallbackOnNotFound makes reads try the next backend when a file is not found.
+func WithFallbackOnNotFound() Option {
+	return OptionFunc(func(f *FailoverFileSystem) error {
+		f.fallbackOnNotFound = true
+		return nil
+	})
+}
+
+// WithWritePolicy sets how write operations are routed.
+func WithWritePolicy(policy WritePolicy) Option {
+	if policy == "" {
+		return noneOption
+	}
+	return OptionFunc(func(f *FailoverFileSystem) error {
+		f.writePolicy = policy
+		return nil
+	})
+}
+
+// WithProbeInterval sets the interval between two health probes, a negative interval disables them.
+func WithProbeInterval(interval time.Duration) Option {
+	if interval == 0 {
+		return noneOption
+	}
+	return OptionFunc(func(f *FailoverFileSystem) error {
+		f.probeInterval = interval
+		return nil
+	})
+}
+
+// WithProbe sets the health probe, a backend is considered down when the probe returns a connection error.
+func WithProbe(probe Probe) Option {
+	if probe == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *FailoverFileSystem) error {
+		f.probe = probe
+		return nil
+	})
+}
+
+// WithClassifier sets the function deciding which errors trigger a failover.
+func WithClassifier(classifier Classifier) Option {
+	if classifier == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *FailoverFileSystem) error {
+		f.classify = classifier
+		return nil
+	})
+}
@@ -0,0 +1,116 @@
+package ftp
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/gopi-frame/ftp"
+)
+
+// writeAtomic stores the content to a temporary file next to the target,
+// and renames it over the target with RNFR/RNTO once the transfer is complete.
+// With os.O_APPEND, the current content is streamed from a second connection to the temporary file first.
+// The temporary file is deleted if any step fails.
+//
+// Most servers replace the target on RNTO, but some refuse to rename over an existing file,
+// in which case the write fails and the target is left unchanged.
+func (f *FTPFileSystem) writeAtomic(conn *ftp.ServerConn, path string, content io.Reader, flag int, mode os.FileMode, tracker *filesystem.ProgressTracker) (err error) {
+	if flag&os.O_APPEND != 0 {
+		current, err := f.retrieveStream(conn, path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+		if current != nil {
+			defer current.Close()
+			content = io.MultiReader(current, content)
+		}
+	}
+	temp := filesystem.AtomicTempPath(path)
+	defer func() {
+		if err != nil {
+			_ = conn.Delete(temp)
+		}
+	}()
+	if err := store(func(r io.Reader) error { return conn.Stor(temp, r) }, content, tracker); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	if err := f.setPermission(conn, temp, mode); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	if err := conn.Rename(temp, path); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	tracker.Done()
+	return nil
+}
+
+// retrieve returns the content of the file, or an os.ErrNotExist error if it does not exist.
+func (f *FTPFileSystem) retrieve(conn *ftp.ServerConn, path string) ([]byte, error) {
+	if _, err := f.getEntry(conn, path); err != nil {
+		return nil, err
+	}
+	resp, err := conn.Retr(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(resp)
+	if err1 := resp.Close(); err == nil {
+		err = err1
+	}
+	return content, err
+}
+
+// retrieveStream opens the content of the file on a connection of its own,
+// so that it is read while conn stores it, or returns an os.ErrNotExist error if it does not exist.
+// Closing the stream puts the connection back.
+func (f *FTPFileSystem) retrieveStream(conn *ftp.ServerConn, path string) (io.ReadCloser, error) {
+	if _, err := f.getEntry(conn, path); err != nil {
+		return nil, err
+	}
+	retrConn, err := f.connPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := retrConn.Retr(path)
+	if err != nil {
+		f.connPool.Put(retrConn)
+		return nil, err
+	}
+	return &pooledResponse{Response: resp, put: func() { f.connPool.Put(retrConn) }}, nil
+}
+
+// pooledResponse puts the connection of the response back once it is closed.
+type pooledResponse struct {
+	*ftp.Response
+	put func()
+}
+
+func (r *pooledResponse) Close() error {
+	err := r.Response.Close()
+	r.put()
+	return err
+}
+
+// store runs the storing command with the content, counting the progress with the tracker.
+// The command copies its reader to the data connection itself,
+// so the content is copied to it through a pipe by a loop which counts the bytes taken by the command.
+func store(stor func(r io.Reader) error, content io.Reader, tracker *filesystem.ProgressTracker) error {
+	if tracker == nil {
+		return stor(content)
+	}
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := filesystem.CopyWithProgress(pw, content, tracker)
+		_ = pw.CloseWithError(err)
+	}()
+	err := stor(pr)
+	// unblocks the copy if the command stopped reading early
+	_ = pr.Close()
+	<-done
+	return err
+}
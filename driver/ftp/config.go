@@ -66,6 +66,8 @@ type Config struct {
 		Directory string
 	}
 	MimeTypeDetector filesystem.MimeTypeDetector
+	// MetadataSidecar keeps the metadata and tags of the files in sidecar files, see [WithMetadataSidecar].
+	MetadataSidecar bool
 }
 
 func ConfigFromMap(config map[string]any) (*Config, error) {
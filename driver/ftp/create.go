@@ -0,0 +1,30 @@
+package ftp
+
+import (
+	"io"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Create returns a writer to the file, its content is sent over the data connection of a STOR command
+// held open until the writer is closed.
+// Aborting the writer closes the data connection, and deletes the partially stored file if the writer created it,
+// see [filesystem.DiscardFunc]. An atomic write only leaves its temporary file, which is deleted.
+func (f *FTPFileSystem) Create(path string, config map[string]any) (filesystem.FileWriter, error) {
+	var cfg *filesystem.Config
+	if config != nil {
+		var err error
+		if cfg, err = filesystem.NewConfig(config); err != nil {
+			return nil, filesystem.NewUnableToWriteFile(path, err)
+		}
+	}
+	existed, err := f.FileExists(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(path, err)
+	}
+	return filesystem.NewPipeWriter(func(stream io.Reader) error {
+		return f.WriteStream(path, stream, config)
+	}, filesystem.DiscardFunc(cfg, existed, func() error {
+		return f.Delete(path)
+	})), nil
+}
@@ -22,5 +22,5 @@ func (d *Driver) Open(configMap map[string]any) (fs.FileSystem, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewFTPFileSystem(config)
+	return NewFTPFileSystem(config, WithMetadataSidecar(config.MetadataSidecar))
 }
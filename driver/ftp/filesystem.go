@@ -3,6 +3,7 @@ package ftp
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	gofs "io/fs"
 	"net/textproto"
@@ -24,6 +25,7 @@ type FTPFileSystem struct {
 	connPool            ConnPool
 	mimetypeDetector    fs.MimeTypeDetector
 	visibilityConvertor unix.VisibilityConvertor
+	metadataSidecar     bool
 }
 
 func NewFTPFileSystem(config *Config, opts ...Option) (*FTPFileSystem, error) {
@@ -47,6 +49,10 @@ func (f *FTPFileSystem) getEntry(conn *ftp.ServerConn, name string) (*ftp.Entry,
 		return entry, nil
 	}
 	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code == ftp.StatusFileUnavailable {
+		// the servers answer 550 for the missing files
+		return nil, fmt.Errorf("%w: %w", os.ErrNotExist, err)
+	}
 	if errors.As(err, &protoErr) && protoErr.Code == ftp.StatusNotImplemented {
 		entries, err := conn.List(filepath.ToSlash(filepath.Dir(name)))
 		if err != nil {
@@ -155,6 +161,74 @@ func (f *FTPFileSystem) ReadStream(path string) (io.ReadCloser, error) {
 	return io.NopCloser(buf), nil
 }
 
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the file is retrieved.
+func (f *FTPFileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	progress := cfg.Progress()
+	if progress == nil {
+		return f.ReadStream(path)
+	}
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	defer f.connPool.Put(conn)
+	entry, err := f.getEntry(conn, path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	} else if !entry.Type().IsRegular() {
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	resp, err := conn.Retr(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	tracker := filesystem.NewProgressTracker(path, int64(entry.Size), progress)
+	buf := bytes.NewBuffer(nil)
+	_, err = filesystem.CopyWithProgress(buf, resp, tracker)
+	if err1 := resp.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	tracker.Done()
+	return io.NopCloser(buf), nil
+}
+
+// ReadRange returns length bytes of the file starting at offset,
+// the transfer is restarted at the offset with REST and streamed from the data connection.
+// The connection is closed with the stream.
+func (f *FTPFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if entry, err := f.getEntry(conn, path); err != nil {
+		f.connPool.Put(conn)
+		return nil, err
+	} else if !entry.Type().IsRegular() {
+		f.connPool.Put(conn)
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	resp, err := conn.RetrFrom(path, uint64(offset))
+	if err != nil {
+		f.connPool.Put(conn)
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return &rangeResponse{
+		ReadCloser: filesystem.LimitReadCloser(resp, length),
+		limited:    length >= 0,
+		release:    func() { f.connPool.Put(conn) },
+	}, nil
+}
+
 // ReadDir returns the content of the directory.
 func (f *FTPFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
 	conn, err := f.connPool.Get()
@@ -175,13 +249,16 @@ func (f *FTPFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
 	}
 	var files []os.DirEntry
 	for _, entry := range entries {
+		if f.isSidecar(entry.Name()) {
+			continue
+		}
 		files = append(files, entry)
 	}
 	return files, nil
 }
 
 // WalkDir walks the file tree rooted at root, calling walkFn for each file or directory in the tree.
-// This function does not follow symbolic links.
+// Symbolic links are reported as such and never followed.
 func (f *FTPFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
 	conn, err := f.connPool.Get()
 	if err != nil {
@@ -190,7 +267,7 @@ func (f *FTPFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
 	defer f.connPool.Put(conn)
 	w := conn.Walk(path)
 	for w.Next() {
-		if w.Stat().Type()&os.ModeSymlink != 0 {
+		if f.isSidecar(w.Stat().Name()) {
 			continue
 		}
 		if err = walkFn(w.Path(), w.Stat(), err); err != nil {
@@ -303,6 +380,7 @@ func (f *FTPFileSystem) Write(path string, content []byte, config map[string]any
 
 // WriteStream writes the content to the file.
 // If the file already exists, it will be overwritten unless the config.WriteFlag() is set to os.O_APPEND.
+// With filesystem.AtomicKey, the content is stored to a temporary file renamed over the file once complete.
 func (f *FTPFileSystem) WriteStream(path string, content io.Reader, config map[string]any) error {
 	conn, err := f.connPool.Get()
 	if err != nil {
@@ -312,8 +390,10 @@ func (f *FTPFileSystem) WriteStream(path string, content io.Reader, config map[s
 	var dirMode = f.visibilityConvertor.DefaultForDir()
 	var fileMode = f.visibilityConvertor.DefaultForFile()
 	var writeFlag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	var atomic bool
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
 			return filesystem.NewUnableToWriteFile(path, err)
 		}
@@ -326,6 +406,12 @@ func (f *FTPFileSystem) WriteStream(path string, content io.Reader, config map[s
 		if cfg.FileWriteFlag != nil {
 			writeFlag = *cfg.FileWriteFlag
 		}
+		if cfg.Atomic != nil {
+			atomic = *cfg.Atomic
+		}
+		if (cfg.Metadata != nil || cfg.Tags != nil) && !f.metadataSidecar {
+			return filesystem.NewUnableToWriteFile(path, filesystem.ErrMetadataUnsupported)
+		}
 	}
 	entry, err := f.getEntry(conn, path)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
@@ -338,18 +424,29 @@ func (f *FTPFileSystem) WriteStream(path string, content io.Reader, config map[s
 	if err := f.mkdirAll(conn, dir, dirMode); err != nil {
 		return filesystem.NewUnableToCreateDirectory(path, err)
 	}
+	tracker := cfg.NewTracker(path, content)
+	if atomic {
+		if err := f.writeAtomic(conn, path, content, writeFlag, fileMode, tracker); err != nil {
+			return err
+		}
+		return f.writeMetadata(conn, path, cfg)
+	}
 	if writeFlag&os.O_APPEND > 0 {
-		if err := conn.Append(path, content); err != nil {
+		if err := store(func(r io.Reader) error { return conn.Append(path, r) }, content, tracker); err != nil {
 			return filesystem.NewUnableToWriteFile(path, err)
 		}
 	} else {
-		if err := conn.Stor(path, content); err != nil {
+		if err := store(func(r io.Reader) error { return conn.Stor(path, r) }, content, tracker); err != nil {
 			return filesystem.NewUnableToWriteFile(path, err)
 		}
 	}
 	if err := f.setPermission(conn, path, fileMode); err != nil {
 		return filesystem.NewUnableToSetPermission(path, err)
 	}
+	if err := f.writeMetadata(conn, path, cfg); err != nil {
+		return err
+	}
+	tracker.Done()
 	return nil
 }
 
@@ -446,6 +543,9 @@ func (f *FTPFileSystem) Delete(path string) error {
 	if err := conn.Delete(path); err != nil {
 		return filesystem.NewUnableToDeleteFile(path, err)
 	}
+	if err := f.deleteSidecar(conn, path); err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
 	return nil
 }
 
@@ -533,6 +633,9 @@ func (f *FTPFileSystem) Move(src string, dst string, config map[string]any) erro
 	if err := conn.Rename(src, dst); err != nil {
 		return filesystem.NewUnableToMove(src, dst, err)
 	}
+	if err := f.moveSidecar(conn, src, dst); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
 	return nil
 }
 
@@ -562,8 +665,9 @@ func (f *FTPFileSystem) Copy(src string, dst string, config map[string]any) erro
 	dirMode := f.visibilityConvertor.DefaultForDir()
 	fileMode := f.visibilityConvertor.DefaultForFile()
 	writeFlag := os.O_WRONLY | os.O_CREATE
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
 			return filesystem.NewUnableToCopyFile(src, dst, err)
 		}
@@ -601,17 +705,45 @@ func (f *FTPFileSystem) Copy(src string, dst string, config map[string]any) erro
 	if err := f.mkdirAll(conn, filepath.ToSlash(filepath.Dir(dst)), dirMode); err != nil {
 		return filesystem.NewUnableToCopyFile(src, dst, err)
 	}
+	tracker := filesystem.NewProgressTracker(dst, int64(buf.Len()), cfg.Progress())
 	if writeFlag&os.O_APPEND != 0 {
-		if err := conn.Append(dst, buf); err != nil {
+		if err := store(func(r io.Reader) error { return conn.Append(dst, r) }, buf, tracker); err != nil {
 			return filesystem.NewUnableToCopyFile(src, dst, err)
 		}
 	} else {
-		if err := conn.Stor(dst, buf); err != nil {
+		if err := store(func(r io.Reader) error { return conn.Stor(dst, r) }, buf, tracker); err != nil {
 			return filesystem.NewUnableToCopyFile(src, dst, err)
 		}
 		if err := f.setPermission(conn, dst, fileMode); err != nil {
 			return filesystem.NewUnableToCopyFile(src, dst, err)
 		}
+		if err := f.copySidecar(conn, src, dst); err != nil {
+			return filesystem.NewUnableToCopyFile(src, dst, err)
+		}
 	}
+	tracker.Done()
 	return nil
 }
+
+// rangeResponse is the stream of a range read, which gives the connection back once closed.
+type rangeResponse struct {
+	io.ReadCloser
+	limited bool
+	release func()
+}
+
+// Close closes the data connection.
+// The server reports an aborted transfer when a limited range ends before the file, which is not an error,
+// any other error is returned.
+func (r *rangeResponse) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	var protoErr *textproto.Error
+	if r.limited && errors.As(err, &protoErr) {
+		switch protoErr.Code {
+		case ftp.StatusTransfertAborted, ftp.StatusActionAborted, ftp.StatusFileActionIgnored:
+			return nil
+		}
+	}
+	return err
+}
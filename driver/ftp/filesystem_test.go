@@ -1,7 +1,9 @@
 package ftp
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -775,3 +777,182 @@ func TestFTPFileSystem_Copy(t *testing.T) {
 		assert.Equal(t, "dst-test-3src-test-3", string(content))
 	})
 }
+
+func TestFTPFileSystem_AtomicWrite(t *testing.T) {
+	fs := newfs(t)
+	atomic := map[string]any{filesystem.AtomicKey: true}
+	if err := fs.Write("for-write/atomic.txt", []byte("hello"), atomic); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	appendAtomic := map[string]any{filesystem.AtomicKey: true, filesystem.FileWriteFlagKey: os.O_APPEND | os.O_CREATE | os.O_WRONLY}
+	if err := fs.Write("for-write/atomic.txt", []byte(" world"), appendAtomic); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := fs.Read("for-write/atomic.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "hello world", string(content))
+	entries, err := os.ReadDir(filepath.Join(testRoot, "for-write"))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for _, entry := range entries {
+		assert.False(t, filesystem.IsAtomicTempFile(entry.Name()))
+	}
+}
+
+func TestFTPFileSystem_Progress(t *testing.T) {
+	fs := newfs(t)
+	content := bytes.Repeat([]byte("x"), 100*1024)
+	for _, atomic := range []bool{false, true} {
+		var last filesystem.Progress
+		err := fs.WriteStream("for-write/progress.bin", bytes.NewReader(content), map[string]any{
+			filesystem.AtomicKey:   atomic,
+			filesystem.ProgressKey: func(p filesystem.Progress) { last = p },
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, last.Done)
+		assert.Equal(t, int64(len(content)), last.Bytes)
+		assert.Equal(t, int64(len(content)), last.Total)
+	}
+	var last filesystem.Progress
+	stream, err := fs.ReadStreamWithConfig("for-write/progress.bin", map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { last = p },
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	_ = stream.Close()
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(len(content)), last.Bytes)
+}
+
+func TestFTPFileSystem_ReadRange(t *testing.T) {
+	fs := newfs(t)
+	if err := fs.Write("for-write/range.txt", []byte("0123456789"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	stream, err := fs.ReadRange("for-write/range.txt", 2, 3)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := io.ReadAll(stream)
+	assert.NoError(t, stream.Close())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "234", string(content))
+
+	stream, err = fs.ReadRange("for-write/range.txt", 7, -1)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err = io.ReadAll(stream)
+	assert.NoError(t, stream.Close())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "789", string(content))
+}
+
+func TestFTPFileSystem_Create(t *testing.T) {
+	fs := newfs(t)
+	w, err := fs.Create("for-write/created.txt", nil)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for _, line := range []string{"hello", " ", "world"} {
+		if _, err := io.WriteString(w, line); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := fs.Read("for-write/created.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "hello world", string(content))
+
+	w, err = fs.Create("for-write/aborted.txt", nil)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := io.WriteString(w, "partial"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NoError(t, w.Abort())
+	exists, err := fs.FileExists("for-write/aborted.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+
+	// an atomic write leaves the existing file as it was
+	w, err = fs.Create("for-write/created.txt", map[string]any{filesystem.AtomicKey: true})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := io.WriteString(w, "partial"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NoError(t, w.Abort())
+	content, err = fs.Read("for-write/created.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "hello world", string(content))
+}
+
+func TestFTPFileSystem_Metadata(t *testing.T) {
+	t.Run("unsupported", func(t *testing.T) {
+		fs := newfs(t)
+		_, err := fs.Metadata(forReadTest("file1.txt"))
+		assert.True(t, errors.Is(err, filesystem.ErrMetadataUnsupported))
+	})
+
+	t.Run("sidecar", func(t *testing.T) {
+		fs := newfs(t)
+		if err := WithMetadataSidecar(true).Apply(fs); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		err := fs.Write("for-write/metadata/a.txt", []byte("hello"), map[string]any{
+			filesystem.MetadataKey: map[string]string{"uploader": "42"},
+			filesystem.TagsKey:     map[string]string{"retention": "short"},
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := fs.Move("for-write/metadata/a.txt", "for-write/metadata/b.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := fs.Metadata("for-write/metadata/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"uploader": "42"}, metadata)
+		tags, err := fs.Tags("for-write/metadata/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "short"}, tags)
+		// the sidecar is hidden
+		entries, err := fs.ReadDir("for-write/metadata")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, entries, 1)
+		if err := fs.Delete("for-write/metadata/b.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err := fs.FileExists(filesystem.MetadataSidecarPath("for-write/metadata/b.txt"))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+}
@@ -0,0 +1,195 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/gopi-frame/ftp"
+)
+
+// lockOwnerFile is the name of the file holding the ownership metadata in a lock directory.
+const lockOwnerFile = "owner.json"
+
+// Lock acquires the lock of the path, waiting until it is released or expires.
+func (f *FTPFileSystem) Lock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	return f.LockContext(context.Background(), path, ttl)
+}
+
+// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+func (f *FTPFileSystem) LockContext(ctx context.Context, path string, ttl time.Duration) (filesystem.Lock, error) {
+	return filesystem.WaitForLock(ctx, func() (filesystem.Lock, error) {
+		return f.TryLock(path, ttl)
+	})
+}
+
+// TryLock acquires the lock of the path, or returns a [filesystem.LockHeld] error if it is held.
+//
+// FTP cannot create a file exclusively, STOR overwrites it, so the lock is a directory
+// named after the path with [filesystem.LockSuffix], created with MKD which fails if it exists,
+// and the ownership metadata of the lock is stored in it.
+// An expired lock directory is renamed aside before being removed,
+// so that only one of the processes recovering it at the same time takes the lock over.
+func (f *FTPFileSystem) TryLock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToLock(path, err)
+	}
+	defer f.connPool.Put(conn)
+	path = filepath.ToSlash(filepath.Clean(path))
+	lockPath := path + filesystem.LockSuffix
+	if err := f.mkdirAll(conn, filepath.Dir(lockPath), f.visibilityConvertor.DefaultForDir()); err != nil {
+		return nil, filesystem.NewUnableToCreateDirectory(filepath.Dir(lockPath), err)
+	}
+	for {
+		info := filesystem.NewLockInfo(ttl)
+		err := conn.MakeDir(lockPath)
+		if err == nil {
+			if err := writeLockOwner(conn, lockPath, info); err != nil {
+				_ = conn.RemoveDirRecur(lockPath)
+				return nil, filesystem.NewUnableToLock(path, err)
+			}
+			return &lock{f: f, path: path, lockPath: lockPath, info: info}, nil
+		}
+		holder, readErr := f.readLockOwner(conn, lockPath)
+		if readErr != nil {
+			if errors.Is(readErr, os.ErrNotExist) {
+				// the creation failed for another reason than an existing lock
+				return nil, filesystem.NewUnableToLock(path, err)
+			}
+			return nil, filesystem.NewUnableToLock(path, readErr)
+		}
+		if !holder.Expired() {
+			return nil, filesystem.NewLockHeld(path, holder)
+		}
+		if err := f.breakLock(conn, lockPath, holder); err != nil {
+			return nil, filesystem.NewLockHeld(path, holder)
+		}
+	}
+}
+
+func writeLockOwner(conn *ftp.ServerConn, lockPath string, info filesystem.LockInfo) error {
+	return conn.Stor(lockPath+"/"+lockOwnerFile, bytes.NewReader(info.Bytes()))
+}
+
+// readLockOwner reads the ownership metadata of the lock directory.
+// The directory of a holder which crashed before storing its metadata is dated by its modification time.
+func (f *FTPFileSystem) readLockOwner(conn *ftp.ServerConn, lockPath string) (filesystem.LockInfo, error) {
+	entry, err := f.getEntry(conn, lockPath)
+	if err != nil {
+		return filesystem.LockInfo{}, err
+	}
+	if !entry.IsDir() {
+		return filesystem.LockInfo{}, filesystem.ErrIsNotDirectory
+	}
+	var content []byte
+	if resp, err := conn.Retr(lockPath + "/" + lockOwnerFile); err == nil {
+		content, err = io.ReadAll(resp)
+		if err1 := resp.Close(); err == nil {
+			err = err1
+		}
+		if err != nil {
+			return filesystem.LockInfo{}, err
+		}
+	}
+	return filesystem.ParseLockInfo(content, entry.Time), nil
+}
+
+// breakLock removes the expired lock of the holder.
+// The lock directory is renamed to a unique name first, and put back if it turns out to be another lock,
+// acquired after the holder's one was read.
+func (f *FTPFileSystem) breakLock(conn *ftp.ServerConn, lockPath string, holder filesystem.LockInfo) error {
+	stale := lockPath + "." + filesystem.NewLockInfo(0).Token
+	if err := conn.Rename(lockPath, stale); err != nil {
+		return err
+	}
+	renamed, err := f.readLockOwner(conn, stale)
+	if err != nil {
+		return err
+	}
+	if renamed.Token != holder.Token || !renamed.ExpiresAt.Equal(holder.ExpiresAt) {
+		if err := conn.Rename(stale, lockPath); err != nil {
+			// the lock was acquired again meanwhile, and the other lock is lost to its holder anyway
+			_ = conn.RemoveDirRecur(stale)
+		}
+		return filesystem.ErrLockLost
+	}
+	return conn.RemoveDirRecur(stale)
+}
+
+type lock struct {
+	mu       sync.Mutex
+	f        *FTPFileSystem
+	path     string
+	lockPath string
+	info     filesystem.LockInfo
+	released bool
+}
+
+func (l *lock) Path() string {
+	return l.path
+}
+
+func (l *lock) Info() filesystem.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+// owned returns a connection, after checking the lock directory still holds this lock.
+func (l *lock) owned() (*ftp.ServerConn, error) {
+	if l.released {
+		return nil, filesystem.ErrLockLost
+	}
+	conn, err := l.f.connPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToLock(l.path, err)
+	}
+	holder, err := l.f.readLockOwner(conn, l.lockPath)
+	if err != nil || holder.Token != l.info.Token {
+		l.f.connPool.Put(conn)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, filesystem.NewUnableToLock(l.path, err)
+		}
+		return nil, filesystem.ErrLockLost
+	}
+	return conn, nil
+}
+
+func (l *lock) Refresh(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	conn, err := l.owned()
+	if err != nil {
+		return err
+	}
+	defer l.f.connPool.Put(conn)
+	info := l.info.Renew(ttl)
+	if err := writeLockOwner(conn, l.lockPath, info); err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.info = info
+	return nil
+}
+
+func (l *lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	conn, err := l.owned()
+	if err != nil {
+		return err
+	}
+	defer l.f.connPool.Put(conn)
+	if err := conn.RemoveDirRecur(l.lockPath); err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.released = true
+	return nil
+}
@@ -0,0 +1,52 @@
+package ftp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFTPFileSystem_Lock(t *testing.T) {
+	t.Run("exclusive", func(t *testing.T) {
+		fs := newfs(t)
+		lock, err := fs.TryLock("for-lock/report.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = fs.TryLock("for-lock/report.csv", time.Minute)
+		var held *filesystem.LockHeld
+		if assert.True(t, errors.As(err, &held)) {
+			assert.Equal(t, lock.Info().Token, held.Holder().Token)
+		}
+		if err := lock.Refresh(time.Minute); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := lock.Unlock(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err := fs.DirExists("for-lock/report.csv" + filesystem.LockSuffix)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		fs := newfs(t)
+		stale, err := fs.TryLock("for-lock/stale.csv", 10*time.Millisecond)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		time.Sleep(20 * time.Millisecond)
+		lock, err := fs.TryLock("for-lock/stale.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.ErrorIs(t, stale.Unlock(), filesystem.ErrLockLost)
+		assert.NoError(t, lock.Unlock())
+	})
+}
@@ -0,0 +1,191 @@
+package ftp
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/ftp"
+)
+
+// Metadata returns the custom metadata of the file, kept in its sidecar file.
+// It fails with [filesystem.ErrMetadataUnsupported] unless the sidecars are enabled with [WithMetadataSidecar].
+func (f *FTPFileSystem) Metadata(path string) (map[string]string, error) {
+	sidecar, err := f.sidecar(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return filesystem.CloneMetadata(sidecar.Metadata), nil
+}
+
+// SetMetadata replaces the custom metadata of the file, kept in its sidecar file.
+func (f *FTPFileSystem) SetMetadata(path string, metadata map[string]string) error {
+	return f.updateSidecar(path, func(sidecar *filesystem.MetadataSidecar) {
+		sidecar.Metadata = metadata
+	})
+}
+
+// Tags returns the tags of the file, kept in its sidecar file.
+func (f *FTPFileSystem) Tags(path string) (map[string]string, error) {
+	sidecar, err := f.sidecar(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return filesystem.CloneMetadata(sidecar.Tags), nil
+}
+
+// SetTags replaces the tags of the file, kept in its sidecar file.
+func (f *FTPFileSystem) SetTags(path string, tags map[string]string) error {
+	return f.updateSidecar(path, func(sidecar *filesystem.MetadataSidecar) {
+		sidecar.Tags = tags
+	})
+}
+
+func (f *FTPFileSystem) sidecar(path string) (*filesystem.MetadataSidecar, error) {
+	if !f.metadataSidecar {
+		return nil, filesystem.ErrMetadataUnsupported
+	}
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer f.connPool.Put(conn)
+	path = filepath.ToSlash(filepath.Clean(path))
+	if err := f.checkFile(conn, path); err != nil {
+		return nil, err
+	}
+	return f.readSidecar(conn, path)
+}
+
+func (f *FTPFileSystem) updateSidecar(path string, update func(sidecar *filesystem.MetadataSidecar)) error {
+	if !f.metadataSidecar {
+		return filesystem.NewUnableToSetMetadata(path, filesystem.ErrMetadataUnsupported)
+	}
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	defer f.connPool.Put(conn)
+	path = filepath.ToSlash(filepath.Clean(path))
+	if err := f.checkFile(conn, path); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	sidecar, err := f.readSidecar(conn, path)
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	update(sidecar)
+	if err := f.saveSidecar(conn, path, sidecar); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// writeMetadata sets the metadata and tags given with the config of a write.
+func (f *FTPFileSystem) writeMetadata(conn *ftp.ServerConn, path string, cfg *filesystem.Config) error {
+	if cfg == nil || (cfg.Metadata == nil && cfg.Tags == nil) {
+		return nil
+	}
+	path = filepath.ToSlash(path)
+	sidecar, err := f.readSidecar(conn, path)
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	if cfg.Metadata != nil {
+		sidecar.Metadata = cfg.Metadata
+	}
+	if cfg.Tags != nil {
+		sidecar.Tags = cfg.Tags
+	}
+	if err := f.saveSidecar(conn, path, sidecar); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+func (f *FTPFileSystem) checkFile(conn *ftp.ServerConn, path string) error {
+	entry, err := f.getEntry(conn, path)
+	if err != nil {
+		return err
+	}
+	if !entry.Type().IsRegular() {
+		return filesystem.ErrIsNotFile
+	}
+	return nil
+}
+
+// readSidecar reads the sidecar of the file, empty if it has none.
+func (f *FTPFileSystem) readSidecar(conn *ftp.ServerConn, path string) (*filesystem.MetadataSidecar, error) {
+	content, err := f.retrieve(conn, filesystem.MetadataSidecarPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return &filesystem.MetadataSidecar{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.ParseMetadataSidecar(content)
+}
+
+// saveSidecar writes the sidecar of the file, or deletes it if it keeps nothing.
+func (f *FTPFileSystem) saveSidecar(conn *ftp.ServerConn, path string, sidecar *filesystem.MetadataSidecar) error {
+	sidecarPath := filesystem.MetadataSidecarPath(path)
+	if sidecar.Empty() {
+		return f.removeIfExists(conn, sidecarPath)
+	}
+	content, err := sidecar.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := conn.Stor(sidecarPath, bytes.NewReader(content)); err != nil {
+		return err
+	}
+	return f.setPermission(conn, sidecarPath, f.visibilityConvertor.DefaultForFile())
+}
+
+// isSidecar reports whether the listed file is a sidecar, hidden from the listings when they are enabled.
+func (f *FTPFileSystem) isSidecar(name string) bool {
+	return f.metadataSidecar && filesystem.IsMetadataSidecar(name)
+}
+
+func (f *FTPFileSystem) deleteSidecar(conn *ftp.ServerConn, path string) error {
+	if !f.metadataSidecar {
+		return nil
+	}
+	return f.removeIfExists(conn, filesystem.MetadataSidecarPath(filepath.ToSlash(path)))
+}
+
+func (f *FTPFileSystem) moveSidecar(conn *ftp.ServerConn, src, dst string) error {
+	if !f.metadataSidecar {
+		return nil
+	}
+	srcSidecar := filesystem.MetadataSidecarPath(src)
+	if _, err := f.getEntry(conn, srcSidecar); errors.Is(err, os.ErrNotExist) {
+		return f.removeIfExists(conn, filesystem.MetadataSidecarPath(dst))
+	} else if err != nil {
+		return err
+	}
+	return conn.Rename(srcSidecar, filesystem.MetadataSidecarPath(dst))
+}
+
+func (f *FTPFileSystem) copySidecar(conn *ftp.ServerConn, src, dst string) error {
+	if !f.metadataSidecar {
+		return nil
+	}
+	sidecar, err := f.readSidecar(conn, src)
+	if err != nil {
+		return err
+	}
+	return f.saveSidecar(conn, dst, sidecar)
+}
+
+// removeIfExists deletes the file, FTP servers don't tell a missing file apart from the other failures.
+func (f *FTPFileSystem) removeIfExists(conn *ftp.ServerConn, path string) error {
+	if _, err := f.getEntry(conn, path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return conn.Delete(path)
+}
@@ -0,0 +1,48 @@
+package ftp
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time of the file to now, creating an empty file if it is missing.
+func (f *FTPFileSystem) Touch(path string) error {
+	exists, err := f.FileExists(path)
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	if !exists {
+		// a new file is stored with the current time
+		return f.WriteStream(path, bytes.NewReader(nil), nil)
+	}
+	return f.SetLastModified(path, time.Now())
+}
+
+// SetLastModified sets the modification time of the file with the MFMT command,
+// or with MDTM if the connection is configured with EnableWritingMDTM.
+// It fails with [filesystem.ErrSetLastModifiedUnsupported] if the server supports neither.
+func (f *FTPFileSystem) SetLastModified(path string, t time.Time) error {
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	defer f.connPool.Put(conn)
+	if !conn.IsSetTimeSupported() {
+		return filesystem.NewUnableToSetLastModified(path, fmt.Errorf("%w: the server supports neither MFMT nor MDTM", filesystem.ErrSetLastModifiedUnsupported))
+	}
+	entry, err := f.getEntry(conn, path)
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	if !entry.Type().IsRegular() {
+		return filesystem.NewUnableToSetLastModified(path, filesystem.ErrIsNotFile)
+	}
+	if err := conn.SetTime(filepath.ToSlash(path), t); err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
@@ -45,3 +45,16 @@ func WithVisibilityConvertor(convertor unix.VisibilityConvertor) Option {
 		return nil
 	})
 }
+
+// WithMetadataSidecar keeps the metadata and tags of every file in a hidden sidecar file next to it,
+// see [filesystem.MetadataSidecarPath], FTP having no way to attach them to the file itself.
+// The sidecars are moved, copied and deleted along with their files, and hidden from the listings.
+func WithMetadataSidecar(enabled bool) Option {
+	if !enabled {
+		return noneOption
+	}
+	return OptionFunc(func(fs *FTPFileSystem) error {
+		fs.metadataSidecar = true
+		return nil
+	})
+}
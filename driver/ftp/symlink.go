@@ -0,0 +1,86 @@
+package ftp
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/ftp"
+)
+
+// Symlink fails with [filesystem.ErrSymlinkUnsupported], FTP has no command to create links.
+func (f *FTPFileSystem) Symlink(_, link string) error {
+	return filesystem.NewUnableToCreateSymlink(link, filesystem.ErrSymlinkUnsupported)
+}
+
+// Readlink returns the target of the link, as listed by the server.
+func (f *FTPFileSystem) Readlink(path string) (string, error) {
+	entry, err := f.listEntry(path)
+	if err != nil {
+		return "", filesystem.NewUnableToReadSymlink(path, err)
+	}
+	if entry.Type()&os.ModeSymlink == 0 || entry.Target == "" {
+		return "", filesystem.NewUnableToReadSymlink(path, filesystem.ErrIsNotSymlink)
+	}
+	return entry.Target, nil
+}
+
+// Lstat returns the info of the file as listed by the server, describing the link itself if it is one.
+func (f *FTPFileSystem) Lstat(path string) (os.FileInfo, error) {
+	entry, err := f.listEntry(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return &entryInfo{entry}, nil
+}
+
+// listEntry returns the entry of the file from the listing of its directory,
+// where links are listed as such, with their targets.
+func (f *FTPFileSystem) listEntry(path string) (*ftp.Entry, error) {
+	conn, err := f.connPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer f.connPool.Put(conn)
+	path = filepath.ToSlash(filepath.Clean(path))
+	entries, err := conn.List(filepath.ToSlash(filepath.Dir(path)))
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Name() == filepath.Base(path) {
+			return entry, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// entryInfo is the [os.FileInfo] of a listed entry.
+type entryInfo struct {
+	entry *ftp.Entry
+}
+
+func (i *entryInfo) Name() string {
+	return i.entry.Name()
+}
+
+func (i *entryInfo) Size() int64 {
+	return int64(i.entry.Size)
+}
+
+func (i *entryInfo) Mode() os.FileMode {
+	return i.entry.Type()
+}
+
+func (i *entryInfo) ModTime() time.Time {
+	return i.entry.Time
+}
+
+func (i *entryInfo) IsDir() bool {
+	return i.entry.IsDir()
+}
+
+func (i *entryInfo) Sys() any {
+	return i.entry
+}
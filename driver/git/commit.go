@@ -0,0 +1,168 @@
+package git
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gopi-frame/filesystem"
+)
+
+// MessageKey is the key of the write config setting the message of the commit, e.g.
+//
+//	f.Write("config.yaml", content, map[string]any{git.MessageKey: "Raise the timeout"})
+const MessageKey = "message"
+
+// change sets the entry at a path of the tree, or removes it if the entry is nil.
+type change struct {
+	path  string
+	entry *object.TreeEntry
+}
+
+// commit applies the changes returned by edit to the tree of the branch, and commits the new tree.
+// The writes are serialized, and the branch is only moved if it was not moved by another writer in between,
+// so that edit can check its conditions against the snapshot it is given.
+// No commit is made if the tree is unchanged.
+func (f *GitFileSystem) commit(message string, edit func(s *snapshot) ([]change, error)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, err := f.snapshot()
+	if err != nil {
+		return err
+	}
+	changes, err := edit(s)
+	if err != nil {
+		return err
+	}
+	treeHash, err := f.editTree(s.tree, changes)
+	if err != nil {
+		return err
+	}
+	if s.commit != nil && treeHash == s.commit.TreeHash {
+		return nil
+	}
+	signature := object.Signature{Name: f.authorName, Email: f.authorEmail, When: time.Now()}
+	commit := &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   message,
+		TreeHash:  treeHash,
+	}
+	var old *plumbing.Reference
+	if s.commit != nil {
+		commit.ParentHashes = []plumbing.Hash{s.commit.Hash}
+		old = plumbing.NewHashReference(f.branch, s.commit.Hash)
+	}
+	obj := f.repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return err
+	}
+	hash, err := f.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+	return f.repo.Storer.CheckAndSetReference(plumbing.NewHashReference(f.branch, hash), old)
+}
+
+// editTree stores the tree with the changes applied, and returns its hash.
+// The directories left empty are removed, as git does not store empty directories.
+func (f *GitFileSystem) editTree(tree *object.Tree, changes []change) (plumbing.Hash, error) {
+	entries := map[string]object.TreeEntry{}
+	if tree != nil {
+		for _, entry := range tree.Entries {
+			entries[entry.Name] = entry
+		}
+	}
+	nested := map[string][]change{}
+	var names []string
+	for _, c := range changes {
+		name, rest, ok := strings.Cut(c.path, "/")
+		if !ok {
+			if c.entry == nil {
+				delete(entries, name)
+			} else {
+				entries[name] = object.TreeEntry{Name: name, Mode: c.entry.Mode, Hash: c.entry.Hash}
+			}
+			// a later change under the entry applies to the new one.
+			delete(nested, name)
+			continue
+		}
+		if _, ok := nested[name]; !ok {
+			names = append(names, name)
+		}
+		nested[name] = append(nested[name], change{path: rest, entry: c.entry})
+	}
+	for _, name := range names {
+		subChanges, ok := nested[name]
+		if !ok {
+			continue
+		}
+		delete(nested, name)
+		var subtree *object.Tree
+		if entry, ok := entries[name]; ok {
+			if entry.Mode != filemode.Dir {
+				return plumbing.ZeroHash, filesystem.ErrIsNotDirectory
+			}
+			var err error
+			if subtree, err = f.repo.TreeObject(entry.Hash); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+		hash, err := f.editTree(subtree, subChanges)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if hash == emptyTreeHash {
+			delete(entries, name)
+		} else {
+			entries[name] = object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash}
+		}
+	}
+	edited := &object.Tree{Entries: make([]object.TreeEntry, 0, len(entries))}
+	for _, entry := range entries {
+		edited.Entries = append(edited.Entries, entry)
+	}
+	sort.Sort(object.TreeEntrySorter(edited.Entries))
+	obj := f.repo.Storer.NewEncodedObject()
+	if err := edited.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return f.repo.Storer.SetEncodedObject(obj)
+}
+
+// emptyTreeHash is the hash of the tree without entries.
+var emptyTreeHash = plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+// writeBlob stores the content as a blob. The content is held in memory until it is stored.
+func (f *GitFileSystem) writeBlob(stream io.Reader, tracker *filesystem.ProgressTracker) (plumbing.Hash, error) {
+	obj := f.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := filesystem.CopyWithProgress(w, stream, tracker); err != nil {
+		_ = w.Close()
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return f.repo.Storer.SetEncodedObject(obj)
+}
+
+// message returns the message set with [MessageKey], or the default one.
+func message(cfg *filesystem.Config, defaultMessage string) string {
+	if cfg != nil {
+		if v, ok := cfg.Get(MessageKey); ok {
+			if m, ok := v.(string); ok && m != "" {
+				return m
+			}
+		}
+	}
+	return defaultMessage
+}
@@ -0,0 +1,72 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+)
+
+type Config struct {
+	// Path is the path of the repository, its work tree, its .git directory or a bare repository.
+	Path string
+	// Ref is the branch, tag or commit read, e.g. "main", "v1.2.0" or a commit hash, default is HEAD.
+	// A branch is resolved on every operation, so that its new commits are read.
+	Ref string
+	// Writable enables the write mode, in which every write is committed on the branch Ref,
+	// or on the branch HEAD points to if Ref is empty. The branch is created by the first write if it does not exist.
+	// The work tree and the index of a non-bare repository are not updated.
+	Writable bool
+	// AuthorName and AuthorEmail sign the commits of the write mode.
+	AuthorName  string
+	AuthorEmail string
+	// Visibility is the visibility reported for every file, default is "public".
+	Visibility string
+}
+
+func (c *Config) Apply(f *GitFileSystem) error {
+	if c.Path != "" {
+		repo, err := git.PlainOpen(c.Path)
+		if err != nil {
+			return err
+		}
+		f.repo = repo
+	}
+	f.ref = c.Ref
+	f.writable = c.Writable
+	if c.AuthorName != "" {
+		f.authorName = c.AuthorName
+	}
+	if c.AuthorEmail != "" {
+		f.authorEmail = c.AuthorEmail
+	}
+	if c.Visibility != "" {
+		f.visibility = c.Visibility
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			env.ExpandStringKeyMapWithEnvHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
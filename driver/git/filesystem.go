@@ -0,0 +1,640 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+)
+
+var ErrNoRepository = errors.New("git repository is required")
+
+var ErrNotBranch = errors.New("git ref is not a branch")
+
+var ErrTooManyLinks = errors.New("too many levels of symbolic links")
+
+var ErrVisibilityUnsupported = errors.New("git does not support visibility")
+
+// sniffSize is the length of the content read to detect the mime type.
+const sniffSize = 512
+
+// GitFileSystem is the tree of a git repository at a ref, read from the object database,
+// so that bare repositories can be read as well as the other ones.
+//
+// It is read-only unless the write mode is enabled, in which every write is a commit on the branch.
+// The modification time of a file is the time of the last commit touching it.
+// Git has no permissions, every file reports the configured visibility.
+type GitFileSystem struct {
+	repo             *git.Repository
+	ref              string
+	branch           plumbing.ReferenceName
+	writable         bool
+	authorName       string
+	authorEmail      string
+	visibility       string
+	mimetypeDetector fs.MimeTypeDetector
+	mu               sync.Mutex
+}
+
+// NewGitFileSystem creates a file system reading the repository at the path of the config.
+func NewGitFileSystem(config *Config, opts ...Option) (*GitFileSystem, error) {
+	f := &GitFileSystem{
+		authorName:  "gopi-frame",
+		authorEmail: "filesystem@gopi-frame",
+		visibility:  "public",
+	}
+	if err := config.Apply(f); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if f.repo == nil {
+		return nil, ErrNoRepository
+	}
+	if f.mimetypeDetector == nil {
+		f.mimetypeDetector = filesystem.NewMimeTypeDetector()
+	}
+	if err := f.resolveRef(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// resolveRef checks that the ref can be read, or names the branch written in the write mode.
+func (f *GitFileSystem) resolveRef() error {
+	if !f.writable {
+		if f.ref == "" {
+			f.ref = string(plumbing.HEAD)
+		}
+		_, err := f.repo.ResolveRevision(plumbing.Revision(f.ref))
+		return err
+	}
+	if f.ref == "" {
+		head, err := f.repo.Storer.Reference(plumbing.HEAD)
+		if err != nil {
+			return err
+		}
+		if head.Type() != plumbing.SymbolicReference {
+			return fmt.Errorf("%w: HEAD is detached", ErrNotBranch)
+		}
+		f.branch = head.Target()
+		return nil
+	}
+	f.branch = plumbing.ReferenceName(f.ref)
+	if !f.branch.IsBranch() {
+		f.branch = plumbing.NewBranchReferenceName(f.ref)
+	}
+	if _, err := f.repo.Storer.Reference(f.branch); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// the branch is created by the first write, unless the ref names something else.
+		if _, err := f.repo.ResolveRevision(plumbing.Revision(f.ref)); err == nil {
+			return fmt.Errorf("%w: %s", ErrNotBranch, f.ref)
+		}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// snapshot returns the tree at the ref, the ref is resolved on every call so that a branch is read at its last commit.
+func (f *GitFileSystem) snapshot() (*snapshot, error) {
+	var hash plumbing.Hash
+	if f.branch != "" {
+		ref, err := f.repo.Storer.Reference(f.branch)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return &snapshot{tree: &object.Tree{Hash: emptyTreeHash}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		hash = ref.Hash()
+	} else {
+		resolved, err := f.repo.ResolveRevision(plumbing.Revision(f.ref))
+		if err != nil {
+			return nil, err
+		}
+		hash = *resolved
+	}
+	commit, err := f.repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot{commit: commit, tree: tree}, nil
+}
+
+// stat returns the snapshot and the node at the path, following the links.
+func (f *GitFileSystem) stat(p string) (*snapshot, *node, error) {
+	s, err := f.snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := f.lookup(s, p, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, n, nil
+}
+
+func (f *GitFileSystem) Exists(path string) (bool, error) {
+	_, _, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return true, nil
+}
+
+func (f *GitFileSystem) FileExists(path string) (bool, error) {
+	_, n, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return !n.isDir(), nil
+}
+
+func (f *GitFileSystem) DirExists(path string) (bool, error) {
+	_, n, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return n.isDir(), nil
+}
+
+func (f *GitFileSystem) Read(path string) ([]byte, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return content, nil
+}
+
+func (f *GitFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	_, n, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if n.isDir() {
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	blob, err := f.repo.BlobObject(n.hash)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	stream, err := blob.Reader()
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return stream, nil
+}
+
+// ReadRange returns length bytes of the file starting at offset.
+// Blobs are stored compressed, so the content before offset is read and discarded.
+func (f *GitFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, stream, offset); err != nil && !errors.Is(err, io.EOF) {
+		_ = stream.Close()
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return filesystem.LimitReadCloser(stream, length), nil
+}
+
+func (f *GitFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	s, n, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if !n.isDir() {
+		return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	infos, err := f.children(s, n)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	entries := make([]os.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, info)
+	}
+	return entries, nil
+}
+
+// children returns the entries of the directory sorted by name, the links are not followed.
+func (f *GitFileSystem) children(s *snapshot, dir *node) ([]*fileInfo, error) {
+	infos := make([]*fileInfo, 0, len(dir.tree.Entries))
+	for i := range dir.tree.Entries {
+		entry := &dir.tree.Entries[i]
+		child, err := f.node(path.Join(dir.path, entry.Name), entry)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.fileInfo(s, child)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// WalkDir walks the tree of the snapshot taken when the walk starts, the links are reported without being followed.
+func (f *GitFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	s, root, err := f.stat(path)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	rootInfo, err := f.fileInfo(s, root)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	var walkDir func(p string, info *fileInfo) error
+	walkDir = func(p string, info *fileInfo) error {
+		if err := walkFn(p, info, nil); err != nil || !info.IsDir() {
+			if errors.Is(err, filepath.SkipDir) && info.IsDir() {
+				err = nil
+			}
+			return err
+		}
+		children, err := f.children(s, info.node)
+		if err != nil {
+			err = walkFn(p, info, filesystem.NewUnableToReadDirectory(p, err))
+			if err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					err = nil
+				}
+				return err
+			}
+		}
+		for _, child := range children {
+			if err := walkDir(filepath.ToSlash(filepath.Join(p, child.Name())), child); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					break
+				}
+				return err
+			}
+		}
+		return nil
+	}
+	err = walkDir(path, rootInfo)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// LastModified returns the time of the last commit touching the path, found by walking the history back from the ref.
+func (f *GitFileSystem) LastModified(path string) (time.Time, error) {
+	s, n, err := f.stat(path)
+	if err != nil {
+		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	modTime, err := f.lastModified(s.commit, n.path)
+	if err != nil {
+		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return modTime, nil
+}
+
+func (f *GitFileSystem) FileSize(path string) (int64, error) {
+	s, n, err := f.stat(path)
+	if err != nil {
+		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	info, err := f.fileInfo(s, n)
+	if err != nil {
+		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info.Size(), nil
+}
+
+// MimeType detects the mime type from the extension of the path and the beginning of the content.
+func (f *GitFileSystem) MimeType(path string) (string, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	defer stream.Close()
+	header, err := io.ReadAll(io.LimitReader(stream, sniffSize))
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return f.mimetypeDetector.Detect(path, header), nil
+}
+
+func (f *GitFileSystem) Visibility(path string) (string, error) {
+	if _, _, err := f.stat(path); err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return f.visibility, nil
+}
+
+// Stat returns the metadata of the file, its version is the hash of its blob.
+func (f *GitFileSystem) Stat(path string) (*filesystem.FileStat, error) {
+	s, n, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if n.isDir() {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrIsNotFile)
+	}
+	info, err := f.fileInfo(s, n)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	modTime, err := f.lastModified(s.commit, n.path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return &filesystem.FileStat{Path: path, Size: info.Size(), LastModified: modTime, Version: n.hash.String()}, nil
+}
+
+// Lstat returns the info of the entry, describing the link itself if it is one.
+func (f *GitFileSystem) Lstat(path string) (os.FileInfo, error) {
+	s, err := f.snapshot()
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: err}
+	}
+	n, err := f.lookup(s, path, false)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: err}
+	}
+	info, err := f.fileInfo(s, n)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: err}
+	}
+	return info, nil
+}
+
+func (f *GitFileSystem) Readlink(path string) (string, error) {
+	s, err := f.snapshot()
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: err}
+	}
+	n, err := f.lookup(s, path, false)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: err}
+	}
+	if !n.isLink() {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: filesystem.ErrIsNotSymlink}
+	}
+	target, err := f.readlink(n)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: err}
+	}
+	return target, nil
+}
+
+// Symlink commits the link, the target is stored as is.
+func (f *GitFileSystem) Symlink(target, link string) error {
+	if !f.writable {
+		return &os.LinkError{Op: "symlink", Old: target, New: link, Err: readonly.ErrReadOnly}
+	}
+	hash, err := f.writeBlob(strings.NewReader(target), nil)
+	if err == nil {
+		err = f.commit("Link "+filesystem.CleanPath(link)+" to "+target, func(s *snapshot) ([]change, error) {
+			if _, err := f.lookup(s, link, false); err == nil {
+				return nil, os.ErrExist
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+			return []change{{path: filesystem.CleanPath(link), entry: &object.TreeEntry{Mode: filemode.Symlink, Hash: hash}}}, nil
+		})
+	}
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: target, New: link, Err: err}
+	}
+	return nil
+}
+
+func (f *GitFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return f.WriteStream(path, bytes.NewReader(content), config)
+}
+
+func (f *GitFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	_, err := f.WriteVersioned(path, stream, config)
+	return err
+}
+
+// WriteVersioned commits the content at the path, and returns the hash of its blob.
+// The message of the commit can be set with [MessageKey].
+// The conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey] are checked against the branch
+// when the commit is made, the write fails if another writer moved the branch in between.
+func (f *GitFileSystem) WriteVersioned(path string, stream io.Reader, config map[string]any) (string, error) {
+	if !f.writable {
+		return "", filesystem.NewUnableToWriteFile(path, readonly.ErrReadOnly)
+	}
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return "", err
+	}
+	p := filesystem.CleanPath(path)
+	if p == "." {
+		return "", filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+	}
+	tracker := cfg.NewTracker(path, stream)
+	hash, err := f.writeBlob(stream, tracker)
+	if err != nil {
+		return "", filesystem.NewUnableToWriteFile(path, err)
+	}
+	mode := filemode.Regular
+	err = f.commit(message(cfg, "Write "+p), func(s *snapshot) ([]change, error) {
+		n, err := f.lookup(s, p, false)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if n != nil && n.isDir() {
+			return nil, filesystem.ErrIsNotFile
+		}
+		version := ""
+		if n != nil {
+			version = n.hash.String()
+			if n.mode == filemode.Executable {
+				mode = filemode.Executable
+			}
+		}
+		if err := cfg.CheckPrecondition(path, n != nil, version); err != nil {
+			return nil, err
+		}
+		return []change{{path: p, entry: &object.TreeEntry{Mode: mode, Hash: hash}}}, nil
+	})
+	if err != nil {
+		var preconditionFailed *filesystem.PreconditionFailed
+		if errors.As(err, &preconditionFailed) {
+			return "", err
+		}
+		return "", filesystem.NewUnableToWriteFile(path, err)
+	}
+	tracker.Done()
+	return hash.String(), nil
+}
+
+func (f *GitFileSystem) SetVisibility(path string, visibility string) error {
+	if !f.writable {
+		return filesystem.NewUnableToSetPermission(path, readonly.ErrReadOnly)
+	}
+	return filesystem.NewUnableToSetPermission(path, ErrVisibilityUnsupported)
+}
+
+// Delete commits the removal of the file, the directories left empty disappear with it.
+func (f *GitFileSystem) Delete(path string) error {
+	if !f.writable {
+		return filesystem.NewUnableToDeleteFile(path, readonly.ErrReadOnly)
+	}
+	p := filesystem.CleanPath(path)
+	err := f.commit("Delete "+p, func(s *snapshot) ([]change, error) {
+		n, err := f.lookup(s, p, false)
+		if err != nil {
+			return nil, err
+		}
+		if n.isDir() {
+			return nil, filesystem.ErrIsNotFile
+		}
+		return []change{{path: p}}, nil
+	})
+	if err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	return nil
+}
+
+func (f *GitFileSystem) DeleteDir(path string) error {
+	if !f.writable {
+		return filesystem.NewUnableToDeleteDirectory(path, readonly.ErrReadOnly)
+	}
+	p := filesystem.CleanPath(path)
+	if p == "." {
+		return filesystem.NewUnableToDeleteDirectory(path, errors.New("can't delete the root of the tree"))
+	}
+	err := f.commit("Delete "+p+"/", func(s *snapshot) ([]change, error) {
+		n, err := f.lookup(s, p, false)
+		if err != nil {
+			return nil, err
+		}
+		if !n.isDir() {
+			return nil, filesystem.ErrIsNotDirectory
+		}
+		return []change{{path: p}}, nil
+	})
+	if err != nil {
+		return filesystem.NewUnableToDeleteDirectory(path, err)
+	}
+	return nil
+}
+
+// CreateDir only checks that no file is in the way, as git does not store empty directories:
+// the directory appears with the first file written in it.
+func (f *GitFileSystem) CreateDir(path string, config map[string]any) error {
+	if !f.writable {
+		return filesystem.NewUnableToCreateDirectory(path, readonly.ErrReadOnly)
+	}
+	_, n, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return filesystem.NewUnableToCreateDirectory(path, err)
+	}
+	if !n.isDir() {
+		return filesystem.NewUnableToCreateDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	return nil
+}
+
+// Move commits the file or directory at its new path, replacing the destination.
+// The links are moved as links.
+func (f *GitFileSystem) Move(src string, dst string, config map[string]any) error {
+	if !f.writable {
+		return filesystem.NewUnableToMove(src, dst, readonly.ErrReadOnly)
+	}
+	if err := f.transfer(src, dst, config, true); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	return nil
+}
+
+// Copy commits a copy of the file or directory, which shares the objects of the source.
+func (f *GitFileSystem) Copy(src string, dst string, config map[string]any) error {
+	if !f.writable {
+		return filesystem.NewUnableToCopyFile(src, dst, readonly.ErrReadOnly)
+	}
+	if err := f.transfer(src, dst, config, false); err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	return nil
+}
+
+func (f *GitFileSystem) transfer(src, dst string, config map[string]any, move bool) error {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return err
+	}
+	srcKey, dstKey := filesystem.CleanPath(src), filesystem.CleanPath(dst)
+	if srcKey == "." || dstKey == "." {
+		return errors.New("can't move or copy the root of the tree")
+	}
+	if dstKey == srcKey || strings.HasPrefix(dstKey, srcKey+"/") {
+		return fmt.Errorf("can't move or copy %s into itself", srcKey)
+	}
+	defaultMessage := "Copy " + srcKey + " to " + dstKey
+	if move {
+		defaultMessage = "Move " + srcKey + " to " + dstKey
+	}
+	return f.commit(message(cfg, defaultMessage), func(s *snapshot) ([]change, error) {
+		n, err := f.lookup(s, srcKey, false)
+		if err != nil {
+			return nil, err
+		}
+		changes := []change{{path: dstKey, entry: &object.TreeEntry{Mode: n.mode, Hash: n.hash}}}
+		if move {
+			changes = append(changes, change{path: srcKey})
+		}
+		return changes, nil
+	})
+}
+
+// ReadOnly reports whether the write mode is disabled.
+func (f *GitFileSystem) ReadOnly() bool {
+	return !f.writable
+}
@@ -0,0 +1,363 @@
+package git
+
+import (
+	"io"
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	firstCommit  = time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)
+	secondCommit = time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC)
+)
+
+// newRepository creates a repository with two commits, the first one tagged v1.
+func newRepository(t *testing.T) string {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	commit := func(when time.Time, files map[string]string) plumbing.Hash {
+		for name, content := range files {
+			if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			if _, err := worktree.Add(name); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+		signature := &object.Signature{Name: "alice", Email: "alice@example.com", When: when}
+		hash, err := worktree.Commit("commit", &git.CommitOptions{Author: signature, Committer: signature})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		return hash
+	}
+	first := commit(firstCommit, map[string]string{
+		"config.yaml":         "version: 1",
+		"templates/mail.html": "<p>hello</p>",
+	})
+	if _, err := repo.CreateTag("v1", first, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "alice", Email: "alice@example.com", When: firstCommit},
+		Message: "v1",
+	}); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := os.Symlink("templates/mail.html", filepath.Join(dir, "mail.html")); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := worktree.Add("mail.html"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	commit(secondCommit, map[string]string{
+		"config.yaml": "version: 2",
+	})
+	return dir
+}
+
+func TestGitFileSystem(t *testing.T) {
+	dir := newRepository(t)
+	f, err := NewGitFileSystem(&Config{Path: dir})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	t.Run("read", func(t *testing.T) {
+		content, err := f.Read("config.yaml")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "version: 2", string(content))
+
+		content, err = f.Read("mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "<p>hello</p>", string(content))
+
+		_, err = f.Read("missing.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = f.Read("templates")
+		assert.ErrorIs(t, err, filesystem.ErrIsNotFile)
+	})
+
+	t.Run("read range", func(t *testing.T) {
+		stream, err := f.ReadRange("config.yaml", 9, 5)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer stream.Close()
+		content, err := io.ReadAll(stream)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "2", string(content))
+	})
+
+	t.Run("read tag", func(t *testing.T) {
+		tagged, err := NewGitFileSystem(&Config{Path: dir, Ref: "v1"})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := tagged.Read("config.yaml")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "version: 1", string(content))
+		exists, err := tagged.Exists("mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+
+		_, err = NewGitFileSystem(&Config{Path: dir, Ref: "v2"})
+		assert.Error(t, err)
+	})
+
+	t.Run("last modified", func(t *testing.T) {
+		modTime, err := f.LastModified("config.yaml")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, modTime.Equal(secondCommit))
+		modTime, err = f.LastModified("templates/mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, modTime.Equal(firstCommit))
+		modTime, err = f.LastModified("templates")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, modTime.Equal(firstCommit))
+	})
+
+	t.Run("read dir", func(t *testing.T) {
+		entries, err := f.ReadDir(".")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.Equal(t, []string{"config.yaml", "mail.html", "templates"}, names)
+		assert.Equal(t, gofs.ModeSymlink, entries[1].Type())
+		assert.True(t, entries[2].IsDir())
+
+		var paths []string
+		err = f.WalkDir(".", func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{".", "config.yaml", "mail.html", "templates", "templates/mail.html"}, paths)
+	})
+
+	t.Run("links", func(t *testing.T) {
+		target, err := f.Readlink("mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "templates/mail.html", target)
+		info, err := f.Lstat("mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, gofs.ModeSymlink, info.Mode().Type())
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		assert.True(t, f.ReadOnly())
+		assert.ErrorIs(t, f.Write("config.yaml", []byte("version: 3"), nil), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.Delete("config.yaml"), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.Move("config.yaml", "moved.yaml", nil), readonly.ErrReadOnly)
+	})
+}
+
+func TestGitFileSystem_Write(t *testing.T) {
+	dir := newRepository(t)
+	f, err := NewGitFileSystem(&Config{Path: dir, Writable: true, AuthorName: "bot", AuthorEmail: "bot@example.com"})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	head := func() *object.Commit {
+		ref, err := repo.Head()
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		return commit
+	}
+
+	t.Run("write", func(t *testing.T) {
+		before := head()
+		err := f.Write("templates/new/welcome.html", []byte("<p>welcome</p>"), map[string]any{MessageKey: "Add the welcome mail"})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		after := head()
+		assert.Equal(t, "Add the welcome mail", after.Message)
+		assert.Equal(t, "bot", after.Author.Name)
+		assert.Equal(t, []plumbing.Hash{before.Hash}, after.ParentHashes)
+		file, err := after.File("templates/new/welcome.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := file.Contents()
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "<p>welcome</p>", content)
+		// the other files are kept.
+		kept, err := f.Read("templates/mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "<p>hello</p>", string(kept))
+
+		// writing the same content again makes no commit.
+		if err := f.Write("templates/new/welcome.html", []byte("<p>welcome</p>"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, after.Hash, head().Hash)
+	})
+
+	t.Run("conditional write", func(t *testing.T) {
+		stat, err := f.Stat("config.yaml")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		version, err := f.WriteVersioned("config.yaml", stringReader("version: 3"), map[string]any{filesystem.IfMatchKey: stat.Version})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NotEqual(t, stat.Version, version)
+		_, err = f.WriteVersioned("config.yaml", stringReader("version: 4"), map[string]any{filesystem.IfMatchKey: stat.Version})
+		assert.ErrorIs(t, err, filesystem.ErrVersionMismatch)
+		_, err = f.WriteVersioned("config.yaml", stringReader("version: 4"), map[string]any{filesystem.IfAbsentKey: true})
+		assert.ErrorIs(t, err, os.ErrExist)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := f.Delete("templates/new/welcome.html"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "Delete templates/new/welcome.html", head().Message)
+		exists, err := f.DirExists("templates/new")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		assert.ErrorIs(t, f.Delete("templates"), filesystem.ErrIsNotFile)
+		assert.ErrorIs(t, f.Delete("missing.txt"), os.ErrNotExist)
+	})
+
+	t.Run("move and copy", func(t *testing.T) {
+		if err := f.Copy("templates", "layouts", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Move("layouts/mail.html", "layouts/base.html", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "Move layouts/mail.html to layouts/base.html", head().Message)
+		content, err := f.Read("layouts/base.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "<p>hello</p>", string(content))
+		exists, err := f.FileExists("layouts/mail.html")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		if err := f.DeleteDir("layouts"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err = f.DirExists("layouts")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("new branch", func(t *testing.T) {
+		branch, err := NewGitFileSystem(&Config{Path: dir, Ref: "drafts", Writable: true})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err := branch.Exists("config.yaml")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		if err := branch.Write("draft.md", []byte("# draft"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName("drafts"), true)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, commit.ParentHashes)
+
+		_, err = NewGitFileSystem(&Config{Path: dir, Ref: "v1", Writable: true})
+		assert.ErrorIs(t, err, ErrNotBranch)
+	})
+}
+
+func TestGitFileSystem_Bare(t *testing.T) {
+	source := newRepository(t)
+	dir := t.TempDir()
+	if _, err := git.PlainClone(dir, true, &git.CloneOptions{URL: source}); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	f, err := NewGitFileSystem(&Config{Path: dir, Ref: "master"})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := f.Read("config.yaml")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "version: 2", string(content))
+}
+
+func stringReader(s string) io.Reader {
+	return strings.NewReader(s)
+}
@@ -0,0 +1,5 @@
+module github.com/gopi-frame/filesystem/driver/git
+
+go 1.22
+
+require github.com/go-git/go-git/v5 v5.12.0
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/gopi-frame/contract"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*GitFileSystem]
+
+type OptionFunc func(f *GitFileSystem) error
+
+func (o OptionFunc) Apply(f *GitFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *GitFileSystem) error {
+	return nil
+})
+
+func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
+	if detector == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *GitFileSystem) error {
+		f.mimetypeDetector = detector
+		return nil
+	})
+}
+
+// WithRepository sets the repository, replacing the one opened at the path of the config,
+// e.g. a repository cloned in memory.
+func WithRepository(repo *git.Repository) Option {
+	if repo == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *GitFileSystem) error {
+		f.repo = repo
+		return nil
+	})
+}
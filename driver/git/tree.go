@@ -0,0 +1,222 @@
+package git
+
+import (
+	"errors"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gopi-frame/filesystem"
+)
+
+// maxLinkHops bounds the symbolic links followed to resolve a path, as the ELOOP limit of Linux.
+const maxLinkHops = 40
+
+// maxLinkSize bounds the size of the blob read as the target of a symbolic link.
+const maxLinkSize = 4096
+
+func split(k string) []string {
+	if k == "." {
+		return nil
+	}
+	return strings.Split(k, "/")
+}
+
+// snapshot is the tree of a commit, the commit is nil for a branch without commits yet.
+type snapshot struct {
+	commit *object.Commit
+	tree   *object.Tree
+}
+
+// node is an entry of the tree, directories and submodules have a tree, the one of a submodule is empty.
+type node struct {
+	path string
+	mode filemode.FileMode
+	hash plumbing.Hash
+	tree *object.Tree
+}
+
+func (n *node) isDir() bool {
+	return n.tree != nil
+}
+
+func (n *node) isLink() bool {
+	return n.mode == filemode.Symlink
+}
+
+// lookup resolves the path in the tree, following the links of its parents,
+// and the link it names if follow is true.
+// The links leading outside of the tree are dangling.
+func (f *GitFileSystem) lookup(s *snapshot, p string, follow bool) (*node, error) {
+	root := &node{path: ".", mode: filemode.Dir, hash: s.tree.Hash, tree: s.tree}
+	parts := split(filesystem.CleanPath(p))
+	current := root
+	for hops, i := 0, 0; i < len(parts); i++ {
+		if !current.isDir() {
+			return nil, os.ErrNotExist
+		}
+		entry, err := current.tree.FindEntry(parts[i])
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, os.ErrNotExist
+		}
+		if err != nil {
+			return nil, err
+		}
+		child, err := f.node(path.Join(current.path, entry.Name), entry)
+		if err != nil {
+			return nil, err
+		}
+		if child.isLink() && (follow || i < len(parts)-1) {
+			if hops++; hops > maxLinkHops {
+				return nil, ErrTooManyLinks
+			}
+			target, err := f.readlink(child)
+			if err != nil {
+				return nil, err
+			}
+			if path.IsAbs(target) {
+				return nil, os.ErrNotExist
+			}
+			resolved := path.Join(current.path, target)
+			if resolved == ".." || strings.HasPrefix(resolved, "../") {
+				return nil, os.ErrNotExist
+			}
+			parts = append(split(resolved), parts[i+1:]...)
+			current, i = root, -1
+			continue
+		}
+		current = child
+	}
+	return current, nil
+}
+
+func (f *GitFileSystem) node(p string, entry *object.TreeEntry) (*node, error) {
+	n := &node{path: p, mode: entry.Mode, hash: entry.Hash}
+	switch entry.Mode {
+	case filemode.Dir:
+		tree, err := f.repo.TreeObject(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		n.tree = tree
+	case filemode.Submodule:
+		n.tree = &object.Tree{}
+	}
+	return n, nil
+}
+
+func (f *GitFileSystem) readlink(n *node) (string, error) {
+	blob, err := f.repo.BlobObject(n.hash)
+	if err != nil {
+		return "", err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	target, err := io.ReadAll(io.LimitReader(r, maxLinkSize))
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
+}
+
+// lastModified returns the time of the last commit touching the path, or of the commit itself for the root.
+func (f *GitFileSystem) lastModified(commit *object.Commit, p string) (time.Time, error) {
+	if commit == nil {
+		return time.Time{}, nil
+	}
+	if p == "." {
+		return commit.Committer.When, nil
+	}
+	iter, err := f.repo.Log(&git.LogOptions{From: commit.Hash, PathFilter: func(name string) bool {
+		return name == p || strings.HasPrefix(name, p+"/")
+	}})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer iter.Close()
+	last, err := iter.Next()
+	if errors.Is(err, io.EOF) {
+		return commit.Committer.When, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return last.Committer.When, nil
+}
+
+// fileInfo is an entry of the tree, it implements both [gofs.FileInfo] and [gofs.DirEntry].
+// The modification time is looked up in the history when it is first asked for.
+type fileInfo struct {
+	fs      *GitFileSystem
+	commit  *object.Commit
+	node    *node
+	size    int64
+	once    sync.Once
+	modTime time.Time
+}
+
+func (f *GitFileSystem) fileInfo(s *snapshot, n *node) (*fileInfo, error) {
+	info := &fileInfo{fs: f, commit: s.commit, node: n}
+	if !n.isDir() {
+		blob, err := f.repo.BlobObject(n.hash)
+		if err != nil {
+			return nil, err
+		}
+		info.size = blob.Size
+	}
+	return info, nil
+}
+
+func (i *fileInfo) Name() string {
+	return path.Base(i.node.path)
+}
+
+func (i *fileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *fileInfo) Mode() gofs.FileMode {
+	switch {
+	case i.node.isLink():
+		return gofs.ModeSymlink | 0777
+	case i.node.isDir():
+		return gofs.ModeDir | 0755
+	case i.node.mode == filemode.Executable:
+		return 0755
+	}
+	return 0644
+}
+
+func (i *fileInfo) ModTime() time.Time {
+	i.once.Do(func() {
+		i.modTime, _ = i.fs.lastModified(i.commit, i.node.path)
+	})
+	return i.modTime
+}
+
+func (i *fileInfo) IsDir() bool {
+	return i.node.isDir()
+}
+
+func (i *fileInfo) Sys() any {
+	return nil
+}
+
+func (i *fileInfo) Type() gofs.FileMode {
+	return i.Mode().Type()
+}
+
+func (i *fileInfo) Info() (gofs.FileInfo, error) {
+	return i, nil
+}
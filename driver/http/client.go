@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"mime"
+	gohttp "net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+)
+
+// StatusError is the error of a request answered with an unexpected status.
+// It wraps os.ErrNotExist for 404 Not Found and 410 Gone, and os.ErrPermission for 401 Unauthorized and 403 Forbidden.
+type StatusError = httpclient.StatusError
+
+func (f *HTTPFileSystem) request(method, url string, header map[string]string) (*gohttp.Response, error) {
+	req, err := gohttp.NewRequestWithContext(context.Background(), method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.username != "" || f.password != "" {
+		req.SetBasicAuth(f.username, f.password)
+	}
+	for k, v := range f.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return f.client.Do(req)
+}
+
+// stat sends a HEAD request for the file. Static servers redirect a directory requested
+// without a trailing slash to its listing, so a file is a directory if the final URL ends with a slash.
+// Servers refusing HEAD are sent a GET request whose body is not read.
+func (f *HTTPFileSystem) stat(p string) (*fileInfo, error) {
+	url := httpclient.URL(f.endpoint, p, false)
+	resp, err := f.request(gohttp.MethodHead, url, nil)
+	if err == nil && (resp.StatusCode == gohttp.StatusMethodNotAllowed || resp.StatusCode == gohttp.StatusNotImplemented) {
+		_ = resp.Body.Close()
+		resp, err = f.request(gohttp.MethodGet, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != gohttp.StatusOK {
+		return nil, &StatusError{Method: resp.Request.Method, URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	info := &fileInfo{
+		name: path.Base(filesystem.CleanPath(p)),
+		dir:  filesystem.CleanPath(p) == "." || strings.HasSuffix(resp.Request.URL.Path, "/"),
+	}
+	if !info.dir {
+		info.size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+		if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+			info.contentType, _, _ = mime.ParseMediaType(contentType)
+		}
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		info.modTime, _ = gohttp.ParseTime(lastModified)
+	}
+	return info, nil
+}
+
+// list reads the listing of the directory.
+func (f *HTTPFileSystem) list(p string) ([]*fileInfo, error) {
+	url := httpclient.URL(f.endpoint, p, true)
+	resp, err := f.request(gohttp.MethodGet, url, map[string]string{
+		"Accept": "application/json, text/html;q=0.9, */*;q=0.8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != gohttp.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, &StatusError{Method: gohttp.MethodGet, URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	base := *resp.Request.URL
+	base.RawQuery = ""
+	entries, err := parseListing(&base, resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, fmt.Errorf("http: unable to parse the listing of %s: %w", url, err)
+	}
+	infos := make([]*fileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info := &fileInfo{name: entry.name, dir: entry.dir, modTime: entry.modTime}
+		if !entry.dir && entry.size > 0 {
+			info.size = entry.size
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// fileInfo is a file of the server, it implements both [gofs.FileInfo] and [gofs.DirEntry].
+// The size of a file read from a listing is approximate when the listing prints human readable sizes.
+type fileInfo struct {
+	name        string
+	dir         bool
+	size        int64
+	modTime     time.Time
+	contentType string
+}
+
+func (i *fileInfo) Name() string {
+	return i.name
+}
+
+func (i *fileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *fileInfo) Mode() gofs.FileMode {
+	if i.dir {
+		return gofs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (i *fileInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+func (i *fileInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i *fileInfo) Sys() any {
+	return nil
+}
+
+func (i *fileInfo) Type() gofs.FileMode {
+	return i.Mode().Type()
+}
+
+func (i *fileInfo) Info() (gofs.FileInfo, error) {
+	return i, nil
+}
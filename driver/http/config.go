@@ -0,0 +1,92 @@
+package http
+
+import (
+	"crypto/tls"
+	gohttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+)
+
+type Config struct {
+	// Endpoint is the URL of the root directory, e.g. https://downloads.example.com/datasets/.
+	Endpoint string
+	// Username and Password are sent with basic authentication.
+	Username string
+	Password string
+	// BearerToken is sent in the Authorization header.
+	BearerToken string
+	// Headers are added to every request.
+	Headers map[string]string
+	// Timeout bounds every request, it is not set by default so that large downloads are not interrupted.
+	Timeout *time.Duration
+	// InsecureSkipVerify disables the verification of the server certificate.
+	InsecureSkipVerify bool
+	// Transport is the round tripper of the requests, default is a clone of http.DefaultTransport.
+	Transport gohttp.RoundTripper
+	// Visibility is the visibility reported for every file, default is "public".
+	Visibility string
+}
+
+func (c *Config) Apply(f *HTTPFileSystem) error {
+	if c.Endpoint == "" {
+		return ErrNoEndpoint
+	}
+	endpoint, err := httpclient.ParseEndpoint(c.Endpoint)
+	if err != nil {
+		return err
+	}
+	f.endpoint = endpoint
+	transport := c.Transport
+	if transport == nil {
+		defaultTransport := gohttp.DefaultTransport.(*gohttp.Transport).Clone()
+		if c.InsecureSkipVerify {
+			defaultTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+		transport = defaultTransport
+	}
+	f.client = &gohttp.Client{Transport: transport}
+	if c.Timeout != nil {
+		f.client.Timeout = *c.Timeout
+	}
+	f.headers = map[string]string{}
+	for k, v := range c.Headers {
+		f.headers[k] = v
+	}
+	f.username, f.password = c.Username, c.Password
+	if c.BearerToken != "" {
+		f.headers["Authorization"] = "Bearer " + c.BearerToken
+	}
+	if c.Visibility != "" {
+		f.visibility = c.Visibility
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			env.ExpandStringKeyMapWithEnvHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,299 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	gohttp "net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+)
+
+var ErrNoEndpoint = errors.New("http endpoint is required")
+
+// HTTPFileSystem is a read-only file system served by a static file server, e.g. nginx, Apache or http.FileServer.
+//
+// Files are read with GET requests and their metadata with HEAD requests.
+// Directories are read from the autoindex pages of the server, in HTML or JSON.
+// Every write returns an error wrapping [readonly.ErrReadOnly].
+type HTTPFileSystem struct {
+	endpoint         *url.URL
+	client           *gohttp.Client
+	headers          map[string]string
+	username         string
+	password         string
+	visibility       string
+	mimetypeDetector fs.MimeTypeDetector
+}
+
+// NewHTTPFileSystem creates a file system rooted at the endpoint of the config.
+func NewHTTPFileSystem(config *Config, opts ...Option) (*HTTPFileSystem, error) {
+	f := &HTTPFileSystem{
+		visibility: "public",
+	}
+	if err := config.Apply(f); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if f.mimetypeDetector == nil {
+		f.mimetypeDetector = filesystem.NewMimeTypeDetector()
+	}
+	return f, nil
+}
+
+func (f *HTTPFileSystem) Exists(path string) (bool, error) {
+	_, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return true, nil
+}
+
+func (f *HTTPFileSystem) FileExists(path string) (bool, error) {
+	info, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return !info.IsDir(), nil
+}
+
+func (f *HTTPFileSystem) DirExists(path string) (bool, error) {
+	info, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return info.IsDir(), nil
+}
+
+func (f *HTTPFileSystem) Read(path string) ([]byte, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return content, nil
+}
+
+// ReadStream returns the body of a GET request, the content is streamed from the server as it is read.
+func (f *HTTPFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	url := httpclient.URL(f.endpoint, path, false)
+	resp, err := f.request(gohttp.MethodGet, url, nil)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if resp.StatusCode != gohttp.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return nil, filesystem.NewUnableToReadFile(path, &StatusError{Method: gohttp.MethodGet, URL: url, StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+	if strings.HasSuffix(resp.Request.URL.Path, "/") && filesystem.CleanPath(path) != "." {
+		_ = resp.Body.Close()
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	return resp.Body, nil
+}
+
+// ReadRange returns length bytes of the file starting at offset, with a Range request.
+// A server ignoring the range answers with the whole file, which is then skipped to offset and cut to length.
+// A range starting past the end of the file is empty.
+func (f *HTTPFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += fmt.Sprint(offset + length - 1)
+	}
+	url := httpclient.URL(f.endpoint, path, false)
+	resp, err := f.request(gohttp.MethodGet, url, map[string]string{"Range": byteRange})
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	switch resp.StatusCode {
+	case gohttp.StatusPartialContent:
+		return resp.Body, nil
+	case gohttp.StatusRequestedRangeNotSatisfiable:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	case gohttp.StatusOK:
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil && !errors.Is(err, io.EOF) {
+			_ = resp.Body.Close()
+			return nil, filesystem.NewUnableToReadFile(path, err)
+		}
+		return filesystem.LimitReadCloser(resp.Body, length), nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return nil, filesystem.NewUnableToReadFile(path, &StatusError{Method: gohttp.MethodGet, URL: url, StatusCode: resp.StatusCode, Status: resp.Status})
+}
+
+// ReadDir parses the listing of the directory, an nginx, Apache or http.FileServer autoindex page,
+// or a JSON listing like the one of nginx with autoindex_format json.
+func (f *HTTPFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if !info.IsDir() {
+		return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	infos, err := f.list(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	entries := make([]os.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, info)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// WalkDir walks the tree reading the listing of every directory.
+func (f *HTTPFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	root, err := f.stat(path)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	var walkDir func(p string, d gofs.DirEntry) error
+	walkDir = func(p string, d gofs.DirEntry) error {
+		if err := walkFn(p, d, nil); err != nil || !d.IsDir() {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+		infos, err := f.list(p)
+		if err != nil {
+			err = walkFn(p, d, filesystem.NewUnableToReadDirectory(p, err))
+			if err != nil {
+				if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+					err = nil
+				}
+				return err
+			}
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Name() < infos[j].Name()
+		})
+		for _, info := range infos {
+			if err := walkDir(filepath.ToSlash(filepath.Join(p, info.Name())), info); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					break
+				}
+				return err
+			}
+		}
+		return nil
+	}
+	err = walkDir(path, root)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (f *HTTPFileSystem) LastModified(path string) (time.Time, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info.modTime, nil
+}
+
+func (f *HTTPFileSystem) FileSize(path string) (int64, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info.size, nil
+}
+
+// MimeType returns the content type reported by the server, or detects it from the extension of the path.
+func (f *HTTPFileSystem) MimeType(path string) (string, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if info.contentType != "" {
+		return info.contentType, nil
+	}
+	return f.mimetypeDetector.DetectFromPath(path), nil
+}
+
+func (f *HTTPFileSystem) Visibility(path string) (string, error) {
+	if _, err := f.stat(path); err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return f.visibility, nil
+}
+
+func (f *HTTPFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return filesystem.NewUnableToWriteFile(path, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	return filesystem.NewUnableToWriteFile(path, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) SetVisibility(path string, visibility string) error {
+	return filesystem.NewUnableToSetPermission(path, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) Delete(path string) error {
+	return filesystem.NewUnableToDeleteFile(path, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) DeleteDir(path string) error {
+	return filesystem.NewUnableToDeleteDirectory(path, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) CreateDir(path string, config map[string]any) error {
+	return filesystem.NewUnableToCreateDirectory(path, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) Move(src string, dst string, config map[string]any) error {
+	return filesystem.NewUnableToMove(src, dst, readonly.ErrReadOnly)
+}
+
+func (f *HTTPFileSystem) Copy(src string, dst string, config map[string]any) error {
+	return filesystem.NewUnableToCopyFile(src, dst, readonly.ErrReadOnly)
+}
+
+// ReadOnly reports that the file system can't be written.
+func (f *HTTPFileSystem) ReadOnly() bool {
+	return true
+}
@@ -0,0 +1,228 @@
+package http
+
+import (
+	"io"
+	gofs "io/fs"
+	gohttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+	"github.com/stretchr/testify/assert"
+)
+
+// newServer serves a temporary directory with http.FileServer under /files/, behind basic authentication.
+func newServer(t *testing.T) string {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"hello.txt":        "hello world",
+		"docs/readme.md":   "# readme",
+		"docs/a b/c%d.txt": "escaped",
+		"empty/.keep":      "",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	modTime := time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(root, "hello.txt"), modTime, modTime); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	files := gohttp.StripPrefix("/files", gohttp.FileServer(gohttp.Dir(root)))
+	server := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "alice" || password != "secret" {
+			w.WriteHeader(gohttp.StatusUnauthorized)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/files"
+}
+
+func TestHTTPFileSystem(t *testing.T) {
+	f, err := NewHTTPFileSystem(&Config{Endpoint: newServer(t), Username: "alice", Password: "secret"})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	t.Run("read", func(t *testing.T) {
+		content, err := f.Read("hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello world", string(content))
+
+		content, err = f.Read("/docs/a b/c%d.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "escaped", string(content))
+
+		_, err = f.Read("missing.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = f.Read("docs")
+		assert.ErrorIs(t, err, filesystem.ErrIsNotFile)
+	})
+
+	t.Run("read range", func(t *testing.T) {
+		for _, c := range []struct {
+			offset, length int64
+			expected       string
+		}{
+			{6, 5, "world"},
+			{6, -1, "world"},
+			{0, 5, "hello"},
+			{6, 100, "world"},
+			{100, 5, ""},
+			{3, 0, ""},
+		} {
+			stream, err := f.ReadRange("hello.txt", c.offset, c.length)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			content, err := io.ReadAll(stream)
+			_ = stream.Close()
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, c.expected, string(content), "offset %d, length %d", c.offset, c.length)
+		}
+		_, err := f.ReadRange("hello.txt", -1, 5)
+		assert.ErrorIs(t, err, filesystem.ErrInvalidRange)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		exists, err := f.Exists("hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+		exists, err = f.FileExists("docs")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		exists, err = f.DirExists("docs")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+		exists, err = f.Exists("missing.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+
+		size, err := f.FileSize("hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(11), size)
+		modTime, err := f.LastModified("hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, modTime.Equal(time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)))
+		mimeType, err := f.MimeType("hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "text/plain", mimeType)
+		visibility, err := f.Visibility("hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "public", visibility)
+	})
+
+	t.Run("read dir", func(t *testing.T) {
+		entries, err := f.ReadDir("docs")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.Equal(t, []string{"a b", "readme.md"}, names)
+		assert.True(t, entries[0].IsDir())
+		assert.False(t, entries[1].IsDir())
+
+		_, err = f.ReadDir("hello.txt")
+		assert.ErrorIs(t, err, filesystem.ErrIsNotDirectory)
+	})
+
+	t.Run("walk dir", func(t *testing.T) {
+		var paths []string
+		err := f.WalkDir(".", func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == "empty" {
+				return filepath.SkipDir
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{".", "docs", "docs/a b", "docs/a b/c%d.txt", "docs/readme.md", "hello.txt"}, paths)
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		assert.ErrorIs(t, f.Write("new.txt", []byte("new"), nil), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.WriteStream("new.txt", nil, nil), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.SetVisibility("hello.txt", "private"), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.Delete("hello.txt"), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.DeleteDir("docs"), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.CreateDir("new", nil), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.Move("hello.txt", "moved.txt", nil), readonly.ErrReadOnly)
+		assert.ErrorIs(t, f.Copy("hello.txt", "copied.txt", nil), readonly.ErrReadOnly)
+	})
+}
+
+func TestHTTPFileSystem_Auth(t *testing.T) {
+	endpoint := newServer(t)
+
+	t.Run("wrong password", func(t *testing.T) {
+		f, err := NewHTTPFileSystem(&Config{Endpoint: endpoint, Username: "alice", Password: "wrong"})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = f.Read("hello.txt")
+		assert.ErrorIs(t, err, os.ErrPermission)
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		var authorization, custom string
+		server := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+			authorization, custom = r.Header.Get("Authorization"), r.Header.Get("X-Custom")
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+		f, err := NewHTTPFileSystem(&Config{Endpoint: server.URL, BearerToken: "token", Headers: map[string]string{"X-Custom": "value"}})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := f.Read("file.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "Bearer token", authorization)
+		assert.Equal(t, "value", custom)
+	})
+
+	t.Run("no endpoint", func(t *testing.T) {
+		_, err := NewHTTPFileSystem(&Config{})
+		assert.ErrorIs(t, err, ErrNoEndpoint)
+	})
+}
@@ -0,0 +1,3 @@
+module github.com/gopi-frame/filesystem/driver/http
+
+go 1.22
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"encoding/json"
+	"html"
+	gohttp "net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listingEntry is an entry of a directory listing, its size is -1 if the listing does not give it.
+type listingEntry struct {
+	name    string
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+var (
+	anchorPattern = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))[^>]*>`)
+	tagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// listingTimeLayouts are the date formats of the nginx and Apache autoindex pages.
+var listingTimeLayouts = []string{
+	"02-Jan-2006 15:04",
+	"02-Jan-2006 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+}
+
+// parseListing parses a JSON listing, as produced by nginx with autoindex_format json or by Caddy,
+// or the HTML autoindex page of nginx, Apache or http.FileServer.
+// Only the direct children of the listed URL are returned.
+func parseListing(base *url.URL, contentType string, body []byte) ([]listingEntry, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if strings.Contains(contentType, "json") || strings.HasPrefix(trimmed, "[") {
+		return parseJSONListing(body)
+	}
+	return parseHTMLListing(base, string(body)), nil
+}
+
+func parseJSONListing(body []byte) ([]listingEntry, error) {
+	var items []struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		IsDir   bool   `json:"is_dir"`
+		Size    *int64 `json:"size"`
+		MTime   string `json:"mtime"`
+		ModTime string `json:"mod_time"`
+	}
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	entries := make([]listingEntry, 0, len(items))
+	for _, item := range items {
+		name := strings.TrimSuffix(item.Name, "/")
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			continue
+		}
+		entry := listingEntry{
+			name: name,
+			dir:  item.Type == "directory" || item.IsDir || strings.HasSuffix(item.Name, "/"),
+			size: -1,
+		}
+		if item.Size != nil {
+			entry.size = *item.Size
+		}
+		if item.MTime != "" {
+			entry.modTime, _ = gohttp.ParseTime(item.MTime)
+		} else if item.ModTime != "" {
+			entry.modTime, _ = time.Parse(time.RFC3339, item.ModTime)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseHTMLListing collects the links to the children of the page, and reads the date and size
+// printed after every link on the same line or table row, when there are.
+func parseHTMLListing(base *url.URL, page string) []listingEntry {
+	matches := anchorPattern.FindAllStringSubmatchIndex(page, -1)
+	var entries []listingEntry
+	seen := map[string]int{}
+	for i, match := range matches {
+		href := ""
+		for group := 1; group <= 3; group++ {
+			if match[2*group] >= 0 {
+				href = html.UnescapeString(page[match[2*group]:match[2*group+1]])
+				break
+			}
+		}
+		name, dir, ok := childName(base, href)
+		if !ok {
+			continue
+		}
+		end := len(page)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		rest := page[match[1]:end]
+		if j := strings.IndexAny(rest, "\r\n"); j >= 0 {
+			rest = rest[:j]
+		}
+		if j := strings.Index(strings.ToLower(rest), "</tr>"); j >= 0 {
+			rest = rest[:j]
+		}
+		// the text of the link itself is not a detail.
+		if j := strings.Index(strings.ToLower(rest), "</a>"); j >= 0 {
+			rest = rest[j+len("</a>"):]
+		}
+		entry := listingEntry{name: name, dir: dir, size: -1}
+		entry.modTime, entry.size = parseDetails(html.UnescapeString(tagPattern.ReplaceAllString(rest, " ")))
+		if dir {
+			entry.size = -1
+		}
+		if j, ok := seen[name]; ok {
+			// fancy indexes link every entry twice, from its icon and from its name.
+			if entries[j].modTime.IsZero() {
+				entries[j] = entry
+			}
+			continue
+		}
+		seen[name] = len(entries)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// childName resolves the link, and returns the name of the direct child of the base it points to.
+func childName(base *url.URL, href string) (string, bool, bool) {
+	if href == "" || strings.HasPrefix(href, "?") || strings.HasPrefix(href, "#") {
+		return "", false, false
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", false, false
+	}
+	target := base.ResolveReference(ref)
+	if target.Host != base.Host || target.RawQuery != "" {
+		return "", false, false
+	}
+	rest, ok := strings.CutPrefix(target.Path, base.Path)
+	if !ok || rest == "" {
+		return "", false, false
+	}
+	dir := strings.HasSuffix(rest, "/")
+	rest = strings.TrimSuffix(rest, "/")
+	if rest == "" || strings.Contains(rest, "/") {
+		return "", false, false
+	}
+	return rest, dir, true
+}
+
+// parseDetails finds the modification time and the size in the text following a link, e.g.
+// "06-Jan-2024 10:00    1234" for nginx or "2024-01-06 10:00  1.2K" for Apache.
+func parseDetails(text string) (time.Time, int64) {
+	fields := strings.Fields(text)
+	for i := 0; i+1 < len(fields); i++ {
+		for _, layout := range listingTimeLayouts {
+			modTime, err := time.Parse(layout, fields[i]+" "+fields[i+1])
+			if err != nil {
+				continue
+			}
+			size := int64(-1)
+			if i+2 < len(fields) {
+				size = parseSize(fields[i+2])
+			}
+			return modTime, size
+		}
+	}
+	return time.Time{}, -1
+}
+
+// parseSize parses a size in bytes, or a human readable one like 1.2K or 4M, it returns -1 if it is not a size.
+func parseSize(s string) int64 {
+	multiplier := float64(1)
+	switch suffix := strings.ToUpper(s[len(s)-1:]); suffix {
+	case "K", "M", "G", "T":
+		multiplier = float64(uint64(1) << (10 * (strings.Index("KMGT", suffix) + 1)))
+		s = s[:len(s)-1]
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return -1
+	}
+	return int64(value * multiplier)
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const nginxListing = `<html>
+<head><title>Index of /files/</title></head>
+<body>
+<h1>Index of /files/</h1><hr><pre><a href="../">../</a>
+<a href="docs/">docs/</a>                                              06-Jan-2024 10:00                   -
+<a href="a%20b.txt">a b.txt</a>                                           06-Jan-2024 10:01                1234
+</pre><hr></body>
+</html>`
+
+const apacheListing = `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">
+<html>
+ <head>
+  <title>Index of /files</title>
+ </head>
+ <body>
+<h1>Index of /files</h1>
+  <table>
+   <tr><th valign="top"><img src="/icons/blank.gif" alt="[ICO]"></th><th><a href="?C=N;O=D">Name</a></th><th><a href="?C=M;O=A">Last modified</a></th><th><a href="?C=S;O=A">Size</a></th></tr>
+   <tr><th colspan="4"><hr></th></tr>
+<tr><td valign="top"><img src="/icons/back.gif" alt="[PARENTDIR]"></td><td><a href="/">Parent Directory</a></td><td>&nbsp;</td><td align="right">  - </td></tr>
+<tr><td valign="top"><a href="docs/"><img src="/icons/folder.gif" alt="[DIR]"></a></td><td><a href="docs/">docs/</a></td><td align="right">2024-01-06 10:00  </td><td align="right">  - </td></tr>
+<tr><td valign="top"><img src="/icons/text.gif" alt="[TXT]"></td><td><a href="report.csv">report.csv</a></td><td align="right">2024-01-06 10:01:30  </td><td align="right">1.5K</td></tr>
+<tr><td valign="top"><img src="/icons/text.gif" alt="[TXT]"></td><td><a href="http://example.com/elsewhere.txt">elsewhere.txt</a></td><td align="right">2024-01-06 10:01  </td><td align="right">1</td></tr>
+   <tr><th colspan="4"><hr></th></tr>
+</table>
+</body></html>`
+
+const nginxJSONListing = `[
+{ "name":"docs", "type":"directory", "mtime":"Sat, 06 Jan 2024 10:00:00 GMT" },
+{ "name":"a b.txt", "type":"file", "mtime":"Sat, 06 Jan 2024 10:01:00 GMT", "size":1234 }
+]`
+
+func TestParseListing(t *testing.T) {
+	base, err := url.Parse("http://localhost/files/")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	t.Run("nginx", func(t *testing.T) {
+		entries, err := parseListing(base, "text/html", []byte(nginxListing))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []listingEntry{
+			{name: "docs", dir: true, size: -1, modTime: time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)},
+			{name: "a b.txt", size: 1234, modTime: time.Date(2024, 1, 6, 10, 1, 0, 0, time.UTC)},
+		}, entries)
+	})
+
+	t.Run("apache", func(t *testing.T) {
+		entries, err := parseListing(base, "text/html;charset=UTF-8", []byte(apacheListing))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []listingEntry{
+			{name: "docs", dir: true, size: -1, modTime: time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)},
+			{name: "report.csv", size: 1536, modTime: time.Date(2024, 1, 6, 10, 1, 30, 0, time.UTC)},
+		}, entries)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		entries, err := parseListing(base, "application/json", []byte(nginxJSONListing))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []listingEntry{
+			{name: "docs", dir: true, size: -1, modTime: time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC)},
+			{name: "a b.txt", size: 1234, modTime: time.Date(2024, 1, 6, 10, 1, 0, 0, time.UTC)},
+		}, entries)
+	})
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	gohttp "net/http"
+
+	"github.com/gopi-frame/contract"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*HTTPFileSystem]
+
+type OptionFunc func(f *HTTPFileSystem) error
+
+func (o OptionFunc) Apply(f *HTTPFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *HTTPFileSystem) error {
+	return nil
+})
+
+func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
+	if detector == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *HTTPFileSystem) error {
+		f.mimetypeDetector = detector
+		return nil
+	})
+}
+
+// WithHTTPClient sets the client sending the requests, replacing the one built from the config.
+func WithHTTPClient(client *gohttp.Client) Option {
+	if client == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *HTTPFileSystem) error {
+		f.client = client
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+package local
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// writeAtomic writes the stream to a temporary file next to the target, syncs it to disk,
+// and renames it over the target, so that readers see either the previous or the new content.
+// With os.O_APPEND, the current content is copied to the temporary file first.
+// The temporary file is removed if any step fails.
+func (f *LocalFileSystem) writeAtomic(path string, stream io.Reader, flag int, mode os.FileMode, tracker *filesystem.ProgressTracker) (err error) {
+	tempPath := filesystem.AtomicTempPath(path)
+	file, err := f.openFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = f.remove(tempPath)
+		}
+	}()
+	// the mode of a new file is masked by the umask, the file replaced must get the mode of the write
+	if err := file.Chmod(mode); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	if flag&os.O_APPEND != 0 {
+		if err := f.appendCurrent(file, path); err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+	}
+	if _, err := filesystem.CopyWithProgress(file, stream, tracker); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	if err := file.Sync(); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	if err := file.Close(); err != nil {
+		return filesystem.NewUnableToCloseFile(path, err)
+	}
+	// the file replaced keeps its metadata and tags, like a file overwritten in place
+	if err := f.copyXattrs(path, tempPath, metadataXattrPrefix, tagsXattrPrefix); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	if err := f.rename(tempPath, path); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	f.syncDir(filepath.Dir(path))
+	tracker.Done()
+	return nil
+}
+
+func (f *LocalFileSystem) appendCurrent(file *os.File, path string) error {
+	current, err := f.openFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer current.Close()
+	_, err = io.Copy(file, current)
+	return err
+}
+
+// syncDir syncs the directory so that the rename survives a crash.
+// It is best effort, since directories cannot be synced on every platform.
+func (f *LocalFileSystem) syncDir(dir string) {
+	d, err := f.openFile(dir, os.O_RDONLY, 0)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,107 @@
+package local
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct {
+	r io.Reader
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestLocalFileSystem_AtomicWrite(t *testing.T) {
+	atomic := map[string]any{filesystem.AtomicKey: true}
+
+	t.Run("write", func(t *testing.T) {
+		root := t.TempDir()
+		f, err := NewLocalFileSystem(root)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("reports/daily.csv", []byte("hello"), atomic); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		appendAtomic := map[string]any{filesystem.AtomicKey: true, filesystem.FileWriteFlagKey: os.O_APPEND | os.O_CREATE | os.O_WRONLY}
+		if err := f.Write("reports/daily.csv", []byte(" world"), appendAtomic); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := f.Read("reports/daily.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello world", string(content))
+		entries, err := os.ReadDir(filepath.Join(root, "reports"))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		root := t.TempDir()
+		f, err := NewLocalFileSystem(root)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("daily.csv", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Error(t, f.WriteStream("daily.csv", &failingReader{r: strings.NewReader("truncated")}, atomic))
+		content, err := f.Read("daily.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "previous", string(content))
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		root := t.TempDir()
+		f, err := NewLocalFileSystem(root)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		stale := filesystem.AtomicTempPath("reports/daily.csv")
+		fresh := filesystem.AtomicTempPath("reports/weekly.csv")
+		for _, path := range []string{stale, fresh, "reports/daily.csv"} {
+			if err := f.Write(path, []byte("partial"), nil); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+		old := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(filepath.Join(root, stale), old, old); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		deleted, err := filesystem.CleanAtomicTempFiles(f, ".", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{filepath.FromSlash(stale)}, deleted)
+		exists, err := f.FileExists(fresh)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+	})
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_AtomicWriteUmask(t *testing.T) {
+	root := t.TempDir()
+	f, err := NewLocalFileSystem(root)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer syscall.Umask(syscall.Umask(0077))
+	if err := f.Write("daily.csv", []byte("hello"), map[string]any{filesystem.AtomicKey: true}); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	info, err := os.Stat(filepath.Join(root, "daily.csv"))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
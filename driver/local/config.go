@@ -30,11 +30,16 @@ type Config struct {
 		Directory string
 	}
 	MimeTypeDetector fs.MimeTypeDetector
+	// SymlinkPolicy is one of "deny", "within_root" or "follow", default is "within_root".
+	SymlinkPolicy SymlinkPolicy
 }
 
 func (c *Config) Apply(f *LocalFileSystem) error {
 	f.root = c.Root
 	f.deferRootCreation = c.DeferRootCreation
+	if c.SymlinkPolicy != "" {
+		f.symlinkPolicy = c.SymlinkPolicy
+	}
 	if c.MimeTypeDetector != nil {
 		f.mimetypeDetector = c.MimeTypeDetector
 	}
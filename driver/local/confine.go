@@ -0,0 +1,115 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// SymlinkPolicy decides how symbolic links found under the root are resolved.
+type SymlinkPolicy string
+
+const (
+	// SymlinkDeny rejects every path going through a symbolic link.
+	SymlinkDeny SymlinkPolicy = "deny"
+	// SymlinkWithinRoot follows symbolic links whose target is under the root, and rejects the others.
+	SymlinkWithinRoot SymlinkPolicy = "within_root"
+	// SymlinkFollow follows every symbolic link, wherever it points to.
+	SymlinkFollow SymlinkPolicy = "follow"
+)
+
+var ErrSymlinkDenied = errors.New("symbolic links are not allowed")
+
+// rel cleans the path and makes it relative to the root.
+// A leading separator is ignored, so "/a" is "a" under the root,
+// and any path escaping the root with ".." is rejected with a [filesystem.PathOutsideRoot] error.
+func (f *LocalFileSystem) rel(path string) (string, error) {
+	rel := strings.TrimLeft(filepath.ToSlash(path), "/")
+	if rel == "" {
+		return ".", nil
+	}
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", filesystem.NewPathOutsideRoot(path)
+	}
+	return rel, nil
+}
+
+// resolve returns the absolute path to operate on.
+//
+// Unless symbolic links are followed, every existing component of the path is checked:
+// links are rejected with [ErrSymlinkDenied] under [SymlinkDeny],
+// and links whose target is outside the root with a [filesystem.PathOutsideRoot] error under [SymlinkWithinRoot].
+func (f *LocalFileSystem) resolve(path string) (string, error) {
+	rel, err := f.rel(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.resolveLinks(path, rel); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.root, rel), nil
+}
+
+// resolveLinks checks the symbolic links of the relative path according to the policy,
+// and returns the path with every link resolved, relative to the real root.
+func (f *LocalFileSystem) resolveLinks(path, rel string) (string, error) {
+	if f.symlinkPolicy == SymlinkFollow || rel == "." {
+		return rel, nil
+	}
+	root, err := filepath.EvalSymlinks(f.root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return rel, nil
+		}
+		return "", err
+	}
+	current := f.root
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// nothing exists from here, so there is no link left to resolve
+				resolved, err := filepath.EvalSymlinks(filepath.Dir(current))
+				if err != nil {
+					return "", err
+				}
+				return confined(path, root, filepath.Join(append([]string{resolved}, parts[i:]...)...))
+			}
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if f.symlinkPolicy == SymlinkDeny {
+			return "", ErrSymlinkDenied
+		}
+		target, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			// a dangling link could be used to create a file anywhere
+			return "", filesystem.NewPathOutsideRoot(path)
+		}
+		if _, err := confined(path, root, target); err != nil {
+			return "", err
+		}
+	}
+	resolved, err := filepath.EvalSymlinks(current)
+	if err != nil {
+		return "", err
+	}
+	return confined(path, root, resolved)
+}
+
+// confined returns the target relative to the root, or a [filesystem.PathOutsideRoot] error if it is not under it.
+func confined(path, root, target string) (string, error) {
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", filesystem.NewPathOutsideRoot(path)
+	}
+	return rel, nil
+}
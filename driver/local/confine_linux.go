@@ -0,0 +1,391 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"golang.org/x/sys/unix"
+)
+
+// Unless symbolic links are followed, every operation goes through descriptors opened with openat2 and RESOLVE_BENEATH,
+// and the *at system calls relative to them,
+// so that the kernel refuses to leave the root even if a link is swapped in after the path was checked.
+// Where openat2 is not available, the path is opened one component at a time with O_NOFOLLOW,
+// after the checks of [LocalFileSystem.resolve].
+
+// openat2Unsupported is set once openat2 is found unavailable: missing before Linux 5.6 (ENOSYS),
+// or refused by the seccomp filters of container runtimes which do not know it (EPERM).
+var openat2Unsupported atomic.Bool
+
+// openFile opens a file under the root.
+func (f *LocalFileSystem) openFile(path string, flag int, perm os.FileMode) (*os.File, error) {
+	rel, err := f.rel(path)
+	if err != nil {
+		return nil, err
+	}
+	if f.symlinkPolicy == SymlinkFollow {
+		return os.OpenFile(filepath.Join(f.root, rel), flag, perm)
+	}
+	resolved, err := f.resolveLinks(path, rel)
+	if err != nil {
+		return nil, err
+	}
+	root, err := os.Open(f.root)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return openAt(path, root, resolved, flag, perm)
+}
+
+// openAt opens the path relative to the directory, without leaving it nor going through any link.
+func openAt(path string, dir *os.File, rel string, flag int, perm os.FileMode) (*os.File, error) {
+	name := filepath.Join(dir.Name(), rel)
+	var fd int
+	var err error
+	if !openat2Unsupported.Load() {
+		fd, err = unix.Openat2(int(dir.Fd()), rel, &unix.OpenHow{
+			Flags:   uint64(flag) | unix.O_CLOEXEC,
+			Mode:    uint64(perm.Perm()),
+			Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_SYMLINKS | unix.RESOLVE_NO_MAGICLINKS,
+		})
+		if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EPERM) && openat2Blocked(int(dir.Fd())) {
+			openat2Unsupported.Store(true)
+		}
+	}
+	if openat2Unsupported.Load() {
+		fd, err = openBeneath(int(dir.Fd()), rel, flag, perm)
+	}
+	switch {
+	case err == nil:
+		return os.NewFile(uintptr(fd), name), nil
+	case errors.Is(err, unix.EXDEV):
+		return nil, filesystem.NewPathOutsideRoot(path)
+	case errors.Is(err, unix.ELOOP):
+		// a link appeared after the path was checked
+		return nil, ErrSymlinkDenied
+	}
+	return nil, &os.PathError{Op: "openat2", Path: name, Err: err}
+}
+
+// openat2Blocked reports whether openat2 itself is refused with EPERM,
+// rather than the opening of the file, by opening the directory again.
+func openat2Blocked(dirfd int) bool {
+	fd, err := unix.Openat2(dirfd, ".", &unix.OpenHow{Flags: unix.O_PATH | unix.O_CLOEXEC})
+	if err == nil {
+		_ = unix.Close(fd)
+	}
+	return errors.Is(err, unix.EPERM)
+}
+
+// openBeneath opens the path relative to the directory without openat2.
+// Every directory on the way is opened with O_NOFOLLOW and O_DIRECTORY relative to the one before,
+// so that a link is refused wherever it is in the path, not only as its last component.
+func openBeneath(dirfd int, rel string, flag int, perm os.FileMode) (int, error) {
+	if rel == "." {
+		return openNoFollow(dirfd, rel, flag, perm)
+	}
+	if filepath.IsAbs(rel) {
+		return -1, unix.EXDEV
+	}
+	names := strings.Split(rel, string(filepath.Separator))
+	for _, name := range names {
+		if name == ".." {
+			return -1, unix.EXDEV
+		}
+	}
+	cur := dirfd
+	defer func() {
+		if cur != dirfd {
+			_ = unix.Close(cur)
+		}
+	}()
+	for _, name := range names[:len(names)-1] {
+		next, err := openNoFollow(cur, name, unix.O_PATH|unix.O_DIRECTORY, 0)
+		if err != nil {
+			return -1, err
+		}
+		if cur != dirfd {
+			_ = unix.Close(cur)
+		}
+		cur = next
+	}
+	return openNoFollow(cur, names[len(names)-1], flag, perm)
+}
+
+// openNoFollow opens the name in the directory with O_NOFOLLOW, and reports a link as ELOOP.
+func openNoFollow(dirfd int, name string, flag int, perm os.FileMode) (int, error) {
+	fd, err := unix.Openat(dirfd, name, flag|unix.O_CLOEXEC|unix.O_NOFOLLOW, uint32(perm.Perm()))
+	if errors.Is(err, unix.ENOTDIR) && flag&unix.O_DIRECTORY != 0 {
+		// with O_PATH, a link is opened itself and then refused as not being a directory
+		var stat unix.Stat_t
+		if unix.Fstatat(dirfd, name, &stat, unix.AT_SYMLINK_NOFOLLOW) == nil && stat.Mode&unix.S_IFMT == unix.S_IFLNK {
+			err = unix.ELOOP
+		}
+	}
+	return fd, err
+}
+
+// parent opens the parent directory of the path under the root, and returns the name of the path in it.
+// If link is true, the last component is not resolved, the operation applies to the link itself.
+func (f *LocalFileSystem) parent(path string, link bool) (*os.File, string, error) {
+	rel, err := f.rel(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if f.symlinkPolicy == SymlinkFollow {
+		dir, err := os.OpenFile(filepath.Join(f.root, filepath.Dir(rel)), unix.O_PATH|unix.O_DIRECTORY, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		return dir, filepath.Base(rel), nil
+	}
+	var resolved string
+	if link {
+		dir, err := f.resolveLinks(path, filepath.Dir(rel))
+		if err != nil {
+			return nil, "", err
+		}
+		resolved = filepath.Join(dir, filepath.Base(rel))
+	} else if resolved, err = f.resolveLinks(path, rel); err != nil {
+		return nil, "", err
+	}
+	root, err := os.Open(f.root)
+	if err != nil {
+		return nil, "", err
+	}
+	defer root.Close()
+	dir, err := openAt(path, root, filepath.Dir(resolved), unix.O_PATH|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	return dir, filepath.Base(resolved), nil
+}
+
+func (f *LocalFileSystem) stat(path string) (os.FileInfo, error) {
+	file, err := f.openFile(path, unix.O_PATH, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+func (f *LocalFileSystem) lstat(path string) (os.FileInfo, error) {
+	dir, name, err := f.parent(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	// with O_PATH and O_NOFOLLOW, a link is opened itself instead of being refused
+	file, err := openAt(path, dir, name, unix.O_PATH|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+func (f *LocalFileSystem) readDir(path string) ([]os.DirEntry, error) {
+	file, err := f.openFile(path, os.O_RDONLY|unix.O_DIRECTORY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries, err := file.ReadDir(-1)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, err
+}
+
+// remove removes the file or the empty directory, a link is removed itself.
+func (f *LocalFileSystem) remove(path string) error {
+	dir, name, err := f.parent(path, true)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	err = unix.Unlinkat(int(dir.Fd()), name, 0)
+	if errors.Is(err, unix.EISDIR) {
+		err = unix.Unlinkat(int(dir.Fd()), name, unix.AT_REMOVEDIR)
+	}
+	if err != nil {
+		return &os.PathError{Op: "unlinkat", Path: filepath.Join(dir.Name(), name), Err: err}
+	}
+	return nil
+}
+
+// removeAll removes the path and everything under it, the links are removed themselves and never followed.
+func (f *LocalFileSystem) removeAll(path string) error {
+	dir, name, err := f.parent(path, true)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return removeAllAt(path, dir, name)
+}
+
+func removeAllAt(path string, dir *os.File, name string) error {
+	err := unix.Unlinkat(int(dir.Fd()), name, 0)
+	if err == nil || errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if !errors.Is(err, unix.EISDIR) {
+		return &os.PathError{Op: "unlinkat", Path: filepath.Join(dir.Name(), name), Err: err}
+	}
+	child, err := openAt(path, dir, name, os.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return err
+	}
+	entries, err := child.ReadDir(-1)
+	if err == nil {
+		for _, entry := range entries {
+			if err = removeAllAt(path, child, entry.Name()); err != nil {
+				break
+			}
+		}
+	}
+	_ = child.Close()
+	if err != nil {
+		return err
+	}
+	if err := unix.Unlinkat(int(dir.Fd()), name, unix.AT_REMOVEDIR); err != nil && !errors.Is(err, unix.ENOENT) {
+		return &os.PathError{Op: "unlinkat", Path: filepath.Join(dir.Name(), name), Err: err}
+	}
+	return nil
+}
+
+func (f *LocalFileSystem) rename(src, dst string) error {
+	srcDir, srcName, err := f.parent(src, true)
+	if err != nil {
+		return err
+	}
+	defer srcDir.Close()
+	dstDir, dstName, err := f.parent(dst, true)
+	if err != nil {
+		return err
+	}
+	defer dstDir.Close()
+	if err := unix.Renameat(int(srcDir.Fd()), srcName, int(dstDir.Fd()), dstName); err != nil {
+		return &os.LinkError{Op: "renameat", Old: filepath.Join(srcDir.Name(), srcName), New: filepath.Join(dstDir.Name(), dstName), Err: err}
+	}
+	return nil
+}
+
+// mkdirAll creates the directory and its missing parents one at a time, each relative to the one before.
+func (f *LocalFileSystem) mkdirAll(path string, perm os.FileMode) error {
+	rel, err := f.rel(path)
+	if err != nil {
+		return err
+	}
+	if f.symlinkPolicy == SymlinkFollow {
+		return os.MkdirAll(filepath.Join(f.root, rel), perm)
+	}
+	resolved, err := f.resolveLinks(path, rel)
+	if err != nil {
+		return err
+	}
+	dir, err := os.Open(f.root)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = dir.Close()
+	}()
+	if resolved == "." {
+		return nil
+	}
+	for _, name := range strings.Split(resolved, string(filepath.Separator)) {
+		if err := unix.Mkdirat(int(dir.Fd()), name, uint32(perm.Perm())); err != nil && !errors.Is(err, unix.EEXIST) {
+			return &os.PathError{Op: "mkdirat", Path: filepath.Join(dir.Name(), name), Err: err}
+		}
+		next, err := openAt(path, dir, name, unix.O_PATH|unix.O_DIRECTORY, 0)
+		if err != nil {
+			return err
+		}
+		_ = dir.Close()
+		dir = next
+	}
+	return nil
+}
+
+func (f *LocalFileSystem) chmod(path string, mode os.FileMode) error {
+	if f.symlinkPolicy == SymlinkFollow {
+		rel, err := f.rel(path)
+		if err != nil {
+			return err
+		}
+		return os.Chmod(filepath.Join(f.root, rel), mode)
+	}
+	file, err := f.openFile(path, unix.O_PATH, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// fchmod refuses O_PATH descriptors, their magic link in /proc designates the very file opened
+	err = unix.Fchmodat(unix.AT_FDCWD, "/proc/self/fd/"+strconv.Itoa(int(file.Fd())), uint32(mode.Perm()), 0)
+	if err != nil {
+		return &os.PathError{Op: "chmod", Path: file.Name(), Err: err}
+	}
+	return nil
+}
+
+// chtimes sets the access and modification times of the path, a zero time is left unchanged.
+func (f *LocalFileSystem) chtimes(path string, atime, mtime time.Time) error {
+	dir, name, err := f.parent(path, false)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	times := []unix.Timespec{timespec(atime), timespec(mtime)}
+	// without following, a link swapped in after the checks gets the times instead of its target
+	if err := unix.UtimesNanoAt(int(dir.Fd()), name, times, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return &os.PathError{Op: "utimensat", Path: filepath.Join(dir.Name(), name), Err: err}
+	}
+	return nil
+}
+
+func timespec(t time.Time) unix.Timespec {
+	if t.IsZero() {
+		return unix.Timespec{Nsec: unix.UTIME_OMIT}
+	}
+	return unix.NsecToTimespec(t.UnixNano())
+}
+
+func (f *LocalFileSystem) symlink(target, link string) error {
+	dir, name, err := f.parent(link, true)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if err := unix.Symlinkat(target, int(dir.Fd()), name); err != nil {
+		return &os.LinkError{Op: "symlinkat", Old: target, New: filepath.Join(dir.Name(), name), Err: err}
+	}
+	return nil
+}
+
+func (f *LocalFileSystem) readlink(path string) (string, error) {
+	dir, name, err := f.parent(path, true)
+	if err != nil {
+		return "", err
+	}
+	defer dir.Close()
+	for size := 128; ; size *= 2 {
+		buf := make([]byte, size)
+		n, err := unix.Readlinkat(int(dir.Fd()), name, buf)
+		if err != nil {
+			return "", &os.PathError{Op: "readlinkat", Path: filepath.Join(dir.Name(), name), Err: err}
+		}
+		if n < size {
+			return string(buf[:n]), nil
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestOpenAt_WithoutOpenat2(t *testing.T) {
+	defer openat2Unsupported.Store(openat2Unsupported.Load())
+	openat2Unsupported.Store(true)
+	f, root, _ := newConfinedFS(t, SymlinkDeny)
+	dir, err := os.Open(root)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer dir.Close()
+
+	file, err := openAt("dir/file.txt", dir, filepath.Join("dir", "file.txt"), os.O_RDONLY, 0)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	_ = file.Close()
+
+	// a link is refused wherever it is in the path, as if it was swapped in after the checks
+	_, err = openAt("inside/file.txt", dir, filepath.Join("inside", "file.txt"), os.O_RDONLY, 0)
+	assert.ErrorIs(t, err, ErrSymlinkDenied)
+	_, err = openAt("inside", dir, "inside", unix.O_PATH|unix.O_DIRECTORY, 0)
+	assert.ErrorIs(t, err, ErrSymlinkDenied)
+	_, err = openAt("relative", dir, "relative", os.O_RDONLY, 0)
+	assert.ErrorIs(t, err, ErrSymlinkDenied)
+	_, err = openAt("../x", dir, filepath.Join("..", "x"), os.O_RDONLY, 0)
+	assertOutsideRoot(t, err)
+
+	content, err := f.Read("dir/file.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "inside", string(content))
+}
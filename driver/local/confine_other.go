@@ -0,0 +1,114 @@
+//go:build !linux
+
+package local
+
+import (
+	"os"
+	"time"
+)
+
+// The operations run on the paths, after the checks of [LocalFileSystem.resolve].
+
+// openFile opens a file under the root.
+func (f *LocalFileSystem) openFile(path string, flag int, perm os.FileMode) (*os.File, error) {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(fp, flag, perm)
+}
+
+func (f *LocalFileSystem) stat(path string) (os.FileInfo, error) {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(fp)
+}
+
+func (f *LocalFileSystem) lstat(path string) (os.FileInfo, error) {
+	fp, err := f.resolveLink(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Lstat(fp)
+}
+
+func (f *LocalFileSystem) readDir(path string) ([]os.DirEntry, error) {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(fp)
+}
+
+// remove removes the file or the empty directory, a link is removed itself.
+func (f *LocalFileSystem) remove(path string) error {
+	fp, err := f.resolveLink(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(fp)
+}
+
+// removeAll removes the path and everything under it, the links are removed themselves and never followed.
+func (f *LocalFileSystem) removeAll(path string) error {
+	fp, err := f.resolveLink(path)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(fp)
+}
+
+func (f *LocalFileSystem) rename(src, dst string) error {
+	srcPath, err := f.resolveLink(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := f.resolveLink(dst)
+	if err != nil {
+		return err
+	}
+	return os.Rename(srcPath, dstPath)
+}
+
+func (f *LocalFileSystem) mkdirAll(path string, perm os.FileMode) error {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(fp, perm)
+}
+
+func (f *LocalFileSystem) chmod(path string, mode os.FileMode) error {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(fp, mode)
+}
+
+// chtimes sets the access and modification times of the path, a zero time is left unchanged.
+func (f *LocalFileSystem) chtimes(path string, atime, mtime time.Time) error {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(fp, atime, mtime)
+}
+
+func (f *LocalFileSystem) symlink(target, link string) error {
+	fp, err := f.resolveLink(link)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(target, fp)
+}
+
+func (f *LocalFileSystem) readlink(path string) (string, error) {
+	fp, err := f.resolveLink(path)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(fp)
+}
@@ -0,0 +1,198 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newConfinedFS(t *testing.T, policy SymlinkPolicy) (*LocalFileSystem, string, string) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0755); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := os.WriteFile(filepath.Join(root, "dir", "file.txt"), []byte("inside"), 0644); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	links := map[string]string{
+		"inside":   filepath.Join(root, "dir"),
+		"relative": "dir/file.txt",
+		"outside":  outside,
+		"escape":   "../" + filepath.Base(outside) + "/secret.txt",
+		"dangling": filepath.Join(outside, "missing.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symbolic links are not supported: %v", err)
+		}
+	}
+	f, err := NewLocalFileSystem(root, WithSymlinkPolicy(policy))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return f, root, outside
+}
+
+func assertOutsideRoot(t *testing.T, err error) {
+	t.Helper()
+	var outsideRoot *filesystem.PathOutsideRoot
+	assert.True(t, errors.As(err, &outsideRoot), "expected a PathOutsideRoot error, got %v", err)
+}
+
+func TestLocalFileSystem_PathOutsideRoot(t *testing.T) {
+	f, root, outside := newConfinedFS(t, SymlinkWithinRoot)
+	escape := "../" + filepath.Base(outside) + "/secret.txt"
+
+	_, err := f.Read(escape)
+	assertOutsideRoot(t, err)
+	_, err = f.ReadStream("dir/../../" + filepath.Base(outside) + "/secret.txt")
+	assertOutsideRoot(t, err)
+	_, err = f.FileExists(escape)
+	assertOutsideRoot(t, err)
+	assertOutsideRoot(t, f.Write("../escaped.txt", []byte("escaped"), nil))
+	assertOutsideRoot(t, f.Move("dir/file.txt", "../moved.txt", nil))
+	assertOutsideRoot(t, f.Delete(escape))
+	assertOutsideRoot(t, f.CreateDir("../dir", nil))
+	_, err = os.Stat(filepath.Join(filepath.Dir(root), "escaped.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// ".." which stays under the root, and absolute paths, are relative to the root
+	content, err := f.Read("dir/../dir/file.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "inside", string(content))
+	content, err = f.Read("/dir/file.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "inside", string(content))
+}
+
+func TestLocalFileSystem_SymlinkPolicy(t *testing.T) {
+	t.Run("within root", func(t *testing.T) {
+		f, _, outside := newConfinedFS(t, SymlinkWithinRoot)
+		content, err := f.Read("inside/file.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "inside", string(content))
+		content, err = f.Read("relative")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "inside", string(content))
+		if err := f.Write("inside/new.txt", []byte("new"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+
+		_, err = f.Read("outside/secret.txt")
+		assertOutsideRoot(t, err)
+		_, err = f.Read("escape")
+		assertOutsideRoot(t, err)
+		assertOutsideRoot(t, f.Write("outside/new.txt", []byte("new"), nil))
+		assertOutsideRoot(t, f.Write("dangling", []byte("new"), nil))
+		_, err = os.Stat(filepath.Join(outside, "new.txt"))
+		assert.True(t, os.IsNotExist(err))
+		_, err = os.Stat(filepath.Join(outside, "missing.txt"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		f, _, _ := newConfinedFS(t, SymlinkDeny)
+		content, err := f.Read("dir/file.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "inside", string(content))
+		_, err = f.Read("inside/file.txt")
+		assert.ErrorIs(t, err, ErrSymlinkDenied)
+		_, err = f.Read("relative")
+		assert.ErrorIs(t, err, ErrSymlinkDenied)
+		assert.ErrorIs(t, f.Write("outside/new.txt", []byte("new"), nil), ErrSymlinkDenied)
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		f, _, _ := newConfinedFS(t, SymlinkFollow)
+		content, err := f.Read("outside/secret.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "secret", string(content))
+		// ".." escapes are rejected whatever the policy
+		_, err = f.Read("../" + "secret.txt")
+		assertOutsideRoot(t, err)
+	})
+
+	t.Run("operations", func(t *testing.T) {
+		f, root, outside := newConfinedFS(t, SymlinkWithinRoot)
+		assertOutsideRoot(t, f.Delete("outside/secret.txt"))
+		assertOutsideRoot(t, f.Move("outside/secret.txt", "moved.txt", nil))
+		assertOutsideRoot(t, f.CreateDir("outside/dir", nil))
+		assertOutsideRoot(t, f.SetVisibility("outside/secret.txt", "private"))
+		assertOutsideRoot(t, f.Touch("escape"))
+		info, err := os.Stat(filepath.Join(outside, "secret.txt"))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+		_, err = os.Stat(filepath.Join(outside, "dir"))
+		assert.True(t, os.IsNotExist(err))
+
+		// the links are removed and moved themselves
+		if err := f.Move("relative", "moved", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.remove("moved"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = os.Lstat(filepath.Join(root, "moved"))
+		assert.True(t, os.IsNotExist(err))
+		if err := f.DeleteDir("dir"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = os.Stat(filepath.Join(root, "dir"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("walk", func(t *testing.T) {
+		f, root, _ := newConfinedFS(t, SymlinkWithinRoot)
+		var paths []string
+		err := f.WalkDir(".", func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		// the paths are joined with the root
+		assert.Contains(t, paths, root)
+		assert.Contains(t, paths, filepath.Join(root, "dir", "file.txt"))
+		assert.NotContains(t, paths, filepath.Join(root, "outside", "secret.txt"))
+
+		var relative []string
+		err = filesystem.WalkDirRelative(f, "dir", func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			relative = append(relative, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{".", "file.txt"}, relative)
+	})
+}
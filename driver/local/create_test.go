@@ -0,0 +1,123 @@
+package local
+
+import (
+	"archive/tar"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_Create(t *testing.T) {
+	f, err := NewLocalFileSystem(t.TempDir())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Implements(t, (*filesystem.Creator)(nil), f)
+
+	t.Run("close", func(t *testing.T) {
+		w, err := f.Create("dir/archive.tar", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		tw := tar.NewWriter(w)
+		if err := tw.WriteHeader(&tar.Header{Name: "hello.txt", Mode: 0644, Size: 5}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := tw.Write([]byte("hello")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, tw.Close())
+		assert.NoError(t, w.Close())
+		size, err := f.FileSize("dir/archive.tar")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(2048), size)
+	})
+
+	t.Run("abort atomic", func(t *testing.T) {
+		if err := f.Write("atomic.txt", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := f.Create("atomic.txt", map[string]any{filesystem.AtomicKey: true})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write([]byte("partial")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		content, _ := f.Read("atomic.txt")
+		assert.Equal(t, "previous", string(content))
+		entries, _ := f.ReadDir(".")
+		for _, entry := range entries {
+			assert.False(t, filesystem.IsAtomicTempFile(entry.Name()))
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		w, err := f.Create("partial.txt", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write([]byte("partial")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		exists, _ := f.FileExists("partial.txt")
+		assert.False(t, exists)
+	})
+
+	t.Run("abort overwrite", func(t *testing.T) {
+		if err := f.Write("existing.txt", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := f.Create("existing.txt", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write([]byte("partial")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		// the write is atomic by default, the file is left as it was
+		content, _ := f.Read("existing.txt")
+		assert.Equal(t, "previous", string(content))
+	})
+
+	t.Run("abort overwrite not atomic", func(t *testing.T) {
+		if err := f.Write("truncated.txt", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := f.Create("truncated.txt", map[string]any{filesystem.AtomicKey: false})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write([]byte("partial")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		// the writer did not create the file, it is not deleted
+		exists, _ := f.FileExists("truncated.txt")
+		assert.True(t, exists)
+	})
+
+	t.Run("precondition failed", func(t *testing.T) {
+		if err := f.Write("kept.txt", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := f.Create("kept.txt", map[string]any{filesystem.IfAbsentKey: true})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, _ = w.Write([]byte("new"))
+		assert.NoError(t, w.Abort())
+		content, _ := f.Read("kept.txt")
+		assert.Equal(t, "previous", string(content))
+	})
+
+	_, err = f.Create("../outside.txt", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// file is an [os.File] named after the path it was opened with, rather than its location on disk.
+type file struct {
+	*os.File
+	name string
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+// OpenFile opens the file for random access, with the flag of [os.OpenFile].
+// With os.O_CREATE, the missing parent directories are created with the default visibility,
+// and the file is created with the given visibility, or the default one if it is empty.
+func (f *LocalFileSystem) OpenFile(path string, flag int, visibility string) (filesystem.File, error) {
+	mode := f.visibilityConvertor.DefaultForFile()
+	if visibility != "" {
+		mode = f.visibilityConvertor.ForFile(visibility)
+	}
+	if flag&os.O_CREATE != 0 {
+		if err := f.mkdirAll(filepath.Dir(path), f.visibilityConvertor.DefaultForDir()); err != nil {
+			return nil, filesystem.NewUnableToCreateDirectory(filepath.Dir(path), err)
+		}
+	}
+	handle, err := f.openFile(path, flag, mode)
+	if err != nil {
+		return nil, filesystem.NewUnableToOpenFile(path, err)
+	}
+	return &file{File: handle, name: path}, nil
+}
@@ -0,0 +1,58 @@
+package local
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_OpenFile(t *testing.T) {
+	f, err := NewLocalFileSystem(t.TempDir())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, filesystem.SupportsRandomAccess(f))
+
+	file, err := f.OpenFile("dir/file.bin", os.O_RDWR|os.O_CREATE, "private")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "dir/file.bin", file.Name())
+	if _, err := file.Write([]byte("hello world")); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := file.WriteAt([]byte("W"), 6); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := file.Truncate(9); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "hello Wor", string(content))
+	buf := make([]byte, 3)
+	if _, err := file.ReadAt(buf, 1); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "ell", string(buf))
+	assert.NoError(t, file.Sync())
+	assert.NoError(t, file.Close())
+
+	visibility, err := f.Visibility("dir/file.bin")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "private", visibility)
+
+	_, err = f.OpenFile("../outside.bin", os.O_RDWR|os.O_CREATE, "")
+	assert.Error(t, err)
+}
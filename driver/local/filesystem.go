@@ -2,6 +2,7 @@ package local
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	gofs "io/fs"
 	"os"
@@ -26,6 +27,7 @@ type LocalFileSystem struct {
 
 	createRootOnce      sync.Once
 	deferRootCreation   bool
+	symlinkPolicy       SymlinkPolicy
 	mimetypeDetector    fs.MimeTypeDetector
 	visibilityConvertor unix.VisibilityConvertor
 }
@@ -36,6 +38,7 @@ func NewLocalFileSystem(root string, opts ...contract.Option[*LocalFileSystem])
 	}
 	f := &LocalFileSystem{
 		root:                root,
+		symlinkPolicy:       SymlinkWithinRoot,
 		mimetypeDetector:    filesystem.NewMimeTypeDetector(),
 		visibilityConvertor: unix.New(),
 	}
@@ -44,6 +47,11 @@ func NewLocalFileSystem(root string, opts ...contract.Option[*LocalFileSystem])
 			return nil, err
 		}
 	}
+	switch f.symlinkPolicy {
+	case SymlinkDeny, SymlinkWithinRoot, SymlinkFollow:
+	default:
+		return nil, fmt.Errorf("unknown symlink policy: %s", f.symlinkPolicy)
+	}
 	if !f.deferRootCreation {
 		if err := f.createRoot(); err != nil {
 			return nil, filesystem.NewUnableToCreateDirectory(f.root, err)
@@ -63,16 +71,14 @@ func (f *LocalFileSystem) createRoot() (err error) {
 }
 
 func (f *LocalFileSystem) setPermission(path string, mode os.FileMode) error {
-	file := filepath.Join(f.root, path)
-	if err := os.Chmod(file, mode); err != nil {
-		return filesystem.NewUnableToSetPermission(file, err)
+	if err := f.chmod(path, mode); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
 	}
 	return nil
 }
 
 func (f *LocalFileSystem) Exists(path string) (bool, error) {
-	file := filepath.Join(f.root, path)
-	if _, err := os.Stat(file); err == nil {
+	if _, err := f.stat(path); err == nil {
 		return true, nil
 	} else if os.IsNotExist(err) {
 		return false, nil
@@ -82,8 +88,7 @@ func (f *LocalFileSystem) Exists(path string) (bool, error) {
 }
 
 func (f *LocalFileSystem) FileExists(path string) (bool, error) {
-	file := filepath.Join(f.root, path)
-	stat, err := os.Stat(file)
+	stat, err := f.stat(path)
 	if err == nil {
 		return !stat.IsDir(), nil
 	}
@@ -94,8 +99,7 @@ func (f *LocalFileSystem) FileExists(path string) (bool, error) {
 }
 
 func (f *LocalFileSystem) DirExists(path string) (bool, error) {
-	file := filepath.Join(f.root, path)
-	stat, err := os.Stat(file)
+	stat, err := f.stat(path)
 	if err == nil {
 		return stat.IsDir(), nil
 	}
@@ -106,8 +110,12 @@ func (f *LocalFileSystem) DirExists(path string) (bool, error) {
 }
 
 func (f *LocalFileSystem) Read(path string) ([]byte, error) {
-	file := filepath.Join(f.root, path)
-	content, err := os.ReadFile(file)
+	file, err := f.openFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
 	if err != nil {
 		return nil, filesystem.NewUnableToReadFile(path, err)
 	}
@@ -115,32 +123,76 @@ func (f *LocalFileSystem) Read(path string) ([]byte, error) {
 }
 
 func (f *LocalFileSystem) ReadStream(path string) (io.ReadCloser, error) {
-	fp := filepath.Join(f.root, path)
-	file, err := os.Open(fp)
+	file, err := f.openFile(path, os.O_RDONLY, 0)
 	if err != nil {
 		return nil, filesystem.NewUnableToReadFile(path, err)
 	}
 	return file, nil
 }
 
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the stream is read.
+func (f *LocalFileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	file, err := f.openFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	progress := cfg.Progress()
+	if progress == nil {
+		return file, nil
+	}
+	var size int64 = -1
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	return filesystem.NewProgressTracker(path, size, progress).ReadCloser(file), nil
+}
+
+// ReadRange returns length bytes of the file starting at offset, the file is opened and seeked to the offset.
+func (f *LocalFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	file, err := f.openFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return filesystem.LimitReadCloser(file, length), nil
+}
+
 func (f *LocalFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
-	dir := filepath.Join(f.root, path)
-	if exists, _ := f.DirExists(dir); !exists {
+	if _, err := f.resolve(path); err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if exists, _ := f.DirExists(path); !exists {
 		return nil, nil
 	}
-	return os.ReadDir(dir)
+	return f.readDir(path)
 }
 
+// WalkDir walks the directory, the paths passed to walkFn are joined with the root,
+// see [filesystem.WalkDirRelative] for paths relative to the directory.
+// Symbolic links are reported as such and never followed.
 func (f *LocalFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
-	dir := filepath.Join(f.root, path)
-	if exists, _ := f.DirExists(dir); !exists {
+	dir, err := f.resolve(path)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if exists, _ := f.DirExists(path); !exists {
 		return filesystem.NewUnableToReadDirectory(path, os.ErrNotExist)
 	}
 	return filepath.WalkDir(dir, walkFn)
 }
 
 func (f *LocalFileSystem) LastModified(path string) (time.Time, error) {
-	if stat, err := os.Stat(filepath.Join(f.root, path)); err == nil {
+	if stat, err := f.stat(path); err == nil {
 		return stat.ModTime(), nil
 	} else {
 		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
@@ -148,7 +200,7 @@ func (f *LocalFileSystem) LastModified(path string) (time.Time, error) {
 }
 
 func (f *LocalFileSystem) FileSize(path string) (int64, error) {
-	if stat, err := os.Stat(filepath.Join(f.root, path)); err == nil {
+	if stat, err := f.stat(path); err == nil {
 		return stat.Size(), nil
 	} else {
 		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
@@ -160,11 +212,15 @@ func (f *LocalFileSystem) MimeType(path string) (string, error) {
 	if detector == nil {
 		detector = filesystem.NewMimeTypeDetector()
 	}
-	return detector.DetectFromFile(filepath.Join(f.root, path)), nil
+	file, err := f.resolve(path)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return detector.DetectFromFile(file), nil
 }
 
 func (f *LocalFileSystem) Visibility(path string) (string, error) {
-	if stat, err := os.Stat(filepath.Join(f.root, path)); err == nil {
+	if stat, err := f.stat(path); err == nil {
 		mode := stat.Mode() & os.ModePerm
 		if stat.IsDir() {
 			return f.visibilityConvertor.InverseForDir(mode), nil
@@ -180,17 +236,37 @@ func (f *LocalFileSystem) Write(path string, content []byte, config map[string]a
 }
 
 func (f *LocalFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	_, err := f.writeStream(path, stream, config, false)
+	return err
+}
+
+// WriteVersioned writes like WriteStream, and returns the version of the written file.
+func (f *LocalFileSystem) WriteVersioned(path string, stream io.Reader, config map[string]any) (string, error) {
+	return f.writeStream(path, stream, config, true)
+}
+
+// writeStream writes the file, and returns its version if versioned is true.
+//
+// Conditional and versioned writes hold the lock of a hidden file next to the path, see [writeLockPath],
+// so that they check the conditions and write the file without any other of them in between.
+// The version of the file is made to change with its content, see [LocalFileSystem.bumpVersion].
+func (f *LocalFileSystem) writeStream(path string, stream io.Reader, config map[string]any, versioned bool) (string, error) {
 	if err := f.createRoot(); err != nil {
-		return err
+		return "", err
+	}
+	if _, err := f.resolve(path); err != nil {
+		return "", filesystem.NewUnableToWriteFile(path, err)
 	}
-	fp := filepath.Join(f.root, path)
 	var dirMode = f.visibilityConvertor.DefaultForDir()
 	var fileMode = f.visibilityConvertor.DefaultForFile()
 	var fileFlag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	var atomic bool
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		var err error
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
-			return err
+			return "", err
 		}
 		if cfg.DirVisibility != nil {
 			dirMode = f.visibilityConvertor.ForDir(*cfg.DirVisibility)
@@ -201,21 +277,60 @@ func (f *LocalFileSystem) WriteStream(path string, stream io.Reader, config map[
 		if cfg.FileWriteFlag != nil {
 			fileFlag = *cfg.FileWriteFlag
 		}
+		if cfg.Atomic != nil {
+			atomic = *cfg.Atomic
+		}
 	}
-	if err := os.MkdirAll(filepath.Dir(fp), dirMode); err != nil {
-		return filesystem.NewUnableToCreateDirectory(filepath.Dir(fp), err)
+	if err := f.mkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return "", filesystem.NewUnableToCreateDirectory(filepath.Dir(path), err)
 	}
-	file, err := os.OpenFile(fp, fileFlag, fileMode)
+	if versioned || cfg.HasPrecondition() {
+		lock, err := f.Lock(writeLockPath(path), filesystem.DefaultLockTTL)
+		if err != nil {
+			return "", filesystem.NewUnableToWriteFile(path, err)
+		}
+		defer lock.Unlock()
+		if err := f.checkPrecondition(path, cfg); err != nil {
+			return "", err
+		}
+	}
+	previous := f.currentVersion(path)
+	tracker := cfg.NewTracker(path, stream)
+	var err error
+	if atomic {
+		err = f.writeAtomic(path, stream, fileFlag, fileMode, tracker)
+	} else {
+		err = f.write(path, stream, fileFlag, fileMode, tracker)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := f.writeMetadata(path, cfg); err != nil {
+		return "", err
+	}
+	version, err := f.bumpVersion(path, previous)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if !versioned {
+		return "", nil
+	}
+	return version, nil
+}
+
+func (f *LocalFileSystem) write(path string, stream io.Reader, flag int, mode os.FileMode, tracker *filesystem.ProgressTracker) error {
+	file, err := f.openFile(path, flag, mode)
 	if err != nil {
 		return filesystem.NewUnableToWriteFile(path, err)
 	}
-	_, err = io.Copy(file, stream)
+	_, err = filesystem.CopyWithProgress(file, stream, tracker)
 	if err1 := file.Close(); err1 != nil && err == nil {
 		return filesystem.NewUnableToCloseFile(path, err1)
 	}
 	if err != nil {
 		return filesystem.NewUnableToWriteFile(path, err)
 	}
+	tracker.Done()
 	return nil
 }
 
@@ -229,22 +344,28 @@ func (f *LocalFileSystem) SetVisibility(path string, visibility string) error {
 }
 
 func (f *LocalFileSystem) Delete(path string) error {
+	if _, err := f.resolve(path); err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
 	exists, _ := f.FileExists(path)
 	if !exists {
 		return nil
 	}
-	if err := os.Remove(filepath.Join(f.root, path)); err != nil {
+	if err := f.remove(path); err != nil {
 		return filesystem.NewUnableToDeleteFile(path, err)
 	}
 	return nil
 }
 
 func (f *LocalFileSystem) DeleteDir(path string) error {
+	if _, err := f.resolve(path); err != nil {
+		return filesystem.NewUnableToDeleteDirectory(path, err)
+	}
 	exists, _ := f.DirExists(path)
 	if !exists {
 		return nil
 	}
-	if err := os.RemoveAll(filepath.Join(f.root, path)); err != nil {
+	if err := f.removeAll(path); err != nil {
 		return filesystem.NewUnableToDeleteDirectory(path, err)
 	}
 	return nil
@@ -265,7 +386,7 @@ func (f *LocalFileSystem) CreateDir(path string, config map[string]any) error {
 	if exists {
 		return f.setPermission(path, mode)
 	}
-	if err := os.MkdirAll(filepath.Join(f.root, path), mode); err != nil {
+	if err := f.mkdirAll(path, mode); err != nil {
 		return filesystem.NewUnableToCreateDirectory(path, err)
 	}
 	return nil
@@ -275,8 +396,12 @@ func (f *LocalFileSystem) Move(src string, dst string, config map[string]any) er
 	if err := f.createRoot(); err != nil {
 		return filesystem.NewUnableToCreateDirectory(f.root, err)
 	}
-	srcPath := filepath.Join(f.root, src)
-	dstPath := filepath.Join(f.root, dst)
+	if _, err := f.resolve(src); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	if _, err := f.resolve(dst); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
 	var mode = f.visibilityConvertor.DefaultForDir()
 	if config != nil {
 		cfg, err := filesystem.NewConfig(config)
@@ -287,10 +412,10 @@ func (f *LocalFileSystem) Move(src string, dst string, config map[string]any) er
 			mode = f.visibilityConvertor.ForDir(*cfg.DirVisibility)
 		}
 	}
-	if err := os.MkdirAll(filepath.Dir(dstPath), mode); err != nil {
-		return filesystem.NewUnableToCreateDirectory(filepath.Dir(dstPath), err)
+	if err := f.mkdirAll(filepath.Dir(dst), mode); err != nil {
+		return filesystem.NewUnableToCreateDirectory(filepath.Dir(dst), err)
 	}
-	if err := os.Rename(srcPath, dstPath); err != nil {
+	if err := f.rename(src, dst); err != nil {
 		return filesystem.NewUnableToMove(src, dst, err)
 	}
 	return nil
@@ -313,5 +438,23 @@ func (f *LocalFileSystem) Copy(src string, dst string, config map[string]any) er
 	if err != nil {
 		return err
 	}
+	// the copy has the metadata and tags of src, unless the config gives them
+	prefixes := []string{metadataXattrPrefix, tagsXattrPrefix}
+	if config != nil {
+		cfg, err := filesystem.NewConfig(config)
+		if err != nil {
+			return err
+		}
+		prefixes = prefixes[:0]
+		if cfg.Metadata == nil {
+			prefixes = append(prefixes, metadataXattrPrefix)
+		}
+		if cfg.Tags == nil {
+			prefixes = append(prefixes, tagsXattrPrefix)
+		}
+	}
+	if err := f.copyXattrs(src, dst, prefixes...); err != nil {
+		return filesystem.NewUnableToSetMetadata(dst, err)
+	}
 	return nil
 }
@@ -1,3 +1,5 @@
 module github.com/gopi-frame/filesystem/driver/local
 
 go 1.22
+
+require golang.org/x/sys v0.28.0
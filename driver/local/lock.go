@@ -0,0 +1,180 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// errLocked is returned by lockFile when the file is locked by another open file.
+var errLocked = errors.New("file is locked")
+
+// Lock acquires the lock of the path, waiting until it is released.
+func (f *LocalFileSystem) Lock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	return f.LockContext(context.Background(), path, ttl)
+}
+
+// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+func (f *LocalFileSystem) LockContext(ctx context.Context, path string, ttl time.Duration) (filesystem.Lock, error) {
+	return filesystem.WaitForLock(ctx, func() (filesystem.Lock, error) {
+		return f.TryLock(path, ttl)
+	})
+}
+
+// TryLock acquires the lock of the path, or returns a [filesystem.LockHeld] error if it is held.
+//
+// The lock is an flock (LockFileEx on windows) on the file named after the path with [filesystem.LockSuffix],
+// which holds the ownership metadata of the lock.
+// The kernel releases the lock of a crashed process, so stale locks are recovered without waiting for their lease.
+// The lock of a live process whose lease expired is taken over by removing its lock file,
+// the holder then gets [filesystem.ErrLockLost] from Refresh and Unlock.
+// On windows an open file can't be removed, so the lock of a live process is held until it is unlocked.
+func (f *LocalFileSystem) TryLock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	if err := f.createRoot(); err != nil {
+		return nil, err
+	}
+	lockPath := path + filesystem.LockSuffix
+	if _, err := f.resolve(lockPath); err != nil {
+		return nil, filesystem.NewUnableToLock(path, err)
+	}
+	if err := f.mkdirAll(filepath.Dir(lockPath), f.visibilityConvertor.DefaultForDir()); err != nil {
+		return nil, filesystem.NewUnableToCreateDirectory(filepath.Dir(lockPath), err)
+	}
+	for {
+		file, err := f.openFile(lockPath, os.O_RDWR|os.O_CREATE, f.visibilityConvertor.DefaultForFile())
+		if err != nil {
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		if err := lockFile(file); err != nil {
+			holder := readLockInfo(file)
+			if errors.Is(err, errLocked) {
+				if holder.Expired() && f.sameFile(file, lockPath) && f.remove(lockPath) == nil {
+					_ = file.Close()
+					continue
+				}
+				_ = file.Close()
+				return nil, filesystem.NewLockHeld(path, holder)
+			}
+			_ = file.Close()
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		// the previous holder may have removed the lock file between the open and the lock,
+		// in which case the lock is on a file nobody else can see
+		if !f.sameFile(file, lockPath) {
+			_ = unlockFile(file)
+			_ = file.Close()
+			continue
+		}
+		l := &lock{fs: f, file: file, path: path, lockPath: lockPath, info: filesystem.NewLockInfo(ttl)}
+		if err := l.write(); err != nil {
+			_ = l.release()
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		return l, nil
+	}
+}
+
+func (f *LocalFileSystem) sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := f.stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
+
+func readLockInfo(file *os.File) filesystem.LockInfo {
+	var modTime time.Time
+	if info, err := file.Stat(); err == nil {
+		modTime = info.ModTime()
+	}
+	content, _ := io.ReadAll(io.NewSectionReader(file, 0, 1<<20))
+	return filesystem.ParseLockInfo(content, modTime)
+}
+
+type lock struct {
+	mu       sync.Mutex
+	fs       *LocalFileSystem
+	file     *os.File
+	path     string
+	lockPath string
+	info     filesystem.LockInfo
+}
+
+func (l *lock) Path() string {
+	return l.path
+}
+
+func (l *lock) Info() filesystem.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+func (l *lock) write() error {
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.WriteAt(l.info.Bytes(), 0); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+func (l *lock) Refresh(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return filesystem.ErrLockLost
+	}
+	if !l.fs.sameFile(l.file, l.lockPath) {
+		l.lost()
+		return filesystem.ErrLockLost
+	}
+	l.info = l.info.Renew(ttl)
+	if err := l.write(); err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	return nil
+}
+
+// Unlock removes the lock file and releases the lock.
+func (l *lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return filesystem.ErrLockLost
+	}
+	if !l.fs.sameFile(l.file, l.lockPath) {
+		l.lost()
+		return filesystem.ErrLockLost
+	}
+	if err := l.release(); err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	return nil
+}
+
+func (l *lock) release() error {
+	file := l.file
+	l.file = nil
+	return releaseFile(file, func() error {
+		return l.fs.remove(l.lockPath)
+	})
+}
+
+// lost closes the file of a lock taken over, without removing the lock file of the new holder.
+func (l *lock) lost() {
+	_ = unlockFile(l.file)
+	_ = l.file.Close()
+	l.file = nil
+}
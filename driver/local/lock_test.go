@@ -0,0 +1,143 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_Lock(t *testing.T) {
+	t.Run("exclusive", func(t *testing.T) {
+		root := t.TempDir()
+		f, err := NewLocalFileSystem(root)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		lock, err := f.TryLock("reports/daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := os.ReadFile(filepath.Join(root, "reports", "daily.csv"+filesystem.LockSuffix))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, lock.Info().Token, filesystem.ParseLockInfo(content, time.Time{}).Token)
+		assert.Equal(t, os.Getpid(), lock.Info().PID)
+
+		_, err = f.TryLock("reports/daily.csv", time.Minute)
+		var held *filesystem.LockHeld
+		if assert.True(t, errors.As(err, &held)) {
+			assert.Equal(t, lock.Info().Token, held.Holder().Token)
+		}
+		if err := lock.Refresh(time.Hour); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := lock.Unlock(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.ErrorIs(t, lock.Unlock(), filesystem.ErrLockLost)
+		_, err = os.Stat(filepath.Join(root, "reports", "daily.csv"+filesystem.LockSuffix))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		f, err := NewLocalFileSystem(t.TempDir())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		lock, err := f.Lock("daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = lock.Unlock()
+		}()
+		next, err := f.Lock("daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NotEqual(t, lock.Info().Token, next.Info().Token)
+		assert.NoError(t, next.Unlock())
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		f, err := NewLocalFileSystem(t.TempDir())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		stale, err := f.TryLock("daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		// a crashed holder leaves its lock file, but the kernel releases the lock
+		_ = stale.(*lock).file.Close()
+		lock, err := f.TryLock("daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NotEqual(t, stale.Info().Token, lock.Info().Token)
+		assert.NoError(t, lock.Unlock())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("an open lock file can't be removed on windows")
+		}
+		f, err := NewLocalFileSystem(t.TempDir())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		expired, err := f.TryLock("daily.csv", 10*time.Millisecond)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		time.Sleep(20 * time.Millisecond)
+		lock, err := f.TryLock("daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.ErrorIs(t, expired.Refresh(time.Minute), filesystem.ErrLockLost)
+		assert.ErrorIs(t, expired.Unlock(), filesystem.ErrLockLost)
+		// the lock taken over is left to its new holder
+		_, err = f.TryLock("daily.csv", time.Minute)
+		var held *filesystem.LockHeld
+		assert.True(t, errors.As(err, &held))
+		assert.NoError(t, lock.Unlock())
+	})
+
+	t.Run("context", func(t *testing.T) {
+		f, err := NewLocalFileSystem(t.TempDir())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		lock, err := f.TryLock("daily.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = f.LockContext(ctx, "daily.csv", time.Minute)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		var held *filesystem.LockHeld
+		assert.True(t, errors.As(err, &held))
+		assert.NoError(t, lock.Unlock())
+	})
+
+	t.Run("outside root", func(t *testing.T) {
+		f, err := NewLocalFileSystem(t.TempDir())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = f.TryLock("../daily.csv", time.Minute)
+		assertOutsideRoot(t, err)
+	})
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package local
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return unix.Flock(int(file.Fd()), unix.LOCK_UN)
+}
+
+// releaseFile removes the lock file while it is still locked,
+// so that a process waiting on it sees it is gone and opens a new one.
+func releaseFile(file *os.File, remove func() error) error {
+	err := remove()
+	if err1 := unlockFile(file); err == nil {
+		err = err1
+	}
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
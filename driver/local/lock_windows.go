@@ -0,0 +1,38 @@
+//go:build windows
+
+package local
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is the offset of the locked byte, far beyond the metadata,
+// since the locks of LockFileEx also prevent other processes from reading the locked range.
+const lockOffset = 1 << 30
+
+func lockFile(file *os.File) error {
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, &windows.Overlapped{OffsetHigh: lockOffset})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{OffsetHigh: lockOffset})
+}
+
+// releaseFile releases the lock before removing the lock file, which cannot be removed while it is open.
+// The removal fails while another process has the file open, and the file is then left for it.
+func releaseFile(file *os.File, remove func() error) error {
+	err := unlockFile(file)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	_ = remove()
+	return err
+}
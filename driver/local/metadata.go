@@ -0,0 +1,104 @@
+package local
+
+import (
+	"errors"
+	"os"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// The metadata and tags are kept in extended attributes of the user namespace, under their own prefixes,
+// so that replacing them leaves the attributes set by other tools alone.
+const (
+	metadataXattrPrefix = "user.meta."
+	tagsXattrPrefix     = "user.tag."
+)
+
+// Metadata returns the custom metadata of the file, kept in its "user.meta.*" extended attributes.
+// It fails with [filesystem.ErrMetadataUnsupported] on other systems than Linux,
+// or if the underlying file system has no extended attributes.
+func (f *LocalFileSystem) Metadata(path string) (map[string]string, error) {
+	return f.readXattrs(path, metadataXattrPrefix)
+}
+
+// SetMetadata replaces the custom metadata of the file, kept in its "user.meta.*" extended attributes.
+func (f *LocalFileSystem) SetMetadata(path string, metadata map[string]string) error {
+	return f.writeXattrs(path, metadataXattrPrefix, metadata)
+}
+
+// Tags returns the tags of the file, kept in its "user.tag.*" extended attributes.
+func (f *LocalFileSystem) Tags(path string) (map[string]string, error) {
+	return f.readXattrs(path, tagsXattrPrefix)
+}
+
+// SetTags replaces the tags of the file, kept in its "user.tag.*" extended attributes.
+func (f *LocalFileSystem) SetTags(path string, tags map[string]string) error {
+	return f.writeXattrs(path, tagsXattrPrefix, tags)
+}
+
+func (f *LocalFileSystem) readXattrs(path, prefix string) (map[string]string, error) {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	attrs, err := getXattrs(fp, prefix)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return attrs, nil
+}
+
+func (f *LocalFileSystem) writeXattrs(path, prefix string, attrs map[string]string) error {
+	fp, err := f.resolve(path)
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	if err := setXattrs(fp, prefix, attrs); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// writeMetadata sets the metadata and tags given with the config of a write.
+func (f *LocalFileSystem) writeMetadata(path string, cfg *filesystem.Config) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Metadata != nil {
+		if err := f.SetMetadata(path, cfg.Metadata); err != nil {
+			return err
+		}
+	}
+	if cfg.Tags != nil {
+		if err := f.SetTags(path, cfg.Tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyXattrs replaces the extended attributes of dst with the prefixes by the ones of src.
+// Nothing is copied if src doesn't exist or if the file system has no extended attributes.
+func (f *LocalFileSystem) copyXattrs(src, dst string, prefixes ...string) error {
+	srcPath, err := f.resolve(src)
+	if err != nil {
+		return err
+	}
+	dstPath, err := f.resolve(dst)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		attrs, err := getXattrs(srcPath, prefix)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, filesystem.ErrMetadataUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := setXattrs(dstPath, prefix, attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gopi-frame/filesystem"
+	"golang.org/x/sys/unix"
+)
+
+// getXattrs returns the extended attributes of the file with the prefix, keyed without it.
+func getXattrs(fp, prefix string) (map[string]string, error) {
+	names, err := listXattrs(fp)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make(map[string]string)
+	for _, name := range names {
+		key, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		value, err := getXattr(fp, name)
+		if errors.Is(err, unix.ENODATA) {
+			// removed since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, xattrError(err)
+		}
+		attrs[key] = value
+	}
+	return attrs, nil
+}
+
+// setXattrs replaces the extended attributes of the file with the prefix.
+func setXattrs(fp, prefix string, attrs map[string]string) error {
+	names, err := listXattrs(fp)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if key, ok := strings.CutPrefix(name, prefix); ok {
+			if _, keep := attrs[key]; keep {
+				continue
+			}
+			if err := unix.Removexattr(fp, name); err != nil && !errors.Is(err, unix.ENODATA) {
+				return xattrError(err)
+			}
+		}
+	}
+	for key, value := range attrs {
+		if err := unix.Setxattr(fp, prefix+key, []byte(value), 0); err != nil {
+			return xattrError(err)
+		}
+	}
+	return nil
+}
+
+func listXattrs(fp string) ([]string, error) {
+	for {
+		size, err := unix.Listxattr(fp, nil)
+		if err != nil {
+			return nil, xattrError(err)
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		size, err = unix.Listxattr(fp, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// an attribute was added since the size was read
+			continue
+		}
+		if err != nil {
+			return nil, xattrError(err)
+		}
+		return strings.Split(strings.TrimSuffix(string(buf[:size]), "\x00"), "\x00"), nil
+	}
+}
+
+func getXattr(fp, name string) (string, error) {
+	for {
+		size, err := unix.Getxattr(fp, name, nil)
+		if err != nil {
+			return "", err
+		}
+		buf := make([]byte, size)
+		size, err = unix.Getxattr(fp, name, buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(buf[:size]), nil
+	}
+}
+
+// xattrError wraps [filesystem.ErrMetadataUnsupported] if the file system has no extended attributes.
+func xattrError(err error) error {
+	if errors.Is(err, unix.ENOTSUP) {
+		return fmt.Errorf("%w: %w", filesystem.ErrMetadataUnsupported, err)
+	}
+	return err
+}
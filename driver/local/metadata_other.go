@@ -0,0 +1,17 @@
+//go:build !linux
+
+package local
+
+import (
+	"github.com/gopi-frame/filesystem"
+)
+
+// getXattrs fails, the extended attributes are only handled on Linux.
+func getXattrs(_, _ string) (map[string]string, error) {
+	return nil, filesystem.ErrMetadataUnsupported
+}
+
+// setXattrs fails, the extended attributes are only handled on Linux.
+func setXattrs(_, _ string, _ map[string]string) error {
+	return filesystem.ErrMetadataUnsupported
+}
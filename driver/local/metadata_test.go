@@ -0,0 +1,125 @@
+package local
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_Metadata(t *testing.T) {
+	f, err := NewLocalFileSystem(t.TempDir())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Implements(t, (*filesystem.MetadataStore)(nil), f)
+	if err := f.Write("probe.txt", nil, nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := f.Metadata("probe.txt"); errors.Is(err, filesystem.ErrMetadataUnsupported) {
+		t.Skip("extended attributes are not supported here")
+	}
+
+	t.Run("write", func(t *testing.T) {
+		err := f.Write("a.txt", []byte("hello"), map[string]any{
+			filesystem.MetadataKey: map[string]string{"uploader": "42"},
+			filesystem.TagsKey:     map[string]string{"retention": "short"},
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := f.Metadata("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"uploader": "42"}, metadata)
+		tags, err := f.Tags("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "short"}, tags)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		if err := f.SetMetadata("a.txt", map[string]string{"source": "erp"}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := f.Metadata("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"source": "erp"}, metadata)
+		// the tags are kept apart
+		tags, err := f.Tags("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "short"}, tags)
+		if err := f.SetTags("a.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		tags, err = f.Tags("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, tags)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		if err := f.SetMetadata("a.txt", map[string]string{"source": "erp"}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for _, atomic := range []bool{false, true} {
+			if err := f.Write("a.txt", []byte("again"), map[string]any{filesystem.AtomicKey: atomic}); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			metadata, err := f.Metadata("a.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, map[string]string{"source": "erp"}, metadata, "atomic: %v", atomic)
+		}
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		if err := f.SetTags("a.txt", map[string]string{"retention": "long"}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Copy("a.txt", "b.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := f.Metadata("b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"source": "erp"}, metadata)
+		tags, err := f.Tags("b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "long"}, tags)
+
+		// the config replaces the metadata of the copy
+		err = f.Copy("a.txt", "c.txt", map[string]any{filesystem.MetadataKey: map[string]string{"source": "crm"}})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err = f.Metadata("c.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"source": "crm"}, metadata)
+		tags, err = f.Tags("c.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "long"}, tags)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := f.Metadata("missing.txt")
+		assert.Error(t, err)
+	})
+}
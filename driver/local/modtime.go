@@ -0,0 +1,39 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time of the file to now, creating an empty file if it is missing.
+// The missing parent directories are created.
+func (f *LocalFileSystem) Touch(path string) error {
+	if _, err := f.stat(path); os.IsNotExist(err) {
+		if err := f.mkdirAll(filepath.Dir(path), f.visibilityConvertor.DefaultForDir()); err != nil {
+			return filesystem.NewUnableToCreateDirectory(filepath.Dir(path), err)
+		}
+		file, err := f.openFile(path, os.O_WRONLY|os.O_CREATE, f.visibilityConvertor.DefaultForFile())
+		if err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+		if err := file.Close(); err != nil {
+			return filesystem.NewUnableToCloseFile(path, err)
+		}
+	}
+	now := time.Now()
+	if err := f.chtimes(path, now, now); err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
+
+// SetLastModified sets the modification time of the file, the access time is left as it is.
+func (f *LocalFileSystem) SetLastModified(path string, t time.Time) error {
+	if err := f.chtimes(path, time.Time{}, t); err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
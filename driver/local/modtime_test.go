@@ -0,0 +1,61 @@
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_SetLastModified(t *testing.T) {
+	f, err := NewLocalFileSystem(t.TempDir())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Implements(t, (*filesystem.Toucher)(nil), f)
+
+	t.Run("set", func(t *testing.T) {
+		if err := f.Write("a.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := f.SetLastModified("a.txt", mtime); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		modified, err := f.LastModified("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, mtime.Equal(modified))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		err := f.SetLastModified("missing.txt", time.Now())
+		assert.Error(t, err)
+	})
+
+	t.Run("touch", func(t *testing.T) {
+		if err := f.Touch("dir/b.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := f.Read("dir/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, content)
+		if err := f.SetLastModified("dir/b.txt", time.Unix(0, 0)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		before := time.Now().Add(-time.Second)
+		if err := f.Touch("dir/b.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		modified, err := f.LastModified("dir/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, modified.After(before))
+	})
+}
@@ -41,3 +41,13 @@ func WithVisibilityConvertor(convertor unix.VisibilityConvertor) OptionFunc {
 		return nil
 	}
 }
+
+func WithSymlinkPolicy(policy SymlinkPolicy) OptionFunc {
+	if policy == "" {
+		return noneOption
+	}
+	return func(lfs *LocalFileSystem) error {
+		lfs.symlinkPolicy = policy
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package local
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_Progress(t *testing.T) {
+	root := t.TempDir()
+	f, err := NewLocalFileSystem(root)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content := bytes.Repeat([]byte("x"), 200*1024)
+
+	for _, atomic := range []bool{false, true} {
+		var reports []filesystem.Progress
+		err := f.WriteStream("file.bin", bytes.NewReader(content), map[string]any{
+			filesystem.AtomicKey:   atomic,
+			filesystem.ProgressKey: func(p filesystem.Progress) { reports = append(reports, p) },
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if assert.NotEmpty(t, reports) {
+			last := reports[len(reports)-1]
+			assert.True(t, last.Done)
+			assert.Equal(t, int64(len(content)), last.Bytes)
+			assert.Equal(t, int64(len(content)), last.Total)
+			for _, report := range reports[:len(reports)-1] {
+				assert.False(t, report.Done)
+				assert.LessOrEqual(t, report.Bytes, last.Bytes)
+			}
+		}
+	}
+
+	var copied filesystem.Progress
+	err = f.Copy("file.bin", "copy.bin", map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { copied = p },
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, copied.Done)
+	assert.Equal(t, "copy.bin", copied.Path)
+	assert.Equal(t, int64(len(content)), copied.Total)
+
+	var read filesystem.Progress
+	stream, err := f.ReadStreamWithConfig("copy.bin", map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { read = p },
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if _, err := io.Copy(io.Discard, stream); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NoError(t, stream.Close())
+	assert.True(t, read.Done)
+	assert.Equal(t, int64(len(content)), read.Bytes)
+	assert.Equal(t, int64(len(content)), read.Total)
+}
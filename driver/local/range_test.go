@@ -0,0 +1,44 @@
+package local
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_ReadRange(t *testing.T) {
+	f, err := NewLocalFileSystem(t.TempDir())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Write("file.txt", []byte("0123456789"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	cases := []struct {
+		name           string
+		offset, length int64
+		expected       string
+	}{
+		{"middle", 2, 3, "234"},
+		{"to the end", 7, -1, "789"},
+		{"past the end", 8, 10, "89"},
+		{"after the end", 12, 1, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stream, err := f.ReadRange("file.txt", c.offset, c.length)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			defer stream.Close()
+			content, err := io.ReadAll(stream)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, c.expected, string(content))
+		})
+	}
+	_, err = f.ReadRange("../file.txt", 0, 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package local
+
+import (
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Symlink creates link as a symbolic link to target, the missing parent directories are created.
+// The target is stored as is, an absolute one is a path of the host, not under the root,
+// and whether the link can be followed is up to the symlink policy.
+// Links cannot be created under [SymlinkDeny].
+func (f *LocalFileSystem) Symlink(target, link string) error {
+	if f.symlinkPolicy == SymlinkDeny {
+		return filesystem.NewUnableToCreateSymlink(link, ErrSymlinkDenied)
+	}
+	if _, err := f.resolveLink(link); err != nil {
+		return filesystem.NewUnableToCreateSymlink(link, err)
+	}
+	if err := f.mkdirAll(filepath.Dir(link), f.visibilityConvertor.DefaultForDir()); err != nil {
+		return filesystem.NewUnableToCreateDirectory(filepath.Dir(link), err)
+	}
+	if err := f.symlink(target, link); err != nil {
+		return filesystem.NewUnableToCreateSymlink(link, err)
+	}
+	return nil
+}
+
+// Readlink returns the target of the link, as it was stored.
+func (f *LocalFileSystem) Readlink(path string) (string, error) {
+	target, err := f.readlink(path)
+	if err != nil {
+		return "", filesystem.NewUnableToReadSymlink(path, err)
+	}
+	return target, nil
+}
+
+// Lstat returns the info of the file, describing the link itself if it is one.
+func (f *LocalFileSystem) Lstat(path string) (os.FileInfo, error) {
+	info, err := f.lstat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info, nil
+}
+
+// WalkDirWithConfig walks the directory like WalkDir, handling the links according to [filesystem.SymlinksKey],
+// the paths passed to walkFn are joined with the root as well.
+// Followed links are still subject to the symlink policy, the links it rejects are reported as links.
+func (f *LocalFileSystem) WalkDirWithConfig(path string, config map[string]any, walkFn gofs.WalkDirFunc) error {
+	var cfg *filesystem.Config
+	if config != nil {
+		var err error
+		if cfg, err = filesystem.NewConfig(config); err != nil {
+			return err
+		}
+	}
+	mode, err := cfg.SymlinkMode()
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	rel, err := f.rel(path)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if exists, _ := f.DirExists(path); !exists {
+		return filesystem.NewUnableToReadDirectory(path, os.ErrNotExist)
+	}
+	walk := &filesystem.SymlinkWalk{
+		ReadDir: f.readDir,
+		Stat:    f.stat,
+		RealPath: func(path string) (string, error) {
+			fp, err := f.resolve(path)
+			if err != nil {
+				return "", err
+			}
+			return filepath.EvalSymlinks(fp)
+		},
+	}
+	return walk.Walk(filepath.ToSlash(rel), mode, func(p string, d gofs.DirEntry, err error) error {
+		return walkFn(filepath.Join(f.root, filepath.FromSlash(p)), d, err)
+	})
+}
+
+// resolveLink returns the absolute path of the link itself:
+// the links of its parent directories are checked according to the policy, but not the link.
+func (f *LocalFileSystem) resolveLink(path string) (string, error) {
+	rel, err := f.rel(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.resolve(filepath.Dir(rel)); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.root, rel), nil
+}
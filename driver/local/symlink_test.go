@@ -0,0 +1,84 @@
+package local
+
+import (
+	"errors"
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_Symlink(t *testing.T) {
+	f, root, outside := newConfinedFS(t, SymlinkWithinRoot)
+	assert.Implements(t, (*filesystem.Symlinker)(nil), f)
+
+	if err := f.Symlink("../dir", "links/dir"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := f.Symlink("..", "links/loop"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	target, err := f.Readlink("links/dir")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "../dir", target)
+	// the link itself is read even if its target is outside the root
+	target, err = f.Readlink("outside")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, outside, target)
+	info, err := f.Lstat("outside")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	content, err := f.Read("links/dir/file.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "inside", string(content))
+
+	walk := func(mode filesystem.SymlinkMode) map[string]gofs.FileMode {
+		paths := map[string]gofs.FileMode{}
+		err := f.WalkDirWithConfig("", map[string]any{filesystem.SymlinksKey: string(mode)}, func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			paths[filepath.ToSlash(rel)] = d.Type()
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		return paths
+	}
+	reported := walk(filesystem.ReportSymlinks)
+	assert.Equal(t, gofs.ModeSymlink, reported["links/dir"])
+	assert.NotContains(t, reported, "links/dir/file.txt")
+	skipped := walk(filesystem.SkipSymlinks)
+	assert.NotContains(t, skipped, "links/dir")
+	assert.Contains(t, skipped, "dir/file.txt")
+	followed := walk(filesystem.FollowSymlinks)
+	assert.Equal(t, gofs.ModeDir, followed["links/dir"])
+	assert.Contains(t, followed, "links/dir/file.txt")
+	assert.Equal(t, gofs.FileMode(0), followed["relative"])
+	// the links out of the root are not followed under the policy
+	assert.Equal(t, gofs.ModeSymlink, followed["outside"])
+	assert.NotContains(t, followed, "outside/secret.txt")
+	// the loop leads back to the root
+	assert.NotContains(t, followed, "links/loop/dir")
+
+	denied, _, _ := newConfinedFS(t, SymlinkDeny)
+	err = denied.Symlink("dir", "link")
+	assert.True(t, errors.Is(err, ErrSymlinkDenied))
+}
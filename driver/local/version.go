@@ -0,0 +1,84 @@
+package local
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// writeLockPath returns the path locked by the conditional writes of the file,
+// a hidden file next to it, ".<name>.write", whose lock file is removed once the write is done.
+func writeLockPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".write")
+}
+
+// Stat returns the metadata of the file, with a version derived from its identity, size and modification time,
+// so that the file is not read.
+func (f *LocalFileSystem) Stat(path string) (*filesystem.FileStat, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if info.IsDir() {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrIsNotFile)
+	}
+	return &filesystem.FileStat{
+		Path:         path,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		Version:      fileVersion(info),
+	}, nil
+}
+
+// checkPrecondition checks the conditions of the write against the current version of the file.
+func (f *LocalFileSystem) checkPrecondition(path string, cfg *filesystem.Config) error {
+	if !cfg.HasPrecondition() {
+		return nil
+	}
+	info, err := f.stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	exists := err == nil && !info.IsDir()
+	var version string
+	if exists {
+		version = fileVersion(info)
+	}
+	return cfg.CheckPrecondition(path, exists, version)
+}
+
+// currentVersion returns the version of the file, empty if it does not exist.
+func (f *LocalFileSystem) currentVersion(path string) string {
+	info, err := f.stat(path)
+	if err != nil || info.IsDir() {
+		return ""
+	}
+	return fileVersion(info)
+}
+
+// bumpVersion makes sure the version of the written file differs from its version before the write,
+// which it does not if the content was rewritten in place with the same size within the resolution of the modification time:
+// the modification time is then moved forward.
+func (f *LocalFileSystem) bumpVersion(path string, previous string) (string, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return "", err
+	}
+	for step := time.Nanosecond; fileVersion(info) == previous && step <= time.Second; step *= 1000 {
+		// the step grows for the file systems storing coarser times, e.g. FAT
+		if err := f.chtimes(path, time.Time{}, info.ModTime().Add(step)); err != nil {
+			return "", err
+		}
+		if info, err = f.stat(path); err != nil {
+			return "", err
+		}
+	}
+	return fileVersion(info), nil
+}
+
+func fileVersion(info os.FileInfo) string {
+	return fmt.Sprintf("%x-%x-%x", fileID(info), info.Size(), info.ModTime().UnixNano())
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package local
+
+import "os"
+
+// fileID returns 0, the identity of the file is not part of the info on these platforms.
+func fileID(info os.FileInfo) uint64 {
+	return 0
+}
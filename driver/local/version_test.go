@@ -0,0 +1,91 @@
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalFileSystem_ConditionalWrite(t *testing.T) {
+	root := t.TempDir()
+	f, err := NewLocalFileSystem(root)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	var precondition *filesystem.PreconditionFailed
+
+	version, err := f.WriteVersioned("state.json", strings.NewReader(`{"n":1}`), map[string]any{filesystem.IfAbsentKey: true})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	err = f.Write("state.json", []byte(`{"n":2}`), map[string]any{filesystem.IfAbsentKey: true})
+	if assert.True(t, errors.As(err, &precondition)) {
+		assert.ErrorIs(t, err, os.ErrExist)
+	}
+
+	stat, err := f.Stat("state.json")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, version, stat.Version)
+	assert.Equal(t, int64(7), stat.Size)
+
+	next, err := f.WriteVersioned("state.json", strings.NewReader(`{"n":2}`), map[string]any{filesystem.IfMatchKey: version, filesystem.AtomicKey: true})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NotEqual(t, version, next)
+	err = f.Write("state.json", []byte(`{"n":3}`), map[string]any{filesystem.IfMatchKey: version})
+	if assert.True(t, errors.As(err, &precondition)) {
+		assert.ErrorIs(t, err, filesystem.ErrVersionMismatch)
+	}
+
+	content, err := f.Read("state.json")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, `{"n":2}`, string(content))
+	// the lock of the conditional writes is released
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Len(t, entries, 1)
+	assert.Equal(t, filepath.Join("dir", ".state.json.write"), writeLockPath(filepath.Join("dir", "state.json")))
+}
+
+func TestLocalFileSystem_Version(t *testing.T) {
+	f, err := NewLocalFileSystem(t.TempDir())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	versions := make(map[string]bool)
+	// rewritten in place with the same size, faster than the resolution of the modification time
+	for _, content := range []string{"aaaa", "bbbb", "cccc", "cccc"} {
+		if err := f.Write("a.txt", []byte(content), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		stat, err := f.Stat("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, versions[stat.Version], "version %s seen before", stat.Version)
+		versions[stat.Version] = true
+	}
+	version, err := f.WriteVersioned("a.txt", strings.NewReader("dddd"), nil)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	stat, err := f.Stat("a.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, version, stat.Version)
+	assert.False(t, versions[version])
+}
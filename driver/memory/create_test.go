@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"encoding/csv"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_Create(t *testing.T) {
+	t.Run("close", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		w, err := fs.Create("dir/data.csv", map[string]any{filesystem.FileVisibilityKey: "private"})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.WriteAll([][]string{{"id", "name"}, {"1", "gopher"}}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, _ := fs.FileExists("dir/data.csv")
+		assert.False(t, exists)
+		assert.NoError(t, w.Close())
+		assert.NoError(t, w.Close())
+		content, err := fs.Read("dir/data.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "id,name\n1,gopher\n", string(content))
+		visibility, _ := fs.Visibility("dir/data.csv")
+		assert.Equal(t, "private", visibility)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("file.txt", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := fs.Create("file.txt", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write([]byte("partial")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		_, err = w.Write([]byte("more"))
+		assert.Error(t, err)
+		content, _ := fs.Read("file.txt")
+		assert.Equal(t, "previous", string(content))
+	})
+
+	t.Run("error on close", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("file.txt", []byte("previous"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := fs.Create("file.txt", map[string]any{filesystem.IfAbsentKey: true})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, _ = w.Write([]byte("new"))
+		var failed *filesystem.PreconditionFailed
+		assert.ErrorAs(t, w.Close(), &failed)
+	})
+}
@@ -1,11 +1,16 @@
 package memory
 
 import (
+	"bytes"
 	"io"
 	"io/fs"
+	"maps"
 	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/gopi-frame/filesystem"
 )
 
 const publicDirMode = 0755
@@ -23,6 +28,10 @@ type dirEntry struct {
 	size       int64
 	visibility string
 	lastModify time.Time
+	// target is the target of a symbolic link, empty for files and directories
+	target   string
+	metadata map[string]string
+	tags     map[string]string
 }
 
 func newDir(name string, visibility string, parent *dirEntry) *dirEntry {
@@ -49,6 +58,17 @@ func newFile(name string, visibility string, parent *dirEntry) *dirEntry {
 	}
 }
 
+func newSymlink(name string, target string, parent *dirEntry) *dirEntry {
+	return &dirEntry{
+		mu:         new(sync.RWMutex),
+		name:       name,
+		entries:    make(map[string]*dirEntry, 0),
+		parent:     parent,
+		lastModify: time.Now(),
+		target:     target,
+	}
+}
+
 func (d *dirEntry) Name() string {
 	return d.name
 }
@@ -61,9 +81,16 @@ func (d *dirEntry) Type() fs.FileMode {
 	if d.isDir {
 		return fs.ModeDir
 	}
+	if d.isSymlink() {
+		return fs.ModeSymlink
+	}
 	return 0
 }
 
+func (d *dirEntry) isSymlink() bool {
+	return d.target != ""
+}
+
 func (d *dirEntry) Info() (fs.FileInfo, error) {
 	return d, nil
 }
@@ -73,7 +100,7 @@ func (d *dirEntry) Size() int64 {
 }
 
 func (d *dirEntry) Mode() fs.FileMode {
-	return 0
+	return d.Type()
 }
 
 func (d *dirEntry) ModTime() time.Time {
@@ -96,6 +123,7 @@ func (d *dirEntry) writeStream(r io.Reader, appendMode bool) error {
 	} else {
 		d.content = content
 	}
+	d.size = int64(len(d.content))
 	d.lastModify = time.Now()
 	return nil
 }
@@ -135,10 +163,66 @@ func (d *dirEntry) createFile(name string, visibility string) *dirEntry {
 	return entry
 }
 
+func (d *dirEntry) createSymlink(name string, target string) *dirEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry := newSymlink(name, target, d)
+	d.entries[name] = entry
+	return entry
+}
+
+// path returns the path of the entry from the root.
+func (d *dirEntry) path() string {
+	var parts []string
+	for entry := d; entry.parent != nil; entry = entry.parent {
+		parts = append([]string{entry.name}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// read returns a copy of the content, which open files may modify in place.
 func (d *dirEntry) read() ([]byte, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
-	return d.content, nil
+	return bytes.Clone(d.content), nil
+}
+
+func (d *dirEntry) readAt(p []byte, off int64) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if off >= int64(len(d.content)) {
+		return 0, io.EOF
+	}
+	n := copy(p, d.content[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// writeAt writes p at the offset, the gap after the current end, if any, is filled with zeros.
+func (d *dirEntry) writeAt(p []byte, off int64) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(d.content)) {
+		d.content = append(d.content, make([]byte, end-int64(len(d.content)))...)
+	}
+	n := copy(d.content[off:], p)
+	d.size = int64(len(d.content))
+	d.lastModify = time.Now()
+	return n, nil
+}
+
+func (d *dirEntry) truncate(size int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if size > int64(len(d.content)) {
+		d.content = append(d.content, make([]byte, size-int64(len(d.content)))...)
+	} else {
+		d.content = d.content[:size]
+	}
+	d.size = size
+	d.lastModify = time.Now()
 }
 
 func (d *dirEntry) readDir() []*dirEntry {
@@ -174,7 +258,45 @@ func (d *dirEntry) copyTo(dst *dirEntry) {
 	if d.isDir {
 		return
 	}
-	dst.content = d.content
+	dst.content = bytes.Clone(d.content)
 	dst.size = d.size
 	dst.lastModify = d.lastModify
+	dst.metadata = maps.Clone(d.metadata)
+	dst.tags = maps.Clone(d.tags)
+}
+
+func (d *dirEntry) version() string {
+	content, _ := d.read()
+	version, _ := filesystem.ContentVersion(bytes.NewReader(content))
+	return version
+}
+
+func (d *dirEntry) setModTime(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastModify = t
+}
+
+func (d *dirEntry) getMetadata() map[string]string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return filesystem.CloneMetadata(d.metadata)
+}
+
+func (d *dirEntry) setMetadata(metadata map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.metadata = maps.Clone(metadata)
+}
+
+func (d *dirEntry) getTags() map[string]string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return filesystem.CloneMetadata(d.tags)
+}
+
+func (d *dirEntry) setTags(tags map[string]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tags = maps.Clone(tags)
 }
@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// file is an open file, reading and writing the content of its entry in place.
+type file struct {
+	mu     sync.Mutex
+	name   string
+	entry  *dirEntry
+	flag   int
+	offset int64
+	closed bool
+}
+
+// OpenFile opens the file for random access, with the flag of [os.OpenFile].
+// With os.O_CREATE, the missing parent directories are created,
+// and the file is created with the visibility, or the default one if it is empty.
+func (f *MemoryFileSystem) OpenFile(location string, flag int, visibility string) (filesystem.File, error) {
+	if visibility == "" {
+		visibility = f.visibility
+	}
+	path := f.preparePath(location)
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	entry := f.searchEntry(path)
+	switch {
+	case entry == nil && flag&os.O_CREATE == 0:
+		return nil, filesystem.NewUnableToOpenFile(location, os.ErrNotExist)
+	case entry == nil:
+		dir, err := f.mkdirAll(filepath.Dir(path), f.visibility)
+		if err != nil {
+			return nil, err
+		}
+		entry = dir.createFile(filepath.Base(path), visibility)
+	case entry.IsDir():
+		return nil, filesystem.NewUnableToOpenFile(location, filesystem.ErrIsNotFile)
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, filesystem.NewUnableToOpenFile(location, os.ErrExist)
+	}
+	handle := &file{name: location, entry: entry, flag: flag}
+	if flag&os.O_TRUNC != 0 && handle.writable() {
+		entry.truncate(0)
+	}
+	return handle, nil
+}
+
+func (h *file) readable() bool {
+	return h.flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) != os.O_WRONLY
+}
+
+func (h *file) writable() bool {
+	return h.flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) != os.O_RDONLY
+}
+
+func (h *file) check(op string, allowed bool) error {
+	if h.closed {
+		return &os.PathError{Op: op, Path: h.name, Err: os.ErrClosed}
+	}
+	if !allowed {
+		return &os.PathError{Op: op, Path: h.name, Err: os.ErrPermission}
+	}
+	return nil
+}
+
+func (h *file) Name() string {
+	return h.name
+}
+
+func (h *file) Read(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("read", h.readable()); err != nil {
+		return 0, err
+	}
+	n, err := h.entry.readAt(p, h.offset)
+	h.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (h *file) ReadAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("read", h.readable()); err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, &os.PathError{Op: "readat", Path: h.name, Err: errors.New("negative offset")}
+	}
+	return h.entry.readAt(p, off)
+}
+
+// Write writes at the current offset, or at the end of the file with os.O_APPEND.
+func (h *file) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("write", h.writable()); err != nil {
+		return 0, err
+	}
+	if h.flag&os.O_APPEND != 0 {
+		h.offset = h.entry.Size()
+	}
+	n, err := h.entry.writeAt(p, h.offset)
+	h.offset += int64(n)
+	return n, err
+}
+
+func (h *file) WriteAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("write", h.writable()); err != nil {
+		return 0, err
+	}
+	if h.flag&os.O_APPEND != 0 {
+		return 0, &os.PathError{Op: "writeat", Path: h.name, Err: errors.New("file opened with O_APPEND")}
+	}
+	if off < 0 {
+		return 0, &os.PathError{Op: "writeat", Path: h.name, Err: errors.New("negative offset")}
+	}
+	return h.entry.writeAt(p, off)
+}
+
+func (h *file) Seek(offset int64, whence int) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("seek", true); err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += h.offset
+	case io.SeekEnd:
+		offset += h.entry.Size()
+	default:
+		return 0, &os.PathError{Op: "seek", Path: h.name, Err: errors.New("invalid whence")}
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: h.name, Err: errors.New("negative offset")}
+	}
+	h.offset = offset
+	return offset, nil
+}
+
+func (h *file) Truncate(size int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("truncate", h.writable()); err != nil {
+		return err
+	}
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: h.name, Err: errors.New("negative size")}
+	}
+	h.entry.truncate(size)
+	return nil
+}
+
+func (h *file) Stat() (os.FileInfo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("stat", true); err != nil {
+		return nil, err
+	}
+	return h.entry, nil
+}
+
+// Sync does nothing, the content is written to the entry as it is written to the file.
+func (h *file) Sync() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.check("sync", true)
+}
+
+func (h *file) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.check("close", true); err != nil {
+		return err
+	}
+	h.closed = true
+	return nil
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_OpenFile(t *testing.T) {
+	t.Run("read and write", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		assert.True(t, filesystem.SupportsRandomAccess(fs))
+		file, err := fs.OpenFile("dir/file.bin", os.O_RDWR|os.O_CREATE, "private")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "dir/file.bin", file.Name())
+		if _, err := file.Write([]byte("hello world")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := file.WriteAt([]byte("W"), 6); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := file.Truncate(9); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := io.ReadAll(file)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello Wor", string(content))
+		buf := make([]byte, 3)
+		if _, err := file.ReadAt(buf, 1); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "ell", string(buf))
+		_, err = file.ReadAt(buf, 8)
+		assert.ErrorIs(t, err, io.EOF)
+		if _, err := file.WriteAt([]byte("!"), 11); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		info, err := file.Stat()
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(12), info.Size())
+		assert.NoError(t, file.Sync())
+		assert.NoError(t, file.Close())
+		_, err = file.Read(buf)
+		assert.ErrorIs(t, err, os.ErrClosed)
+
+		got, err := fs.Read("dir/file.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello Wor\x00\x00!", string(got))
+		visibility, err := fs.Visibility("dir/file.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "private", visibility)
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		_, err := fs.OpenFile("file.txt", os.O_RDONLY, "")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		if err := fs.Write("file.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = fs.OpenFile("file.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL, "")
+		assert.ErrorIs(t, err, os.ErrExist)
+		_, err = fs.OpenFile("/", os.O_RDONLY, "")
+		assert.True(t, errors.Is(err, filesystem.ErrIsNotFile))
+
+		file, err := fs.OpenFile("file.txt", os.O_RDONLY, "")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = file.Write([]byte("x"))
+		assert.ErrorIs(t, err, os.ErrPermission)
+		assert.NoError(t, file.Close())
+
+		file, err = fs.OpenFile("file.txt", os.O_WRONLY|os.O_APPEND, "")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := file.Write([]byte(" world")); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = file.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrPermission)
+		assert.NoError(t, file.Close())
+		got, _ := fs.Read("file.txt")
+		assert.Equal(t, "hello world", string(got))
+
+		file, err = fs.OpenFile("file.txt", os.O_RDWR|os.O_TRUNC, "")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, file.Close())
+		got, _ = fs.Read("file.txt")
+		assert.Empty(t, got)
+	})
+}
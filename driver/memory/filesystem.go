@@ -20,6 +20,8 @@ type MemoryFileSystem struct {
 	root             *dirEntry
 	visibility       string
 	mimetypeDetector fs.MimeTypeDetector
+	locks            *lockTable
+	writeMu          *sync.Mutex
 }
 
 func NewMemoryFileSystem(visibility string, mimetypeDetector fs.MimeTypeDetector) *MemoryFileSystem {
@@ -31,6 +33,8 @@ func NewMemoryFileSystem(visibility string, mimetypeDetector fs.MimeTypeDetector
 		root:             newDir("/", visibility, nil),
 		visibility:       visibility,
 		mimetypeDetector: mimetypeDetector,
+		locks:            &lockTable{held: make(map[string]filesystem.LockInfo)},
+		writeMu:          new(sync.Mutex),
 	}
 }
 
@@ -75,6 +79,37 @@ func (f *MemoryFileSystem) ReadStream(path string) (io.ReadCloser, error) {
 	return io.NopCloser(strings.NewReader(string(content))), nil
 }
 
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the stream is read.
+func (f *MemoryFileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	content, err := f.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	tracker := filesystem.NewProgressTracker(path, int64(len(content)), cfg.Progress())
+	return tracker.ReadCloser(io.NopCloser(bytes.NewReader(content))), nil
+}
+
+// ReadRange returns length bytes of the file starting at offset, sliced from its content.
+func (f *MemoryFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	content, err := f.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	offset = min(offset, int64(len(content)))
+	end := int64(len(content))
+	if length >= 0 {
+		end = min(end, offset+length)
+	}
+	return io.NopCloser(bytes.NewReader(content[offset:end])), nil
+}
+
 func (f *MemoryFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
 	entry := f.searchEntry(path)
 	if entry == nil {
@@ -175,18 +210,36 @@ func (f *MemoryFileSystem) Write(location string, content []byte, config map[str
 }
 
 func (f *MemoryFileSystem) WriteStream(location string, stream io.Reader, config map[string]any) error {
+	_, err := f.writeStream(location, stream, config, false)
+	return err
+}
+
+// WriteVersioned writes like WriteStream, and returns the version of the written file.
+func (f *MemoryFileSystem) WriteVersioned(location string, stream io.Reader, config map[string]any) (string, error) {
+	return f.writeStream(location, stream, config, true)
+}
+
+// writeStream writes the file, and returns its version if versioned is true,
+// the version being the hash of the content, computed only then.
+//
+// The stream is read before the writes are serialized, so that a slow stream does not hold the other writes,
+// the conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey] are then checked
+// and the file written without any other write in between.
+func (f *MemoryFileSystem) writeStream(location string, stream io.Reader, config map[string]any, versioned bool) (string, error) {
 	path := f.preparePath(location)
 	if path == "." || path == "/" || path == "./" || path == "" {
-		return nil
+		return "", nil
 	}
 	parts := strings.Split(path, "/")
 	var dirVisibility = f.visibility
 	var fileVisibility = f.visibility
 	var fileFlag = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		var err error
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
-			return err
+			return "", err
 		}
 		if cfg.DirVisibility != nil {
 			dirVisibility = *cfg.DirVisibility
@@ -198,6 +251,24 @@ func (f *MemoryFileSystem) WriteStream(location string, stream io.Reader, config
 			fileFlag = *cfg.FileWriteFlag
 		}
 	}
+	tracker := cfg.NewTracker(location, stream)
+	var buf bytes.Buffer
+	if _, err := filesystem.CopyWithProgress(&buf, stream, tracker); err != nil {
+		return "", filesystem.NewUnableToWriteFile(location, err)
+	}
+	content := buf.Bytes()
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if cfg.HasPrecondition() {
+		var version string
+		current := f.searchEntry(path)
+		if current != nil && !current.IsDir() {
+			version = current.version()
+		}
+		if err := cfg.CheckPrecondition(location, current != nil && !current.IsDir(), version); err != nil {
+			return "", err
+		}
+	}
 	var dirEntry *dirEntry
 	if len(parts) == 1 {
 		entry := f.root.findEntry(parts[0])
@@ -205,28 +276,59 @@ func (f *MemoryFileSystem) WriteStream(location string, stream io.Reader, config
 			entry = f.root.createFile(path, fileVisibility)
 		}
 		if entry.IsDir() {
-			return filesystem.NewUnableToWriteFile(location, errors.New("not a file"))
+			return "", filesystem.NewUnableToWriteFile(location, errors.New("not a file"))
 		}
 	}
 	dirEntry, err := f.mkdirAll(filepath.Dir(path), dirVisibility)
 	if err != nil {
-		return err
+		return "", err
 	}
 	filename := filepath.Base(path)
-	if entry := dirEntry.findEntry(filename); entry != nil {
-		if entry.IsDir() {
-			return filesystem.NewUnableToWriteFile(location, errors.New("directory already exists"))
+	entry := dirEntry.findEntry(filename)
+	if entry != nil && entry.isSymlink() {
+		// the content is written to the target of the link
+		if entry = f.searchEntry(path); entry == nil {
+			return "", filesystem.NewUnableToWriteFile(location, os.ErrNotExist)
 		}
-		if err := entry.writeStream(stream, fileFlag&os.O_APPEND > 0); err != nil {
-			return filesystem.NewUnableToWriteFile(location, err)
-		}
-		return nil
 	}
-	entry := dirEntry.createFile(filename, fileVisibility)
-	if err := entry.writeStream(stream, fileFlag&os.O_APPEND > 0); err != nil {
-		return filesystem.NewUnableToWriteFile(location, err)
+	if entry != nil && entry.IsDir() {
+		return "", filesystem.NewUnableToWriteFile(location, errors.New("directory already exists"))
 	}
-	return nil
+	if entry == nil {
+		entry = dirEntry.createFile(filename, fileVisibility)
+	}
+	if err := entry.writeStream(bytes.NewReader(content), fileFlag&os.O_APPEND > 0); err != nil {
+		return "", filesystem.NewUnableToWriteFile(location, err)
+	}
+	if cfg != nil && cfg.Metadata != nil {
+		entry.setMetadata(cfg.Metadata)
+	}
+	if cfg != nil && cfg.Tags != nil {
+		entry.setTags(cfg.Tags)
+	}
+	tracker.Done()
+	if !versioned {
+		return "", nil
+	}
+	return entry.version(), nil
+}
+
+// Stat returns the metadata of the file, with a version derived from its content.
+func (f *MemoryFileSystem) Stat(location string) (*filesystem.FileStat, error) {
+	entry := f.searchEntry(location)
+	if entry == nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(location, os.ErrNotExist)
+	}
+	if entry.IsDir() {
+		return nil, filesystem.NewUnableToRetrieveMetadata(location, filesystem.ErrIsNotFile)
+	}
+	content, _ := entry.read()
+	return &filesystem.FileStat{
+		Path:         location,
+		Size:         int64(len(content)),
+		LastModified: entry.ModTime(),
+		Version:      entry.version(),
+	}, nil
 }
 
 func (f *MemoryFileSystem) SetVisibility(location string, visibility string) error {
@@ -271,11 +373,11 @@ func (f *MemoryFileSystem) CreateDir(path string, config map[string]any) error {
 }
 
 func (f *MemoryFileSystem) Move(src string, dst string, config map[string]any) error {
-	srcEntry := f.searchEntry(src)
+	srcEntry := f.lsearchEntry(src)
 	if srcEntry == nil {
 		return filesystem.NewUnableToMove(src, dst, os.ErrNotExist)
 	}
-	dstEntry := f.searchEntry(dst)
+	dstEntry := f.lsearchEntry(dst)
 	if dstEntry != nil {
 		return filesystem.NewUnableToMove(dst, src, os.ErrExist)
 	}
@@ -292,7 +394,7 @@ func (f *MemoryFileSystem) Move(src string, dst string, config map[string]any) e
 	var err error
 	var dstDir *dirEntry
 	if srcEntry.IsDir() {
-		dstDir, err = f.mkdirAll(dst, dirVisibility)
+		dstDir, err = f.mkdirAll(filepath.Dir(filepath.Clean(dst)), dirVisibility)
 		if err != nil {
 			return filesystem.NewUnableToMove(src, dst, err)
 		}
@@ -317,8 +419,10 @@ func (f *MemoryFileSystem) Copy(src string, dst string, config map[string]any) e
 	var dirVisibility = f.visibility
 	var fileVisibility = f.visibility
 	var fileFlag int
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		var err error
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
 			return err
 		}
@@ -339,6 +443,14 @@ func (f *MemoryFileSystem) Copy(src string, dst string, config map[string]any) e
 	if srcEntry.IsDir() {
 		return filesystem.NewUnableToCopyFile(src, dst, filesystem.ErrIsNotFile)
 	}
+	if link := f.lsearchEntry(dst); link != nil && link.isSymlink() {
+		// the content is copied to the target of the link
+		target := f.searchEntry(dst)
+		if target == nil {
+			return filesystem.NewUnableToCopyFile(src, dst, os.ErrNotExist)
+		}
+		dst = target.path()
+	}
 	dstEntry := f.searchEntry(dst)
 	if dstEntry != nil && fileFlag&os.O_TRUNC <= 0 {
 		return filesystem.NewUnableToCopyFile(dst, src, os.ErrExist)
@@ -350,28 +462,68 @@ func (f *MemoryFileSystem) Copy(src string, dst string, config map[string]any) e
 	copyEntry := newFile(filepath.Base(dst), fileVisibility, nil)
 	srcEntry.copyTo(copyEntry)
 	dstEntry.addEntry(copyEntry)
+	if progress := cfg.Progress(); progress != nil {
+		// the content is copied at once, there is a single report
+		size := int64(len(copyEntry.content))
+		tracker := filesystem.NewProgressTracker(dst, size, progress)
+		tracker.Add(size)
+		tracker.Done()
+	}
 	return nil
 }
 
+// maxSymlinkHops is the number of links followed while resolving a path before giving up.
+const maxSymlinkHops = 40
+
+// searchEntry returns the entry at the path, following the links.
 func (f *MemoryFileSystem) searchEntry(path string) *dirEntry {
+	return f.lookup(path, true)
+}
+
+// lsearchEntry returns the entry at the path, following the links but the last one.
+func (f *MemoryFileSystem) lsearchEntry(path string) *dirEntry {
+	return f.lookup(path, false)
+}
+
+// lookup returns the entry at the path, following the links of its directories, and of its last part if followLast.
+// A relative target is resolved from the directory of the link, an absolute one from the root.
+func (f *MemoryFileSystem) lookup(path string, followLast bool) *dirEntry {
 	path = f.preparePath(path)
 	if path == "/" || path == "" || path == "." || path == "./" {
 		return f.root
 	}
 	parts := strings.Split(path, "/")
 	var currentEntry = f.root
-	for _, part := range parts {
-		var entry = currentEntry.findEntry(part)
+	hops := 0
+	for i := 0; i < len(parts); i++ {
+		var entry = currentEntry.findEntry(parts[i])
 		if entry == nil {
 			return nil
 		}
+		if entry.isSymlink() && (followLast || i < len(parts)-1) {
+			if hops++; hops > maxSymlinkHops {
+				return nil
+			}
+			target := entry.target
+			if !strings.HasPrefix(target, "/") {
+				target = filepath.Join(strings.Join(parts[:i], "/"), target)
+			}
+			resolved := f.preparePath(filepath.Join(append([]string{target}, parts[i+1:]...)...))
+			if resolved == "" || resolved == "." {
+				return f.root
+			}
+			parts = strings.Split(resolved, "/")
+			currentEntry = f.root
+			i = -1
+			continue
+		}
 		currentEntry = entry
 	}
 	return currentEntry
 }
 
 func (f *MemoryFileSystem) deleteAll(path string, file bool) error {
-	entry := f.searchEntry(path)
+	entry := f.lsearchEntry(path)
 	if entry == nil {
 		return nil
 	}
@@ -397,9 +549,14 @@ func (f *MemoryFileSystem) mkdirAll(path string, visibility string) (*dirEntry,
 		return f.root, nil
 	}
 	parts := strings.Split(path, "/")
-	for _, part := range parts {
+	for i, part := range parts {
 		var entry *dirEntry
 		entry = currentEntry.findEntry(part)
+		if entry != nil && entry.isSymlink() {
+			if resolved := f.searchEntry(strings.Join(parts[:i+1], "/")); resolved != nil {
+				entry = resolved
+			}
+		}
 		if entry == nil {
 			entry = newDir(part, visibility, currentEntry)
 			currentEntry = currentEntry.createDir(part, visibility)
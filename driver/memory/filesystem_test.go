@@ -450,6 +450,11 @@ func TestMemoryFileSystem_Move(t *testing.T) {
 			assert.FailNow(t, err.Error())
 		}
 		assert.True(t, exists)
+		exists, err = fs.FileExists("dir2/dir11/dir2/test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
 	})
 	t.Run("unknown source", func(t *testing.T) {
 		fs := NewMemoryFileSystem("public", nil)
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// lockTable is the in-process table of the locks held on a [MemoryFileSystem].
+type lockTable struct {
+	mu   sync.Mutex
+	held map[string]filesystem.LockInfo
+}
+
+// Lock acquires the lock of the path, waiting until it is released or expires.
+func (f *MemoryFileSystem) Lock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	return f.LockContext(context.Background(), path, ttl)
+}
+
+// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+func (f *MemoryFileSystem) LockContext(ctx context.Context, path string, ttl time.Duration) (filesystem.Lock, error) {
+	return filesystem.WaitForLock(ctx, func() (filesystem.Lock, error) {
+		return f.TryLock(path, ttl)
+	})
+}
+
+// TryLock acquires the lock of the path, or returns a [filesystem.LockHeld] error if it is held.
+// The path does not need to exist.
+func (f *MemoryFileSystem) TryLock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	path = f.preparePath(path)
+	f.locks.mu.Lock()
+	defer f.locks.mu.Unlock()
+	if holder, ok := f.locks.held[path]; ok && !holder.Expired() {
+		return nil, filesystem.NewLockHeld(path, holder)
+	}
+	info := filesystem.NewLockInfo(ttl)
+	f.locks.held[path] = info
+	return &lock{locks: f.locks, path: path, info: info}, nil
+}
+
+type lock struct {
+	locks *lockTable
+	path  string
+	info  filesystem.LockInfo
+}
+
+func (l *lock) Path() string {
+	return l.path
+}
+
+func (l *lock) Info() filesystem.LockInfo {
+	return l.info
+}
+
+func (l *lock) Refresh(ttl time.Duration) error {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+	if l.locks.held[l.path].Token != l.info.Token {
+		return filesystem.ErrLockLost
+	}
+	l.info = l.info.Renew(ttl)
+	l.locks.held[l.path] = l.info
+	return nil
+}
+
+func (l *lock) Unlock() error {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+	if l.locks.held[l.path].Token != l.info.Token {
+		return filesystem.ErrLockLost
+	}
+	delete(l.locks.held, l.path)
+	return nil
+}
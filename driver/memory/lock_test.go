@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_Lock(t *testing.T) {
+	t.Run("exclusive", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		lock, err := fs.TryLock("report.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = fs.TryLock("/report.csv", time.Minute)
+		var held *filesystem.LockHeld
+		if assert.True(t, errors.As(err, &held)) {
+			assert.Equal(t, lock.Info().Token, held.Holder().Token)
+		}
+		if err := lock.Refresh(time.Minute); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := lock.Unlock(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		lock, err = fs.TryLock("report.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, lock.Unlock())
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		lock, err := fs.Lock("report.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = lock.Unlock()
+		}()
+		next, err := fs.Lock("report.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NotEqual(t, lock.Info().Token, next.Info().Token)
+		assert.NoError(t, next.Unlock())
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		stale, err := fs.TryLock("report.csv", 10*time.Millisecond)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		time.Sleep(20 * time.Millisecond)
+		lock, err := fs.TryLock("report.csv", time.Minute)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.ErrorIs(t, stale.Refresh(time.Minute), filesystem.ErrLockLost)
+		assert.ErrorIs(t, stale.Unlock(), filesystem.ErrLockLost)
+		assert.NoError(t, lock.Unlock())
+	})
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"os"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Metadata returns the custom metadata of the file or directory.
+func (f *MemoryFileSystem) Metadata(path string) (map[string]string, error) {
+	entry := f.searchEntry(path)
+	if entry == nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, os.ErrNotExist)
+	}
+	return entry.getMetadata(), nil
+}
+
+// SetMetadata replaces the custom metadata of the file or directory.
+func (f *MemoryFileSystem) SetMetadata(path string, metadata map[string]string) error {
+	entry := f.searchEntry(path)
+	if entry == nil {
+		return filesystem.NewUnableToSetMetadata(path, os.ErrNotExist)
+	}
+	entry.setMetadata(metadata)
+	return nil
+}
+
+// Tags returns the tags of the file or directory.
+func (f *MemoryFileSystem) Tags(path string) (map[string]string, error) {
+	entry := f.searchEntry(path)
+	if entry == nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, os.ErrNotExist)
+	}
+	return entry.getTags(), nil
+}
+
+// SetTags replaces the tags of the file or directory.
+func (f *MemoryFileSystem) SetTags(path string, tags map[string]string) error {
+	entry := f.searchEntry(path)
+	if entry == nil {
+		return filesystem.NewUnableToSetMetadata(path, os.ErrNotExist)
+	}
+	entry.setTags(tags)
+	return nil
+}
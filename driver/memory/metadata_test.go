@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_Metadata(t *testing.T) {
+	t.Run("write", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		assert.Implements(t, (*filesystem.MetadataStore)(nil), fs)
+		err := fs.Write("a.txt", []byte("hello"), map[string]any{
+			filesystem.MetadataKey: map[string]string{"uploader": "42"},
+			filesystem.TagsKey:     map[string]any{"retention": "short"},
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := fs.Metadata("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"uploader": "42"}, metadata)
+		tags, err := fs.Tags("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "short"}, tags)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("a.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := fs.Metadata("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, metadata)
+		if err := fs.SetMetadata("a.txt", map[string]string{"source": "erp"}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := fs.SetTags("a.txt", map[string]string{"class": "invoice"}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		// the returned maps are copies
+		metadata, err = fs.Metadata("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata["source"] = "changed"
+		if err := fs.Copy("a.txt", "b.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err = fs.Metadata("b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"source": "erp"}, metadata)
+		tags, err := fs.Tags("b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"class": "invoice"}, tags)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		_, err := fs.Metadata("missing.txt")
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+		err = fs.SetTags("missing.txt", map[string]string{"a": "b"})
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
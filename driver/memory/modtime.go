@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time of the file to now, creating an empty file if it is missing.
+// The missing parent directories are created.
+func (f *MemoryFileSystem) Touch(location string) error {
+	path := f.preparePath(location)
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if entry := f.searchEntry(path); entry != nil {
+		entry.setModTime(time.Now())
+		return nil
+	}
+	dir, err := f.mkdirAll(filepath.Dir(path), f.visibility)
+	if err != nil {
+		return err
+	}
+	dir.createFile(filepath.Base(path), f.visibility)
+	return nil
+}
+
+// SetLastModified sets the modification time of the file or directory.
+func (f *MemoryFileSystem) SetLastModified(location string, t time.Time) error {
+	entry := f.searchEntry(location)
+	if entry == nil {
+		return filesystem.NewUnableToSetLastModified(location, os.ErrNotExist)
+	}
+	entry.setModTime(t)
+	return nil
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_SetLastModified(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("a.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		if err := fs.SetLastModified("a.txt", mtime); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		modified, err := fs.LastModified("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, mtime.Equal(modified))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		err := fs.SetLastModified("missing.txt", time.Now())
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
+
+func TestMemoryFileSystem_Touch(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Touch("dir/a.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := fs.Read("dir/a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, content)
+	})
+
+	t.Run("existing", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("a.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := fs.SetLastModified("a.txt", time.Unix(0, 0)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		before := time.Now()
+		if err := fs.Touch("a.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		modified, err := fs.LastModified("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, modified.Before(before))
+		content, err := fs.Read("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(content))
+	})
+}
@@ -0,0 +1,231 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_Progress(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100*1024)
+
+	t.Run("write", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		var reports []filesystem.Progress
+		err := fs.WriteStream("file.bin", bytes.NewReader(content), map[string]any{
+			filesystem.ProgressKey: func(p filesystem.Progress) { reports = append(reports, p) },
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if assert.NotEmpty(t, reports) {
+			last := reports[len(reports)-1]
+			assert.True(t, last.Done)
+			assert.Equal(t, "file.bin", last.Path)
+			assert.Equal(t, int64(len(content)), last.Bytes)
+			assert.Equal(t, int64(len(content)), last.Total)
+			assert.Nil(t, last.Overall)
+		}
+	})
+
+	t.Run("unknown total from context", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		var last filesystem.Progress
+		ctx := filesystem.WithProgress(context.Background(), func(p filesystem.Progress) { last = p })
+		// a reader hiding its size
+		stream := struct{ io.Reader }{bytes.NewReader(content)}
+		err := fs.WriteStream("file.bin", stream, map[string]any{filesystem.ContextKey: ctx})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, last.Done)
+		assert.Equal(t, int64(len(content)), last.Bytes)
+		assert.Equal(t, int64(-1), last.Total)
+	})
+
+	t.Run("read", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("file.bin", content, nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var last filesystem.Progress
+		stream, err := fs.ReadStreamWithConfig("file.bin", map[string]any{
+			filesystem.ProgressKey: filesystem.ProgressFunc(func(p filesystem.Progress) { last = p }),
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := io.Copy(io.Discard, stream); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, stream.Close())
+		assert.True(t, last.Done)
+		assert.Equal(t, int64(len(content)), last.Bytes)
+		assert.Equal(t, int64(len(content)), last.Total)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("file.bin", content, nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var last filesystem.Progress
+		err := fs.Copy("file.bin", "copy.bin", map[string]any{
+			filesystem.ProgressKey: func(p filesystem.Progress) { last = p },
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, last.Done)
+		assert.Equal(t, "copy.bin", last.Path)
+		assert.Equal(t, int64(len(content)), last.Bytes)
+	})
+
+	t.Run("move directory across file systems", func(t *testing.T) {
+		manager := filesystem.NewFileSystemManager()
+		src := NewMemoryFileSystem("public", nil)
+		dst := NewMemoryFileSystem("public", nil)
+		manager.AddFS("src", src)
+		manager.AddFS("dst", dst)
+		files := map[string]string{"dir/a.txt": "hello", "dir/sub/b.txt": "world!", "dir/sub/c.txt": ""}
+		for path, content := range files {
+			if err := src.Write(path, []byte(content), nil); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+		var done []string
+		var last filesystem.Progress
+		err := manager.Move("src://dir", "dst://moved", map[string]any{
+			filesystem.ProgressKey: func(p filesystem.Progress) {
+				if p.Done {
+					done = append(done, p.Path)
+				}
+				last = p
+			},
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.ElementsMatch(t, []string{"moved/a.txt", "moved/sub/b.txt", "moved/sub/c.txt"}, done)
+		if assert.NotNil(t, last.Overall) {
+			assert.Equal(t, 3, last.Overall.Files)
+			assert.Equal(t, 3, last.Overall.TotalFiles)
+			assert.Equal(t, int64(11), last.Overall.Bytes)
+			assert.Equal(t, int64(11), last.Overall.Total)
+		}
+		for path, content := range files {
+			got, err := dst.Read(strings.Replace(path, "dir/", "moved/", 1))
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, content, string(got))
+		}
+		exists, _ := src.Exists("dir")
+		assert.False(t, exists)
+	})
+}
+
+// failingWrites fails the writes of the files named failing.
+type failingWrites struct {
+	*MemoryFileSystem
+	failing string
+}
+
+func (f *failingWrites) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	if f.failing != "" && strings.HasSuffix(path, "/"+f.failing) {
+		return filesystem.NewUnableToWriteFile(path, errors.New("disk full"))
+	}
+	return f.MemoryFileSystem.WriteStream(path, stream, config)
+}
+
+// dirConfigs records the configs the directories are created with.
+type dirConfigs struct {
+	*MemoryFileSystem
+	configs []map[string]any
+}
+
+func (f *dirConfigs) CreateDir(path string, config map[string]any) error {
+	f.configs = append(f.configs, config)
+	return f.MemoryFileSystem.CreateDir(path, config)
+}
+
+func TestFileSystemManager_Dir(t *testing.T) {
+	newManager := func(t *testing.T, failing string) (*filesystem.FileSystemManager, *MemoryFileSystem, *MemoryFileSystem) {
+		manager := filesystem.NewFileSystemManager()
+		src := NewMemoryFileSystem("public", nil)
+		dst := NewMemoryFileSystem("public", nil)
+		manager.AddFS("src", src)
+		manager.AddFS("dst", &failingWrites{MemoryFileSystem: dst, failing: failing})
+		if err := src.Write("dir/a.txt", []byte("a"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := src.Write("dir/sub/b.txt", []byte("b"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := src.CreateDir("dir/empty", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		return manager, src, dst
+	}
+
+	t.Run("copy keeps empty directories", func(t *testing.T) {
+		manager, _, dst := newManager(t, "")
+		if err := manager.Copy("src://dir", "dst://copied", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err := dst.DirExists("copied/empty")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+		content, err := dst.Read("copied/sub/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "b", string(content))
+	})
+
+	t.Run("directories only get the directory keys", func(t *testing.T) {
+		manager, _, _ := newManager(t, "")
+		dst := &dirConfigs{MemoryFileSystem: NewMemoryFileSystem("public", nil)}
+		manager.AddFS("recorded", dst)
+		err := manager.Copy("src://dir", "recorded://copied", map[string]any{
+			filesystem.DirVisibilityKey:  "private",
+			filesystem.FileVisibilityKey: "private",
+			filesystem.ProgressKey:       func(filesystem.Progress) {},
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if assert.NotEmpty(t, dst.configs) {
+			for _, config := range dst.configs {
+				assert.Equal(t, map[string]any{filesystem.DirVisibilityKey: "private"}, config)
+			}
+		}
+	})
+
+	t.Run("failed move keeps the source", func(t *testing.T) {
+		manager, src, _ := newManager(t, "b.txt")
+		err := manager.Move("src://dir", "dst://moved", nil)
+		assert.Error(t, err)
+		for _, path := range []string{"dir/a.txt", "dir/sub/b.txt"} {
+			exists, err := src.FileExists(path)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.True(t, exists, path)
+		}
+		exists, err := src.DirExists("dir/empty")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+	})
+}
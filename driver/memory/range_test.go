@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_ReadRange(t *testing.T) {
+	fs := NewMemoryFileSystem("public", nil)
+	if err := fs.Write("file.txt", []byte("0123456789"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	cases := []struct {
+		name           string
+		offset, length int64
+		expected       string
+	}{
+		{"middle", 2, 3, "234"},
+		{"to the end", 7, -1, "789"},
+		{"past the end", 8, 10, "89"},
+		{"after the end", 12, 1, ""},
+		{"empty", 3, 0, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stream, err := fs.ReadRange("file.txt", c.offset, c.length)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			defer stream.Close()
+			content, err := io.ReadAll(stream)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, c.expected, string(content))
+		})
+	}
+	_, err := fs.ReadRange("file.txt", -1, 1)
+	assert.ErrorIs(t, err, filesystem.ErrInvalidRange)
+}
+
+func TestMemoryFileSystem_ReaderAt(t *testing.T) {
+	fs := NewMemoryFileSystem("public", nil)
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		file, err := w.Create(name)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, _ = file.Write([]byte("content of " + name))
+	}
+	if err := w.Close(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := fs.Write("archive.zip", archive.Bytes(), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+
+	readerAt, err := filesystem.NewReaderAt(fs, "archive.zip")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, int64(archive.Len()), readerAt.Size())
+	r, err := zip.NewReader(readerAt, readerAt.Size())
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if assert.Len(t, r.File, 2) {
+		file, err := r.File[1].Open()
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := io.ReadAll(file)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "content of dir/b.txt", string(content))
+	}
+
+	buf := make([]byte, 8)
+	n, err := readerAt.ReadAt(buf, readerAt.Size()-4)
+	assert.Equal(t, 4, n)
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"errors"
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Symlink creates link as a symbolic link to target, the missing parent directories are created.
+// The target is stored as is, an absolute one is resolved from the root.
+func (f *MemoryFileSystem) Symlink(target, link string) error {
+	if target == "" {
+		return filesystem.NewUnableToCreateSymlink(link, errors.New("empty target"))
+	}
+	path := f.preparePath(link)
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	if f.lsearchEntry(path) != nil {
+		return filesystem.NewUnableToCreateSymlink(link, os.ErrExist)
+	}
+	dir, err := f.mkdirAll(filepath.Dir(path), f.visibility)
+	if err != nil {
+		return err
+	}
+	dir.createSymlink(filepath.Base(path), target)
+	return nil
+}
+
+// Readlink returns the target of the link, as it was stored.
+func (f *MemoryFileSystem) Readlink(path string) (string, error) {
+	entry := f.lsearchEntry(path)
+	if entry == nil {
+		return "", filesystem.NewUnableToReadSymlink(path, os.ErrNotExist)
+	}
+	if !entry.isSymlink() {
+		return "", filesystem.NewUnableToReadSymlink(path, filesystem.ErrIsNotSymlink)
+	}
+	return entry.target, nil
+}
+
+// Lstat returns the info of the file, describing the link itself if it is one.
+func (f *MemoryFileSystem) Lstat(path string) (os.FileInfo, error) {
+	entry := f.lsearchEntry(path)
+	if entry == nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, os.ErrNotExist)
+	}
+	return entry, nil
+}
+
+// WalkDirWithConfig walks the directory like WalkDir, handling the links according to [filesystem.SymlinksKey].
+func (f *MemoryFileSystem) WalkDirWithConfig(path string, config map[string]any, walkFn gofs.WalkDirFunc) error {
+	var cfg *filesystem.Config
+	if config != nil {
+		var err error
+		if cfg, err = filesystem.NewConfig(config); err != nil {
+			return err
+		}
+	}
+	mode, err := cfg.SymlinkMode()
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if exists, _ := f.DirExists(path); !exists {
+		return filesystem.NewUnableToReadDirectory(path, os.ErrNotExist)
+	}
+	walk := &filesystem.SymlinkWalk{
+		ReadDir: f.ReadDir,
+		Stat: func(path string) (gofs.FileInfo, error) {
+			entry := f.searchEntry(path)
+			if entry == nil {
+				return nil, os.ErrNotExist
+			}
+			return entry, nil
+		},
+		RealPath: func(path string) (string, error) {
+			entry := f.searchEntry(path)
+			if entry == nil {
+				return "", os.ErrNotExist
+			}
+			return entry.path(), nil
+		},
+	}
+	return walk.Walk(path, mode, walkFn)
+}
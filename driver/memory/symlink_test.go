@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"errors"
+	gofs "io/fs"
+	"os"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_Symlink(t *testing.T) {
+	newFS := func(t *testing.T) *MemoryFileSystem {
+		fs := NewMemoryFileSystem("public", nil)
+		if err := fs.Write("data/a.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for link, target := range map[string]string{
+			"links/file.txt": "../data/a.txt",
+			"links/dir":      "/data",
+			"links/loop":     "..",
+			"links/dangling": "missing.txt",
+		} {
+			if err := fs.Symlink(target, link); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+		return fs
+	}
+
+	t.Run("read", func(t *testing.T) {
+		fs := newFS(t)
+		target, err := fs.Readlink("links/file.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "../data/a.txt", target)
+		_, err = fs.Readlink("data/a.txt")
+		assert.True(t, errors.Is(err, filesystem.ErrIsNotSymlink))
+
+		info, err := fs.Lstat("links/file.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NotZero(t, info.Mode()&os.ModeSymlink)
+
+		content, err := fs.Read("links/file.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(content))
+		content, err = fs.Read("links/dir/a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(content))
+		exists, _ := fs.FileExists("links/dangling")
+		assert.False(t, exists)
+		assert.Error(t, fs.Symlink("elsewhere", "links/file.txt"))
+	})
+
+	t.Run("write and delete", func(t *testing.T) {
+		fs := newFS(t)
+		if err := fs.Write("links/dir/b.txt", []byte("world"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := fs.Write("links/file.txt", []byte("updated"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := fs.Read("data/b.txt")
+		assert.Equal(t, "world", string(content))
+		content, _ = fs.Read("data/a.txt")
+		assert.Equal(t, "updated", string(content))
+
+		if err := fs.Delete("links/file.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err := fs.Lstat("links/file.txt")
+		assert.Error(t, err)
+		exists, _ := fs.FileExists("data/a.txt")
+		assert.True(t, exists)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		fs := newFS(t)
+		if err := fs.Write("data/c.txt", []byte("copied"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		truncate := map[string]any{filesystem.FileWriteFlagKey: os.O_WRONLY | os.O_CREATE | os.O_TRUNC}
+		if err := fs.Copy("data/c.txt", "links/file.txt", truncate); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := fs.Read("data/a.txt")
+		assert.Equal(t, "copied", string(content))
+		// the link is kept
+		target, err := fs.Readlink("links/file.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "../data/a.txt", target)
+		assert.Error(t, fs.Copy("data/c.txt", "links/dangling", truncate))
+	})
+
+	t.Run("walk", func(t *testing.T) {
+		fs := newFS(t)
+		walk := func(mode filesystem.SymlinkMode) map[string]gofs.FileMode {
+			paths := map[string]gofs.FileMode{}
+			err := fs.WalkDirWithConfig("links", map[string]any{filesystem.SymlinksKey: string(mode)}, func(path string, d gofs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				paths[path] = d.Type()
+				return nil
+			})
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			return paths
+		}
+
+		assert.Equal(t, map[string]gofs.FileMode{
+			"links":          gofs.ModeDir,
+			"links/dangling": gofs.ModeSymlink,
+			"links/dir":      gofs.ModeSymlink,
+			"links/file.txt": gofs.ModeSymlink,
+			"links/loop":     gofs.ModeSymlink,
+		}, walk(filesystem.ReportSymlinks))
+		assert.Equal(t, map[string]gofs.FileMode{
+			"links": gofs.ModeDir,
+		}, walk(filesystem.SkipSymlinks))
+		followed := walk(filesystem.FollowSymlinks)
+		assert.Equal(t, gofs.ModeSymlink, followed["links/dangling"])
+		assert.Equal(t, gofs.ModeDir, followed["links/dir"])
+		assert.Equal(t, gofs.FileMode(0), followed["links/dir/a.txt"])
+		assert.Equal(t, gofs.FileMode(0), followed["links/file.txt"])
+		// the loop leads to the root, walked once through it, but not back into links
+		assert.Contains(t, followed, "links/loop/data/a.txt")
+		assert.NotContains(t, followed, "links/loop/links/file.txt")
+
+		err := fs.WalkDirWithConfig("links", map[string]any{filesystem.SymlinksKey: "unknown"}, func(string, gofs.DirEntry, error) error {
+			return nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("manager", func(t *testing.T) {
+		manager := filesystem.NewFileSystemManager()
+		manager.AddFS("memory", newFS(t))
+		target, err := manager.Readlink("memory://links/dir")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "/data", target)
+	})
+}
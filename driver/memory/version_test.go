@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFileSystem_ConditionalWrite(t *testing.T) {
+	fs := NewMemoryFileSystem("public", nil)
+	var precondition *filesystem.PreconditionFailed
+
+	version, err := fs.WriteVersioned("state.json", strings.NewReader(`{"n":1}`), map[string]any{filesystem.IfAbsentKey: true})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	err = fs.Write("state.json", []byte(`{"n":2}`), map[string]any{filesystem.IfAbsentKey: true})
+	if assert.True(t, errors.As(err, &precondition)) {
+		assert.ErrorIs(t, err, os.ErrExist)
+	}
+
+	stat, err := fs.Stat("state.json")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, version, stat.Version)
+	assert.Equal(t, int64(7), stat.Size)
+
+	next, err := fs.WriteVersioned("state.json", strings.NewReader(`{"n":2}`), map[string]any{filesystem.IfMatchKey: version})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.NotEqual(t, version, next)
+	// a writer still holding the first version lost the race
+	err = fs.Write("state.json", []byte(`{"n":3}`), map[string]any{filesystem.IfMatchKey: version})
+	if assert.True(t, errors.As(err, &precondition)) {
+		assert.ErrorIs(t, err, filesystem.ErrVersionMismatch)
+	}
+	err = fs.Write("missing.json", []byte(`{}`), map[string]any{filesystem.IfMatchKey: version})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	content, err := fs.Read("state.json")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, `{"n":2}`, string(content))
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gopi-frame/env"
 
@@ -33,6 +34,12 @@ type Config struct {
 	MaxRetries         int
 	CustomMD5          func() md5simd.Hasher
 	CustomSHA256       func() md5simd.Hasher
+	// PartSize is the size of the parts of the multipart uploads of the streams of unknown size, default is 16MiB.
+	PartSize uint64
+	// AbandonedUploadAge enables the janitor, which aborts the multipart uploads older than it.
+	AbandonedUploadAge time.Duration
+	// JanitorInterval is the interval between the runs of the janitor, default is 1h.
+	JanitorInterval time.Duration
 }
 
 func (c *Config) Apply(fs *MinioFileSystem) error {
@@ -60,6 +67,15 @@ func (c *Config) Apply(fs *MinioFileSystem) error {
 		return err
 	}
 	fs.client = client
+	if c.PartSize > 0 {
+		fs.partSize = c.PartSize
+	}
+	if c.AbandonedUploadAge > 0 {
+		fs.abandonedAfter = c.AbandonedUploadAge
+	}
+	if c.JanitorInterval > 0 {
+		fs.janitorInterval = c.JanitorInterval
+	}
 	return nil
 }
 
@@ -94,6 +110,7 @@ func ConfigFromMap(m map[string]any) (*Config, error) {
 				}
 				return data, nil
 			},
+			mapstructure.StringToTimeDurationHookFunc(),
 			mapstructure.StringToBasicTypeHookFunc(),
 		),
 	})
@@ -0,0 +1,27 @@
+package minio
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Create returns a writer to the object, its content is streamed as the parts of a multipart upload,
+// completed on Close. Aborting the writer aborts the multipart upload, the object is left as it was.
+func (m *MinioFileSystem) Create(path string, config map[string]any) (filesystem.FileWriter, error) {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return nil, filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+	}
+	if config != nil {
+		if _, err := filesystem.NewConfig(config); err != nil {
+			return nil, filesystem.NewUnableToWriteFile(path, err)
+		}
+	}
+	// the client aborts the multipart upload when the stream fails, nothing is left to discard
+	return filesystem.NewPipeWriter(func(stream io.Reader) error {
+		return m.WriteStream(path, stream, config)
+	}, nil), nil
+}
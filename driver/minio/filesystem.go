@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	gofs "io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -24,11 +26,25 @@ type MinioFileSystem struct {
 	client           *minio.Client
 	bucket           string
 	mimeTypeDetector fs.MimeTypeDetector
+	partSize         uint64
+
+	janitorInterval time.Duration
+	abandonedAfter  time.Duration
+	done            chan struct{}
+	closeOnce       sync.Once
+	wg              sync.WaitGroup
 }
 
+// NewMinioFileSystem creates a new minio file system.
+//
+// If abandoned multipart uploads are cleaned up, see [WithJanitor],
+// a background worker runs periodically, call [MinioFileSystem.Close] to stop it.
 func NewMinioFileSystem(opts ...Option) (*MinioFileSystem, error) {
 	f := new(MinioFileSystem)
 	f.mimeTypeDetector = filesystem.NewMimeTypeDetector()
+	f.partSize = DefaultPartSize
+	f.janitorInterval = defaultJanitorInterval
+	f.done = make(chan struct{})
 	for _, opt := range opts {
 		if err := opt.Apply(f); err != nil {
 			return nil, err
@@ -37,6 +53,10 @@ func NewMinioFileSystem(opts ...Option) (*MinioFileSystem, error) {
 	if f.client == nil {
 		return nil, errors.New("minio client is required")
 	}
+	if f.abandonedAfter > 0 {
+		f.wg.Add(1)
+		go f.runJanitor()
+	}
 	return f, nil
 }
 
@@ -109,6 +129,66 @@ func (m *MinioFileSystem) ReadStream(path string) (io.ReadCloser, error) {
 	return resp, nil
 }
 
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the stream is read.
+func (m *MinioFileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	stream, err := m.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	progress := cfg.Progress()
+	if progress == nil {
+		return stream, nil
+	}
+	var size int64 = -1
+	if object, ok := stream.(*minio.Object); ok {
+		if info, err := object.Stat(); err == nil {
+			size = info.Size
+		}
+	} else if info, err := m.client.StatObject(context.Background(), m.bucket, filepath.ToSlash(path), minio.StatObjectOptions{}); err == nil {
+		size = info.Size
+	}
+	return filesystem.NewProgressTracker(path, size, progress).ReadCloser(stream), nil
+}
+
+// ReadRange returns length bytes of the file starting at offset, with a Range request.
+// A range starting past the end of the file is empty.
+func (m *MinioFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	path = filepath.ToSlash(path)
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if strings.HasSuffix(path, "/") {
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += fmt.Sprint(offset + length - 1)
+	}
+	var opts minio.GetObjectOptions
+	// SetRange cannot express a range from 0 to the end of the file
+	opts.Set("Range", byteRange)
+	object, err := m.client.GetObject(context.Background(), m.bucket, path, opts)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	// the request is sent lazily, stat it so that errors are returned here
+	if _, err := object.Stat(); err != nil {
+		_ = object.Close()
+		if isStatus(err, http.StatusRequestedRangeNotSatisfiable) {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return object, nil
+}
+
 func (m *MinioFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
 	path = filepath.ToSlash(path)
 	if !strings.HasSuffix(path, "/") {
@@ -168,6 +248,9 @@ func (m *MinioFileSystem) LastModified(path string) (time.Time, error) {
 	if err != nil {
 		return time.Time{}, filesystem.NewUnableToCheckExistence(path, err)
 	}
+	if mtime, ok := filesystem.MtimeFromMetadata(object.UserMetadata); ok {
+		return mtime, nil
+	}
 	return object.LastModified, nil
 }
 
@@ -210,20 +293,61 @@ func (m *MinioFileSystem) Write(path string, content []byte, config map[string]a
 	return m.WriteStream(path, bytes.NewReader(content), config)
 }
 
-func (m *MinioFileSystem) WriteStream(path string, stream io.Reader, _ map[string]any) error {
+func (m *MinioFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	_, err := m.WriteVersioned(path, stream, config)
+	return err
+}
+
+// WriteVersioned writes like WriteStream, and returns the ETag of the written object as its version.
+//
+// The conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey]
+// are sent as the If-None-Match and If-Match headers of the PutObject request.
+func (m *MinioFileSystem) WriteVersioned(path string, stream io.Reader, config map[string]any) (string, error) {
 	path = filepath.ToSlash(path)
 	if strings.HasSuffix(path, "/") {
-		return filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+		return "", filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+	}
+	var opts minio.PutObjectOptions
+	var ifAbsent, ifMatch bool
+	var cfg *filesystem.Config
+	if config != nil {
+		var err error
+		cfg, err = filesystem.NewConfig(config)
+		if err != nil {
+			return "", filesystem.NewUnableToWriteFile(path, err)
+		}
+		if cfg.IfAbsent != nil && *cfg.IfAbsent {
+			ifAbsent = true
+			opts.SetMatchETagExcept("*")
+		}
+		if cfg.IfMatch != nil {
+			ifMatch = true
+			opts.SetMatchETag(*cfg.IfMatch)
+		}
+		opts.UserMetadata = cfg.Metadata
+		opts.UserTags = cfg.Tags
 	}
-	var size int64 = -1
-	if sizer, ok := stream.(interface{ Size() int64 }); ok {
+	var size = cfg.Size()
+	if sizer, ok := stream.(interface{ Size() int64 }); ok && size < 0 {
 		size = sizer.Size()
 	}
-	_, err := m.client.PutObject(context.Background(), m.bucket, path, stream, size, minio.PutObjectOptions{})
+	if size < 0 {
+		// without it, a stream of unknown size is buffered in parts sized for the largest possible object
+		opts.PartSize = m.partSize
+	}
+	tracker := filesystem.NewProgressTracker(path, size, cfg.Progress())
+	if tracker != nil {
+		opts.Progress = &progressReader{tracker: tracker}
+	}
+	resp, err := m.client.PutObject(context.Background(), m.bucket, path, stream, size, opts)
 	if err != nil {
-		return filesystem.NewUnableToWriteFile(path, err)
+		if err := preconditionFailed(path, err, ifAbsent, ifMatch); err != nil {
+			return "", err
+		}
+		return "", filesystem.NewUnableToWriteFile(path, err)
 	}
-	return nil
+	tracker.Done()
+	return resp.ETag, nil
 }
 
 func (m *MinioFileSystem) SetVisibility(_ string, _ string) error {
@@ -292,7 +416,7 @@ func (m *MinioFileSystem) Move(src string, dst string, _ map[string]any) error {
 	return nil
 }
 
-func (m *MinioFileSystem) Copy(src string, dst string, _ map[string]any) error {
+func (m *MinioFileSystem) Copy(src string, dst string, config map[string]any) error {
 	src = filepath.ToSlash(src)
 	dst = filepath.ToSlash(dst)
 	if strings.HasSuffix(src, "/") {
@@ -301,7 +425,11 @@ func (m *MinioFileSystem) Copy(src string, dst string, _ map[string]any) error {
 	if strings.HasSuffix(dst, "/") {
 		return filesystem.NewUnableToCheckExistence(dst, filesystem.ErrIsNotFile)
 	}
-	_, err := m.client.CopyObject(context.Background(), minio.CopyDestOptions{
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	_, err = m.client.CopyObject(context.Background(), minio.CopyDestOptions{
 		Bucket: m.bucket,
 		Object: dst,
 	}, minio.CopySrcOptions{
@@ -311,5 +439,15 @@ func (m *MinioFileSystem) Copy(src string, dst string, _ map[string]any) error {
 	if err != nil {
 		return filesystem.NewUnableToCopyFile(src, dst, err)
 	}
+	if progress := cfg.Progress(); progress != nil {
+		// the object is copied by the server in a single request, there is a single report
+		size, err := m.FileSize(dst)
+		if err != nil {
+			size = -1
+		}
+		tracker := filesystem.NewProgressTracker(dst, size, progress)
+		tracker.Add(max(size, 0))
+		tracker.Done()
+	}
 	return nil
 }
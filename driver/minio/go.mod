@@ -31,3 +31,5 @@ require (
 	golang.org/x/sys v0.27.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
 )
+
+replace github.com/gopi-frame/filesystem => ../..
@@ -0,0 +1,54 @@
+package minio
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultJanitorInterval = time.Hour
+
+// CleanIncompleteUploads aborts the uploads initiated more than olderThan ago,
+// i.e. abandoned by processes which crashed or gave up, and returns how many were aborted.
+// Their parts are kept by the server until they are aborted.
+func (m *MinioFileSystem) CleanIncompleteUploads(olderThan time.Duration) (int, error) {
+	uploads, err := m.ListIncomplete("")
+	if err != nil {
+		return 0, err
+	}
+	var aborted int
+	var errs []error
+	for _, upload := range uploads {
+		if time.Since(upload.Initiated) < olderThan {
+			continue
+		}
+		if err := m.Abort(upload); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		aborted++
+	}
+	return aborted, errors.Join(errs...)
+}
+
+// Close stops the janitor.
+func (m *MinioFileSystem) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	m.wg.Wait()
+	return nil
+}
+
+func (m *MinioFileSystem) runJanitor() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			_, _ = m.CleanIncompleteUploads(m.abandonedAfter)
+		}
+	}
+}
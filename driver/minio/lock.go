@@ -0,0 +1,174 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Lock acquires the lock of the path, waiting until it is released or its lease expires.
+func (m *MinioFileSystem) Lock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	return m.LockContext(context.Background(), path, ttl)
+}
+
+// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+func (m *MinioFileSystem) LockContext(ctx context.Context, path string, ttl time.Duration) (filesystem.Lock, error) {
+	return filesystem.WaitForLock(ctx, func() (filesystem.Lock, error) {
+		return m.TryLock(path, ttl)
+	})
+}
+
+// TryLock acquires the lock of the path, or returns a [filesystem.LockHeld] error if it is held.
+//
+// The lock is an object named after the path with [filesystem.LockSuffix], holding the ownership metadata of the lock.
+// It is created with If-None-Match, so that only one writer creates it,
+// and a lock whose lease expired is taken over with If-Match on the expired object,
+// so that only one of the processes recovering it succeeds.
+func (m *MinioFileSystem) TryLock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	path = filepath.ToSlash(path)
+	key := path + filesystem.LockSuffix
+	for {
+		info := filesystem.NewLockInfo(ttl)
+		opts := minio.PutObjectOptions{}
+		opts.SetMatchETagExcept("*")
+		etag, err := m.putLock(key, info, opts)
+		if err == nil {
+			return &lock{m: m, path: path, key: key, info: info, etag: etag}, nil
+		}
+		if !isStatus(err, http.StatusPreconditionFailed, http.StatusConflict) {
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		holder, holderETag, err := m.readLock(key)
+		if err != nil {
+			if isStatus(err, http.StatusNotFound) {
+				// released meanwhile
+				continue
+			}
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		if !holder.Expired() {
+			return nil, filesystem.NewLockHeld(path, holder)
+		}
+		opts = minio.PutObjectOptions{}
+		opts.SetMatchETag(holderETag)
+		etag, err = m.putLock(key, info, opts)
+		if err == nil {
+			return &lock{m: m, path: path, key: key, info: info, etag: etag}, nil
+		}
+		if !isStatus(err, http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound) {
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		// another process took the expired lock over, or released it
+	}
+}
+
+// putLock writes the metadata to the lock object with the conditions of the options, and returns the new ETag.
+func (m *MinioFileSystem) putLock(key string, info filesystem.LockInfo, opts minio.PutObjectOptions) (string, error) {
+	content := info.Bytes()
+	opts.ContentType = "application/json"
+	resp, err := m.client.PutObject(context.Background(), m.bucket, key, bytes.NewReader(content), int64(len(content)), opts)
+	if err != nil {
+		return "", err
+	}
+	return resp.ETag, nil
+}
+
+func (m *MinioFileSystem) readLock(key string) (filesystem.LockInfo, string, error) {
+	obj, err := m.client.GetObject(context.Background(), m.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return filesystem.LockInfo{}, "", err
+	}
+	defer obj.Close()
+	stat, err := obj.Stat()
+	if err != nil {
+		return filesystem.LockInfo{}, "", err
+	}
+	content, err := io.ReadAll(obj)
+	if err != nil {
+		return filesystem.LockInfo{}, "", err
+	}
+	return filesystem.ParseLockInfo(content, stat.LastModified), stat.ETag, nil
+}
+
+func isStatus(err error, codes ...int) bool {
+	status := minio.ToErrorResponse(err).StatusCode
+	for _, code := range codes {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+type lock struct {
+	mu       sync.Mutex
+	m        *MinioFileSystem
+	path     string
+	key      string
+	info     filesystem.LockInfo
+	etag     string
+	released bool
+}
+
+func (l *lock) Path() string {
+	return l.path
+}
+
+func (l *lock) Info() filesystem.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+// Refresh extends the lease with If-Match on the lock object, which fails if the lock was taken over.
+func (l *lock) Refresh(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return filesystem.ErrLockLost
+	}
+	info := l.info.Renew(ttl)
+	opts := minio.PutObjectOptions{}
+	opts.SetMatchETag(l.etag)
+	etag, err := l.m.putLock(l.key, info, opts)
+	if err != nil {
+		if isStatus(err, http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound) {
+			return filesystem.ErrLockLost
+		}
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.info, l.etag = info, etag
+	return nil
+}
+
+// Unlock removes the lock object, after checking it was not taken over.
+func (l *lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return filesystem.ErrLockLost
+	}
+	stat, err := l.m.client.StatObject(context.Background(), l.m.bucket, l.key, minio.StatObjectOptions{})
+	if err != nil {
+		if isStatus(err, http.StatusNotFound) {
+			return filesystem.ErrLockLost
+		}
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	if stat.ETag != l.etag {
+		return filesystem.ErrLockLost
+	}
+	if err := l.m.client.RemoveObject(context.Background(), l.m.bucket, l.key, minio.RemoveObjectOptions{}); err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.released = true
+	return nil
+}
@@ -0,0 +1,82 @@
+package minio
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Metadata returns the user metadata of the object, sent as its x-amz-meta-* headers.
+// The modification time set with SetLastModified is not part of it.
+func (m *MinioFileSystem) Metadata(path string) (map[string]string, error) {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrIsNotFile)
+	}
+	info, err := m.client.StatObject(context.Background(), m.bucket, path, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	metadata := filesystem.CloneMetadata(info.UserMetadata)
+	deleteMtime(metadata)
+	return metadata, nil
+}
+
+// SetMetadata replaces the user metadata of the object, keeping the modification time set with SetLastModified.
+// The metadata can't be changed in place, the object is copied onto itself.
+func (m *MinioFileSystem) SetMetadata(path string, metadata map[string]string) error {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return filesystem.NewUnableToSetMetadata(path, filesystem.ErrIsNotFile)
+	}
+	err := m.replaceMetadata(path, func(current map[string]string) {
+		for key := range current {
+			if !strings.EqualFold(key, filesystem.MtimeMetadataKey) {
+				delete(current, key)
+			}
+		}
+		for key, value := range metadata {
+			current[key] = value
+		}
+	})
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// Tags returns the tags of the object.
+func (m *MinioFileSystem) Tags(path string) (map[string]string, error) {
+	path = filepath.ToSlash(path)
+	objectTags, err := m.client.GetObjectTagging(context.Background(), m.bucket, path, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return objectTags.ToMap(), nil
+}
+
+// SetTags replaces the tags of the object, up to 10 tags per object.
+func (m *MinioFileSystem) SetTags(path string, tagMap map[string]string) error {
+	path = filepath.ToSlash(path)
+	if len(tagMap) == 0 {
+		err := m.client.RemoveObjectTagging(context.Background(), m.bucket, path, minio.RemoveObjectTaggingOptions{})
+		if err != nil {
+			return filesystem.NewUnableToSetMetadata(path, err)
+		}
+		return nil
+	}
+	objectTags, err := tags.NewTags(tagMap, true)
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	err = m.client.PutObjectTagging(context.Background(), m.bucket, path, objectTags, minio.PutObjectTaggingOptions{})
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
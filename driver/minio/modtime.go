@@ -0,0 +1,76 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time of the object to now, creating an empty object if it is missing.
+func (m *MinioFileSystem) Touch(path string) error {
+	exists, err := m.FileExists(path)
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	if !exists {
+		return m.WriteStream(path, bytes.NewReader(nil), nil)
+	}
+	return m.SetLastModified(path, time.Now())
+}
+
+// SetLastModified records the modification time in the "mtime" metadata of the object,
+// which LastModified reports instead of the time the object was stored.
+// The metadata can't be changed in place, the object is copied onto itself, keeping its content type and metadata.
+func (m *MinioFileSystem) SetLastModified(path string, t time.Time) error {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return filesystem.NewUnableToSetLastModified(path, filesystem.ErrIsNotFile)
+	}
+	err := m.replaceMetadata(path, func(metadata map[string]string) {
+		deleteMtime(metadata)
+		metadata[filesystem.MtimeMetadataKey] = filesystem.FormatMtime(t)
+	})
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
+
+// replaceMetadata copies the object onto itself with its user metadata changed by update,
+// keeping its content type and tags.
+func (m *MinioFileSystem) replaceMetadata(path string, update func(metadata map[string]string)) error {
+	info, err := m.client.StatObject(context.Background(), m.bucket, path, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	metadata := filesystem.CloneMetadata(info.UserMetadata)
+	update(metadata)
+	if info.ContentType != "" {
+		metadata["Content-Type"] = info.ContentType
+	}
+	_, err = m.client.CopyObject(context.Background(), minio.CopyDestOptions{
+		Bucket:          m.bucket,
+		Object:          path,
+		UserMetadata:    metadata,
+		ReplaceMetadata: true,
+	}, minio.CopySrcOptions{
+		Bucket: m.bucket,
+		Object: path,
+	})
+	return err
+}
+
+// deleteMtime deletes the modification time from the metadata, whatever the case of its key.
+func deleteMtime(metadata map[string]string) {
+	for key := range metadata {
+		if strings.EqualFold(key, filesystem.MtimeMetadataKey) {
+			delete(metadata, key)
+		}
+	}
+}
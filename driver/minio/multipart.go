@@ -0,0 +1,172 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+const (
+	// MinPartSize is the minimum size of the parts of a multipart upload, but the last one.
+	MinPartSize = 5 << 20
+	// MaxParts is the maximum number of parts of a multipart upload.
+	MaxParts = 10000
+)
+
+// Upload is a multipart upload session.
+//
+// It can be persisted, e.g. as JSON, to resume the upload after a failure or in another process:
+// [MinioFileSystem.ListParts] returns the parts already uploaded.
+type Upload struct {
+	Path      string    `json:"path"`
+	UploadID  string    `json:"upload_id"`
+	Initiated time.Time `json:"initiated"`
+}
+
+// UploadedPart is an uploaded part of a multipart upload.
+type UploadedPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// core exposes the multipart API, which the client runs internally.
+func (m *MinioFileSystem) core() minio.Core {
+	return minio.Core{Client: m.client}
+}
+
+// BeginUpload starts a multipart upload to the path.
+// The metadata and tags of the config are applied to the object once the upload is complete.
+func (m *MinioFileSystem) BeginUpload(path string, config map[string]any) (*Upload, error) {
+	path = filepath.ToSlash(path)
+	var opts minio.PutObjectOptions
+	if config != nil {
+		cfg, err := filesystem.NewConfig(config)
+		if err != nil {
+			return nil, filesystem.NewUnableToWriteFile(path, err)
+		}
+		opts.UserMetadata = cfg.Metadata
+		opts.UserTags = cfg.Tags
+	}
+	uploadID, err := m.core().NewMultipartUpload(context.Background(), m.bucket, path, opts)
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(path, err)
+	}
+	return &Upload{Path: path, UploadID: uploadID, Initiated: time.Now()}, nil
+}
+
+// UploadPart uploads a part of the upload, numbered from 1 to [MaxParts].
+// Every part but the last must be at least [MinPartSize] bytes.
+// Uploading a part again with the same number replaces it.
+func (m *MinioFileSystem) UploadPart(upload *Upload, number int, content io.Reader) (*UploadedPart, error) {
+	if number < 1 || number > MaxParts {
+		return nil, filesystem.NewUnableToWriteFile(upload.Path, fmt.Errorf("part number must be between 1 and %d", MaxParts))
+	}
+	// the size of a part is sent before its content
+	buf, err := io.ReadAll(content)
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(upload.Path, err)
+	}
+	part, err := m.core().PutObjectPart(context.Background(), m.bucket, upload.Path, upload.UploadID, number,
+		bytes.NewReader(buf), int64(len(buf)), minio.PutObjectPartOptions{})
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(upload.Path, err)
+	}
+	return &UploadedPart{Number: number, ETag: part.ETag, Size: int64(len(buf))}, nil
+}
+
+// ListParts returns the parts of the upload uploaded so far, ordered by number.
+func (m *MinioFileSystem) ListParts(upload *Upload) ([]UploadedPart, error) {
+	var parts []UploadedPart
+	var marker int
+	for {
+		result, err := m.core().ListObjectParts(context.Background(), m.bucket, upload.Path, upload.UploadID, marker, 0)
+		if err != nil {
+			return nil, filesystem.NewUnableToRetrieveMetadata(upload.Path, err)
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, UploadedPart{Number: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// Complete assembles the uploaded parts into the file, and returns its version.
+// The conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey] of the config are checked on completion.
+func (m *MinioFileSystem) Complete(upload *Upload, config map[string]any) (string, error) {
+	var opts minio.PutObjectOptions
+	var ifAbsent, ifMatch bool
+	if config != nil {
+		cfg, err := filesystem.NewConfig(config)
+		if err != nil {
+			return "", filesystem.NewUnableToWriteFile(upload.Path, err)
+		}
+		if cfg.IfAbsent != nil && *cfg.IfAbsent {
+			ifAbsent = true
+			opts.SetMatchETagExcept("*")
+		}
+		if cfg.IfMatch != nil {
+			ifMatch = true
+			opts.SetMatchETag(*cfg.IfMatch)
+		}
+	}
+	parts, err := m.ListParts(upload)
+	if err != nil {
+		return "", err
+	}
+	completed := make([]minio.CompletePart, len(parts))
+	for i, part := range parts {
+		completed[i] = minio.CompletePart{PartNumber: part.Number, ETag: part.ETag}
+	}
+	info, err := m.core().CompleteMultipartUpload(context.Background(), m.bucket, upload.Path, upload.UploadID, completed, opts)
+	if err != nil {
+		if err := preconditionFailed(upload.Path, err, ifAbsent, ifMatch); err != nil {
+			return "", err
+		}
+		return "", filesystem.NewUnableToWriteFile(upload.Path, err)
+	}
+	return info.ETag, nil
+}
+
+// Abort aborts the upload and deletes its parts.
+func (m *MinioFileSystem) Abort(upload *Upload) error {
+	if err := m.core().AbortMultipartUpload(context.Background(), m.bucket, upload.Path, upload.UploadID); err != nil {
+		return filesystem.NewUnableToDeleteFile(upload.Path, err)
+	}
+	return nil
+}
+
+// ListIncomplete returns the uploads under the prefix which were neither completed nor aborted.
+func (m *MinioFileSystem) ListIncomplete(prefix string) ([]*Upload, error) {
+	prefix = filepath.ToSlash(prefix)
+	var uploads []*Upload
+	var keyMarker, uploadIDMarker string
+	for {
+		result, err := m.core().ListMultipartUploads(context.Background(), m.bucket, prefix, keyMarker, uploadIDMarker, "", 0)
+		if err != nil {
+			return nil, filesystem.NewUnableToReadDirectory(prefix, err)
+		}
+		for _, upload := range result.Uploads {
+			uploads = append(uploads, &Upload{
+				Path:      upload.Key,
+				UploadID:  upload.UploadID,
+				Initiated: upload.Initiated,
+			})
+		}
+		if !result.IsTruncated {
+			return uploads, nil
+		}
+		keyMarker = result.NextKeyMarker
+		uploadIDMarker = result.NextUploadIDMarker
+	}
+}
@@ -0,0 +1,220 @@
+package minio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// the tests run against the S3 mock of docker-compose.yml
+func newMultipartFS(t *testing.T, opts ...Option) *MinioFileSystem {
+	cfg := &Config{
+		Endpoint:        "localhost:19090",
+		Bucket:          "mock",
+		AccessKeyID:     "mock-access-key-id",
+		SecretAccessKey: "mock-secret-access-key",
+	}
+	fs, err := NewMinioFileSystem(append([]Option{cfg}, opts...)...)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	t.Cleanup(func() {
+		_ = fs.Close()
+	})
+	return fs
+}
+
+func TestMinioFileSystem_Upload(t *testing.T) {
+	t.Run("resume", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		upload, err := fs.BeginUpload("testdata/multipart/resumed.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		first := bytes.Repeat([]byte("a"), MinPartSize)
+		if _, err := fs.UploadPart(upload, 1, bytes.NewReader(first)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+
+		// another process resumes the upload
+		uploads, err := fs.ListIncomplete("testdata/multipart/")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var resumed *Upload
+		for _, u := range uploads {
+			if u.UploadID == upload.UploadID {
+				resumed = u
+			}
+		}
+		if !assert.NotNil(t, resumed) {
+			return
+		}
+		parts, err := fs.ListParts(resumed)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, parts, 1)
+		if _, err := fs.UploadPart(resumed, 2, bytes.NewReader([]byte("b"))); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := fs.Complete(resumed, nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		size, err := fs.FileSize("testdata/multipart/resumed.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(MinPartSize+1), size)
+	})
+
+	t.Run("complete if absent", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		if err := fs.Write("testdata/multipart/existing.bin", []byte("existing"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		upload, err := fs.BeginUpload("testdata/multipart/existing.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := fs.UploadPart(upload, 1, bytes.NewReader([]byte("new"))); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = fs.Complete(upload, map[string]any{filesystem.IfAbsentKey: true})
+		var precondition *filesystem.PreconditionFailed
+		assert.ErrorAs(t, err, &precondition)
+		assert.NoError(t, fs.Abort(upload))
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		upload, err := fs.BeginUpload("testdata/multipart/aborted.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := fs.UploadPart(upload, 1, bytes.NewReader([]byte("a"))); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := fs.Abort(upload); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		uploads, err := fs.ListIncomplete("testdata/multipart/aborted.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, uploads)
+		exists, err := fs.FileExists("testdata/multipart/aborted.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("janitor", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		upload, err := fs.BeginUpload("testdata/multipart/abandoned.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		aborted, err := fs.CleanIncompleteUploads(0)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.GreaterOrEqual(t, aborted, 1)
+		uploads, err := fs.ListIncomplete("testdata/multipart/")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for _, u := range uploads {
+			assert.NotEqual(t, upload.UploadID, u.UploadID)
+		}
+	})
+}
+
+func TestMinioFileSystem_UploadPart(t *testing.T) {
+	// the part number is checked before any request
+	fs := newMultipartFS(t)
+	upload := &Upload{Path: "testdata/multipart/invalid.bin", UploadID: "invalid"}
+	_, err := fs.UploadPart(upload, 0, bytes.NewReader(nil))
+	assert.Error(t, err)
+	_, err = fs.UploadPart(upload, MaxParts+1, bytes.NewReader(nil))
+	assert.Error(t, err)
+}
+
+func TestMinioFileSystem_Close(t *testing.T) {
+	fs := newMultipartFS(t, WithJanitor(time.Hour, 24*time.Hour))
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		assert.NoError(t, fs.Close())
+		// closing twice is a no-op
+		assert.NoError(t, fs.Close())
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the janitor did not stop")
+	}
+}
+
+func TestConfigFromMap_Janitor(t *testing.T) {
+	cfg, err := ConfigFromMap(map[string]any{
+		"abandoned_upload_age": "24h",
+		"janitor-interval":     "30m",
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 24*time.Hour, cfg.AbandonedUploadAge)
+	assert.Equal(t, 30*time.Minute, cfg.JanitorInterval)
+}
+
+func TestMinioFileSystem_Create(t *testing.T) {
+	t.Run("close", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		content := bytes.Repeat([]byte("0123456789"), MinPartSize/5)
+		w, err := fs.Create("testdata/multipart/created.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for offset := 0; offset < len(content); offset += 1 << 20 {
+			if _, err := w.Write(content[offset:min(offset+1<<20, len(content))]); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+		if err := w.Close(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		read, err := fs.Read("testdata/multipart/created.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, bytes.Equal(content, read))
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		w, err := fs.Create("testdata/multipart/aborted.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write(make([]byte, MinPartSize+1)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		exists, err := fs.FileExists("testdata/multipart/aborted.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		uploads, err := fs.ListIncomplete("testdata/multipart/aborted.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, uploads)
+	})
+}
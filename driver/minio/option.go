@@ -1,6 +1,8 @@
 package minio
 
 import (
+	"time"
+
 	"github.com/gopi-frame/contract"
 	fs "github.com/gopi-frame/contract/filesystem"
 	"github.com/minio/minio-go/v7"
@@ -44,3 +46,32 @@ func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
 		return nil
 	})
 }
+
+// DefaultPartSize is the default size of the parts of the multipart uploads of the streams of unknown size.
+const DefaultPartSize = 16 << 20
+
+// WithPartSize sets the size of the parts of the multipart uploads of the streams of unknown size,
+// e.g. written with Create, the minimum is 5MiB.
+func WithPartSize(size uint64) Option {
+	if size == 0 {
+		return noneOption
+	}
+	return OptionFunc(func(fs *MinioFileSystem) error {
+		fs.partSize = size
+		return nil
+	})
+}
+
+// WithJanitor enables the janitor, which aborts every interval the multipart uploads older than olderThan
+func WithJanitor(interval, olderThan time.Duration) Option {
+	if olderThan <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(fs *MinioFileSystem) error {
+		if interval > 0 {
+			fs.janitorInterval = interval
+		}
+		fs.abandonedAfter = olderThan
+		return nil
+	})
+}
@@ -0,0 +1,14 @@
+package minio
+
+import "github.com/gopi-frame/filesystem"
+
+// progressReader counts the bytes uploaded by the client,
+// which reads the chunks it has sent from its progress reader.
+type progressReader struct {
+	tracker *filesystem.ProgressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	r.tracker.Add(int64(len(p)))
+	return len(p), nil
+}
@@ -0,0 +1,22 @@
+package minio
+
+import (
+	"os"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Symlink fails with [filesystem.ErrSymlinkUnsupported], objects cannot be links.
+func (m *MinioFileSystem) Symlink(_, link string) error {
+	return filesystem.NewUnableToCreateSymlink(link, filesystem.ErrSymlinkUnsupported)
+}
+
+// Readlink fails with [filesystem.ErrSymlinkUnsupported], objects cannot be links.
+func (m *MinioFileSystem) Readlink(path string) (string, error) {
+	return "", filesystem.NewUnableToReadSymlink(path, filesystem.ErrSymlinkUnsupported)
+}
+
+// Lstat fails with [filesystem.ErrSymlinkUnsupported], objects cannot be links.
+func (m *MinioFileSystem) Lstat(path string) (os.FileInfo, error) {
+	return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrSymlinkUnsupported)
+}
@@ -0,0 +1,46 @@
+package minio
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Stat returns the metadata of the object, with its ETag as its version.
+func (m *MinioFileSystem) Stat(path string) (*filesystem.FileStat, error) {
+	path = filepath.ToSlash(path)
+	info, err := m.client.StatObject(context.Background(), m.bucket, path, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	lastModified := info.LastModified
+	if mtime, ok := filesystem.MtimeFromMetadata(info.UserMetadata); ok {
+		lastModified = mtime
+	}
+	return &filesystem.FileStat{
+		Path:         path,
+		Size:         info.Size,
+		LastModified: lastModified,
+		Version:      info.ETag,
+	}, nil
+}
+
+// preconditionFailed returns a [filesystem.PreconditionFailed] error if err is the failure of the conditions of a write,
+// or nil.
+func preconditionFailed(path string, err error, ifAbsent, ifMatch bool) error {
+	switch {
+	case ifAbsent && isStatus(err, http.StatusPreconditionFailed, http.StatusConflict):
+		return filesystem.NewPreconditionFailed(path, errors.Join(os.ErrExist, err))
+	case ifMatch && isStatus(err, http.StatusPreconditionFailed, http.StatusConflict):
+		return filesystem.NewPreconditionFailed(path, errors.Join(filesystem.ErrVersionMismatch, err))
+	case ifMatch && isStatus(err, http.StatusNotFound):
+		return filesystem.NewPreconditionFailed(path, errors.Join(os.ErrNotExist, err))
+	}
+	return nil
+}
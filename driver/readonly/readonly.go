@@ -99,3 +99,9 @@ func (r *ReadOnlyFileSystem) Move(src string, dst string, config map[string]any)
 func (r *ReadOnlyFileSystem) Copy(src string, dst string, config map[string]any) error {
 	return filesystem.NewUnableToCopyFile(src, dst, ErrReadOnly)
 }
+
+// ReadOnly reports that the file system can't be written,
+// for the adapters which refuse the writes up front, e.g. the WebDAV handler.
+func (r *ReadOnlyFileSystem) ReadOnly() bool {
+	return true
+}
@@ -0,0 +1,85 @@
+package replicate
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Config struct {
+	// Primary is the file system every operation is applied to first, and the one reads are served from.
+	Primary fs.FileSystem
+	// Replicas are the file systems writes are fanned out to.
+	Replicas []fs.FileSystem
+	// Consistency is one of "all", "quorum" or "async", default is "all".
+	Consistency Consistency
+	// Quorum is the number of copies (primary included) that must be written
+	// when Consistency is "quorum", default is a majority of all copies.
+	Quorum int
+	// QueueDir is the local directory of the durable retry queue.
+	// If empty, failed replications are not retried, and Consistency cannot be "async".
+	QueueDir string
+	// ReplicaBuffer is the size in bytes of the buffer every replica reads a write from, default is 4MiB.
+	ReplicaBuffer int
+	// RetryInterval is the interval between two passes of the retry queue, default is 30s.
+	RetryInterval time.Duration
+	// Prune makes Reconcile delete files which exist on a replica but not on the primary.
+	Prune bool
+	// ErrorHandler is called for every failed replica operation.
+	ErrorHandler ErrorHandler
+}
+
+func (c *Config) Apply(r *ReplicatedFileSystem) error {
+	if c.Consistency != "" {
+		r.consistency = c.Consistency
+	}
+	if c.Quorum > 0 {
+		r.quorum = c.Quorum
+	}
+	if c.QueueDir != "" {
+		queue, err := NewFileQueue(c.QueueDir)
+		if err != nil {
+			return err
+		}
+		r.queue = queue
+	}
+	if c.ReplicaBuffer > 0 {
+		r.replicaBuffer = c.ReplicaBuffer
+	}
+	if c.RetryInterval > 0 {
+		r.retryInterval = c.RetryInterval
+	}
+	r.prune = c.Prune
+	if c.ErrorHandler != nil {
+		r.errorHandler = c.ErrorHandler
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
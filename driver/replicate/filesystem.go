@@ -0,0 +1,572 @@
+package replicate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"sync"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Consistency decides how many replicas must acknowledge a write before it returns.
+type Consistency string
+
+const (
+	// ConsistencyAll waits for the primary and every replica.
+	ConsistencyAll Consistency = "all"
+	// ConsistencyQuorum waits for the primary and enough replicas to reach the quorum.
+	ConsistencyQuorum Consistency = "quorum"
+	// ConsistencyAsync only waits for the primary, replicas are updated in the background from the retry queue.
+	ConsistencyAsync Consistency = "async"
+)
+
+const defaultRetryInterval = 30 * time.Second
+
+// defaultReplicaBuffer is the size of the buffer of every replica during a write.
+const defaultReplicaBuffer = 4 << 20
+
+var ErrNoPrimary = errors.New("primary file system is required")
+var ErrNoQueue = errors.New("async consistency requires a retry queue")
+var ErrInsufficientReplicas = errors.New("not enough replicas acknowledged the operation")
+
+// ErrorHandler is called every time an operation fails on a replica.
+// The replica is the index in the replicas passed to [NewReplicatedFileSystem].
+type ErrorHandler func(replica int, task *Task, err error)
+
+// ReplicatedFileSystem is a wrapper which applies every write to a primary file system
+// and fans it out to a list of replicas.
+//
+// Reads are always served by the primary.
+// Replica failures are reported to the error handler and, when a retry queue is configured,
+// pushed to it so that a background worker or [ReplicatedFileSystem.Reconcile] repairs them later.
+type ReplicatedFileSystem struct {
+	primary  fs.FileSystem
+	replicas []fs.FileSystem
+
+	consistency   Consistency
+	quorum        int
+	queue         Queue
+	replicaBuffer int
+	retryInterval time.Duration
+	prune         bool
+	errorHandler  ErrorHandler
+
+	drainMu   sync.Mutex
+	wake      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewReplicatedFileSystem creates a replicated file system.
+//
+// A background worker draining the retry queue is started when a queue is configured.
+// The consistency [ConsistencyAsync] requires a queue, since the replicas are only updated from it:
+// a [FileQueue] keeps the pending replications across restarts, a [MemoryQueue] loses them.
+// Call [ReplicatedFileSystem.Close] to stop the worker.
+func NewReplicatedFileSystem(primary fs.FileSystem, replicas []fs.FileSystem, opts ...Option) (*ReplicatedFileSystem, error) {
+	if primary == nil {
+		return nil, ErrNoPrimary
+	}
+	r := &ReplicatedFileSystem{
+		primary:       primary,
+		replicas:      replicas,
+		consistency:   ConsistencyAll,
+		replicaBuffer: defaultReplicaBuffer,
+		retryInterval: defaultRetryInterval,
+		errorHandler:  func(int, *Task, error) {},
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(r); err != nil {
+			return nil, err
+		}
+	}
+	switch r.consistency {
+	case ConsistencyAll, ConsistencyQuorum, ConsistencyAsync:
+	default:
+		return nil, fmt.Errorf("unknown consistency: %s", r.consistency)
+	}
+	if r.quorum == 0 {
+		r.quorum = (len(r.replicas)+1)/2 + 1
+	}
+	if r.quorum > len(r.replicas)+1 {
+		return nil, fmt.Errorf("quorum %d is greater than the number of copies %d", r.quorum, len(r.replicas)+1)
+	}
+	if r.consistency == ConsistencyAsync && r.queue == nil {
+		return nil, ErrNoQueue
+	}
+	if r.queue != nil {
+		r.wg.Add(1)
+		go r.run()
+	}
+	return r, nil
+}
+
+// Close stops the background worker, pending tasks stay in the queue.
+func (r *ReplicatedFileSystem) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+	r.wg.Wait()
+	return nil
+}
+
+func (r *ReplicatedFileSystem) Exists(path string) (bool, error) {
+	return r.primary.Exists(path)
+}
+
+func (r *ReplicatedFileSystem) FileExists(path string) (bool, error) {
+	return r.primary.FileExists(path)
+}
+
+func (r *ReplicatedFileSystem) DirExists(path string) (bool, error) {
+	return r.primary.DirExists(path)
+}
+
+func (r *ReplicatedFileSystem) Read(path string) ([]byte, error) {
+	return r.primary.Read(path)
+}
+
+func (r *ReplicatedFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	return r.primary.ReadStream(path)
+}
+
+func (r *ReplicatedFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	return r.primary.ReadDir(path)
+}
+
+func (r *ReplicatedFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	return r.primary.WalkDir(path, walkFn)
+}
+
+func (r *ReplicatedFileSystem) LastModified(path string) (time.Time, error) {
+	return r.primary.LastModified(path)
+}
+
+func (r *ReplicatedFileSystem) FileSize(path string) (int64, error) {
+	return r.primary.FileSize(path)
+}
+
+func (r *ReplicatedFileSystem) MimeType(path string) (string, error) {
+	return r.primary.MimeType(path)
+}
+
+func (r *ReplicatedFileSystem) Visibility(path string) (string, error) {
+	return r.primary.Visibility(path)
+}
+
+func (r *ReplicatedFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return r.WriteStream(path, bytes.NewReader(content), config)
+}
+
+// WriteStream writes the stream to the primary and, unless the consistency is async,
+// tees it to every replica at the same time, so the content is never buffered as a whole.
+//
+// Every replica reads from its own buffer, see [WithReplicaBuffer], so a replica slower than the others
+// only holds the write back once its buffer is full: the write then goes at the pace of the slowest replica.
+// A replica which fails stops receiving data without slowing down the others.
+func (r *ReplicatedFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	task := Task{Op: OpWrite, Path: path, Config: taskConfig(config, false)}
+	if r.consistency == ConsistencyAsync || len(r.replicas) == 0 {
+		if err := r.primary.WriteStream(path, stream, config); err != nil {
+			return err
+		}
+		if err := r.enqueue(task); err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+		return nil
+	}
+	writers := make([]io.Writer, len(r.replicas))
+	buffers := make([]*replicaBuffer, len(r.replicas))
+	errs := make([]error, len(r.replicas))
+	var wg sync.WaitGroup
+	for i, replica := range r.replicas {
+		buffers[i] = newReplicaBuffer(r.replicaBuffer)
+		writers[i] = buffers[i]
+		wg.Add(1)
+		go func(i int, replica fs.FileSystem) {
+			defer wg.Done()
+			errs[i] = replica.WriteStream(path, buffers[i], replicaConfig(config))
+			buffers[i].stop()
+		}(i, replica)
+	}
+	err := r.primary.WriteStream(path, io.TeeReader(stream, io.MultiWriter(writers...)), config)
+	for _, buffer := range buffers {
+		buffer.closeWithError(err)
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if err := r.settle(task, errs); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	return nil
+}
+
+func (r *ReplicatedFileSystem) SetVisibility(path string, visibility string) error {
+	if err := r.primary.SetVisibility(path, visibility); err != nil {
+		return err
+	}
+	if err := r.replicate(Task{Op: OpSetVisibility, Path: path, Visibility: visibility}); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	return nil
+}
+
+func (r *ReplicatedFileSystem) Delete(path string) error {
+	if err := r.primary.Delete(path); err != nil {
+		return err
+	}
+	if err := r.replicate(Task{Op: OpDelete, Path: path}); err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	return nil
+}
+
+func (r *ReplicatedFileSystem) DeleteDir(path string) error {
+	if err := r.primary.DeleteDir(path); err != nil {
+		return err
+	}
+	if err := r.replicate(Task{Op: OpDeleteDir, Path: path}); err != nil {
+		return filesystem.NewUnableToDeleteDirectory(path, err)
+	}
+	return nil
+}
+
+func (r *ReplicatedFileSystem) CreateDir(path string, config map[string]any) error {
+	if err := r.primary.CreateDir(path, config); err != nil {
+		return err
+	}
+	if err := r.replicate(Task{Op: OpCreateDir, Path: path, Config: taskConfig(config, true)}); err != nil {
+		return filesystem.NewUnableToCreateDirectory(path, err)
+	}
+	return nil
+}
+
+func (r *ReplicatedFileSystem) Move(src string, dst string, config map[string]any) error {
+	if err := r.primary.Move(src, dst, config); err != nil {
+		return err
+	}
+	if err := r.replicate(Task{Op: OpMove, Path: src, Dst: dst, Config: taskConfig(config, true)}); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	return nil
+}
+
+func (r *ReplicatedFileSystem) Copy(src string, dst string, config map[string]any) error {
+	if err := r.primary.Copy(src, dst, config); err != nil {
+		return err
+	}
+	if err := r.replicate(Task{Op: OpCopy, Path: src, Dst: dst, Config: taskConfig(config, true)}); err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	return nil
+}
+
+// replicate applies a task which has already succeeded on the primary to the replicas,
+// according to the consistency level.
+func (r *ReplicatedFileSystem) replicate(task Task) error {
+	if r.consistency == ConsistencyAsync {
+		return r.enqueue(task)
+	}
+	errs := make([]error, len(r.replicas))
+	var wg sync.WaitGroup
+	for i, replica := range r.replicas {
+		wg.Add(1)
+		go func(i int, replica fs.FileSystem) {
+			defer wg.Done()
+			errs[i] = r.apply(replica, &task)
+		}(i, replica)
+	}
+	wg.Wait()
+	return r.settle(task, errs)
+}
+
+// enqueue pushes the task of every replica to the retry queue and wakes up the worker.
+func (r *ReplicatedFileSystem) enqueue(task Task) error {
+	if r.queue == nil {
+		return nil
+	}
+	var errs []error
+	for i := range r.replicas {
+		t := task
+		t.Replica = i
+		t.CreatedAt = time.Now()
+		if err := r.queue.Push(&t); err != nil {
+			r.errorHandler(i, &t, err)
+			errs = append(errs, err)
+		}
+	}
+	r.notify()
+	return errors.Join(errs...)
+}
+
+// settle reports the failed replicas, queues them for a retry and checks the consistency level.
+func (r *ReplicatedFileSystem) settle(task Task, errs []error) error {
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		t := task
+		t.Replica = i
+		t.CreatedAt = time.Now()
+		r.errorHandler(i, &t, err)
+		failed = append(failed, fmt.Errorf("replica %d: %w", i, err))
+		if task.Op == OpCopy || task.Op == OpMove {
+			// the primary state cannot be replayed for these two, resync the destination instead
+			t.Op, t.Path, t.Dst = OpWrite, task.Dst, ""
+		}
+		if r.queue != nil {
+			if err := r.queue.Push(&t); err != nil {
+				r.errorHandler(i, &t, err)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		r.notify()
+	}
+	acknowledged := 1 + len(errs) - len(failed)
+	switch {
+	case r.consistency == ConsistencyAll && len(failed) > 0,
+		r.consistency == ConsistencyQuorum && acknowledged < r.quorum:
+		return errors.Join(append([]error{ErrInsufficientReplicas}, failed...)...)
+	}
+	return nil
+}
+
+// apply runs the task against the replica.
+// Writes are replayed by copying the file from the primary;
+// if the primary does not have it anymore, the task is obsolete and silently dropped.
+func (r *ReplicatedFileSystem) apply(replica fs.FileSystem, task *Task) error {
+	switch task.Op {
+	case OpWrite:
+		stream, err := r.primary.ReadStream(task.Path)
+		if err != nil {
+			if exists, err1 := r.primary.FileExists(task.Path); err1 == nil && !exists {
+				return nil
+			}
+			return err
+		}
+		err = replica.WriteStream(task.Path, stream, task.Config)
+		if err1 := stream.Close(); err1 != nil && err == nil {
+			err = err1
+		}
+		return err
+	case OpSetVisibility:
+		return replica.SetVisibility(task.Path, task.Visibility)
+	case OpDelete:
+		return replica.Delete(task.Path)
+	case OpDeleteDir:
+		return replica.DeleteDir(task.Path)
+	case OpCreateDir:
+		return replica.CreateDir(task.Path, task.Config)
+	case OpMove:
+		return replica.Move(task.Path, task.Dst, task.Config)
+	case OpCopy:
+		return replica.Copy(task.Path, task.Dst, task.Config)
+	}
+	return fmt.Errorf("unknown operation: %s", task.Op)
+}
+
+func (r *ReplicatedFileSystem) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *ReplicatedFileSystem) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+		_ = r.drain()
+	}
+}
+
+// drain applies the pending tasks of the queue once.
+// When a task of a replica fails, the following tasks of the same replica are kept for the next pass,
+// so operations are always applied in order.
+func (r *ReplicatedFileSystem) drain() error {
+	if r.queue == nil {
+		return nil
+	}
+	r.drainMu.Lock()
+	defer r.drainMu.Unlock()
+	tasks, err := r.queue.Pending()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	blocked := make(map[int]bool)
+	for _, task := range tasks {
+		if blocked[task.Replica] {
+			continue
+		}
+		if task.Replica < 0 || task.Replica >= len(r.replicas) {
+			err := fmt.Errorf("replica %d is not configured", task.Replica)
+			r.errorHandler(task.Replica, task, err)
+			errs = append(errs, err)
+			blocked[task.Replica] = true
+			continue
+		}
+		if err := r.apply(r.replicas[task.Replica], task); err != nil {
+			task.Attempts++
+			r.errorHandler(task.Replica, task, err)
+			errs = append(errs, fmt.Errorf("replica %d: %w", task.Replica, err))
+			blocked[task.Replica] = true
+			if err := r.queue.Update(task); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := r.queue.Remove(task); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// primaryOnlyKeys are the config keys which only make sense for the write of the primary:
+// the progress is reported once, by the primary, and the preconditions are checked against the primary,
+// the version of a replica having nothing to do with the one the caller has seen.
+var primaryOnlyKeys = map[string]bool{
+	filesystem.ProgressKey:      true,
+	filesystem.ContextKey:       true,
+	filesystem.IfMatchKey:       true,
+	filesystem.IfAbsentKey:      true,
+	filesystem.ContentLengthKey: true,
+}
+
+// replicaConfig returns a copy of the config without the keys which only apply to the primary.
+func replicaConfig(config map[string]any) map[string]any {
+	if config == nil {
+		return nil
+	}
+	m := make(map[string]any, len(config))
+	for k, v := range config {
+		if !primaryOnlyKeys[k] {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// taskConfig keeps the scalar values of a write config, so the task can be persisted.
+// The write flag is dropped for writes, because they are replayed as a full copy of the primary file,
+// and so are the keys which only apply to the primary.
+func taskConfig(config map[string]any, keepWriteFlag bool) map[string]any {
+	if config == nil {
+		return nil
+	}
+	m := make(map[string]any)
+	for k, v := range config {
+		if k == filesystem.FileWriteFlagKey && !keepWriteFlag || primaryOnlyKeys[k] {
+			continue
+		}
+		switch v := v.(type) {
+		case string, bool, int, int32, int64, uint32, float64:
+			m[k] = v
+		case *string:
+			if v != nil {
+				m[k] = *v
+			}
+		case *int:
+			if v != nil {
+				m[k] = *v
+			}
+		}
+	}
+	return m
+}
+
+// replicaBuffer is the buffer a replica reads a write from, filled by the write of the primary.
+// The errors of the replica are swallowed, so that a failed replica never interrupts the primary or the other replicas.
+type replicaBuffer struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  bytes.Buffer
+	size int
+	// closed is set when the primary is done, err is the error it failed with
+	closed bool
+	err    error
+	// stopped is set when the replica is done reading
+	stopped bool
+}
+
+func newReplicaBuffer(size int) *replicaBuffer {
+	b := &replicaBuffer{size: size}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Write appends p to the buffer, waiting for the replica to read when the buffer is full.
+func (b *replicaBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 && !b.stopped {
+		for b.buf.Len() >= b.size && !b.stopped {
+			b.cond.Wait()
+		}
+		if b.stopped {
+			break
+		}
+		chunk := min(len(p), b.size-b.buf.Len())
+		b.buf.Write(p[:chunk])
+		p = p[chunk:]
+		b.cond.Broadcast()
+	}
+	return n, nil
+}
+
+func (b *replicaBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.buf.Len() == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if b.buf.Len() > 0 {
+		n, _ := b.buf.Read(p)
+		b.cond.Broadcast()
+		return n, nil
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return 0, io.EOF
+}
+
+// closeWithError ends the content of the replica, with the error of the primary if it failed.
+func (b *replicaBuffer) closeWithError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.err = err
+	b.cond.Broadcast()
+}
+
+// stop discards the content the replica did not read, once it returned.
+func (b *replicaBuffer) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	b.buf.Reset()
+	b.cond.Broadcast()
+}
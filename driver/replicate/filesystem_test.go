@@ -0,0 +1,388 @@
+package replicate
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/driver/memory"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicatedFileSystem_Write(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica1 := memory.NewMemoryFileSystem("public", nil)
+		replica2 := memory.NewMemoryFileSystem("public", nil)
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica1, replica2})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		if err := r.Write("dir/test.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for _, f := range []fs.FileSystem{primary, replica1, replica2} {
+			content, err := f.Read("dir/test.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "hello", string(content))
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		content := strings.Repeat("0123456789", 10000)
+		if err := r.WriteStream("test.txt", strings.NewReader(content), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		replicated, err := replica.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, content, string(replicated))
+	})
+
+	t.Run("primary only config", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		version, err := primary.WriteVersioned("test.txt", strings.NewReader("previous"), nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		var done int
+		if err := r.Write("test.txt", []byte("hello"), map[string]any{
+			filesystem.IfMatchKey: version,
+			filesystem.ProgressKey: func(p filesystem.Progress) {
+				if p.Done {
+					done++
+				}
+			},
+		}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, 1, done)
+		replicated, err := replica.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(replicated))
+	})
+
+	t.Run("all with failed replica", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		var failures []int
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica, readonly.NewReadOnlyFileSystem(memory.NewMemoryFileSystem("public", nil))},
+			WithErrorHandler(func(replica int, task *Task, err error) {
+				failures = append(failures, replica)
+			}))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		err = r.Write("test.txt", []byte("hello"), nil)
+		assert.ErrorIs(t, err, ErrInsufficientReplicas)
+		assert.Equal(t, []int{1}, failures)
+		content, err := replica.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(content))
+	})
+
+	t.Run("slow replica", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		fast := memory.NewMemoryFileSystem("public", nil)
+		slow := &slowFileSystem{FileSystem: memory.NewMemoryFileSystem("public", nil), release: make(chan struct{})}
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{slow, fast}, WithReplicaBuffer(1<<20))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		content := strings.Repeat("0123456789", 10000)
+		done := make(chan error, 1)
+		go func() {
+			done <- r.WriteStream("test.txt", strings.NewReader(content), nil)
+		}()
+		// the content fits in the buffer of the slow replica, so the primary and the fast replica are not held back
+		assert.Eventually(t, func() bool {
+			exists, err := fast.FileExists("test.txt")
+			return err == nil && exists
+		}, time.Second, 10*time.Millisecond)
+		close(slow.release)
+		if err := <-done; err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		replicated, err := slow.Read("test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, content, string(replicated))
+	})
+
+	t.Run("quorum with failed replica", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		queue := NewMemoryQueue()
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica, readonly.NewReadOnlyFileSystem(memory.NewMemoryFileSystem("public", nil))},
+			WithConsistency(ConsistencyQuorum), WithQueue(queue))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := r.Write("test.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_ = r.Close()
+		tasks, err := queue.Pending()
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if assert.Len(t, tasks, 1) {
+			assert.Equal(t, 1, tasks[0].Replica)
+			assert.Equal(t, OpWrite, tasks[0].Op)
+			assert.Equal(t, "test.txt", tasks[0].Path)
+		}
+	})
+
+	t.Run("quorum not reached", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		failing := readonly.NewReadOnlyFileSystem(memory.NewMemoryFileSystem("public", nil))
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{failing, failing}, WithConsistency(ConsistencyQuorum))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		err = r.Write("test.txt", []byte("hello"), nil)
+		assert.ErrorIs(t, err, ErrInsufficientReplicas)
+		assert.ErrorIs(t, err, readonly.ErrReadOnly)
+	})
+}
+
+func TestReplicatedFileSystem_Mutations(t *testing.T) {
+	primary := memory.NewMemoryFileSystem("public", nil)
+	replica := memory.NewMemoryFileSystem("public", nil)
+	r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	defer r.Close()
+	if err := r.Write("src.txt", []byte("hello"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := r.Copy("src.txt", "copy.txt", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := r.Move("src.txt", "moved.txt", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := r.SetVisibility("moved.txt", "private"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := r.Delete("copy.txt"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := r.CreateDir("dir", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	exists, err := replica.FileExists("src.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+	exists, err = replica.FileExists("copy.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+	visibility, err := replica.Visibility("moved.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "private", visibility)
+	exists, err = replica.DirExists("dir")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, exists)
+}
+
+func TestReplicatedFileSystem_Reconcile(t *testing.T) {
+	t.Run("async", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		_, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica}, WithConsistency(ConsistencyAsync))
+		assert.ErrorIs(t, err, ErrNoQueue)
+		queue, err := NewFileQueue(t.TempDir())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica}, WithConsistency(ConsistencyAsync), WithQueue(queue))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		if err := r.Write("dir/test.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := r.Reconcile(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := replica.Read("dir/test.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(content))
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		if err := primary.Write("a.txt", []byte("a"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := primary.Write("dir/b.txt", []byte("b"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := replica.Write("extra.txt", []byte("extra"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica}, WithPrune())
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		if err := r.Reconcile(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := replica.Read("dir/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "b", string(content))
+		exists, err := replica.FileExists("extra.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("same size", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		replica := memory.NewMemoryFileSystem("public", nil)
+		if err := primary.Write("a.txt", []byte("new"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := replica.Write("a.txt", []byte("old"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{replica})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		if err := r.Reconcile(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := replica.Read("a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "new", string(content))
+	})
+
+	t.Run("failed replica", func(t *testing.T) {
+		primary := memory.NewMemoryFileSystem("public", nil)
+		if err := primary.Write("a.txt", []byte("a"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var mu sync.Mutex
+		var failures []*Task
+		r, err := NewReplicatedFileSystem(primary, []fs.FileSystem{readonly.NewReadOnlyFileSystem(memory.NewMemoryFileSystem("public", nil))},
+			WithErrorHandler(func(replica int, task *Task, err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				failures = append(failures, task)
+			}))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer r.Close()
+		err = r.Reconcile()
+		assert.True(t, errors.Is(err, readonly.ErrReadOnly))
+		if assert.Len(t, failures, 1) {
+			assert.Equal(t, "a.txt", failures[0].Path)
+		}
+	})
+}
+
+// slowFileSystem does not read the streams it writes until it is released.
+type slowFileSystem struct {
+	fs.FileSystem
+	release chan struct{}
+}
+
+func (f *slowFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	<-f.release
+	return f.FileSystem.WriteStream(path, stream, config)
+}
+
+func TestFileQueue(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := NewFileQueue(dir)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for _, path := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := queue.Push(&Task{Op: OpWrite, Path: path}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	queue, err = NewFileQueue(dir)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	tasks, err := queue.Pending()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if !assert.Len(t, tasks, 3) {
+		return
+	}
+	assert.Equal(t, "a.txt", tasks[0].Path)
+	assert.Equal(t, "c.txt", tasks[2].Path)
+	tasks[1].Attempts++
+	if err := queue.Update(tasks[1]); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if err := queue.Remove(tasks[0]); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	tasks, err = queue.Pending()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "b.txt", tasks[0].Path)
+		assert.Equal(t, 1, tasks[0].Attempts)
+	}
+}
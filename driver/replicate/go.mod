@@ -0,0 +1,3 @@
+module github.com/gopi-frame/filesystem/driver/replicate
+
+go 1.22
@@ -0,0 +1,94 @@
+package replicate
+
+import (
+	"time"
+
+	"github.com/gopi-frame/contract"
+)
+
+type Option = contract.Option[*ReplicatedFileSystem]
+
+type OptionFunc func(r *ReplicatedFileSystem) error
+
+func (f OptionFunc) Apply(r *ReplicatedFileSystem) error {
+	return f(r)
+}
+
+var noneOption = OptionFunc(func(r *ReplicatedFileSystem) error {
+	return nil
+})
+
+// WithConsistency sets the consistency level of write operations.
+func WithConsistency(consistency Consistency) Option {
+	if consistency == "" {
+		return noneOption
+	}
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.consistency = consistency
+		return nil
+	})
+}
+
+// WithQuorum sets the number of copies (primary included) required by [ConsistencyQuorum].
+func WithQuorum(quorum int) Option {
+	if quorum <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.quorum = quorum
+		return nil
+	})
+}
+
+// WithQueue sets the retry queue failed replications are pushed to.
+func WithQueue(queue Queue) Option {
+	if queue == nil {
+		return noneOption
+	}
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.queue = queue
+		return nil
+	})
+}
+
+// WithReplicaBuffer sets the size in bytes of the buffer every replica reads a write from,
+// which a replica slower than the primary may lag behind by.
+func WithReplicaBuffer(size int) Option {
+	if size <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.replicaBuffer = size
+		return nil
+	})
+}
+
+// WithRetryInterval sets the interval between two passes of the retry queue.
+func WithRetryInterval(interval time.Duration) Option {
+	if interval <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.retryInterval = interval
+		return nil
+	})
+}
+
+// WithErrorHandler sets the callback invoked for every failed replica operation.
+func WithErrorHandler(handler ErrorHandler) Option {
+	if handler == nil {
+		return noneOption
+	}
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.errorHandler = handler
+		return nil
+	})
+}
+
+// WithPrune makes Reconcile delete replica files which do not exist on the primary.
+func WithPrune() Option {
+	return OptionFunc(func(r *ReplicatedFileSystem) error {
+		r.prune = true
+		return nil
+	})
+}
@@ -0,0 +1,194 @@
+package replicate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Operations recorded in a [Task].
+const (
+	OpWrite         = "write"
+	OpSetVisibility = "set_visibility"
+	OpDelete        = "delete"
+	OpDeleteDir     = "delete_dir"
+	OpCreateDir     = "create_dir"
+	OpMove          = "move"
+	OpCopy          = "copy"
+)
+
+// Task is a replica operation which is waiting to be applied.
+//
+// A write task does not carry any content,
+// the file is copied from the primary when the task is applied.
+type Task struct {
+	ID         string         `json:"id"`
+	Replica    int            `json:"replica"`
+	Op         string         `json:"op"`
+	Path       string         `json:"path"`
+	Dst        string         `json:"dst,omitempty"`
+	Visibility string         `json:"visibility,omitempty"`
+	Config     map[string]any `json:"config,omitempty"`
+	Attempts   int            `json:"attempts"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Queue stores the tasks of failed or deferred replications.
+// Pending must return the tasks in the order they were pushed.
+type Queue interface {
+	Push(task *Task) error
+	Pending() ([]*Task, error)
+	Update(task *Task) error
+	Remove(task *Task) error
+}
+
+var taskSeq atomic.Uint64
+
+func newTaskID() string {
+	return fmt.Sprintf("%020d-%010d", time.Now().UnixNano(), taskSeq.Add(1))
+}
+
+// MemoryQueue is a [Queue] which keeps the tasks in memory,
+// pending tasks are lost when the process exits, use a [FileQueue] to keep them.
+type MemoryQueue struct {
+	mu    sync.Mutex
+	tasks []*Task
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{}
+}
+
+func (q *MemoryQueue) Push(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if task.ID == "" {
+		task.ID = newTaskID()
+	}
+	q.tasks = append(q.tasks, task)
+	return nil
+}
+
+func (q *MemoryQueue) Pending() ([]*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := make([]*Task, len(q.tasks))
+	copy(tasks, q.tasks)
+	return tasks, nil
+}
+
+func (q *MemoryQueue) Update(_ *Task) error {
+	return nil
+}
+
+func (q *MemoryQueue) Remove(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, t := range q.tasks {
+		if t.ID == task.ID {
+			q.tasks = append(q.tasks[:i], q.tasks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// FileQueue is a durable [Queue] which stores every task as a json file in a local directory.
+// Tasks are written to a temporary file, synced and renamed, so a crash never leaves a partial task behind.
+type FileQueue struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileQueue(dir string) (*FileQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileQueue{dir: dir}, nil
+}
+
+func (q *FileQueue) Push(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if task.ID == "" {
+		task.ID = newTaskID()
+	}
+	return q.save(task)
+}
+
+func (q *FileQueue) Pending() ([]*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	tasks := make([]*Task, 0, len(names))
+	for _, name := range names {
+		content, err := os.ReadFile(filepath.Join(q.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var task Task
+		if err := json.Unmarshal(content, &task); err != nil {
+			return nil, fmt.Errorf("corrupted task %s: %w", name, err)
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks, nil
+}
+
+func (q *FileQueue) Update(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.save(task)
+}
+
+func (q *FileQueue) Remove(task *Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := os.Remove(q.filename(task)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (q *FileQueue) filename(task *Task) string {
+	return filepath.Join(q.dir, task.ID+".json")
+}
+
+func (q *FileQueue) save(task *Task) error {
+	content, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(q.dir, ".task-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err1 := tmp.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), q.filename(task))
+}
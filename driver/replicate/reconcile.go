@@ -0,0 +1,148 @@
+package replicate
+
+import (
+	"errors"
+	"fmt"
+	gofs "io/fs"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Reconcile repairs the replicas from the root of the primary, see [ReplicatedFileSystem.ReconcileDir].
+func (r *ReplicatedFileSystem) Reconcile() error {
+	return r.ReconcileDir(".")
+}
+
+// ReconcileDir repairs the replicas under the given directory.
+//
+// It first applies the pending tasks of the retry queue,
+// then walks the primary and copies every file which is missing on a replica or differs from the primary, see [ReplicatedFileSystem.inSync].
+// When pruning is enabled, files which only exist on a replica are deleted.
+//
+// Every failure is reported to the error handler, and the returned error joins all of them.
+func (r *ReplicatedFileSystem) ReconcileDir(dir string) error {
+	var errs []error
+	if err := r.drain(); err != nil {
+		errs = append(errs, err)
+	}
+	files := make(map[string]*primaryFile)
+	err := filesystem.WalkDirRelative(r.primary, dir, func(path string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		path = filesystem.JoinWalked(dir, path, false)
+		if versioner, ok := r.primary.(filesystem.Versioner); ok {
+			stat, err := versioner.Stat(path)
+			if err != nil {
+				return err
+			}
+			files[path] = &primaryFile{size: stat.Size, version: stat.Version}
+			return nil
+		}
+		size, err := r.primary.FileSize(path)
+		if err != nil {
+			return err
+		}
+		files[path] = &primaryFile{size: size}
+		return nil
+	})
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for i, replica := range r.replicas {
+		if err := r.reconcileReplica(i, replica, dir, files); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// primaryFile is a file of the primary to reconcile, checksum is computed once the first replica needs it.
+type primaryFile struct {
+	size     int64
+	version  string
+	checksum string
+}
+
+func (r *ReplicatedFileSystem) reconcileReplica(i int, replica fs.FileSystem, dir string, files map[string]*primaryFile) error {
+	var errs []error
+	for path, file := range files {
+		task := &Task{Replica: i, Op: OpWrite, Path: path}
+		if r.inSync(replica, path, file) {
+			continue
+		}
+		if err := r.apply(replica, task); err != nil {
+			r.errorHandler(i, task, err)
+			errs = append(errs, err)
+		}
+	}
+	if !r.prune {
+		return errors.Join(errs...)
+	}
+	var extra []string
+	err := filesystem.WalkDirRelative(replica, dir, func(path string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		path = filesystem.JoinWalked(dir, path, false)
+		if _, ok := files[path]; !ok {
+			extra = append(extra, path)
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, path := range extra {
+		task := &Task{Replica: i, Op: OpDelete, Path: path}
+		if err := replica.Delete(path); err != nil {
+			r.errorHandler(i, task, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inSync reports whether the replica has the same file as the primary.
+// When both expose versions, the same version is enough,
+// otherwise the sizes and then the checksums of the contents are compared:
+// the versions of different drivers never match, even for the same content.
+// Any error makes the file out of sync, so that it is copied again.
+func (r *ReplicatedFileSystem) inSync(replica fs.FileSystem, path string, file *primaryFile) bool {
+	if exists, err := replica.FileExists(path); err != nil || !exists {
+		return false
+	}
+	if versioner, ok := replica.(filesystem.Versioner); ok && file.version != "" {
+		if stat, err := versioner.Stat(path); err == nil && stat.Version == file.version {
+			return true
+		}
+	}
+	if size, err := replica.FileSize(path); err != nil || size != file.size {
+		return false
+	}
+	if file.checksum == "" {
+		checksum, err := checksum(r.primary, path)
+		if err != nil {
+			return false
+		}
+		file.checksum = checksum
+	}
+	replicaChecksum, err := checksum(replica, path)
+	return err == nil && replicaChecksum == file.checksum
+}
+
+func checksum(f fs.FileSystem, path string) (string, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	return filesystem.ContentVersion(stream)
+}
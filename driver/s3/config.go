@@ -3,6 +3,7 @@ package s3
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/gopi-frame/env"
@@ -43,6 +44,14 @@ type Config struct {
 	ConfigOptions []func(*config.LoadOptions) error
 	// Options are the extra options to use to configure the S3 client.
 	Options []func(o *s3.Options)
+	// PartSize is the size of the parts of the multipart uploads of WriteStream, default is 8MiB, minimum is 5MiB.
+	PartSize int64
+	// Concurrency is the number of parts uploaded in parallel by WriteStream, default is 4.
+	Concurrency int
+	// AbandonedUploadAge enables the janitor, which aborts the multipart uploads older than it.
+	AbandonedUploadAge time.Duration
+	// JanitorInterval is the interval between the runs of the janitor, default is 1h.
+	JanitorInterval time.Duration
 }
 
 func (c *Config) Apply(fs *S3FileSystem) error {
@@ -56,6 +65,18 @@ func (c *Config) Apply(fs *S3FileSystem) error {
 		}
 	})
 	fs.bucket = c.Bucket
+	if c.PartSize > 0 {
+		fs.partSize = c.PartSize
+	}
+	if c.Concurrency > 0 {
+		fs.concurrency = c.Concurrency
+	}
+	if c.AbandonedUploadAge > 0 {
+		fs.abandonedAfter = c.AbandonedUploadAge
+	}
+	if c.JanitorInterval > 0 {
+		fs.janitorInterval = c.JanitorInterval
+	}
 	return nil
 }
 
@@ -113,6 +134,7 @@ func ConfigFromMap(configMap map[string]any) (*Config, error) {
 		DecodeHook: mapstructure.ComposeDecodeHookFunc(
 			env.ExpandStringWithEnvHookFunc(),
 			env.ExpandStringKeyMapWithEnvHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
 			mapstructure.StringToBasicTypeHookFunc(),
 			mapstructure.TextUnmarshallerHookFunc(),
 		),
@@ -0,0 +1,28 @@
+package s3
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Create returns a writer to the object, written like WriteStream:
+// its content is uploaded part by part as it is written, and the multipart upload is completed on Close.
+// Aborting the writer aborts the multipart upload, the object is left as it was.
+func (s *S3FileSystem) Create(path string, config map[string]any) (filesystem.FileWriter, error) {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return nil, filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+	}
+	if config != nil {
+		if _, err := filesystem.NewConfig(config); err != nil {
+			return nil, filesystem.NewUnableToWriteFile(path, err)
+		}
+	}
+	// the upload fails with the aborted stream and is aborted, nothing is left to discard
+	return filesystem.NewPipeWriter(func(stream io.Reader) error {
+		return s.WriteStream(path, stream, config)
+	}, nil), nil
+}
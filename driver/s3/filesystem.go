@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gopi-frame/filesystem/visibility/acl"
@@ -34,12 +35,29 @@ type S3FileSystem struct {
 	bucket            string
 	visibilityConvert acl.VisibilityConvertor
 	mimeTypeDetector  fs2.MimeTypeDetector
+
+	partSize        int64
+	concurrency     int
+	buffers         sync.Pool
+	janitorInterval time.Duration
+	abandonedAfter  time.Duration
+	done            chan struct{}
+	closeOnce       sync.Once
+	wg              sync.WaitGroup
 }
 
+// NewS3FileSystem creates a new S3 file system.
+//
+// If abandoned multipart uploads are cleaned up, see [WithJanitor],
+// a background worker runs periodically, call [S3FileSystem.Close] to stop it.
 func NewS3FileSystem(opts ...Option) (*S3FileSystem, error) {
 	f := new(S3FileSystem)
 	f.visibilityConvert = acl.New()
 	f.mimeTypeDetector = filesystem.NewMimeTypeDetector()
+	f.partSize = DefaultPartSize
+	f.concurrency = DefaultConcurrency
+	f.janitorInterval = defaultJanitorInterval
+	f.done = make(chan struct{})
 	for _, opt := range opts {
 		if err := opt.Apply(f); err != nil {
 			return nil, err
@@ -48,6 +66,17 @@ func NewS3FileSystem(opts ...Option) (*S3FileSystem, error) {
 	if f.client == nil {
 		return nil, errors.New("s3 client is required")
 	}
+	if f.partSize < MinPartSize {
+		return nil, fmt.Errorf("part size must be at least %d bytes", MinPartSize)
+	}
+	f.buffers.New = func() any {
+		buf := make([]byte, f.partSize)
+		return &buf
+	}
+	if f.abandonedAfter > 0 {
+		f.wg.Add(1)
+		go f.runJanitor()
+	}
 	return f, nil
 }
 
@@ -86,10 +115,13 @@ func (s *S3FileSystem) FileExists(path string) (bool, error) {
 // DirExists checks if the given path exists and is a directory.
 // If the path does not end with a slash, it returns false.
 func (s *S3FileSystem) DirExists(path string) (bool, error) {
-	path = filepath.ToSlash(path)
-	if !strings.HasSuffix(path, "/") {
+	path, ok := dirPrefix(path)
+	if !ok {
 		return false, nil
 	}
+	if path == "" {
+		return true, nil
+	}
 	resp, err := s.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
 		Bucket:    aws.String(s.bucket),
 		Prefix:    aws.String(strings.TrimRight(path, "/") + "/"),
@@ -106,6 +138,17 @@ func (s *S3FileSystem) DirExists(path string) (bool, error) {
 	return *resp.KeyCount > 0, nil
 }
 
+// dirPrefix returns the prefix of the keys under the directory, and whether the path is one of a directory,
+// i.e. ends with a slash. The root of the bucket, "", ".", "./" or "/", is the empty prefix.
+func dirPrefix(path string) (string, bool) {
+	path = filepath.ToSlash(path)
+	switch path {
+	case "", ".", "./", "/":
+		return "", true
+	}
+	return path, strings.HasSuffix(path, "/")
+}
+
 // Read reads the file at the given path and returns its contents.
 // If the path ends with a slash, it returns an error.
 func (s *S3FileSystem) Read(path string) ([]byte, error) {
@@ -133,11 +176,64 @@ func (s *S3FileSystem) ReadStream(path string) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the stream is read.
+func (s *S3FileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if strings.HasSuffix(filepath.ToSlash(path), "/") {
+		return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotFile)
+	}
+	resp, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	var size int64 = -1
+	if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+	return filesystem.NewProgressTracker(path, size, cfg.Progress()).ReadCloser(resp.Body), nil
+}
+
+// ReadRange returns length bytes of the file starting at offset, with a Range request.
+// A range starting past the end of the file is empty.
+func (s *S3FileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if strings.HasSuffix(filepath.ToSlash(path), "/") {
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += fmt.Sprint(offset + length - 1)
+	}
+	resp, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+		Range:  aws.String(byteRange),
+	})
+	if err != nil {
+		if isStatus(err, http.StatusRequestedRangeNotSatisfiable) {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return resp.Body, nil
+}
+
 // ReadDir reads the directory at the given path and returns a list of its contents.
 // If the path does not end with a slash, it returns an error.
 func (s *S3FileSystem) ReadDir(path string) ([]os.DirEntry, error) {
-	path = filepath.ToSlash(path)
-	if !strings.HasSuffix(path, "/") {
+	path, ok := dirPrefix(path)
+	if !ok {
 		return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
 	}
 	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
@@ -164,10 +260,10 @@ func (s *S3FileSystem) ReadDir(path string) ([]os.DirEntry, error) {
 }
 
 // WalkDir walks the directory tree rooted at the given path, calling walkFn for each file or directory in the tree.
-// If the path does not end with a slash, it returns an error.
+// If the path does not end with a slash, it returns an error, but for the root of the bucket, see [dirPrefix].
 func (s *S3FileSystem) WalkDir(path string, walkFn fs.WalkDirFunc) error {
-	path = filepath.ToSlash(path)
-	if !strings.HasSuffix(path, "/") {
+	path, ok := dirPrefix(path)
+	if !ok {
 		return filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
 	}
 	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
@@ -199,7 +295,8 @@ func (s *S3FileSystem) WalkDir(path string, walkFn fs.WalkDirFunc) error {
 	return nil
 }
 
-// LastModified returns the last modified time of the file at the given path.
+// LastModified returns the last modified time of the file at the given path,
+// the one set with SetLastModified if any.
 // If the path ends with a slash, it returns an error.
 func (s *S3FileSystem) LastModified(path string) (time.Time, error) {
 	path = filepath.ToSlash(path)
@@ -213,6 +310,9 @@ func (s *S3FileSystem) LastModified(path string) (time.Time, error) {
 	if err != nil {
 		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
 	}
+	if mtime, ok := filesystem.MtimeFromMetadata(resp.Metadata); ok {
+		return mtime, nil
+	}
 	return *resp.LastModified, nil
 }
 
@@ -299,18 +399,39 @@ func (s *S3FileSystem) Write(path string, content []byte, config map[string]any)
 //	if the object's storage class is [types.StorageClassExpressOnezone],
 //	it will use the s3.PutObjectInput.WriteOffsetBytes field to specify the offset to write to.
 //	Else, it will read the content of the original file first and then append the new content to it.
+//
+// Note about large files:
+//
+//	content larger than the part size is uploaded with a multipart upload, whose parts are uploaded in parallel,
+//	so that streams of unknown length and files over 5GB can be written. See [S3FileSystem.BeginUpload].
 func (s *S3FileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	_, err := s.WriteVersioned(path, stream, config)
+	return err
+}
+
+// WriteVersioned writes like WriteStream, and returns the ETag of the written object as its version.
+//
+// The conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey]
+// are sent as the If-None-Match and If-Match headers of the PutObject request.
+func (s *S3FileSystem) WriteVersioned(path string, stream io.Reader, config map[string]any) (string, error) {
 	path = filepath.ToSlash(path)
 	if strings.HasSuffix(path, "/") {
-		return filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+		return "", filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
 	}
 	var fileMode = s.visibilityConvert.DefaultForFile()
 	var writeFlag int
+	var ifNoneMatch, ifMatch *string
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		var err error
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
-			return filesystem.NewUnableToWriteFile(path, err)
+			return "", filesystem.NewUnableToWriteFile(path, err)
+		}
+		if cfg.IfAbsent != nil && *cfg.IfAbsent {
+			ifNoneMatch = aws.String("*")
 		}
+		ifMatch = cfg.IfMatch
 		if cfg.DirVisibility != nil {
 			fileMode = *cfg.DirVisibility
 		}
@@ -321,12 +442,17 @@ func (s *S3FileSystem) WriteStream(path string, stream io.Reader, config map[str
 			writeFlag = *cfg.FileWriteFlag
 		}
 	}
-	var err error
 	var input = &s3.PutObjectInput{
-		Bucket: aws.String(s.bucket),
-		Key:    aws.String(path),
-		Body:   stream,
-		ACL:    types.ObjectCannedACL(fileMode),
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path),
+		Body:        stream,
+		ACL:         types.ObjectCannedACL(fileMode),
+		IfNoneMatch: ifNoneMatch,
+		IfMatch:     ifMatch,
+	}
+	if cfg != nil {
+		input.Metadata = cfg.Metadata
+		input.Tagging = encodeTags(cfg.Tags)
 	}
 	if writeFlag&os.O_APPEND > 0 {
 		fi, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
@@ -334,30 +460,41 @@ func (s *S3FileSystem) WriteStream(path string, stream io.Reader, config map[str
 			Key:    aws.String(path),
 		})
 		if err != nil {
-			return filesystem.NewUnableToWriteFile(path, err)
+			return "", filesystem.NewUnableToWriteFile(path, err)
 		}
 		if fi.StorageClass != types.StorageClassExpressOnezone {
 			content, err := s.Read(path)
 			if err != nil {
-				return filesystem.NewUnableToWriteFile(path, err)
-			}
-			var stream2 = bytes.NewBuffer(nil)
-			if _, err := stream2.Write(content); err != nil {
-				return filesystem.NewUnableToWriteFile(path, err)
-			}
-			if _, err := stream2.ReadFrom(stream); err != nil {
-				return filesystem.NewUnableToWriteFile(path, err)
+				return "", filesystem.NewUnableToWriteFile(path, err)
 			}
-			input.Body = bytes.NewReader(stream2.Bytes())
+			input.Body = io.MultiReader(bytes.NewReader(content), stream)
 		} else {
 			input.WriteOffsetBytes = fi.ContentLength
 		}
 	}
-	_, err = s.client.PutObject(context.Background(), input)
+	// an appended body is prefixed with the current content, its size is not known
+	tracker := cfg.NewTracker(path, input.Body)
+	var etag string
+	var err error
+	if input.WriteOffsetBytes != nil {
+		size := filesystem.StreamSize(stream)
+		var resp *s3.PutObjectOutput
+		if resp, err = s.client.PutObject(context.Background(), input); err == nil {
+			etag = aws.ToString(resp.ETag)
+			// the body is sent in a single request, it is reported once complete
+			tracker.Add(max(size, 0))
+		}
+	} else {
+		etag, err = s.upload(input, tracker)
+	}
 	if err != nil {
-		return filesystem.NewUnableToWriteFile(path, err)
+		if err := preconditionFailed(path, err, ifNoneMatch != nil, ifMatch != nil); err != nil {
+			return "", err
+		}
+		return "", filesystem.NewUnableToWriteFile(path, err)
 	}
-	return nil
+	tracker.Done()
+	return etag, nil
 }
 
 // SetVisibility sets the visibility of the file at the given path.
@@ -502,8 +639,10 @@ func (s *S3FileSystem) Copy(src string, dst string, config map[string]any) error
 		return filesystem.NewUnableToCopyFile(src, dst, filesystem.ErrIsNotFile)
 	}
 	var dirMode = s.visibilityConvert.DefaultForDir()
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		var err error
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
 			return filesystem.NewUnableToCopyFile(src, dst, err)
 		}
@@ -520,5 +659,15 @@ func (s *S3FileSystem) Copy(src string, dst string, config map[string]any) error
 	if err != nil {
 		return filesystem.NewUnableToMove(src, dst, err)
 	}
+	if progress := cfg.Progress(); progress != nil {
+		// the object is copied by the server in a single request, there is a single report
+		size, err := s.FileSize(dst)
+		if err != nil {
+			size = -1
+		}
+		tracker := filesystem.NewProgressTracker(dst, size, progress)
+		tracker.Add(max(size, 0))
+		tracker.Done()
+	}
 	return nil
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gopi-frame/filesystem"
 
@@ -436,3 +438,131 @@ func TestS3FileSystem_DeleteDir(t *testing.T) {
 		}
 	})
 }
+
+func TestS3FileSystem_ConditionalWrite(t *testing.T) {
+	path := "testdata/file/for-write/conditional/state.json"
+	version, err := mockFS.WriteVersioned(path, strings.NewReader(`{"n":1}`), map[string]any{filesystem.IfAbsentKey: true})
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	var precondition *filesystem.PreconditionFailed
+	err = mockFS.Write(path, []byte(`{"n":2}`), map[string]any{filesystem.IfAbsentKey: true})
+	assert.True(t, errors.As(err, &precondition))
+
+	stat, err := mockFS.Stat(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, version, stat.Version)
+
+	_, err = mockFS.WriteVersioned(path, strings.NewReader(`{"n":2}`), map[string]any{filesystem.IfMatchKey: version})
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	err = mockFS.Write(path, []byte(`{"n":3}`), map[string]any{filesystem.IfMatchKey: version})
+	assert.True(t, errors.As(err, &precondition))
+}
+
+func TestS3FileSystem_Progress(t *testing.T) {
+	path := "testdata/file/for-write/progress.txt"
+	var written filesystem.Progress
+	err := mockFS.Write(path, []byte("hello world"), map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { written = p },
+	})
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, written.Done)
+	assert.Equal(t, int64(11), written.Bytes)
+	assert.Equal(t, int64(11), written.Total)
+
+	var read filesystem.Progress
+	stream, err := mockFS.ReadStreamWithConfig(path, map[string]any{
+		filesystem.ProgressKey: func(p filesystem.Progress) { read = p },
+	})
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	_, err = io.Copy(io.Discard, stream)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Close())
+	assert.True(t, read.Done)
+	assert.Equal(t, int64(11), read.Bytes)
+}
+
+func TestS3FileSystem_ReadRange(t *testing.T) {
+	path := "testdata/file/for-write/range.txt"
+	if err := mockFS.Write(path, []byte("0123456789"), nil); !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	stream, err := mockFS.ReadRange(path, 2, 3)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := io.ReadAll(stream)
+	assert.NoError(t, stream.Close())
+	assert.NoError(t, err)
+	assert.Equal(t, "234", string(content))
+
+	stream, err = mockFS.ReadRange(path, 12, 1)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	content, err = io.ReadAll(stream)
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestS3FileSystem_Metadata(t *testing.T) {
+	path := "testdata/file/for-write/metadata.txt"
+	err := mockFS.Write(path, []byte("hello"), map[string]any{
+		filesystem.MetadataKey: map[string]string{"uploader": "42"},
+		filesystem.TagsKey:     map[string]string{"retention": "short"},
+	})
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	metadata, err := mockFS.Metadata(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, map[string]string{"uploader": "42"}, metadata)
+	tags, err := mockFS.Tags(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, map[string]string{"retention": "short"}, tags)
+
+	// the metadata and the modification time are copied along with the object
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := mockFS.SetLastModified(path, mtime); !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	if err := mockFS.SetMetadata(path, map[string]string{"source": "erp"}); !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	metadata, err = mockFS.Metadata(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, map[string]string{"source": "erp"}, metadata)
+	modified, err := mockFS.LastModified(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, mtime.Equal(modified))
+	content, err := mockFS.Read(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "hello", string(content))
+
+	if err := mockFS.SetTags(path, nil); !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	tags, err = mockFS.Tags(path)
+	if !assert.NoError(t, err) {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Empty(t, tags)
+}
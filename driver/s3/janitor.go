@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultJanitorInterval = time.Hour
+
+// CleanIncompleteUploads aborts the uploads initiated more than olderThan ago,
+// i.e. abandoned by processes which crashed or gave up, and returns how many were aborted.
+// Their parts are billed until they are aborted.
+func (s *S3FileSystem) CleanIncompleteUploads(olderThan time.Duration) (int, error) {
+	uploads, err := s.ListIncomplete("")
+	if err != nil {
+		return 0, err
+	}
+	var aborted int
+	var errs []error
+	for _, upload := range uploads {
+		if time.Since(upload.Initiated) < olderThan {
+			continue
+		}
+		if err := s.Abort(upload); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		aborted++
+	}
+	return aborted, errors.Join(errs...)
+}
+
+// Close stops the janitor.
+func (s *S3FileSystem) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+func (s *S3FileSystem) runJanitor() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			_, _ = s.CleanIncompleteUploads(s.abandonedAfter)
+		}
+	}
+}
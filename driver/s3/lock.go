@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Lock acquires the lock of the path, waiting until it is released or its lease expires.
+func (s *S3FileSystem) Lock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	return s.LockContext(context.Background(), path, ttl)
+}
+
+// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+func (s *S3FileSystem) LockContext(ctx context.Context, path string, ttl time.Duration) (filesystem.Lock, error) {
+	return filesystem.WaitForLock(ctx, func() (filesystem.Lock, error) {
+		return s.TryLock(path, ttl)
+	})
+}
+
+// TryLock acquires the lock of the path, or returns a [filesystem.LockHeld] error if it is held.
+//
+// The lock is an object named after the path with [filesystem.LockSuffix], holding the ownership metadata of the lock.
+// It is created with If-None-Match, so that only one writer creates it,
+// and a lock whose lease expired is taken over with If-Match on the expired object,
+// so that only one of the processes recovering it succeeds.
+func (s *S3FileSystem) TryLock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	path = filepath.ToSlash(path)
+	key := path + filesystem.LockSuffix
+	for {
+		info := filesystem.NewLockInfo(ttl)
+		etag, err := s.putLock(key, info, &s3.PutObjectInput{IfNoneMatch: aws.String("*")})
+		if err == nil {
+			return &lock{s: s, path: path, key: key, info: info, etag: etag}, nil
+		}
+		if !isStatus(err, http.StatusPreconditionFailed, http.StatusConflict) {
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		holder, holderETag, err := s.readLock(key)
+		if err != nil {
+			if isStatus(err, http.StatusNotFound) {
+				// released meanwhile
+				continue
+			}
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		if !holder.Expired() {
+			return nil, filesystem.NewLockHeld(path, holder)
+		}
+		etag, err = s.putLock(key, info, &s3.PutObjectInput{IfMatch: aws.String(holderETag)})
+		if err == nil {
+			return &lock{s: s, path: path, key: key, info: info, etag: etag}, nil
+		}
+		if !isStatus(err, http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound) {
+			return nil, filesystem.NewUnableToLock(path, err)
+		}
+		// another process took the expired lock over, or released it
+	}
+}
+
+// putLock writes the metadata to the lock object with the conditions of the input, and returns the new ETag.
+func (s *S3FileSystem) putLock(key string, info filesystem.LockInfo, input *s3.PutObjectInput) (string, error) {
+	input.Bucket = aws.String(s.bucket)
+	input.Key = aws.String(key)
+	input.Body = bytes.NewReader(info.Bytes())
+	input.ContentType = aws.String("application/json")
+	resp, err := s.client.PutObject(context.Background(), input)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(resp.ETag), nil
+}
+
+func (s *S3FileSystem) readLock(key string) (filesystem.LockInfo, string, error) {
+	resp, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return filesystem.LockInfo{}, "", err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return filesystem.LockInfo{}, "", err
+	}
+	return filesystem.ParseLockInfo(content, aws.ToTime(resp.LastModified)), aws.ToString(resp.ETag), nil
+}
+
+func isStatus(err error, codes ...int) bool {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	for _, code := range codes {
+		if respErr.Response.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+type lock struct {
+	mu       sync.Mutex
+	s        *S3FileSystem
+	path     string
+	key      string
+	info     filesystem.LockInfo
+	etag     string
+	released bool
+}
+
+func (l *lock) Path() string {
+	return l.path
+}
+
+func (l *lock) Info() filesystem.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+// Refresh extends the lease with If-Match on the lock object, which fails if the lock was taken over.
+func (l *lock) Refresh(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return filesystem.ErrLockLost
+	}
+	info := l.info.Renew(ttl)
+	etag, err := l.s.putLock(l.key, info, &s3.PutObjectInput{IfMatch: aws.String(l.etag)})
+	if err != nil {
+		if isStatus(err, http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound) {
+			return filesystem.ErrLockLost
+		}
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.info, l.etag = info, etag
+	return nil
+}
+
+// Unlock deletes the lock object with If-Match, which fails if the lock was taken over.
+func (l *lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return filesystem.ErrLockLost
+	}
+	if _, err := l.s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket:  aws.String(l.s.bucket),
+		Key:     aws.String(l.key),
+		IfMatch: aws.String(l.etag),
+	}); err != nil {
+		if isStatus(err, http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound) {
+			return filesystem.ErrLockLost
+		}
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.released = true
+	return nil
+}
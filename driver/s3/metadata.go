@@ -0,0 +1,111 @@
+package s3
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Metadata returns the user metadata of the object, sent as its x-amz-meta-* headers.
+// The modification time set with SetLastModified is not part of it.
+func (s *S3FileSystem) Metadata(path string) (map[string]string, error) {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrIsNotFile)
+	}
+	resp, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	metadata := filesystem.CloneMetadata(resp.Metadata)
+	deleteMtime(metadata)
+	return metadata, nil
+}
+
+// SetMetadata replaces the user metadata of the object, keeping the modification time set with SetLastModified.
+// S3 has no way to change the metadata in place, the object is copied onto itself,
+// so objects larger than 5GiB are not supported.
+func (s *S3FileSystem) SetMetadata(path string, metadata map[string]string) error {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return filesystem.NewUnableToSetMetadata(path, filesystem.ErrIsNotFile)
+	}
+	err := s.replaceMetadata(path, func(current map[string]string) {
+		for key := range current {
+			if !strings.EqualFold(key, filesystem.MtimeMetadataKey) {
+				delete(current, key)
+			}
+		}
+		for key, value := range metadata {
+			current[key] = value
+		}
+	})
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// Tags returns the tags of the object.
+func (s *S3FileSystem) Tags(path string) (map[string]string, error) {
+	path = filepath.ToSlash(path)
+	resp, err := s.client.GetObjectTagging(context.Background(), &s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	tags := make(map[string]string, len(resp.TagSet))
+	for _, tag := range resp.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags, nil
+}
+
+// SetTags replaces the tags of the object, S3 allows up to 10 tags per object.
+func (s *S3FileSystem) SetTags(path string, tags map[string]string) error {
+	path = filepath.ToSlash(path)
+	var err error
+	if len(tags) == 0 {
+		_, err = s.client.DeleteObjectTagging(context.Background(), &s3.DeleteObjectTaggingInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(path),
+		})
+	} else {
+		tagSet := make([]types.Tag, 0, len(tags))
+		for key, value := range tags {
+			tagSet = append(tagSet, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		_, err = s.client.PutObjectTagging(context.Background(), &s3.PutObjectTaggingInput{
+			Bucket:  aws.String(s.bucket),
+			Key:     aws.String(path),
+			Tagging: &types.Tagging{TagSet: tagSet},
+		})
+	}
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// encodeTags encodes the tags as the x-amz-tagging header of a write, or returns nil if there are none.
+func encodeTags(tags map[string]string) *string {
+	if len(tags) == 0 {
+		return nil
+	}
+	values := make(url.Values, len(tags))
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return aws.String(values.Encode())
+}
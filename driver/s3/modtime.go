@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time of the object to now, creating an empty object if it is missing.
+func (s *S3FileSystem) Touch(path string) error {
+	exists, err := s.FileExists(path)
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	if !exists {
+		return s.WriteStream(path, bytes.NewReader(nil), nil)
+	}
+	return s.SetLastModified(path, time.Now())
+}
+
+// SetLastModified records the modification time in the "mtime" metadata of the object,
+// which LastModified reports instead of the time the object was stored.
+// S3 has no way to change the metadata in place, the object is copied onto itself,
+// keeping its content headers and visibility, so objects larger than 5GiB are not supported.
+func (s *S3FileSystem) SetLastModified(path string, t time.Time) error {
+	path = filepath.ToSlash(path)
+	if strings.HasSuffix(path, "/") {
+		return filesystem.NewUnableToSetLastModified(path, filesystem.ErrIsNotFile)
+	}
+	err := s.replaceMetadata(path, func(metadata map[string]string) {
+		deleteMtime(metadata)
+		metadata[filesystem.MtimeMetadataKey] = filesystem.FormatMtime(t)
+	})
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
+
+// replaceMetadata copies the object onto itself with its user metadata changed by update,
+// keeping its content headers, tags and visibility.
+func (s *S3FileSystem) replaceMetadata(path string, update func(metadata map[string]string)) error {
+	head, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return err
+	}
+	metadata := filesystem.CloneMetadata(head.Metadata)
+	update(metadata)
+	input := &s3.CopyObjectInput{
+		Bucket:             aws.String(s.bucket),
+		CopySource:         aws.String(s.bucket + "/" + path),
+		Key:                aws.String(path),
+		MetadataDirective:  types.MetadataDirectiveReplace,
+		Metadata:           metadata,
+		ContentType:        head.ContentType,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		ContentLanguage:    head.ContentLanguage,
+		CacheControl:       head.CacheControl,
+		StorageClass:       types.StorageClass(head.StorageClass),
+	}
+	// the copy gets the default ACL, unless the current one is given again
+	if visibility, err := s.Visibility(path); err == nil {
+		input.ACL = types.ObjectCannedACL(visibility)
+	}
+	_, err = s.client.CopyObject(context.Background(), input)
+	return err
+}
+
+// deleteMtime deletes the modification time from the metadata, whatever the case of its key.
+func deleteMtime(metadata map[string]string) {
+	for key := range metadata {
+		if strings.EqualFold(key, filesystem.MtimeMetadataKey) {
+			delete(metadata, key)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	// MinPartSize is the minimum size of the parts of a multipart upload, but the last one.
+	MinPartSize = 5 << 20
+	// MaxParts is the maximum number of parts of a multipart upload.
+	MaxParts = 10000
+	// DefaultPartSize is the default size of the parts uploaded by WriteStream.
+	DefaultPartSize = 8 << 20
+	// DefaultConcurrency is the default number of parts uploaded in parallel by WriteStream.
+	DefaultConcurrency = 4
+)
+
+var ErrTooManyParts = fmt.Errorf("multipart upload exceeds %d parts, increase the part size", MaxParts)
+
+// Upload is a multipart upload session.
+//
+// It can be persisted, e.g. as JSON, to resume the upload after a failure or in another process:
+// [S3FileSystem.ListParts] returns the parts already uploaded.
+type Upload struct {
+	Path      string    `json:"path"`
+	UploadID  string    `json:"upload_id"`
+	Initiated time.Time `json:"initiated"`
+}
+
+// UploadedPart is an uploaded part of a multipart upload.
+type UploadedPart struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// BeginUpload starts a multipart upload to the path.
+// The file visibility, metadata and tags of the config are applied to the object once the upload is complete.
+func (s *S3FileSystem) BeginUpload(path string, config map[string]any) (*Upload, error) {
+	path = filepath.ToSlash(path)
+	var fileMode = s.visibilityConvert.DefaultForFile()
+	var metadata map[string]string
+	var tagging *string
+	if config != nil {
+		cfg, err := filesystem.NewConfig(config)
+		if err != nil {
+			return nil, filesystem.NewUnableToWriteFile(path, err)
+		}
+		if cfg.FileVisibility != nil {
+			fileMode = *cfg.FileVisibility
+		}
+		metadata = cfg.Metadata
+		tagging = encodeTags(cfg.Tags)
+	}
+	return s.beginUpload(path, types.ObjectCannedACL(fileMode), metadata, tagging)
+}
+
+func (s *S3FileSystem) beginUpload(path string, acl types.ObjectCannedACL, metadata map[string]string, tagging *string) (*Upload, error) {
+	resp, err := s.client.CreateMultipartUpload(context.Background(), &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(path),
+		ACL:      acl,
+		Metadata: metadata,
+		Tagging:  tagging,
+	})
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(path, err)
+	}
+	return &Upload{Path: path, UploadID: aws.ToString(resp.UploadId), Initiated: time.Now()}, nil
+}
+
+// UploadPart uploads a part of the upload, numbered from 1 to [MaxParts].
+// Every part but the last must be at least [MinPartSize] bytes.
+// Uploading a part again with the same number replaces it.
+func (s *S3FileSystem) UploadPart(upload *Upload, number int32, content io.Reader) (*UploadedPart, error) {
+	if number < 1 || number > MaxParts {
+		return nil, filesystem.NewUnableToWriteFile(upload.Path, fmt.Errorf("part number must be between 1 and %d", MaxParts))
+	}
+	buf, err := io.ReadAll(content)
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(upload.Path, err)
+	}
+	return s.uploadPart(upload, number, buf)
+}
+
+func (s *S3FileSystem) uploadPart(upload *Upload, number int32, content []byte) (*UploadedPart, error) {
+	resp, err := s.client.UploadPart(context.Background(), &s3.UploadPartInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(upload.Path),
+		UploadId:      aws.String(upload.UploadID),
+		PartNumber:    aws.Int32(number),
+		Body:          bytes.NewReader(content),
+		ContentLength: aws.Int64(int64(len(content))),
+	})
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(upload.Path, err)
+	}
+	return &UploadedPart{Number: number, ETag: aws.ToString(resp.ETag), Size: int64(len(content))}, nil
+}
+
+// ListParts returns the parts of the upload uploaded so far, ordered by number.
+func (s *S3FileSystem) ListParts(upload *Upload) ([]UploadedPart, error) {
+	var parts []UploadedPart
+	paginator := s3.NewListPartsPaginator(s.client, &s3.ListPartsInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(upload.Path),
+		UploadId: aws.String(upload.UploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, filesystem.NewUnableToRetrieveMetadata(upload.Path, err)
+		}
+		for _, part := range page.Parts {
+			parts = append(parts, UploadedPart{
+				Number: aws.ToInt32(part.PartNumber),
+				ETag:   aws.ToString(part.ETag),
+				Size:   aws.ToInt64(part.Size),
+			})
+		}
+	}
+	return parts, nil
+}
+
+// Complete assembles the uploaded parts into the file, and returns its version.
+// The conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey] of the config are checked on completion.
+func (s *S3FileSystem) Complete(upload *Upload, config map[string]any) (string, error) {
+	var ifNoneMatch, ifMatch *string
+	if config != nil {
+		cfg, err := filesystem.NewConfig(config)
+		if err != nil {
+			return "", filesystem.NewUnableToWriteFile(upload.Path, err)
+		}
+		if cfg.IfAbsent != nil && *cfg.IfAbsent {
+			ifNoneMatch = aws.String("*")
+		}
+		ifMatch = cfg.IfMatch
+	}
+	parts, err := s.ListParts(upload)
+	if err != nil {
+		return "", err
+	}
+	etag, err := s.complete(upload, parts, ifNoneMatch, ifMatch)
+	if err != nil {
+		if err := preconditionFailed(upload.Path, err, ifNoneMatch != nil, ifMatch != nil); err != nil {
+			return "", err
+		}
+		return "", filesystem.NewUnableToWriteFile(upload.Path, err)
+	}
+	return etag, nil
+}
+
+func (s *S3FileSystem) complete(upload *Upload, parts []UploadedPart, ifNoneMatch, ifMatch *string) (string, error) {
+	completed := make([]types.CompletedPart, len(parts))
+	for i, part := range parts {
+		completed[i] = types.CompletedPart{PartNumber: aws.Int32(part.Number), ETag: aws.String(part.ETag)}
+	}
+	resp, err := s.client.CompleteMultipartUpload(context.Background(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(upload.Path),
+		UploadId:        aws.String(upload.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+		IfNoneMatch:     ifNoneMatch,
+		IfMatch:         ifMatch,
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(resp.ETag), nil
+}
+
+// Abort aborts the upload and deletes its parts.
+func (s *S3FileSystem) Abort(upload *Upload) error {
+	_, err := s.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.bucket),
+		Key:      aws.String(upload.Path),
+		UploadId: aws.String(upload.UploadID),
+	})
+	if err != nil {
+		return filesystem.NewUnableToDeleteFile(upload.Path, err)
+	}
+	return nil
+}
+
+// ListIncomplete returns the uploads under the prefix which were neither completed nor aborted.
+func (s *S3FileSystem) ListIncomplete(prefix string) ([]*Upload, error) {
+	prefix = filepath.ToSlash(prefix)
+	var uploads []*Upload
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		resp, err := s.client.ListMultipartUploads(context.Background(), input)
+		if err != nil {
+			return nil, filesystem.NewUnableToReadDirectory(prefix, err)
+		}
+		for _, upload := range resp.Uploads {
+			uploads = append(uploads, &Upload{
+				Path:      aws.ToString(upload.Key),
+				UploadID:  aws.ToString(upload.UploadId),
+				Initiated: aws.ToTime(upload.Initiated),
+			})
+		}
+		if !aws.ToBool(resp.IsTruncated) {
+			return uploads, nil
+		}
+		input.KeyMarker = resp.NextKeyMarker
+		input.UploadIdMarker = resp.NextUploadIdMarker
+	}
+}
+
+// upload writes the body of the input with a single PutObject if it is smaller than the part size,
+// or with a multipart upload otherwise, and returns the ETag of the object.
+func (s *S3FileSystem) upload(input *s3.PutObjectInput, tracker *filesystem.ProgressTracker) (string, error) {
+	first, err := readPart(input.Body, s.partSize)
+	if errors.Is(err, io.EOF) {
+		input.Body = bytes.NewReader(first)
+		input.ContentLength = aws.Int64(int64(len(first)))
+		resp, err := s.client.PutObject(context.Background(), input)
+		if err != nil {
+			return "", err
+		}
+		tracker.Add(int64(len(first)))
+		return aws.ToString(resp.ETag), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	upload, err := s.beginUpload(aws.ToString(input.Key), input.ACL, input.Metadata, input.Tagging)
+	if err != nil {
+		return "", err
+	}
+	parts, err := s.uploadParts(upload, io.MultiReader(bytes.NewReader(first), input.Body), tracker)
+	if err == nil {
+		var etag string
+		if etag, err = s.complete(upload, parts, input.IfNoneMatch, input.IfMatch); err == nil {
+			return etag, nil
+		}
+	}
+	if abortErr := s.Abort(upload); abortErr != nil {
+		// the janitor cleans it up eventually
+		err = errors.Join(err, abortErr)
+	}
+	return "", err
+}
+
+// readPart reads size bytes of the reader, or returns what it read with [io.EOF] if the reader ends before.
+// The buffer grows with the content, so that a small file does not allocate a whole part.
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, 0, min(size, 64<<10))
+	for int64(len(buf)) < size {
+		if len(buf) == cap(buf) {
+			grown := make([]byte, len(buf), min(2*int64(cap(buf)), size))
+			copy(grown, buf)
+			buf = grown
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			return buf, err
+		}
+	}
+	return buf, nil
+}
+
+// uploadParts splits the content into parts of the part size, and uploads them in parallel.
+// At most concurrency parts are buffered at once, in buffers reused across the parts and the uploads.
+// The progress is counted part by part, as the uploads complete.
+func (s *S3FileSystem) uploadParts(upload *Upload, content io.Reader, tracker *filesystem.ProgressTracker) ([]UploadedPart, error) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		parts []UploadedPart
+		errs  []error
+		slots = make(chan struct{}, s.concurrency)
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}
+	for number := int32(1); !failed(); number++ {
+		slots <- struct{}{}
+		buf := s.buffers.Get().(*[]byte)
+		release := func() {
+			s.buffers.Put(buf)
+			<-slots
+		}
+		n, err := io.ReadFull(content, *buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			release()
+			fail(err)
+			break
+		}
+		if n == 0 {
+			release()
+			break
+		}
+		if number > MaxParts {
+			release()
+			fail(ErrTooManyParts)
+			break
+		}
+		wg.Add(1)
+		go func(number int32, part []byte, release func()) {
+			defer wg.Done()
+			defer release()
+			uploaded, err := s.uploadPart(upload, number, part)
+			if err != nil {
+				fail(err)
+				return
+			}
+			tracker.Add(uploaded.Size)
+			mu.Lock()
+			defer mu.Unlock()
+			parts = append(parts, *uploaded)
+		}(number, (*buf)[:n], release)
+		if err != nil {
+			// a short read is the last part
+			break
+		}
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+	return parts, nil
+}
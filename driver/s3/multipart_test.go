@@ -0,0 +1,192 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func newMultipartFS(t *testing.T) *S3FileSystem {
+	fs, err := NewS3FileSystem(&Config{
+		Bucket:   "mock",
+		Endpoint: "http://localhost:19090/",
+		Credentials: &Credentials{
+			AccessKeyID:     "mock-access-key-id",
+			SecretAccessKey: "mock-secret-access-key",
+		},
+		Options: []func(o *s3.Options){
+			func(o *s3.Options) {
+				o.UsePathStyle = true
+			},
+		},
+		PartSize:    MinPartSize,
+		Concurrency: 2,
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return fs
+}
+
+func TestS3FileSystem_MultipartWrite(t *testing.T) {
+	fs := newMultipartFS(t)
+	content := make([]byte, 2*MinPartSize+1024)
+	if _, err := rand.Read(content); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// a reader of unknown length
+	stream := io.MultiReader(bytes.NewReader(content[:100]), bytes.NewReader(content[100:]))
+	if err := fs.WriteStream("testdata/file/for-write/multipart/large.bin", stream, nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	read, err := fs.Read("testdata/file/for-write/multipart/large.bin")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, bytes.Equal(content, read))
+}
+
+func TestReadPart(t *testing.T) {
+	t.Run("short", func(t *testing.T) {
+		part, err := readPart(bytes.NewReader([]byte("small")), MinPartSize)
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, []byte("small"), part)
+		// the buffer is sized for the content, not for a whole part
+		assert.Less(t, cap(part), MinPartSize)
+	})
+
+	t.Run("full", func(t *testing.T) {
+		content := make([]byte, 300<<10)
+		if _, err := rand.Read(content); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		stream := io.MultiReader(bytes.NewReader(content[:100]), bytes.NewReader(content[100:]))
+		part, err := readPart(stream, 200<<10)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(content[:200<<10], part))
+		rest, err := io.ReadAll(stream)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, bytes.Equal(content[200<<10:], rest))
+	})
+}
+
+func TestS3FileSystem_Upload(t *testing.T) {
+	t.Run("resume", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		upload, err := fs.BeginUpload("testdata/file/for-write/multipart/resumed.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		first := bytes.Repeat([]byte("a"), MinPartSize)
+		if _, err := fs.UploadPart(upload, 1, bytes.NewReader(first)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+
+		// another process resumes the upload
+		uploads, err := fs.ListIncomplete("testdata/file/for-write/multipart/")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var resumed *Upload
+		for _, u := range uploads {
+			if u.UploadID == upload.UploadID {
+				resumed = u
+			}
+		}
+		if !assert.NotNil(t, resumed) {
+			return
+		}
+		parts, err := fs.ListParts(resumed)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, parts, 1)
+		if _, err := fs.UploadPart(resumed, 2, bytes.NewReader([]byte("b"))); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := fs.Complete(resumed, nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		size, err := fs.FileSize("testdata/file/for-write/multipart/resumed.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(MinPartSize+1), size)
+	})
+
+	t.Run("janitor", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		upload, err := fs.BeginUpload("testdata/file/for-write/multipart/abandoned.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		aborted, err := fs.CleanIncompleteUploads(0)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.GreaterOrEqual(t, aborted, 1)
+		uploads, err := fs.ListIncomplete("testdata/file/for-write/multipart/")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for _, u := range uploads {
+			assert.NotEqual(t, upload.UploadID, u.UploadID)
+		}
+	})
+}
+
+func TestS3FileSystem_Create(t *testing.T) {
+	t.Run("close", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		content := make([]byte, 2*MinPartSize+1024)
+		if _, err := rand.Read(content); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		w, err := fs.Create("testdata/file/for-write/multipart/created.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for offset := 0; offset < len(content); offset += 1 << 20 {
+			if _, err := w.Write(content[offset:min(offset+1<<20, len(content))]); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+		if err := w.Close(); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		read, err := fs.Read("testdata/file/for-write/multipart/created.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, bytes.Equal(content, read))
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		fs := newMultipartFS(t)
+		w, err := fs.Create("testdata/file/for-write/multipart/aborted.bin", nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if _, err := w.Write(make([]byte, MinPartSize+1)); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NoError(t, w.Abort())
+		exists, err := fs.FileExists("testdata/file/for-write/multipart/aborted.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		uploads, err := fs.ListIncomplete("testdata/file/for-write/multipart/aborted.bin")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, uploads)
+	})
+}
@@ -1,6 +1,8 @@
 package s3
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gopi-frame/contract"
 	"github.com/gopi-frame/contract/filesystem"
@@ -39,3 +41,39 @@ func WithMimeTypeDetector(d filesystem.MimeTypeDetector) Option {
 		return nil
 	})
 }
+
+// WithPartSize sets the size of the parts of the multipart uploads of WriteStream
+func WithPartSize(size int64) Option {
+	if size <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(fs *S3FileSystem) error {
+		fs.partSize = size
+		return nil
+	})
+}
+
+// WithConcurrency sets the number of parts uploaded in parallel by WriteStream
+func WithConcurrency(n int) Option {
+	if n <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(fs *S3FileSystem) error {
+		fs.concurrency = n
+		return nil
+	})
+}
+
+// WithJanitor enables the janitor, which aborts every interval the multipart uploads older than olderThan
+func WithJanitor(interval, olderThan time.Duration) Option {
+	if olderThan <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(fs *S3FileSystem) error {
+		if interval > 0 {
+			fs.janitorInterval = interval
+		}
+		fs.abandonedAfter = olderThan
+		return nil
+	})
+}
@@ -0,0 +1,22 @@
+package s3
+
+import (
+	"os"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Symlink fails with [filesystem.ErrSymlinkUnsupported], objects cannot be links.
+func (s *S3FileSystem) Symlink(_, link string) error {
+	return filesystem.NewUnableToCreateSymlink(link, filesystem.ErrSymlinkUnsupported)
+}
+
+// Readlink fails with [filesystem.ErrSymlinkUnsupported], objects cannot be links.
+func (s *S3FileSystem) Readlink(path string) (string, error) {
+	return "", filesystem.NewUnableToReadSymlink(path, filesystem.ErrSymlinkUnsupported)
+}
+
+// Lstat fails with [filesystem.ErrSymlinkUnsupported], objects cannot be links.
+func (s *S3FileSystem) Lstat(path string) (os.FileInfo, error) {
+	return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrSymlinkUnsupported)
+}
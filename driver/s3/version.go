@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Stat returns the metadata of the object, with its ETag as its version.
+func (s *S3FileSystem) Stat(path string) (*filesystem.FileStat, error) {
+	path = filepath.ToSlash(path)
+	resp, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	lastModified := aws.ToTime(resp.LastModified)
+	if mtime, ok := filesystem.MtimeFromMetadata(resp.Metadata); ok {
+		lastModified = mtime
+	}
+	return &filesystem.FileStat{
+		Path:         path,
+		Size:         aws.ToInt64(resp.ContentLength),
+		LastModified: lastModified,
+		Version:      aws.ToString(resp.ETag),
+	}, nil
+}
+
+// preconditionFailed returns a [filesystem.PreconditionFailed] error if err is the failure of the conditions of a write,
+// or nil.
+func preconditionFailed(path string, err error, ifAbsent, ifMatch bool) error {
+	switch {
+	case ifAbsent && isStatus(err, http.StatusPreconditionFailed, http.StatusConflict):
+		return filesystem.NewPreconditionFailed(path, errors.Join(os.ErrExist, err))
+	case ifMatch && isStatus(err, http.StatusPreconditionFailed, http.StatusConflict):
+		return filesystem.NewPreconditionFailed(path, errors.Join(filesystem.ErrVersionMismatch, err))
+	case ifMatch && isStatus(err, http.StatusNotFound):
+		return filesystem.NewPreconditionFailed(path, errors.Join(os.ErrNotExist, err))
+	}
+	return nil
+}
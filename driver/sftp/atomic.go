@@ -0,0 +1,77 @@
+package sftp
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+var ErrPosixRenameUnsupported = errors.New("server does not support posix-rename@openssh.com")
+
+// copyBufferSize is the size of the chunks written while reporting progress,
+// large enough for the client to keep pipelining the writes.
+const copyBufferSize = 1 << 20
+
+// writeAtomic writes the stream to a temporary file next to the target,
+// and renames it over the target with posix-rename@openssh.com,
+// the only SFTP rename which replaces an existing file atomically.
+// The temporary file is synced first when the server supports fsync@openssh.com.
+// With os.O_APPEND, the current content is copied to the temporary file first.
+// The temporary file is removed if any step fails.
+func (fs *SFTPFileSystem) writeAtomic(sc *sftp.Client, path string, stream io.Reader, flag int, mode os.FileMode, tracker *filesystem.ProgressTracker) (err error) {
+	if _, ok := sc.HasExtension("posix-rename@openssh.com"); !ok {
+		return filesystem.NewUnableToWriteFile(path, ErrPosixRenameUnsupported)
+	}
+	temp := filesystem.AtomicTempPath(path)
+	file, err := sc.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = sc.Remove(temp)
+		}
+	}()
+	if flag&os.O_APPEND != 0 {
+		if err := appendCurrent(sc, file, path); err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+	}
+	if _, err := filesystem.CopyBufferWithProgress(file, stream, make([]byte, copyBufferSize), tracker); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	if _, ok := sc.HasExtension("fsync@openssh.com"); ok {
+		if err := file.Sync(); err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return filesystem.NewUnableToCloseFile(path, err)
+	}
+	if err := sc.Chmod(temp, mode); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	if err := sc.PosixRename(temp, path); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	tracker.Done()
+	return nil
+}
+
+func appendCurrent(sc *sftp.Client, file *sftp.File, path string) error {
+	current, err := sc.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer current.Close()
+	_, err = io.Copy(file, current)
+	return err
+}
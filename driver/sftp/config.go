@@ -55,6 +55,9 @@ type Config struct {
 	UseConcurrentWrites          bool
 	UseFstat                     bool
 
+	// MetadataSidecar keeps the metadata and tags of the files in sidecar files, see [WithMetadataSidecar].
+	MetadataSidecar bool
+
 	once      sync.Once
 	sshConfig *ssh.ClientConfig
 }
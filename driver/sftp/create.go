@@ -0,0 +1,28 @@
+package sftp
+
+import (
+	"io"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Create returns a writer to the file, written like WriteStream as the content is written to it,
+// over a single SFTP file handle.
+// The write is atomic unless the config sets [filesystem.AtomicKey] to false,
+// so that an aborted write leaves an existing file as it was.
+func (fs *SFTPFileSystem) Create(path string, config map[string]any) (filesystem.FileWriter, error) {
+	config = filesystem.AtomicByDefault(config)
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(path, err)
+	}
+	existed, err := fs.FileExists(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToWriteFile(path, err)
+	}
+	return filesystem.NewPipeWriter(func(stream io.Reader) error {
+		return fs.WriteStream(path, stream, config)
+	}, filesystem.DiscardFunc(cfg, existed, func() error {
+		return fs.Delete(path)
+	})), nil
+}
@@ -21,5 +21,5 @@ func (d *Driver) Open(options map[string]any) (fs.FileSystem, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewSFTPFileSystem(config)
+	return NewSFTPFileSystem(config, WithMetadataSidecar(config.MetadataSidecar))
 }
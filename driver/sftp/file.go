@@ -0,0 +1,47 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// OpenFile opens the file for random access, with the flag of [os.OpenFile], the handle is a [sftp.File].
+// With os.O_CREATE, the missing parent directories are created,
+// and a created file is given the visibility, or the default one if it is empty.
+// Sync needs the fsync@openssh.com extension on the server.
+func (fs *SFTPFileSystem) OpenFile(path string, flag int, visibility string) (filesystem.File, error) {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToOpenFile(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	mode := fs.visibilityConvertor.DefaultForFile()
+	if visibility != "" {
+		mode = fs.visibilityConvertor.ForFile(visibility)
+	}
+	created := false
+	if flag&os.O_CREATE != 0 {
+		if _, err := sc.Stat(path); errors.Is(err, os.ErrNotExist) {
+			created = true
+			if err := sc.MkdirAll(filepath.ToSlash(filepath.Dir(path))); err != nil {
+				return nil, filesystem.NewUnableToCreateDirectory(path, err)
+			}
+		}
+	}
+	file, err := sc.OpenFile(path, flag)
+	if err != nil {
+		return nil, filesystem.NewUnableToOpenFile(path, err)
+	}
+	if created {
+		if err := sc.Chmod(path, mode); err != nil {
+			_ = file.Close()
+			return nil, filesystem.NewUnableToSetPermission(path, err)
+		}
+	}
+	return file, nil
+}
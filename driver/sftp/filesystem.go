@@ -20,6 +20,7 @@ type SFTPFileSystem struct {
 	clientPool          ClientPool
 	mimeTypeDetector    fs2.MimeTypeDetector
 	visibilityConvertor unix.VisibilityConvertor
+	metadataSidecar     bool
 }
 
 func NewSFTPFileSystem(config *Config, opts ...Option) (*SFTPFileSystem, error) {
@@ -116,49 +117,78 @@ func (fs *SFTPFileSystem) ReadStream(path string) (io.ReadCloser, error) {
 	return file, nil
 }
 
-func (fs *SFTPFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the stream is read.
+func (fs *SFTPFileSystem) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
 	client, err := fs.clientPool.Get()
 	if err != nil {
 		return nil, filesystem.NewUnableToReadFile(path, err)
 	}
 	defer fs.clientPool.Put(client)
 	path = filepath.ToSlash(filepath.Clean(path))
-	entries, err := client.SFTPClient().ReadDir(path)
+	file, err := client.SFTPClient().Open(path)
 	if err != nil {
-		return nil, filesystem.NewUnableToReadDirectory(path, err)
+		return nil, filesystem.NewUnableToReadFile(path, err)
 	}
-	var dirEntries []os.DirEntry
-	for _, entry := range entries {
-		dirEntries = append(dirEntries, &dirEntry{entry})
+	progress := cfg.Progress()
+	if progress == nil {
+		return file, nil
 	}
-	return dirEntries, nil
+	var size int64 = -1
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	return filesystem.NewProgressTracker(path, size, progress).ReadCloser(file), nil
 }
 
-func (fs *SFTPFileSystem) WalkDir(path string, walkFn fs.WalkDirFunc) error {
+// ReadRange returns length bytes of the file starting at offset, the file is opened and seeked to the offset.
+func (fs *SFTPFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	stream, err := fs.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	seeker, ok := stream.(io.Seeker)
+	if !ok {
+		_ = stream.Close()
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrRandomAccessUnsupported)
+	}
+	if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		_ = stream.Close()
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return filesystem.LimitReadCloser(stream, length), nil
+}
+
+func (fs *SFTPFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
 	client, err := fs.clientPool.Get()
 	if err != nil {
-		return filesystem.NewUnableToReadDirectory(path, err)
+		return nil, filesystem.NewUnableToReadFile(path, err)
 	}
 	defer fs.clientPool.Put(client)
 	path = filepath.ToSlash(filepath.Clean(path))
-	w := client.SFTPClient().Walk(path)
-	for w.Step() {
-		if w.Stat().Mode()&os.ModeSymlink != 0 {
+	entries, err := client.SFTPClient().ReadDir(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	var dirEntries []os.DirEntry
+	for _, entry := range entries {
+		if fs.isSidecar(entry.Name()) {
 			continue
 		}
-		if err := walkFn(w.Path(), &dirEntry{w.Stat()}, err); err != nil {
-			if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
-				err = nil
-			}
-		}
-		if w.Err() != nil && !errors.Is(w.Err(), io.EOF) {
-			return filesystem.NewUnableToReadDirectory(path, w.Err())
-		}
-	}
-	if err != nil {
-		return filesystem.NewUnableToReadDirectory(path, err)
+		dirEntries = append(dirEntries, &dirEntry{entry})
 	}
-	return nil
+	return dirEntries, nil
+}
+
+// WalkDir walks the directory, symbolic links are reported as such and never followed.
+func (fs *SFTPFileSystem) WalkDir(path string, walkFn fs.WalkDirFunc) error {
+	return fs.WalkDirWithConfig(path, nil, walkFn)
 }
 
 func (fs *SFTPFileSystem) LastModified(path string) (time.Time, error) {
@@ -243,8 +273,10 @@ func (fs *SFTPFileSystem) WriteStream(path string, stream io.Reader, config map[
 	var dirMode = fs.visibilityConvertor.DefaultForDir()
 	var fileMode = fs.visibilityConvertor.DefaultForFile()
 	var writeFlag = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	var atomic bool
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
 			return filesystem.NewUnableToWriteFile(path, err)
 		}
@@ -257,6 +289,12 @@ func (fs *SFTPFileSystem) WriteStream(path string, stream io.Reader, config map[
 		if cfg.FileWriteFlag != nil {
 			writeFlag = *cfg.FileWriteFlag
 		}
+		if cfg.Atomic != nil {
+			atomic = *cfg.Atomic
+		}
+		if (cfg.Metadata != nil || cfg.Tags != nil) && !fs.metadataSidecar {
+			return filesystem.NewUnableToWriteFile(path, filesystem.ErrMetadataUnsupported)
+		}
 	}
 	if err := client.SFTPClient().MkdirAll(filepath.ToSlash(filepath.Dir(path))); err != nil {
 		return filesystem.NewUnableToCreateDirectory(path, err)
@@ -264,6 +302,13 @@ func (fs *SFTPFileSystem) WriteStream(path string, stream io.Reader, config map[
 	if err := client.SFTPClient().Chmod(filepath.ToSlash(filepath.Dir(path)), dirMode); err != nil {
 		return filesystem.NewUnableToSetPermission(path, err)
 	}
+	tracker := cfg.NewTracker(path, stream)
+	if atomic {
+		if err := fs.writeAtomic(client.SFTPClient(), path, stream, writeFlag, fileMode, tracker); err != nil {
+			return err
+		}
+		return fs.writeMetadata(client.SFTPClient(), path, cfg)
+	}
 	file, err := client.SFTPClient().OpenFile(path, writeFlag)
 	if err != nil {
 		return filesystem.NewUnableToWriteFile(path, err)
@@ -273,12 +318,16 @@ func (fs *SFTPFileSystem) WriteStream(path string, stream io.Reader, config map[
 			//TODO: error handle
 		}
 	}()
-	if _, err := io.Copy(file, stream); err != nil {
+	if _, err := filesystem.CopyBufferWithProgress(file, stream, make([]byte, copyBufferSize), tracker); err != nil {
 		return filesystem.NewUnableToWriteFile(path, err)
 	}
 	if err := client.SFTPClient().Chmod(path, fileMode); err != nil {
 		return filesystem.NewUnableToSetPermission(path, err)
 	}
+	if err := fs.writeMetadata(client.SFTPClient(), path, cfg); err != nil {
+		return err
+	}
+	tracker.Done()
 	return nil
 }
 
@@ -325,6 +374,9 @@ func (fs *SFTPFileSystem) Delete(path string) error {
 		if err := client.SFTPClient().Remove(path); err != nil {
 			return filesystem.NewUnableToDeleteFile(path, err)
 		}
+		if err := fs.deleteSidecar(client.SFTPClient(), path); err != nil {
+			return filesystem.NewUnableToDeleteFile(path, err)
+		}
 	}
 	return nil
 }
@@ -413,6 +465,9 @@ func (fs *SFTPFileSystem) Move(src string, dst string, config map[string]any) er
 	if err := client.SFTPClient().Rename(src, dst); err != nil {
 		return filesystem.NewUnableToMove(src, dst, err)
 	}
+	if err := fs.moveSidecar(client.SFTPClient(), src, dst); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
 	return nil
 }
 
@@ -425,8 +480,9 @@ func (fs *SFTPFileSystem) Copy(src string, dst string, config map[string]any) er
 	var dirMode = fs.visibilityConvertor.DefaultForDir()
 	var fileMode = fs.visibilityConvertor.DefaultForFile()
 	var writeFlag = os.O_CREATE | os.O_WRONLY
+	var cfg *filesystem.Config
 	if config != nil {
-		cfg, err := filesystem.NewConfig(config)
+		cfg, err = filesystem.NewConfig(config)
 		if err != nil {
 			return filesystem.NewUnableToCopyFile(src, dst, err)
 		}
@@ -460,11 +516,27 @@ func (fs *SFTPFileSystem) Copy(src string, dst string, config map[string]any) er
 		return filesystem.NewUnableToSetPermission(filepath.Dir(dst), err)
 	}
 	dstFile, err := client.SFTPClient().OpenFile(dst, writeFlag)
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	if err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	defer dstFile.Close()
+	var tracker *filesystem.ProgressTracker
+	if progress := cfg.Progress(); progress != nil {
+		var size int64 = -1
+		if info, err := srcFile.Stat(); err == nil {
+			size = info.Size()
+		}
+		tracker = filesystem.NewProgressTracker(dst, size, progress)
+	}
+	if _, err := filesystem.CopyBufferWithProgress(dstFile, srcFile, make([]byte, copyBufferSize), tracker); err != nil {
 		return filesystem.NewUnableToCopyFile(src, dst, err)
 	}
 	if err := client.SFTPClient().Chmod(dst, fileMode); err != nil {
 		return filesystem.NewUnableToSetPermission(dst, err)
 	}
+	if err := fs.copySidecar(client.SFTPClient(), src, dst); err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	tracker.Done()
 	return nil
 }
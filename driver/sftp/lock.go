@@ -0,0 +1,197 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Lock acquires the lock of the path, waiting until it is released or expires.
+func (fs *SFTPFileSystem) Lock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	return fs.LockContext(context.Background(), path, ttl)
+}
+
+// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+func (fs *SFTPFileSystem) LockContext(ctx context.Context, path string, ttl time.Duration) (filesystem.Lock, error) {
+	return filesystem.WaitForLock(ctx, func() (filesystem.Lock, error) {
+		return fs.TryLock(path, ttl)
+	})
+}
+
+// TryLock acquires the lock of the path, or returns a [filesystem.LockHeld] error if it is held.
+//
+// The lock is a file named after the path with [filesystem.LockSuffix],
+// created exclusively and holding the ownership metadata of the lock.
+// An expired lock file is renamed aside before being removed,
+// so that only one of the processes recovering it at the same time takes the lock over.
+func (fs *SFTPFileSystem) TryLock(path string, ttl time.Duration) (filesystem.Lock, error) {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToLock(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	lockPath := path + filesystem.LockSuffix
+	if err := sc.MkdirAll(filepath.ToSlash(filepath.Dir(lockPath))); err != nil {
+		return nil, filesystem.NewUnableToCreateDirectory(filepath.Dir(lockPath), err)
+	}
+	for {
+		info := filesystem.NewLockInfo(ttl)
+		err := createLockFile(sc, lockPath, info)
+		if err == nil {
+			return &lock{fs: fs, path: path, lockPath: lockPath, info: info}, nil
+		}
+		holder, statErr := readLockFile(sc, lockPath)
+		if statErr != nil {
+			if errors.Is(statErr, os.ErrNotExist) {
+				// the creation failed for another reason than an existing lock
+				return nil, filesystem.NewUnableToLock(path, err)
+			}
+			return nil, filesystem.NewUnableToLock(path, statErr)
+		}
+		if !holder.Expired() {
+			return nil, filesystem.NewLockHeld(path, holder)
+		}
+		if err := breakLockFile(sc, lockPath, holder); err != nil {
+			return nil, filesystem.NewLockHeld(path, holder)
+		}
+	}
+}
+
+func createLockFile(sc *sftp.Client, lockPath string, info filesystem.LockInfo) error {
+	file, err := sc.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(info.Bytes())
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+func readLockFile(sc *sftp.Client, lockPath string) (filesystem.LockInfo, error) {
+	file, err := sc.Open(lockPath)
+	if err != nil {
+		return filesystem.LockInfo{}, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return filesystem.LockInfo{}, err
+	}
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return filesystem.LockInfo{}, err
+	}
+	return filesystem.ParseLockInfo(content, stat.ModTime()), nil
+}
+
+// breakLockFile removes the expired lock of the holder.
+// The lock file is renamed to a unique name first, and put back if it turns out to be another lock,
+// acquired after the holder's one was read.
+func breakLockFile(sc *sftp.Client, lockPath string, holder filesystem.LockInfo) error {
+	stale := lockPath + "." + filesystem.NewLockInfo(0).Token
+	if err := sc.Rename(lockPath, stale); err != nil {
+		return err
+	}
+	renamed, err := readLockFile(sc, stale)
+	if err != nil {
+		return err
+	}
+	if renamed.Token != holder.Token || !renamed.ExpiresAt.Equal(holder.ExpiresAt) {
+		if err := sc.Rename(stale, lockPath); err != nil {
+			// the lock was acquired again meanwhile, and the other lock is lost to its holder anyway
+			_ = sc.Remove(stale)
+		}
+		return filesystem.ErrLockLost
+	}
+	return sc.Remove(stale)
+}
+
+type lock struct {
+	mu       sync.Mutex
+	fs       *SFTPFileSystem
+	path     string
+	lockPath string
+	info     filesystem.LockInfo
+	released bool
+}
+
+func (l *lock) Path() string {
+	return l.path
+}
+
+func (l *lock) Info() filesystem.LockInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.info
+}
+
+// owned returns the client, after checking the lock file still holds this lock.
+func (l *lock) owned() (Client, error) {
+	if l.released {
+		return nil, filesystem.ErrLockLost
+	}
+	client, err := l.fs.clientPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToLock(l.path, err)
+	}
+	holder, err := readLockFile(client.SFTPClient(), l.lockPath)
+	if err != nil || holder.Token != l.info.Token {
+		l.fs.clientPool.Put(client)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, filesystem.NewUnableToLock(l.path, err)
+		}
+		return nil, filesystem.ErrLockLost
+	}
+	return client, nil
+}
+
+func (l *lock) Refresh(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client, err := l.owned()
+	if err != nil {
+		return err
+	}
+	defer l.fs.clientPool.Put(client)
+	info := l.info.Renew(ttl)
+	file, err := client.SFTPClient().OpenFile(l.lockPath, os.O_WRONLY|os.O_TRUNC)
+	if err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	_, err = file.Write(info.Bytes())
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.info = info
+	return nil
+}
+
+func (l *lock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	client, err := l.owned()
+	if err != nil {
+		return err
+	}
+	defer l.fs.clientPool.Put(client)
+	if err := client.SFTPClient().Remove(l.lockPath); err != nil {
+		return filesystem.NewUnableToLock(l.path, err)
+	}
+	l.released = true
+	return nil
+}
@@ -0,0 +1,204 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Metadata returns the custom metadata of the file, kept in its sidecar file.
+// It fails with [filesystem.ErrMetadataUnsupported] unless the sidecars are enabled with [WithMetadataSidecar].
+func (fs *SFTPFileSystem) Metadata(path string) (map[string]string, error) {
+	sidecar, err := fs.sidecar(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return filesystem.CloneMetadata(sidecar.Metadata), nil
+}
+
+// SetMetadata replaces the custom metadata of the file, kept in its sidecar file.
+func (fs *SFTPFileSystem) SetMetadata(path string, metadata map[string]string) error {
+	return fs.updateSidecar(path, func(sidecar *filesystem.MetadataSidecar) {
+		sidecar.Metadata = metadata
+	})
+}
+
+// Tags returns the tags of the file, kept in its sidecar file.
+func (fs *SFTPFileSystem) Tags(path string) (map[string]string, error) {
+	sidecar, err := fs.sidecar(path)
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return filesystem.CloneMetadata(sidecar.Tags), nil
+}
+
+// SetTags replaces the tags of the file, kept in its sidecar file.
+func (fs *SFTPFileSystem) SetTags(path string, tags map[string]string) error {
+	return fs.updateSidecar(path, func(sidecar *filesystem.MetadataSidecar) {
+		sidecar.Tags = tags
+	})
+}
+
+func (fs *SFTPFileSystem) sidecar(path string) (*filesystem.MetadataSidecar, error) {
+	if !fs.metadataSidecar {
+		return nil, filesystem.ErrMetadataUnsupported
+	}
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	if err := checkFile(sc, path); err != nil {
+		return nil, err
+	}
+	return readSidecar(sc, path)
+}
+
+func (fs *SFTPFileSystem) updateSidecar(path string, update func(sidecar *filesystem.MetadataSidecar)) error {
+	if !fs.metadataSidecar {
+		return filesystem.NewUnableToSetMetadata(path, filesystem.ErrMetadataUnsupported)
+	}
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	if err := checkFile(sc, path); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	sidecar, err := readSidecar(sc, path)
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	update(sidecar)
+	if err := fs.saveSidecar(sc, path, sidecar); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// writeMetadata sets the metadata and tags given with the config of a write.
+func (fs *SFTPFileSystem) writeMetadata(sc *sftp.Client, path string, cfg *filesystem.Config) error {
+	if cfg == nil || (cfg.Metadata == nil && cfg.Tags == nil) {
+		return nil
+	}
+	sidecar, err := readSidecar(sc, path)
+	if err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	if cfg.Metadata != nil {
+		sidecar.Metadata = cfg.Metadata
+	}
+	if cfg.Tags != nil {
+		sidecar.Tags = cfg.Tags
+	}
+	if err := fs.saveSidecar(sc, path, sidecar); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+func checkFile(sc *sftp.Client, path string) error {
+	info, err := sc.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return filesystem.ErrIsNotFile
+	}
+	return nil
+}
+
+// readSidecar reads the sidecar of the file, empty if it has none.
+func readSidecar(sc *sftp.Client, path string) (*filesystem.MetadataSidecar, error) {
+	file, err := sc.Open(filesystem.MetadataSidecarPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return &filesystem.MetadataSidecar{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.ParseMetadataSidecar(content)
+}
+
+// saveSidecar writes the sidecar of the file, or deletes it if it keeps nothing.
+func (fs *SFTPFileSystem) saveSidecar(sc *sftp.Client, path string, sidecar *filesystem.MetadataSidecar) error {
+	if sidecar.Empty() {
+		return removeIfExists(sc, filesystem.MetadataSidecarPath(path))
+	}
+	content, err := sidecar.Marshal()
+	if err != nil {
+		return err
+	}
+	file, err := sc.OpenFile(filesystem.MetadataSidecarPath(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, bytes.NewReader(content)); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return sc.Chmod(filesystem.MetadataSidecarPath(path), fs.visibilityConvertor.DefaultForFile())
+}
+
+// isSidecar reports whether the listed file is a sidecar, hidden from the listings when they are enabled.
+func (fs *SFTPFileSystem) isSidecar(name string) bool {
+	return fs.metadataSidecar && filesystem.IsMetadataSidecar(name)
+}
+
+func (fs *SFTPFileSystem) deleteSidecar(sc *sftp.Client, path string) error {
+	if !fs.metadataSidecar {
+		return nil
+	}
+	return removeIfExists(sc, filesystem.MetadataSidecarPath(path))
+}
+
+func (fs *SFTPFileSystem) moveSidecar(sc *sftp.Client, src, dst string) error {
+	if !fs.metadataSidecar {
+		return nil
+	}
+	if err := removeIfExists(sc, filesystem.MetadataSidecarPath(dst)); err != nil {
+		return err
+	}
+	err := sc.Rename(filesystem.MetadataSidecarPath(src), filesystem.MetadataSidecarPath(dst))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (fs *SFTPFileSystem) copySidecar(sc *sftp.Client, src, dst string) error {
+	if !fs.metadataSidecar {
+		return nil
+	}
+	sidecar, err := readSidecar(sc, src)
+	if err != nil {
+		return err
+	}
+	return fs.saveSidecar(sc, dst, sidecar)
+}
+
+func removeIfExists(sc *sftp.Client, path string) error {
+	if err := sc.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
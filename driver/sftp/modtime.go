@@ -0,0 +1,67 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Touch sets the modification time of the file to now, creating an empty file if it is missing.
+// The missing parent directories are created.
+func (fs *SFTPFileSystem) Touch(path string) error {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	if _, err := sc.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := sc.MkdirAll(filepath.ToSlash(filepath.Dir(path))); err != nil {
+			return filesystem.NewUnableToCreateDirectory(path, err)
+		}
+		file, err := sc.OpenFile(path, os.O_WRONLY|os.O_CREATE)
+		if err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+		if err := file.Close(); err != nil {
+			return filesystem.NewUnableToCloseFile(path, err)
+		}
+		if err := sc.Chmod(path, fs.visibilityConvertor.DefaultForFile()); err != nil {
+			return filesystem.NewUnableToSetPermission(path, err)
+		}
+	}
+	now := time.Now()
+	if err := sc.Chtimes(path, now, now); err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
+
+// SetLastModified sets the modification time of the file, the access time is kept when the server reports it.
+func (fs *SFTPFileSystem) SetLastModified(path string, t time.Time) error {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	info, err := sc.Stat(path)
+	if err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	atime := t
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		atime = time.Unix(int64(stat.Atime), 0)
+	}
+	if err := sc.Chtimes(path, atime, t); err != nil {
+		return filesystem.NewUnableToSetLastModified(path, err)
+	}
+	return nil
+}
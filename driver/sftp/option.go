@@ -47,3 +47,16 @@ func WithVisibilityConvertor(convertor unix.VisibilityConvertor) OptionFunc {
 		return nil
 	}
 }
+
+// WithMetadataSidecar keeps the metadata and tags of every file in a hidden sidecar file next to it,
+// see [filesystem.MetadataSidecarPath], SFTP having no way to attach them to the file itself.
+// The sidecars are moved, copied and deleted along with their files, and hidden from the listings.
+func WithMetadataSidecar(enabled bool) OptionFunc {
+	if !enabled {
+		return noneOption
+	}
+	return func(fs *SFTPFileSystem) error {
+		fs.metadataSidecar = true
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package sftp
+
+import (
+	gofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Symlink creates link as a symbolic link to target, the missing parent directories are created.
+// The target is stored as is, an absolute one is a path of the server.
+func (fs *SFTPFileSystem) Symlink(target, link string) error {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToCreateSymlink(link, err)
+	}
+	defer fs.clientPool.Put(client)
+	link = filepath.ToSlash(filepath.Clean(link))
+	if err := client.SFTPClient().MkdirAll(path.Dir(link)); err != nil {
+		return filesystem.NewUnableToCreateDirectory(link, err)
+	}
+	if err := client.SFTPClient().Symlink(target, link); err != nil {
+		return filesystem.NewUnableToCreateSymlink(link, err)
+	}
+	return nil
+}
+
+// Readlink returns the target of the link, as it was stored.
+func (fs *SFTPFileSystem) Readlink(path string) (string, error) {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return "", filesystem.NewUnableToReadSymlink(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	target, err := client.SFTPClient().ReadLink(filepath.ToSlash(filepath.Clean(path)))
+	if err != nil {
+		return "", filesystem.NewUnableToReadSymlink(path, err)
+	}
+	return target, nil
+}
+
+// Lstat returns the info of the file, describing the link itself if it is one.
+func (fs *SFTPFileSystem) Lstat(path string) (os.FileInfo, error) {
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	info, err := client.SFTPClient().Lstat(filepath.ToSlash(filepath.Clean(path)))
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info, nil
+}
+
+// WalkDirWithConfig walks the directory like WalkDir, handling the links according to [filesystem.SymlinksKey].
+// Followed links are resolved by the server.
+func (fs *SFTPFileSystem) WalkDirWithConfig(path string, config map[string]any, walkFn gofs.WalkDirFunc) error {
+	var cfg *filesystem.Config
+	if config != nil {
+		var err error
+		if cfg, err = filesystem.NewConfig(config); err != nil {
+			return err
+		}
+	}
+	mode, err := cfg.SymlinkMode()
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	client, err := fs.clientPool.Get()
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	defer fs.clientPool.Put(client)
+	sc := client.SFTPClient()
+	path = filepath.ToSlash(filepath.Clean(path))
+	if info, err := sc.Stat(path); err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	} else if !info.IsDir() {
+		return filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	walk := &filesystem.SymlinkWalk{
+		ReadDir: func(path string) ([]gofs.DirEntry, error) {
+			infos, err := sc.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]gofs.DirEntry, 0, len(infos))
+			for _, info := range infos {
+				if fs.isSidecar(info.Name()) {
+					continue
+				}
+				entries = append(entries, &dirEntry{info})
+			}
+			return entries, nil
+		},
+		Stat: func(path string) (gofs.FileInfo, error) {
+			return sc.Stat(path)
+		},
+		RealPath: sc.RealPath,
+	}
+	return walk.Walk(path, mode, walkFn)
+}
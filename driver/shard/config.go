@@ -0,0 +1,89 @@
+package shard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+	"github.com/gopi-frame/filesystem"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+// ShardConfig describes a shard, either an opened file system or a driver to open it with.
+type ShardConfig struct {
+	// Name identifies the shard on the ring, it must never change once files are stored,
+	// or the files of the shard would be moved by the next rebalance.
+	Name string
+	// FileSystem is the backend of the shard.
+	FileSystem fs.FileSystem
+	// Driver is the name of a registered driver, used with Options when FileSystem is nil.
+	Driver string
+	// Options are the options passed to the driver.
+	Options map[string]any
+	// Weight is the relative share of files the shard receives, default is 1.
+	Weight int
+}
+
+func (c *ShardConfig) open() (fs.FileSystem, error) {
+	if c.FileSystem != nil {
+		return c.FileSystem, nil
+	}
+	if c.Driver == "" {
+		return nil, fmt.Errorf("shard %s: a file system or a driver is required", c.Name)
+	}
+	return filesystem.Open(c.Driver, c.Options)
+}
+
+type Config struct {
+	// Shards are the members of the ring.
+	Shards []ShardConfig
+	// VirtualNodes is the number of points per unit of weight on the ring, default is 160.
+	VirtualNodes int
+	// Pending marks a rebalance as pending, see [WithPending].
+	// Set it when shards were added to the configuration, until [ShardedFileSystem.Rebalance] has run.
+	Pending bool
+}
+
+func (c *Config) Apply(f *ShardedFileSystem) error {
+	if c.VirtualNodes > 0 {
+		f.virtualNodes = c.VirtualNodes
+	}
+	if c.Pending {
+		f.pending = true
+	}
+	for _, shard := range c.Shards {
+		backend, err := shard.open()
+		if err != nil {
+			return err
+		}
+		if err := f.addShard(shard.Name, backend, shard.Weight); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
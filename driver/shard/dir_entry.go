@@ -0,0 +1,44 @@
+package shard
+
+import (
+	"io/fs"
+	"path"
+	"time"
+)
+
+// dirEntry describes a directory of the merged tree, which exists on one or more shards.
+type dirEntry struct {
+	name string
+}
+
+func (d *dirEntry) Name() string {
+	return path.Base(d.name)
+}
+
+func (d *dirEntry) IsDir() bool {
+	return true
+}
+
+func (d *dirEntry) Type() fs.FileMode {
+	return fs.ModeDir
+}
+
+func (d *dirEntry) Info() (fs.FileInfo, error) {
+	return d, nil
+}
+
+func (d *dirEntry) Size() int64 {
+	return 0
+}
+
+func (d *dirEntry) Mode() fs.FileMode {
+	return fs.ModeDir | 0755
+}
+
+func (d *dirEntry) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (d *dirEntry) Sys() any {
+	return nil
+}
@@ -0,0 +1,552 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+var ErrNoShard = errors.New("at least one shard is required")
+
+type member struct {
+	name    string
+	backend fs.FileSystem
+	weight  int
+}
+
+// ShardedFileSystem distributes files over several backends with consistent hashing.
+//
+// Every file is stored on the shard owning its path on the ring.
+// Directories have no owner: they are created on every shard, with [ShardedFileSystem.CreateDir]
+// as well as the parents of the files written, and listings merge the entries of all shards.
+//
+// After [ShardedFileSystem.AddShard], or when opened with [WithPending], reads fall back to the other shards
+// until [ShardedFileSystem.Rebalance] has moved the files to their new owner.
+type ShardedFileSystem struct {
+	mu           sync.RWMutex
+	virtualNodes int
+	members      []*member
+	ring         *Ring
+	pending      bool
+	// generation is incremented every time the ring changes
+	generation int
+	// dirs holds the directories known to exist on every shard, so that their files do not check them again
+	dirs sync.Map
+}
+
+// NewShardedFileSystem creates a sharded file system, shards are added with [WithShard].
+func NewShardedFileSystem(opts ...Option) (*ShardedFileSystem, error) {
+	f := &ShardedFileSystem{
+		virtualNodes: defaultVirtualNodes,
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if len(f.members) == 0 {
+		return nil, ErrNoShard
+	}
+	f.ring = NewRing(f.virtualNodes)
+	for _, m := range f.members {
+		f.ring.Add(m.name, m.weight)
+	}
+	return f, nil
+}
+
+func (f *ShardedFileSystem) addShard(name string, backend fs.FileSystem, weight int) error {
+	if name == "" {
+		return errors.New("shard name is required")
+	}
+	if backend == nil {
+		return fmt.Errorf("shard %s: file system is required", name)
+	}
+	for _, m := range f.members {
+		if m.name == name {
+			return fmt.Errorf("duplicate shard: %s", name)
+		}
+	}
+	f.members = append(f.members, &member{name: name, backend: backend, weight: weight})
+	return nil
+}
+
+// AddShard adds a shard to a running file system.
+//
+// The files now owned by the new shard stay where they are until [ShardedFileSystem.Rebalance] is called,
+// in the meantime reads look for them on the other shards.
+func (f *ShardedFileSystem) AddShard(name string, backend fs.FileSystem, weight int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.addShard(name, backend, weight); err != nil {
+		return err
+	}
+	f.ring.Add(name, weight)
+	f.pending = true
+	f.generation++
+	f.forgetDirs()
+	return nil
+}
+
+// Shards returns the names of the shards, in the order they were added.
+func (f *ShardedFileSystem) Shards() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, len(f.members))
+	for i, m := range f.members {
+		names[i] = m.name
+	}
+	return names
+}
+
+// Locate returns the name of the shard owning the path.
+func (f *ShardedFileSystem) Locate(path string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.ring.Locate(filesystem.CleanPath(path))
+}
+
+func (f *ShardedFileSystem) snapshot() ([]*member, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	members := make([]*member, len(f.members))
+	copy(members, f.members)
+	return members, f.pending
+}
+
+// owner returns the shard the path is written to.
+func (f *ShardedFileSystem) owner(path string) *member {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	name := f.ring.Locate(filesystem.CleanPath(path))
+	for _, m := range f.members {
+		if m.name == name {
+			return m
+		}
+	}
+	return f.members[0]
+}
+
+// holder returns the shard the file is read from,
+// which is the owner unless a rebalance is pending and another shard still has the file.
+func (f *ShardedFileSystem) holder(path string) *member {
+	owner := f.owner(path)
+	members, pending := f.snapshot()
+	if !pending {
+		return owner
+	}
+	if exists, err := owner.backend.FileExists(path); err != nil || exists {
+		return owner
+	}
+	for _, m := range members {
+		if m == owner {
+			continue
+		}
+		if exists, err := m.backend.FileExists(path); err == nil && exists {
+			return m
+		}
+	}
+	return owner
+}
+
+func (f *ShardedFileSystem) Exists(path string) (bool, error) {
+	exists, err := f.FileExists(path)
+	if err != nil || exists {
+		return exists, err
+	}
+	return f.DirExists(path)
+}
+
+func (f *ShardedFileSystem) FileExists(path string) (bool, error) {
+	return f.holder(path).backend.FileExists(path)
+}
+
+func (f *ShardedFileSystem) DirExists(path string) (bool, error) {
+	members, _ := f.snapshot()
+	for _, m := range members {
+		exists, err := m.backend.DirExists(path)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *ShardedFileSystem) Read(path string) ([]byte, error) {
+	return f.holder(path).backend.Read(path)
+}
+
+func (f *ShardedFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	return f.holder(path).backend.ReadStream(path)
+}
+
+// ReadDir merges the entries of the directory on every shard, sorted by name.
+// A directory present on several shards is listed once.
+func (f *ShardedFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	members, _ := f.snapshot()
+	found := false
+	seen := make(map[string]bool)
+	var entries []os.DirEntry
+	for _, m := range members {
+		exists, err := m.backend.DirExists(path)
+		if err != nil {
+			return nil, filesystem.NewUnableToReadDirectory(path, err)
+		}
+		if !exists {
+			continue
+		}
+		found = true
+		list, err := m.backend.ReadDir(path)
+		if err != nil {
+			return nil, filesystem.NewUnableToReadDirectory(path, fmt.Errorf("shard %s: %w", m.name, err))
+		}
+		for _, entry := range list {
+			if seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+			entries = append(entries, entry)
+		}
+	}
+	if !found {
+		return nil, filesystem.NewUnableToReadDirectory(path, os.ErrNotExist)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// WalkDir walks the merged tree in lexical order, see [ShardedFileSystem.ReadDir].
+func (f *ShardedFileSystem) WalkDir(root string, walkFn gofs.WalkDirFunc) error {
+	exists, err := f.DirExists(root)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(root, err)
+	}
+	if !exists {
+		return filesystem.NewUnableToReadDirectory(root, os.ErrNotExist)
+	}
+	err = f.walkDir(root, &dirEntry{name: root}, walkFn)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (f *ShardedFileSystem) walkDir(p string, d gofs.DirEntry, walkFn gofs.WalkDirFunc) error {
+	if err := walkFn(p, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := f.ReadDir(p)
+	if err != nil {
+		err = walkFn(p, d, err)
+		if err != nil {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := f.walkDir(path.Join(p, entry.Name()), entry, walkFn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ShardedFileSystem) LastModified(path string) (time.Time, error) {
+	return f.holder(path).backend.LastModified(path)
+}
+
+func (f *ShardedFileSystem) FileSize(path string) (int64, error) {
+	return f.holder(path).backend.FileSize(path)
+}
+
+func (f *ShardedFileSystem) MimeType(path string) (string, error) {
+	return f.holder(path).backend.MimeType(path)
+}
+
+func (f *ShardedFileSystem) Visibility(path string) (string, error) {
+	if exists, err := f.FileExists(path); err == nil && !exists {
+		if m := f.dirHolder(path); m != nil {
+			return m.backend.Visibility(path)
+		}
+	}
+	return f.holder(path).backend.Visibility(path)
+}
+
+func (f *ShardedFileSystem) Write(path string, content []byte, config map[string]any) error {
+	owner := f.owner(path)
+	if err := f.createParents(path, owner, config); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	return owner.backend.Write(path, content, config)
+}
+
+func (f *ShardedFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	owner := f.owner(path)
+	if err := f.createParents(path, owner, config); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	return owner.backend.WriteStream(path, stream, config)
+}
+
+// createParents creates the parent directory of the file on every shard but the owner,
+// which creates it along with the file.
+func (f *ShardedFileSystem) createParents(p string, owner *member, config map[string]any) error {
+	dir := path.Dir(filesystem.CleanPath(p))
+	if dir == "." {
+		return nil
+	}
+	if _, ok := f.dirs.Load(dir); ok {
+		return nil
+	}
+	members, _ := f.snapshot()
+	for _, m := range members {
+		if m == owner {
+			continue
+		}
+		// the object stores only know a directory by its trailing slash
+		exists, err := m.backend.DirExists(dir + "/")
+		if err != nil {
+			return fmt.Errorf("shard %s: %w", m.name, err)
+		}
+		if exists {
+			continue
+		}
+		if err := m.backend.CreateDir(dir+"/", config); err != nil {
+			return fmt.Errorf("shard %s: %w", m.name, err)
+		}
+	}
+	f.dirs.Store(dir, struct{}{})
+	return nil
+}
+
+// forgetDirs empties the directories known to exist on every shard.
+func (f *ShardedFileSystem) forgetDirs() {
+	f.dirs.Range(func(dir, _ any) bool {
+		f.dirs.Delete(dir)
+		return true
+	})
+}
+
+// SetVisibility sets the visibility of a file on its shard, or of a directory on every shard.
+func (f *ShardedFileSystem) SetVisibility(path string, visibility string) error {
+	if exists, err := f.FileExists(path); err == nil && !exists && f.dirHolder(path) != nil {
+		return f.eachDir(path, func(m *member) error {
+			return m.backend.SetVisibility(path, visibility)
+		})
+	}
+	return f.holder(path).backend.SetVisibility(path, visibility)
+}
+
+// Delete deletes the file from its shard,
+// and from any other shard still holding a copy when a rebalance is pending.
+func (f *ShardedFileSystem) Delete(path string) error {
+	owner := f.owner(path)
+	members, pending := f.snapshot()
+	if !pending {
+		return owner.backend.Delete(path)
+	}
+	var errs []error
+	deleted := false
+	for _, m := range members {
+		if m != owner {
+			if exists, err := m.backend.FileExists(path); err != nil || !exists {
+				continue
+			}
+		}
+		if err := m.backend.Delete(path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		deleted = true
+	}
+	if !deleted && len(errs) > 0 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// DeleteDir deletes the directory from every shard it exists on,
+// a directory which does not exist is not an error, as for the local file system.
+func (f *ShardedFileSystem) DeleteDir(path string) error {
+	defer f.forgetDirs()
+	err := f.eachDir(path, func(m *member) error {
+		return m.backend.DeleteDir(path)
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// CreateDir creates the directory on every shard, so that empty directories are listed
+// whatever the shard their future files are stored on.
+func (f *ShardedFileSystem) CreateDir(path string, config map[string]any) error {
+	members, _ := f.snapshot()
+	for _, m := range members {
+		if err := m.backend.CreateDir(path, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move moves a file or a directory.
+// Files which stay on the same shard are moved by the backend,
+// the others are streamed to their new shard and deleted from the old one.
+func (f *ShardedFileSystem) Move(src string, dst string, config map[string]any) error {
+	if err := f.transfer(src, dst, config, true); err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	return nil
+}
+
+// Copy copies a file or a directory, see [ShardedFileSystem.Move].
+func (f *ShardedFileSystem) Copy(src string, dst string, config map[string]any) error {
+	if err := f.transfer(src, dst, config, false); err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	return nil
+}
+
+func (f *ShardedFileSystem) transfer(src, dst string, config map[string]any, move bool) error {
+	exists, err := f.FileExists(src)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return f.transferFile(src, dst, config, move)
+	}
+	if f.dirHolder(src) == nil {
+		return os.ErrNotExist
+	}
+	if err := f.CreateDir(dst, config); err != nil {
+		return err
+	}
+	var files []string
+	err = f.WalkDir(src, func(p string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == "." {
+				return nil
+			}
+			return f.CreateDir(path.Join(dst, filepath.ToSlash(rel)), config)
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, rel := range files {
+		if err := f.transferFile(path.Join(src, filepath.ToSlash(rel)), path.Join(dst, filepath.ToSlash(rel)), config, move); err != nil {
+			return err
+		}
+	}
+	if move {
+		return f.DeleteDir(src)
+	}
+	return nil
+}
+
+func (f *ShardedFileSystem) transferFile(src, dst string, config map[string]any, move bool) error {
+	from := f.holder(src)
+	to := f.owner(dst)
+	if err := f.createParents(dst, to, config); err != nil {
+		return err
+	}
+	if from == to {
+		if move {
+			return from.backend.Move(src, dst, config)
+		}
+		return from.backend.Copy(src, dst, config)
+	}
+	if err := copyFile(from.backend, to.backend, src, dst, config); err != nil {
+		return err
+	}
+	if move {
+		return from.backend.Delete(src)
+	}
+	return nil
+}
+
+// copyFile streams a file between two backends, keeping its visibility unless the config sets one.
+func copyFile(from, to fs.FileSystem, src, dst string, config map[string]any) error {
+	cfg := make(map[string]any, len(config)+1)
+	if visibility, err := from.Visibility(src); err == nil && visibility != "" {
+		cfg[filesystem.FileVisibilityKey] = visibility
+	}
+	for k, v := range config {
+		cfg[k] = v
+	}
+	stream, err := from.ReadStream(src)
+	if err != nil {
+		return err
+	}
+	err = to.WriteStream(dst, stream, cfg)
+	if err1 := stream.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	return err
+}
+
+// dirHolder returns the first shard the directory exists on.
+func (f *ShardedFileSystem) dirHolder(path string) *member {
+	members, _ := f.snapshot()
+	for _, m := range members {
+		if exists, err := m.backend.DirExists(path); err == nil && exists {
+			return m
+		}
+	}
+	return nil
+}
+
+// eachDir runs fn on every shard the directory exists on.
+func (f *ShardedFileSystem) eachDir(path string, fn func(m *member) error) error {
+	members, _ := f.snapshot()
+	found := false
+	for _, m := range members {
+		exists, err := m.backend.DirExists(path)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		found = true
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return os.ErrNotExist
+	}
+	return nil
+}
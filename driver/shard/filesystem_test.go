@@ -0,0 +1,309 @@
+package shard
+
+import (
+	"fmt"
+	gofs "io/fs"
+	"testing"
+
+	"github.com/gopi-frame/filesystem/driver/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newShards(t *testing.T, names ...string) (*ShardedFileSystem, map[string]*memory.MemoryFileSystem) {
+	backends := make(map[string]*memory.MemoryFileSystem)
+	var opts []Option
+	for _, name := range names {
+		backends[name] = memory.NewMemoryFileSystem("public", nil)
+		opts = append(opts, WithShard(name, backends[name], 1))
+	}
+	f, err := NewShardedFileSystem(opts...)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return f, backends
+}
+
+func TestRing(t *testing.T) {
+	t.Run("distribution", func(t *testing.T) {
+		ring := NewRing(0)
+		ring.Add("a", 1)
+		ring.Add("b", 1)
+		ring.Add("c", 1)
+		counts := make(map[string]int)
+		for i := 0; i < 3000; i++ {
+			counts[ring.Locate(fmt.Sprintf("media/%d.jpg", i))]++
+		}
+		for _, name := range []string{"a", "b", "c"} {
+			assert.InDelta(t, 1000, counts[name], 250)
+		}
+	})
+
+	t.Run("add shard", func(t *testing.T) {
+		ring := NewRing(0)
+		ring.Add("a", 1)
+		ring.Add("b", 1)
+		before := make(map[string]string)
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("media/%d.jpg", i)
+			before[key] = ring.Locate(key)
+		}
+		ring.Add("c", 1)
+		for key, owner := range before {
+			if after := ring.Locate(key); after != owner {
+				assert.Equal(t, "c", after)
+			}
+		}
+	})
+}
+
+func TestShardedFileSystem_Write(t *testing.T) {
+	f, backends := newShards(t, "a", "b", "c")
+	for i := 0; i < 30; i++ {
+		if err := f.Write(fmt.Sprintf("dir/%d.txt", i), []byte("content"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	for i := 0; i < 30; i++ {
+		path := fmt.Sprintf("dir/%d.txt", i)
+		for name, backend := range backends {
+			exists, err := backend.FileExists(path)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, name == f.Locate(path), exists, path)
+		}
+		content, err := f.Read(path)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "content", string(content))
+	}
+	entries, err := f.ReadDir("dir")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Len(t, entries, 30)
+	var files []string
+	err = f.WalkDir(".", func(path string, d gofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Len(t, files, 30)
+	assert.Contains(t, files, "dir/0.txt")
+}
+
+func TestShardedFileSystem_Move(t *testing.T) {
+	f, _ := newShards(t, "a", "b", "c")
+	for i := 0; i < 10; i++ {
+		if err := f.Write(fmt.Sprintf("src/%d.txt", i), []byte(fmt.Sprint(i)), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if err := f.Move("src", "dst", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	exists, err := f.DirExists("src")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.False(t, exists)
+	for i := 0; i < 10; i++ {
+		content, err := f.Read(fmt.Sprintf("dst/%d.txt", i))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, fmt.Sprint(i), string(content))
+	}
+	if err := f.Copy("dst/0.txt", "copy.txt", nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	content, err := f.Read("copy.txt")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "0", string(content))
+}
+
+func TestShardedFileSystem_Rebalance(t *testing.T) {
+	f, backends := newShards(t, "a", "b")
+	for i := 0; i < 100; i++ {
+		if err := f.Write(fmt.Sprintf("%d.txt", i), []byte(fmt.Sprint(i)), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	owners := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("%d.txt", i)
+		owners[path] = f.Locate(path)
+	}
+	c := memory.NewMemoryFileSystem("public", nil)
+	if err := f.AddShard("c", c, 1); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// files are still readable before the rebalance
+	for i := 0; i < 100; i++ {
+		content, err := f.Read(fmt.Sprintf("%d.txt", i))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, fmt.Sprint(i), string(content))
+	}
+	expected := 0
+	for path := range owners {
+		if f.Locate(path) == "c" {
+			expected++
+		}
+	}
+	moved, err := f.Rebalance()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, expected, moved)
+	assert.Greater(t, moved, 0)
+	for path, previous := range owners {
+		owner := f.Locate(path)
+		if owner != "c" {
+			assert.Equal(t, previous, owner)
+		}
+		exists, err := c.FileExists(path)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, owner == "c", exists)
+		exists, err = backends[previous].FileExists(path)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, owner != "c", exists)
+	}
+}
+
+func TestShardedFileSystem_Dirs(t *testing.T) {
+	f, backends := newShards(t, "a", "b")
+	if err := f.Write("dir/sub/file.txt", []byte("content"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// the parents of a file exist on every shard, as the directories created with CreateDir
+	for name, backend := range backends {
+		exists, err := backend.DirExists("dir/sub")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists, name)
+	}
+	// nested files are rebalanced, and their parents created on the new shard
+	c := memory.NewMemoryFileSystem("public", nil)
+	if err := f.AddShard("c", c, 1); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for i := 0; i < 30; i++ {
+		if err := backends["a"].Write(fmt.Sprintf("nested/%d.txt", i), []byte(fmt.Sprint(i)), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	if _, err := f.Rebalance(); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for i := 0; i < 30; i++ {
+		path := fmt.Sprintf("nested/%d.txt", i)
+		exists, err := backends["a"].FileExists(path)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, f.Locate(path) == "a", exists, path)
+	}
+	exists, err := c.DirExists("dir/sub")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.True(t, exists)
+
+	if err := f.DeleteDir("dir"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	// a missing directory is not an error, as for the local file system
+	assert.Nil(t, f.DeleteDir("dir"))
+	if err := f.Write("dir/file.txt", []byte("content"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for name, backend := range backends {
+		exists, err := backend.DirExists("dir")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists, name)
+	}
+}
+
+func TestDriver_Open(t *testing.T) {
+	a := memory.NewMemoryFileSystem("public", nil)
+	b := memory.NewMemoryFileSystem("public", nil)
+	f, err := new(Driver).Open(map[string]any{
+		"shards": []map[string]any{
+			{"name": "a", "filesystem": a},
+			{"name": "b", "filesystem": b, "weight": "2"},
+		},
+		"virtual_nodes": 64,
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, []string{"a", "b"}, f.(*ShardedFileSystem).Shards())
+}
+
+func TestDriver_OpenPending(t *testing.T) {
+	previous, backends := newShards(t, "a", "b")
+	for i := 0; i < 100; i++ {
+		if err := previous.Write(fmt.Sprintf("%d.txt", i), []byte(fmt.Sprint(i)), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+	}
+	options := func(pending bool) map[string]any {
+		return map[string]any{
+			"shards": []map[string]any{
+				{"name": "a", "filesystem": backends["a"]},
+				{"name": "b", "filesystem": backends["b"]},
+				{"name": "c", "filesystem": memory.NewMemoryFileSystem("public", nil)},
+			},
+			"pending": pending,
+		}
+	}
+	f, err := new(Driver).Open(options(false))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	missing := 0
+	for i := 0; i < 100; i++ {
+		if _, err := f.Read(fmt.Sprintf("%d.txt", i)); err != nil {
+			missing++
+		}
+	}
+	// without the flag, the files now owned by c are not found
+	assert.Greater(t, missing, 0)
+
+	f, err = new(Driver).Open(options(true))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for i := 0; i < 100; i++ {
+		content, err := f.Read(fmt.Sprintf("%d.txt", i))
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, fmt.Sprint(i), string(content))
+	}
+	moved, err := f.(*ShardedFileSystem).Rebalance()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, missing, moved)
+}
@@ -0,0 +1,46 @@
+package shard
+
+import (
+	"github.com/gopi-frame/contract"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*ShardedFileSystem]
+
+type OptionFunc func(f *ShardedFileSystem) error
+
+func (o OptionFunc) Apply(f *ShardedFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *ShardedFileSystem) error {
+	return nil
+})
+
+// WithShard adds a shard to the ring.
+func WithShard(name string, backend fs.FileSystem, weight int) Option {
+	return OptionFunc(func(f *ShardedFileSystem) error {
+		return f.addShard(name, backend, weight)
+	})
+}
+
+// WithVirtualNodes sets the number of points per unit of weight on the ring.
+func WithVirtualNodes(n int) Option {
+	if n <= 0 {
+		return noneOption
+	}
+	return OptionFunc(func(f *ShardedFileSystem) error {
+		f.virtualNodes = n
+		return nil
+	})
+}
+
+// WithPending marks a rebalance as pending, e.g. when shards were added to the configuration since the last one:
+// reads then fall back to the other shards until [ShardedFileSystem.Rebalance] has moved the files to their owner.
+func WithPending() Option {
+	return OptionFunc(func(f *ShardedFileSystem) error {
+		f.pending = true
+		return nil
+	})
+}
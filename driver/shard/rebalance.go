@@ -0,0 +1,81 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	gofs "io/fs"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Rebalance moves every file which is not stored on the shard owning it, and returns the number of files moved.
+//
+// Only the keys whose owner changed since they were written are touched.
+// The files are walked from the root of every shard, and their parent directories are created on every shard.
+// When the owner already has a copy, it was written after the ring changed,
+// so the misplaced copy is stale and is deleted instead of moved.
+//
+// Failures do not stop the rebalance, they are joined in the returned error,
+// and reads keep falling back to the other shards until a rebalance completes without errors.
+func (f *ShardedFileSystem) Rebalance() (int, error) {
+	f.mu.RLock()
+	generation := f.generation
+	f.mu.RUnlock()
+	members, _ := f.snapshot()
+	moved := 0
+	var errs []error
+	for _, m := range members {
+		var files []string
+		err := filesystem.WalkDirRelative(m.backend, ".", func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("shard %s: %w", m.name, err))
+			continue
+		}
+		for _, path := range files {
+			owner := f.owner(path)
+			if owner == m {
+				continue
+			}
+			err := f.createParents(path, owner, nil)
+			if err == nil {
+				err = rebalanceFile(m, owner, path)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("shard %s: %s: %w", m.name, path, err))
+				continue
+			}
+			moved++
+		}
+	}
+	if len(errs) > 0 {
+		return moved, errors.Join(errs...)
+	}
+	f.mu.Lock()
+	// a shard added during the rebalance needs another pass
+	if f.generation == generation {
+		f.pending = false
+	}
+	f.mu.Unlock()
+	return moved, nil
+}
+
+func rebalanceFile(from, to *member, path string) error {
+	exists, err := to.backend.FileExists(path)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := copyFile(from.backend, to.backend, path, path, nil); err != nil {
+			return err
+		}
+	}
+	return from.backend.Delete(path)
+}
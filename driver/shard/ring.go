@@ -0,0 +1,105 @@
+package shard
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const defaultVirtualNodes = 160
+
+// Ring is a consistent hash ring mapping keys to shard names.
+//
+// Every shard owns weight*virtualNodes points on the ring,
+// a key belongs to the shard owning the first point at or after its hash.
+// Adding or removing a shard only changes the owner of the keys between its points and their predecessors.
+type Ring struct {
+	virtualNodes int
+	weights      map[string]int
+	points       []uint64
+	owners       map[uint64]string
+}
+
+func NewRing(virtualNodes int) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+	return &Ring{
+		virtualNodes: virtualNodes,
+		weights:      make(map[string]int),
+		owners:       make(map[uint64]string),
+	}
+}
+
+// Add adds a shard to the ring, a weight lower than 1 is treated as 1.
+func (r *Ring) Add(name string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	r.weights[name] = weight
+	r.build()
+}
+
+// Remove removes a shard from the ring.
+func (r *Ring) Remove(name string) {
+	delete(r.weights, name)
+	r.build()
+}
+
+// Shards returns the names of the shards in the ring, sorted.
+func (r *Ring) Shards() []string {
+	names := make([]string, 0, len(r.weights))
+	for name := range r.weights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locate returns the shard owning the key, or an empty string if the ring is empty.
+func (r *Ring) Locate(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+func (r *Ring) build() {
+	r.points = r.points[:0]
+	r.owners = make(map[uint64]string)
+	// iterate in a stable order so that colliding points always resolve to the same shard
+	for _, name := range r.Shards() {
+		for i := 0; i < r.weights[name]*r.virtualNodes; i++ {
+			h := hash(name + "#" + strconv.Itoa(i))
+			if _, ok := r.owners[h]; ok {
+				continue
+			}
+			r.owners[h] = name
+			r.points = append(r.points, h)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i] < r.points[j]
+	})
+}
+
+// hash is FNV-1a followed by the splitmix64 finalizer,
+// which spreads keys sharing a long common prefix, such as paths, over the whole ring.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
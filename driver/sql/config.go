@@ -0,0 +1,89 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+)
+
+type Config struct {
+	// DB is the database, opened with DriverName and DSN if it is nil.
+	// The database driver has to be imported by the application, e.g. github.com/mattn/go-sqlite3 or github.com/jackc/pgx/v5/stdlib.
+	DB         *gosql.DB
+	DriverName string
+	DSN        string
+	// Dialect is "sqlite" or "postgres", default is guessed from DriverName.
+	Dialect string
+	// Table prefixes the names of the tables, default is "filesystem", e.g. filesystem_files and filesystem_chunks.
+	Table string
+	// ChunkSize is the size of the rows the content is split into, default is 1 MiB.
+	ChunkSize int
+	// SkipMigrations disables the migrations run when the file system is created,
+	// for the deployments applying them with their own tool, see [Migrate] and the migrations directory.
+	SkipMigrations bool
+	// Visibility is the default visibility of the files and directories, default is "public".
+	Visibility string
+}
+
+func (c *Config) Apply(f *SQLFileSystem) error {
+	if c.DB != nil {
+		f.db = c.DB
+	} else if c.DriverName != "" {
+		db, err := gosql.Open(c.DriverName, c.DSN)
+		if err != nil {
+			return err
+		}
+		f.db = db
+	}
+	if c.Dialect != "" || c.DriverName != "" {
+		name := c.Dialect
+		if name == "" {
+			name = c.DriverName
+		}
+		dialect, err := DialectByName(name)
+		if err != nil {
+			return err
+		}
+		f.dialect = dialect
+	}
+	if c.Table != "" {
+		if !validTable(c.Table) {
+			return ErrInvalidTable
+		}
+		f.table = c.Table
+	}
+	if c.ChunkSize > 0 {
+		f.chunkSize = c.ChunkSize
+	}
+	f.migrate = !c.SkipMigrations
+	if c.Visibility != "" {
+		f.visibility = c.Visibility
+	}
+	return nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			env.ExpandStringKeyMapWithEnvHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,119 @@
+package sql
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	gofs "io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownDialect = errors.New("unknown sql dialect")
+
+//go:embed migrations
+var migrations embed.FS
+
+// Dialect is what differs between the databases: the placeholders of the queries and the migrations.
+// The queries otherwise use the SQL common to SQLite and PostgreSQL, e.g. INSERT ... ON CONFLICT.
+type Dialect interface {
+	// Name returns the name of the dialect.
+	Name() string
+	// Placeholder returns the placeholder of the n-th argument of a query, counted from 1.
+	Placeholder(n int) string
+	// Migrations returns the migrations creating and updating the tables, ordered by version.
+	Migrations() ([]Migration, error)
+}
+
+// Migration is a step of the schema, its SQL names the tables with the {{table}} prefix.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Statements returns the statements of the migration for the table prefix.
+func (m Migration) Statements(table string) []string {
+	var statements []string
+	for _, statement := range strings.Split(strings.ReplaceAll(m.SQL, "{{table}}", table), ";\n") {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, strings.TrimSuffix(statement, ";"))
+		}
+	}
+	return statements
+}
+
+var (
+	// SQLite is the dialect of SQLite 3.24 or later.
+	SQLite Dialect = &embeddedDialect{name: "sqlite", placeholder: func(int) string { return "?" }}
+	// Postgres is the dialect of PostgreSQL 9.5 or later.
+	Postgres Dialect = &embeddedDialect{name: "postgres", placeholder: func(n int) string { return "$" + strconv.Itoa(n) }}
+)
+
+// DialectByName returns the dialect of the name, or of the name of a database driver, e.g. "sqlite3" or "pgx".
+func DialectByName(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	case "postgres", "postgresql", "pgx", "pq":
+		return Postgres, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownDialect, name)
+}
+
+// embeddedDialect reads its migrations from the embedded migrations/<name> directory.
+type embeddedDialect struct {
+	name        string
+	placeholder func(n int) string
+}
+
+func (d *embeddedDialect) Name() string {
+	return d.name
+}
+
+func (d *embeddedDialect) Placeholder(n int) string {
+	return d.placeholder(n)
+}
+
+func (d *embeddedDialect) Migrations() ([]Migration, error) {
+	dir := path.Join("migrations", d.name)
+	entries, err := gofs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []Migration
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration name %s: %w", entry.Name(), err)
+		}
+		content, err := gofs.ReadFile(migrations, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	return result, nil
+}
+
+// rebind replaces the ? placeholders of the query with the ones of the dialect.
+func rebind(dialect Dialect, query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(dialect.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
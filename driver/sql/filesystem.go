@@ -0,0 +1,586 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	gosql "database/sql"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+var ErrNoDatabase = errors.New("sql database is required")
+
+var ErrNoDialect = errors.New("sql dialect is required")
+
+// sniffSize is the length of the content used to detect the mime type.
+const sniffSize = 512
+
+// SQLFileSystem is a file system stored in the tables of a database, for the deployments without an object store.
+//
+// Every file and directory is a row of the <table>_files table, with its visibility and custom metadata.
+// The content of the files is split into rows of the <table>_chunks table, written and read one chunk at a time.
+// A write stores the chunks of the new content first, and then replaces the row of the file in a transaction,
+// so that the readers see either the old or the new content. Move and Copy are transactions too.
+type SQLFileSystem struct {
+	db               *gosql.DB
+	dialect          Dialect
+	table            string
+	chunkSize        int
+	migrate          bool
+	visibility       string
+	mimetypeDetector fs.MimeTypeDetector
+}
+
+// NewSQLFileSystem creates a file system in the database of the config, applying the migrations unless they are skipped.
+func NewSQLFileSystem(config *Config, opts ...Option) (*SQLFileSystem, error) {
+	f := &SQLFileSystem{
+		table:      "filesystem",
+		chunkSize:  1 << 20,
+		visibility: "public",
+	}
+	if err := config.Apply(f); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if f.db == nil {
+		return nil, ErrNoDatabase
+	}
+	if f.dialect == nil {
+		return nil, ErrNoDialect
+	}
+	if f.mimetypeDetector == nil {
+		f.mimetypeDetector = filesystem.NewMimeTypeDetector()
+	}
+	if f.migrate {
+		if err := Migrate(context.Background(), f.db, f.dialect, f.table); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *SQLFileSystem) Exists(path string) (bool, error) {
+	_, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return true, nil
+}
+
+func (f *SQLFileSystem) FileExists(path string) (bool, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return !fl.dir, nil
+}
+
+func (f *SQLFileSystem) DirExists(path string) (bool, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return fl.dir, nil
+}
+
+func (f *SQLFileSystem) Read(path string) ([]byte, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return content, nil
+}
+
+// ReadStream returns the content read chunk by chunk, the stream fails with [ErrChanged]
+// if the file is overwritten or deleted before it is read to the end.
+func (f *SQLFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	return f.ReadRange(path, 0, -1)
+}
+
+// ReadRange returns length bytes of the file starting at offset, reading the chunks of the range only.
+func (f *SQLFileSystem) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	if err := filesystem.CheckRange(offset); err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if fl.dir {
+		return nil, filesystem.NewUnableToReadFile(path, filesystem.ErrIsNotFile)
+	}
+	return filesystem.LimitReadCloser(f.newChunkReader(fl, offset), length), nil
+}
+
+func (f *SQLFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	ctx := context.Background()
+	k := filesystem.CleanPath(path)
+	fl, err := f.stat(ctx, f.db, k)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	if !fl.dir {
+		return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+	}
+	files, err := f.children(ctx, f.db, k)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	entries := make([]os.DirEntry, 0, len(files))
+	for _, fl := range files {
+		entries = append(entries, fl)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// WalkDir walks the tree with a query per directory.
+func (f *SQLFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	root, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	var walkDir func(p string, d gofs.DirEntry) error
+	walkDir = func(p string, d gofs.DirEntry) error {
+		if err := walkFn(p, d, nil); err != nil || !d.IsDir() {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+		entries, err := f.ReadDir(p)
+		if err != nil {
+			err = walkFn(p, d, err)
+			if err != nil {
+				if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+					err = nil
+				}
+				return err
+			}
+		}
+		for _, entry := range entries {
+			if err := walkDir(filepath.ToSlash(filepath.Join(p, entry.Name())), entry); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					break
+				}
+				return err
+			}
+		}
+		return nil
+	}
+	err = walkDir(path, root)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (f *SQLFileSystem) LastModified(path string) (time.Time, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return fl.modTime, nil
+}
+
+func (f *SQLFileSystem) FileSize(path string) (int64, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return fl.size, nil
+}
+
+// MimeType returns the mime type detected when the file was written.
+func (f *SQLFileSystem) MimeType(path string) (string, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if fl.dir {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrIsNotFile)
+	}
+	return fl.mimeType, nil
+}
+
+func (f *SQLFileSystem) Visibility(path string) (string, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return fl.visibility, nil
+}
+
+// Stat returns the metadata of the file, its version is the id of its content, renewed by every write.
+func (f *SQLFileSystem) Stat(path string) (*filesystem.FileStat, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if fl.dir {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, filesystem.ErrIsNotFile)
+	}
+	return &filesystem.FileStat{Path: path, Size: fl.size, LastModified: fl.modTime, Version: fl.contentID}, nil
+}
+
+func (f *SQLFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return f.WriteStream(path, bytes.NewReader(content), config)
+}
+
+func (f *SQLFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	_, err := f.WriteVersioned(path, stream, config)
+	return err
+}
+
+// WriteVersioned writes like WriteStream, and returns the version of the written file.
+//
+// The chunks are stored outside of the transaction replacing the file, so that a large upload holds no lock.
+// The conditions of [filesystem.IfAbsentKey] and [filesystem.IfMatchKey] are checked in that transaction.
+// The visibility of an overwritten file is kept unless it is set in the config, its metadata and tags are replaced.
+//
+// With os.O_APPEND, the full chunks of the file are copied to the new content in the database, without being read,
+// and the stream is stored after its last chunk; the write fails with [ErrChanged] if the file is replaced meanwhile.
+func (f *SQLFileSystem) WriteVersioned(path string, stream io.Reader, config map[string]any) (string, error) {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return "", err
+	}
+	k := filesystem.CleanPath(path)
+	if k == "." {
+		return "", filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+	}
+	ctx := context.Background()
+	appending := cfg.FileWriteFlag != nil && *cfg.FileWriteFlag&os.O_APPEND != 0
+	var base *file
+	if appending {
+		base, err = f.stat(ctx, f.db, k)
+		if errors.Is(err, os.ErrNotExist) {
+			base = nil
+		} else if err != nil {
+			return "", filesystem.NewUnableToWriteFile(path, err)
+		} else if base.dir {
+			return "", filesystem.NewUnableToWriteFile(path, filesystem.ErrIsNotFile)
+		}
+	}
+	contentID, err := newContentID()
+	if err != nil {
+		return "", filesystem.NewUnableToWriteFile(path, err)
+	}
+	tracker := cfg.NewTracker(path, stream)
+	body := stream
+	if tracker != nil {
+		body = tracker.ReadCloser(io.NopCloser(stream))
+	}
+	chunkSize := int64(f.chunkSize)
+	var seq int64
+	if base != nil && base.size > 0 {
+		chunkSize = base.chunkSize
+		var tail []byte
+		if seq, tail, err = f.copyFullChunks(ctx, base, contentID); err != nil {
+			_ = f.deleteChunks(ctx, f.db, contentID)
+			return "", filesystem.NewUnableToWriteFile(path, err)
+		}
+		body = io.MultiReader(bytes.NewReader(tail), body)
+	}
+	size, header, err := f.writeChunks(ctx, contentID, body, seq, chunkSize)
+	if err != nil {
+		_ = f.deleteChunks(ctx, f.db, contentID)
+		return "", filesystem.NewUnableToWriteFile(path, err)
+	}
+	mimeType := f.mimetypeDetector.Detect(path, header)
+	if seq > 0 {
+		size += seq * chunkSize
+		mimeType = base.mimeType
+	}
+	dirVisibility := f.visibility
+	if cfg.DirVisibility != nil {
+		dirVisibility = *cfg.DirVisibility
+	}
+	fl := &file{
+		path:       k,
+		size:       size,
+		chunkSize:  chunkSize,
+		contentID:  contentID,
+		mimeType:   mimeType,
+		visibility: f.visibility,
+		metadata:   cfg.Metadata,
+		tags:       cfg.Tags,
+		modTime:    time.Now(),
+	}
+	err = f.tx(ctx, func(tx *gosql.Tx) error {
+		current, err := f.stat(ctx, tx, k)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if current != nil && current.dir {
+			return filesystem.ErrIsNotFile
+		}
+		// the content appended to was copied before the transaction, the file must not have changed since
+		if appending {
+			switch {
+			case base == nil && current != nil, base != nil && (current == nil || current.contentID != base.contentID):
+				return ErrChanged
+			}
+		}
+		version := ""
+		if current != nil {
+			version = current.contentID
+			fl.visibility = current.visibility
+		}
+		if err := cfg.CheckPrecondition(path, current != nil, version); err != nil {
+			return err
+		}
+		if cfg.FileVisibility != nil {
+			fl.visibility = *cfg.FileVisibility
+		}
+		if err := f.mkdirAll(ctx, tx, parent(k), dirVisibility); err != nil {
+			return err
+		}
+		if err := f.put(ctx, tx, fl); err != nil {
+			return err
+		}
+		if current != nil {
+			return f.deleteChunks(ctx, tx, current.contentID)
+		}
+		return nil
+	})
+	if err != nil {
+		_ = f.deleteChunks(ctx, f.db, contentID)
+		var preconditionFailed *filesystem.PreconditionFailed
+		if errors.As(err, &preconditionFailed) {
+			return "", err
+		}
+		return "", filesystem.NewUnableToWriteFile(path, err)
+	}
+	tracker.Done()
+	return contentID, nil
+}
+
+func (f *SQLFileSystem) SetVisibility(path string, visibility string) error {
+	k := filesystem.CleanPath(path)
+	if k == "." {
+		return filesystem.NewUnableToSetPermission(path, errors.New("can't set the visibility of the root directory"))
+	}
+	if err := f.update(k, "visibility = ?", visibility); err != nil {
+		return filesystem.NewUnableToSetPermission(path, err)
+	}
+	return nil
+}
+
+// update sets columns of the row of the path, it fails if the path has no row.
+func (f *SQLFileSystem) update(k string, set string, args ...any) error {
+	result, err := f.db.ExecContext(context.Background(), f.query("UPDATE {files} SET "+set+" WHERE path = ?"), append(args, k)...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+func (f *SQLFileSystem) Delete(path string) error {
+	ctx := context.Background()
+	k := filesystem.CleanPath(path)
+	err := f.tx(ctx, func(tx *gosql.Tx) error {
+		fl, err := f.stat(ctx, tx, k)
+		if err != nil {
+			return err
+		}
+		if fl.dir {
+			return filesystem.ErrIsNotFile
+		}
+		if _, err := tx.ExecContext(ctx, f.query("DELETE FROM {files} WHERE path = ?"), k); err != nil {
+			return err
+		}
+		return f.deleteChunks(ctx, tx, fl.contentID)
+	})
+	if err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	return nil
+}
+
+// DeleteDir deletes the directory and everything it contains, in a transaction.
+func (f *SQLFileSystem) DeleteDir(path string) error {
+	ctx := context.Background()
+	k := filesystem.CleanPath(path)
+	if k == "." {
+		return filesystem.NewUnableToDeleteDirectory(path, errors.New("can't delete the root directory"))
+	}
+	err := f.tx(ctx, func(tx *gosql.Tx) error {
+		fl, err := f.stat(ctx, tx, k)
+		if err != nil {
+			return err
+		}
+		if !fl.dir {
+			return filesystem.ErrIsNotDirectory
+		}
+		if _, err := tx.ExecContext(ctx, f.query("DELETE FROM {chunks} WHERE content_id IN "+
+			"(SELECT content_id FROM {files} WHERE "+subtree+")"), subtreeArgs(k)...); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, f.query("DELETE FROM {files} WHERE "+subtree), subtreeArgs(k)...)
+		return err
+	})
+	if err != nil {
+		return filesystem.NewUnableToDeleteDirectory(path, err)
+	}
+	return nil
+}
+
+func (f *SQLFileSystem) CreateDir(path string, config map[string]any) error {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return err
+	}
+	visibility := f.visibility
+	if cfg.DirVisibility != nil {
+		visibility = *cfg.DirVisibility
+	}
+	ctx := context.Background()
+	if err := f.tx(ctx, func(tx *gosql.Tx) error {
+		return f.mkdirAll(ctx, tx, filesystem.CleanPath(path), visibility)
+	}); err != nil {
+		return filesystem.NewUnableToCreateDirectory(path, err)
+	}
+	return nil
+}
+
+// Move renames the file or directory and everything it contains in a transaction, the content is not copied.
+// A file replaces the file at the destination, a directory can't replace anything.
+func (f *SQLFileSystem) Move(src string, dst string, config map[string]any) error {
+	ctx := context.Background()
+	srcKey, dstKey := filesystem.CleanPath(src), filesystem.CleanPath(dst)
+	err := f.tx(ctx, func(tx *gosql.Tx) error {
+		if _, err := f.prepareTransfer(ctx, tx, srcKey, dstKey); err != nil {
+			return err
+		}
+		n := utf8.RuneCountInString(srcKey) + 1
+		args := append([]any{dstKey, n, srcKey, parent(dstKey), dstKey, n}, subtreeArgs(srcKey)...)
+		_, err := tx.ExecContext(ctx, f.query("UPDATE {files} SET path = CAST(? AS TEXT) || substr(path, ?), "+
+			"parent = CASE WHEN path = ? THEN CAST(? AS TEXT) ELSE CAST(? AS TEXT) || substr(parent, ?) END WHERE "+subtree), args...)
+		return err
+	})
+	if err != nil {
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	return nil
+}
+
+// Copy copies the file or directory and everything it contains in a transaction,
+// the chunks are copied by the database without being read.
+func (f *SQLFileSystem) Copy(src string, dst string, config map[string]any) error {
+	ctx := context.Background()
+	srcKey, dstKey := filesystem.CleanPath(src), filesystem.CleanPath(dst)
+	err := f.tx(ctx, func(tx *gosql.Tx) error {
+		if _, err := f.prepareTransfer(ctx, tx, srcKey, dstKey); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx, f.query("SELECT "+fileColumns+" FROM {files} WHERE "+subtree), subtreeArgs(srcKey)...)
+		if err != nil {
+			return err
+		}
+		var files []*file
+		for rows.Next() {
+			fl, err := scanFile(rows)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+			files = append(files, fl)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, fl := range files {
+			fl.path = dstKey + strings.TrimPrefix(fl.path, srcKey)
+			fl.modTime = now
+			if fl.contentID, err = f.copyChunks(ctx, tx, fl.contentID); err != nil {
+				return err
+			}
+			if err := f.put(ctx, tx, fl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	return nil
+}
+
+// prepareTransfer checks the source and the destination of a move or a copy, creates the parents of the destination,
+// and deletes the file it replaces.
+func (f *SQLFileSystem) prepareTransfer(ctx context.Context, tx *gosql.Tx, srcKey, dstKey string) (*file, error) {
+	if srcKey == "." || dstKey == "." {
+		return nil, errors.New("can't move or copy the root directory")
+	}
+	if dstKey == srcKey || strings.HasPrefix(dstKey, srcKey+"/") {
+		return nil, fmt.Errorf("can't move or copy %s into itself", srcKey)
+	}
+	fl, err := f.stat(ctx, tx, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	current, err := f.stat(ctx, tx, dstKey)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if current != nil {
+		if fl.dir || current.dir {
+			return nil, os.ErrExist
+		}
+		if _, err := tx.ExecContext(ctx, f.query("DELETE FROM {files} WHERE path = ?"), dstKey); err != nil {
+			return nil, err
+		}
+		if err := f.deleteChunks(ctx, tx, current.contentID); err != nil {
+			return nil, err
+		}
+	}
+	parentVisibility := f.visibility
+	if p, err := f.stat(ctx, tx, parent(srcKey)); err == nil {
+		parentVisibility = p.visibility
+	}
+	if err := f.mkdirAll(ctx, tx, parent(dstKey), parentVisibility); err != nil {
+		return nil, err
+	}
+	return fl, nil
+}
@@ -0,0 +1,353 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	gosql "database/sql"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFS(t *testing.T, chunkSize int) (*SQLFileSystem, *gosql.DB) {
+	db, err := gosql.Open("sqlite3", filepath.Join(t.TempDir(), "fs.db")+"?_busy_timeout=5000")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	f, err := NewSQLFileSystem(&Config{DB: db, Dialect: "sqlite", ChunkSize: chunkSize})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return f, db
+}
+
+func countRows(t *testing.T, db *gosql.DB, table string) int {
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM " + table).Scan(&n); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return n
+}
+
+func TestSQLFileSystem(t *testing.T) {
+	f, db := newFS(t, 4)
+
+	t.Run("write and read", func(t *testing.T) {
+		if err := f.Write("docs/a/hello.txt", []byte("hello world"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		// 11 bytes in chunks of 4.
+		assert.Equal(t, 3, countRows(t, db, "filesystem_chunks"))
+		content, err := f.Read("docs/a/hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello world", string(content))
+		exists, err := f.DirExists("docs/a")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.True(t, exists)
+
+		if err := f.Write("docs/a/hello.txt", []byte("bye"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err = f.Read("docs/a/hello.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "bye", string(content))
+		// the chunks of the old content are deleted.
+		assert.Equal(t, 1, countRows(t, db, "filesystem_chunks"))
+
+		if err := f.Write("empty.txt", nil, nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err = f.Read("empty.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Empty(t, content)
+
+		assert.ErrorIs(t, f.Write("docs", []byte("x"), nil), filesystem.ErrIsNotFile)
+		assert.ErrorIs(t, f.Write("docs/a/hello.txt/x", []byte("x"), nil), filesystem.ErrIsNotDirectory)
+		_, err = f.Read("missing.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("read range", func(t *testing.T) {
+		if err := f.Write("range.txt", []byte("0123456789abcdef"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for _, c := range []struct {
+			offset, length int64
+			expected       string
+		}{
+			{0, 3, "012"},
+			{3, 6, "345678"},
+			{8, -1, "89abcdef"},
+			{15, 10, "f"},
+			{16, 10, ""},
+			{100, -1, ""},
+		} {
+			stream, err := f.ReadRange("range.txt", c.offset, c.length)
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			content, err := io.ReadAll(stream)
+			_ = stream.Close()
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, c.expected, string(content), "offset %d, length %d", c.offset, c.length)
+		}
+	})
+
+	t.Run("changed while read", func(t *testing.T) {
+		if err := f.Write("changing.txt", []byte("0123456789"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		stream, err := f.ReadStream("changing.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		defer stream.Close()
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(stream, buf); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("changing.txt", []byte("new"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		_, err = io.ReadAll(stream)
+		assert.ErrorIs(t, err, ErrChanged)
+	})
+
+	t.Run("metadata and visibility", func(t *testing.T) {
+		err := f.Write("report.csv", []byte("a,b\n1,2\n"), map[string]any{
+			filesystem.MetadataKey:       map[string]string{"uploader": "alice"},
+			filesystem.FileVisibilityKey: "private",
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		metadata, err := f.Metadata("report.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"uploader": "alice"}, metadata)
+		visibility, err := f.Visibility("report.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "private", visibility)
+		mimeType, err := f.MimeType("report.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "text/plain; charset=utf-8", mimeType)
+
+		// an overwrite keeps the visibility.
+		if err := f.Write("report.csv", []byte("a,b\n"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		visibility, err = f.Visibility("report.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "private", visibility)
+
+		if err := f.SetVisibility("report.csv", "public"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.SetTags("report.csv", map[string]string{"retention": "1y"}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		tags, err := f.Tags("report.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, map[string]string{"retention": "1y"}, tags)
+		assert.ErrorIs(t, f.SetMetadata("missing.csv", nil), os.ErrNotExist)
+	})
+
+	t.Run("conditional write", func(t *testing.T) {
+		stat, err := f.Stat("report.csv")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		version, err := f.WriteVersioned("report.csv", strings.NewReader("c,d\n"), map[string]any{filesystem.IfMatchKey: stat.Version})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.NotEqual(t, stat.Version, version)
+		_, err = f.WriteVersioned("report.csv", strings.NewReader("e,f\n"), map[string]any{filesystem.IfMatchKey: stat.Version})
+		assert.ErrorIs(t, err, filesystem.ErrVersionMismatch)
+		_, err = f.WriteVersioned("report.csv", strings.NewReader("e,f\n"), map[string]any{filesystem.IfAbsentKey: true})
+		assert.ErrorIs(t, err, os.ErrExist)
+	})
+
+	t.Run("append", func(t *testing.T) {
+		appendFlag := map[string]any{filesystem.FileWriteFlagKey: os.O_WRONLY | os.O_CREATE | os.O_APPEND}
+		if err := f.Write("log.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		before := countRows(t, db, "filesystem_chunks")
+		// the full chunk is copied, the partial one is stored again with the appended content
+		if err := f.Write("log.txt", []byte(" world"), appendFlag); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, before+1, countRows(t, db, "filesystem_chunks"))
+		if err := f.Write("log.txt", []byte("!!!"), appendFlag); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := f.Read("log.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello world!!!", string(content))
+		size, _ := f.FileSize("log.txt")
+		assert.Equal(t, int64(14), size)
+		if err := f.Write("new.log", []byte("new"), appendFlag); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ = f.Read("new.log")
+		assert.Equal(t, "new", string(content))
+		for _, p := range []string{"log.txt", "new.log"} {
+			if err := f.Delete(p); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+		}
+	})
+
+	t.Run("move and copy", func(t *testing.T) {
+		if err := f.Write("tree/a.txt", []byte("a"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("tree/sub/b.txt", []byte("b"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Move("tree", "moved/tree", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, err := f.Exists("tree")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+		content, err := f.Read("moved/tree/sub/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "b", string(content))
+		entries, err := f.ReadDir("moved/tree")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Len(t, entries, 2)
+
+		if err := f.Copy("moved/tree", "copied", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("copied/a.txt", []byte("changed"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err = f.Read("moved/tree/a.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "a", string(content))
+
+		// a file replaces a file, not a directory.
+		if err := f.Move("copied/a.txt", "copied/sub/b.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err = f.Read("copied/sub/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "changed", string(content))
+		assert.ErrorIs(t, f.Move("copied/sub/b.txt", "moved", nil), os.ErrExist)
+		assert.Error(t, f.Move("moved", "moved/inside", nil))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.ErrorIs(t, f.Delete("moved"), filesystem.ErrIsNotFile)
+		if err := f.Delete("moved/tree/a.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		before := countRows(t, db, "filesystem_chunks")
+		if err := f.DeleteDir("copied"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		// copied/sub/b.txt holds "changed", in 2 chunks.
+		assert.Equal(t, before-2, countRows(t, db, "filesystem_chunks"))
+		exists, err := f.Exists("copied/sub/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, exists)
+	})
+
+	t.Run("walk dir", func(t *testing.T) {
+		var paths []string
+		err := f.WalkDir("moved", func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{"moved", "moved/tree", "moved/tree/sub", "moved/tree/sub/b.txt"}, paths)
+	})
+}
+
+func TestSQLFileSystem_Stream(t *testing.T) {
+	f, db := newFS(t, 1024)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	if err := f.WriteStream("large.bin", bytes.NewReader(content), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 10, countRows(t, db, "filesystem_chunks"))
+	size, err := f.FileSize("large.bin")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, int64(len(content)), size)
+	read, err := f.Read("large.bin")
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, content, read)
+}
+
+func TestMigrate(t *testing.T) {
+	_, db := newFS(t, 0)
+	// the migrations are applied once.
+	if err := Migrate(context.Background(), db, SQLite, "filesystem"); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, 1, countRows(t, db, "filesystem_migrations"))
+	assert.ErrorIs(t, Migrate(context.Background(), db, SQLite, "files; DROP TABLE x"), ErrInvalidTable)
+
+	migrations, err := Postgres.Migrations()
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Len(t, migrations[0].Statements("uploads"), 3)
+	assert.Contains(t, migrations[0].Statements("uploads")[0], "CREATE TABLE uploads_files")
+	assert.Equal(t, "SELECT data FROM t WHERE a = $1 AND b = $2", rebind(Postgres, "SELECT data FROM t WHERE a = ? AND b = ?"))
+}
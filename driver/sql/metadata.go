@@ -0,0 +1,50 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// Metadata returns the custom metadata of the file or directory.
+func (f *SQLFileSystem) Metadata(path string) (map[string]string, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return filesystem.CloneMetadata(fl.metadata), nil
+}
+
+// SetMetadata replaces the custom metadata of the file or directory.
+func (f *SQLFileSystem) SetMetadata(path string, metadata map[string]string) error {
+	if err := f.setMap(path, "metadata", metadata); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+// Tags returns the tags of the file or directory.
+func (f *SQLFileSystem) Tags(path string) (map[string]string, error) {
+	fl, err := f.stat(context.Background(), f.db, filesystem.CleanPath(path))
+	if err != nil {
+		return nil, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return filesystem.CloneMetadata(fl.tags), nil
+}
+
+// SetTags replaces the tags of the file or directory.
+func (f *SQLFileSystem) SetTags(path string, tags map[string]string) error {
+	if err := f.setMap(path, "tags", tags); err != nil {
+		return filesystem.NewUnableToSetMetadata(path, err)
+	}
+	return nil
+}
+
+func (f *SQLFileSystem) setMap(path string, column string, m map[string]string) error {
+	value, err := json.Marshal(filesystem.CloneMetadata(m))
+	if err != nil {
+		return err
+	}
+	return f.update(filesystem.CleanPath(path), column+" = ?", string(value))
+}
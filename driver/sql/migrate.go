@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidTable = errors.New("table prefix can only contain letters, digits and underscores")
+
+// Migrate applies the migrations of the dialect which were not applied yet, each one in a transaction.
+// The applied versions are recorded in the <table>_migrations table.
+func Migrate(ctx context.Context, db *gosql.DB, dialect Dialect, table string) error {
+	if !validTable(table) {
+		return ErrInvalidTable
+	}
+	migrations, err := dialect.Migrations()
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s_migrations (version INTEGER NOT NULL PRIMARY KEY, applied_at BIGINT NOT NULL)", table,
+	)); err != nil {
+		return err
+	}
+	applied := map[int]bool{}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %s_migrations", table))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if err := applyMigration(ctx, db, dialect, table, migration); err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *gosql.DB, dialect Dialect, table string, migration Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// the version is recorded first, so that a concurrent migration fails on the primary key instead of applying it twice.
+	if _, err := tx.ExecContext(ctx, rebind(dialect, fmt.Sprintf(
+		"INSERT INTO %s_migrations (version, applied_at) VALUES (?, ?)", table,
+	)), migration.Version, time.Now().UnixNano()); err != nil {
+		return err
+	}
+	for _, statement := range migration.Statements(table) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func validTable(table string) bool {
+	if table == "" {
+		return false
+	}
+	for _, r := range table {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
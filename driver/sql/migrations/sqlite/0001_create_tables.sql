@@ -0,0 +1,22 @@
+CREATE TABLE {{table}}_files (
+    path        TEXT    NOT NULL PRIMARY KEY,
+    parent      TEXT    NOT NULL,
+    is_dir      INTEGER NOT NULL,
+    size        INTEGER NOT NULL DEFAULT 0,
+    chunk_size  INTEGER NOT NULL DEFAULT 0,
+    content_id  TEXT    NOT NULL DEFAULT '',
+    mime_type   TEXT    NOT NULL DEFAULT '',
+    visibility  TEXT    NOT NULL,
+    metadata    TEXT    NOT NULL DEFAULT '{}',
+    tags        TEXT    NOT NULL DEFAULT '{}',
+    modified_at INTEGER NOT NULL
+);
+
+CREATE INDEX {{table}}_files_parent ON {{table}}_files (parent);
+
+CREATE TABLE {{table}}_chunks (
+    content_id TEXT    NOT NULL,
+    seq        INTEGER NOT NULL,
+    data       BLOB    NOT NULL,
+    PRIMARY KEY (content_id, seq)
+);
@@ -0,0 +1,40 @@
+package sql
+
+import (
+	"github.com/gopi-frame/contract"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*SQLFileSystem]
+
+type OptionFunc func(f *SQLFileSystem) error
+
+func (o OptionFunc) Apply(f *SQLFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *SQLFileSystem) error {
+	return nil
+})
+
+func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
+	if detector == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *SQLFileSystem) error {
+		f.mimetypeDetector = detector
+		return nil
+	})
+}
+
+// WithDialect sets the dialect, replacing the one of the config, e.g. a dialect of another database.
+func WithDialect(dialect Dialect) Option {
+	if dialect == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *SQLFileSystem) error {
+		f.dialect = dialect
+		return nil
+	})
+}
@@ -0,0 +1,335 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	gosql "database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	gofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// ErrChanged is the error of a stream whose file was overwritten or deleted while it was read.
+var ErrChanged = errors.New("file changed while it was read")
+
+func parent(k string) string {
+	return path.Dir(k)
+}
+
+// querier is implemented by both the database and its transactions.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (gosql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*gosql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *gosql.Row
+}
+
+// query formats the query with the names of the tables, {files} and {chunks}, and rebinds its placeholders.
+func (f *SQLFileSystem) query(query string) string {
+	return rebind(f.dialect, strings.NewReplacer("{files}", f.table+"_files", "{chunks}", f.table+"_chunks").Replace(query))
+}
+
+// subtree is the condition selecting the file or directory and all its descendants,
+// it compares prefixes with substr rather than LIKE, so that the paths need no escaping.
+const subtree = "(path = ? OR substr(path, 1, ?) = ?)"
+
+func subtreeArgs(k string) []any {
+	return []any{k, utf8.RuneCountInString(k) + 1, k + "/"}
+}
+
+const fileColumns = "path, is_dir, size, chunk_size, content_id, mime_type, visibility, metadata, tags, modified_at"
+
+// file is a row of the files table, it implements both [gofs.FileInfo] and [gofs.DirEntry].
+type file struct {
+	path       string
+	dir        bool
+	size       int64
+	chunkSize  int64
+	contentID  string
+	mimeType   string
+	visibility string
+	metadata   map[string]string
+	tags       map[string]string
+	modTime    time.Time
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFile(row scanner) (*file, error) {
+	var (
+		fl             file
+		metadata, tags string
+		modifiedAt     int64
+	)
+	if err := row.Scan(&fl.path, &fl.dir, &fl.size, &fl.chunkSize, &fl.contentID, &fl.mimeType, &fl.visibility, &metadata, &tags, &modifiedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(metadata), &fl.metadata); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &fl.tags); err != nil {
+		return nil, err
+	}
+	fl.modTime = time.Unix(0, modifiedAt)
+	return &fl, nil
+}
+
+// stat returns the row of the path, the root directory has no row and is always returned.
+func (f *SQLFileSystem) stat(ctx context.Context, q querier, k string) (*file, error) {
+	if k == "." {
+		return &file{path: ".", dir: true, visibility: f.visibility}, nil
+	}
+	fl, err := scanFile(q.QueryRowContext(ctx, f.query("SELECT "+fileColumns+" FROM {files} WHERE path = ?"), k))
+	if errors.Is(err, gosql.ErrNoRows) {
+		return nil, os.ErrNotExist
+	}
+	return fl, err
+}
+
+// children returns the rows of the entries of the directory.
+func (f *SQLFileSystem) children(ctx context.Context, q querier, k string) ([]*file, error) {
+	rows, err := q.QueryContext(ctx, f.query("SELECT "+fileColumns+" FROM {files} WHERE parent = ?"), k)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []*file
+	for rows.Next() {
+		fl, err := scanFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, fl)
+	}
+	return files, rows.Err()
+}
+
+// put inserts or replaces the row of the file.
+func (f *SQLFileSystem) put(ctx context.Context, q querier, fl *file) error {
+	metadata, err := json.Marshal(filesystem.CloneMetadata(fl.metadata))
+	if err != nil {
+		return err
+	}
+	tags, err := json.Marshal(filesystem.CloneMetadata(fl.tags))
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, f.query("INSERT INTO {files} ("+fileColumns+", parent) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON CONFLICT (path) DO UPDATE SET is_dir = excluded.is_dir, size = excluded.size, chunk_size = excluded.chunk_size, "+
+		"content_id = excluded.content_id, mime_type = excluded.mime_type, visibility = excluded.visibility, "+
+		"metadata = excluded.metadata, tags = excluded.tags, modified_at = excluded.modified_at"),
+		fl.path, fl.dir, fl.size, fl.chunkSize, fl.contentID, fl.mimeType, fl.visibility, string(metadata), string(tags), fl.modTime.UnixNano(), parent(fl.path))
+	return err
+}
+
+// mkdirAll inserts the rows of the directory and of its missing parents.
+func (f *SQLFileSystem) mkdirAll(ctx context.Context, q querier, k string, visibility string) error {
+	if k == "." {
+		return nil
+	}
+	fl, err := f.stat(ctx, q, k)
+	if err == nil {
+		if !fl.dir {
+			return filesystem.ErrIsNotDirectory
+		}
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := f.mkdirAll(ctx, q, parent(k), visibility); err != nil {
+		return err
+	}
+	return f.put(ctx, q, &file{path: k, dir: true, visibility: visibility, modTime: time.Now()})
+}
+
+// tx runs fn in a transaction, committed if fn returns no error.
+func (f *SQLFileSystem) tx(ctx context.Context, fn func(tx *gosql.Tx) error) error {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func newContentID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// writeChunks stores the stream in rows of the chunk size from the chunk seq,
+// and returns its size and its first bytes to detect its mime type.
+// A chunk is inserted as soon as it is read, so that only one chunk is held in memory.
+func (f *SQLFileSystem) writeChunks(ctx context.Context, contentID string, stream io.Reader, seq, chunkSize int64) (int64, []byte, error) {
+	buf := make([]byte, chunkSize)
+	var (
+		size   int64
+		header []byte
+	)
+	for ; ; seq++ {
+		n, err := io.ReadFull(stream, buf)
+		if n > 0 {
+			if header == nil {
+				header = append([]byte(nil), buf[:min(n, sniffSize)]...)
+			}
+			if _, err := f.db.ExecContext(ctx, f.query("INSERT INTO {chunks} (content_id, seq, data) VALUES (?, ?, ?)"), contentID, seq, buf[:n]); err != nil {
+				return 0, nil, err
+			}
+			size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return size, header, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+func (f *SQLFileSystem) deleteChunks(ctx context.Context, q querier, contentID string) error {
+	if contentID == "" {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, f.query("DELETE FROM {chunks} WHERE content_id = ?"), contentID)
+	return err
+}
+
+// copyChunks copies the chunks of the content to a new content in the database, without reading them.
+// The parameters without a column to infer their type from are cast, as PostgreSQL requires.
+func (f *SQLFileSystem) copyChunks(ctx context.Context, q querier, contentID string) (string, error) {
+	if contentID == "" {
+		return "", nil
+	}
+	newID, err := newContentID()
+	if err != nil {
+		return "", err
+	}
+	_, err = q.ExecContext(ctx, f.query("INSERT INTO {chunks} (content_id, seq, data) SELECT CAST(? AS TEXT), seq, data FROM {chunks} WHERE content_id = ?"), newID, contentID)
+	return newID, err
+}
+
+// copyFullChunks copies the full chunks of the file to the content in the database, without reading them,
+// and returns the number of chunks copied and the content of the last chunk of the file if it is partial,
+// which is stored again with the content appended to the file.
+func (f *SQLFileSystem) copyFullChunks(ctx context.Context, fl *file, contentID string) (int64, []byte, error) {
+	full := fl.size / fl.chunkSize
+	_, err := f.db.ExecContext(ctx, f.query("INSERT INTO {chunks} (content_id, seq, data) SELECT CAST(? AS TEXT), seq, data FROM {chunks} WHERE content_id = ? AND seq < ?"), contentID, fl.contentID, full)
+	if err != nil {
+		return 0, nil, err
+	}
+	if fl.size%fl.chunkSize == 0 {
+		return full, nil, nil
+	}
+	var tail []byte
+	err = f.db.QueryRowContext(ctx, f.query("SELECT data FROM {chunks} WHERE content_id = ? AND seq = ?"), fl.contentID, full).Scan(&tail)
+	if errors.Is(err, gosql.ErrNoRows) {
+		return 0, nil, ErrChanged
+	}
+	return full, tail, err
+}
+
+// chunkReader reads the content chunk by chunk, with a query per chunk so that no connection is held between the reads.
+type chunkReader struct {
+	f         *SQLFileSystem
+	contentID string
+	seq       int64
+	end       int64
+	skip      int64
+	buf       []byte
+}
+
+// newChunkReader returns a reader of the content of the file starting at offset.
+func (f *SQLFileSystem) newChunkReader(fl *file, offset int64) *chunkReader {
+	r := &chunkReader{f: f, contentID: fl.contentID}
+	if fl.size == 0 || fl.chunkSize == 0 {
+		return r
+	}
+	r.end = (fl.size + fl.chunkSize - 1) / fl.chunkSize
+	r.seq = offset / fl.chunkSize
+	r.skip = offset % fl.chunkSize
+	return r
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.seq >= r.end {
+			return 0, io.EOF
+		}
+		var data []byte
+		err := r.f.db.QueryRowContext(context.Background(), r.f.query("SELECT data FROM {chunks} WHERE content_id = ? AND seq = ?"), r.contentID, r.seq).Scan(&data)
+		if errors.Is(err, gosql.ErrNoRows) {
+			return 0, ErrChanged
+		}
+		if err != nil {
+			return 0, err
+		}
+		if r.skip >= int64(len(data)) {
+			r.seq = r.end
+			return 0, io.EOF
+		}
+		r.buf = data[r.skip:]
+		r.skip = 0
+		r.seq++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *chunkReader) Close() error {
+	r.buf = nil
+	r.seq = r.end
+	return nil
+}
+
+func (fl *file) Name() string {
+	return path.Base(fl.path)
+}
+
+func (fl *file) Size() int64 {
+	return fl.size
+}
+
+func (fl *file) Mode() gofs.FileMode {
+	if fl.dir {
+		return gofs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (fl *file) ModTime() time.Time {
+	return fl.modTime
+}
+
+func (fl *file) IsDir() bool {
+	return fl.dir
+}
+
+func (fl *file) Sys() any {
+	return nil
+}
+
+func (fl *file) Type() gofs.FileMode {
+	return fl.Mode().Type()
+}
+
+func (fl *file) Info() (gofs.FileInfo, error) {
+	return fl, nil
+}
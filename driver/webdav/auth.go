@@ -0,0 +1,243 @@
+package webdav
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// authTransport authenticates the requests with basic or digest authentication.
+//
+// The challenge of the server is remembered, so that the following requests are authenticated up front.
+// A request whose body can't be sent twice is preceded by an OPTIONS request
+// when the challenge is not known yet, so that its body is streamed only once.
+type authTransport struct {
+	base     http.RoundTripper
+	auth     AuthType
+	username string
+	password string
+
+	mu     sync.Mutex
+	scheme AuthType
+	digest *digestChallenge
+	nc     uint32
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.auth == AuthBasic {
+		return t.send(req, AuthBasic)
+	}
+	scheme := t.knownScheme()
+	if scheme == "" && !replayable(req) {
+		if err := t.probe(req); err != nil {
+			return nil, err
+		}
+		scheme = t.knownScheme()
+	}
+	resp, err := t.send(req, scheme)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !replayable(req) {
+		return resp, err
+	}
+	// the challenge is new, or the nonce is stale: answer it with a copy of the request.
+	if !t.learn(resp) {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.send(retry, t.knownScheme())
+}
+
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func (t *authTransport) knownScheme() AuthType {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scheme
+}
+
+// probe sends an OPTIONS request to the URL of the request to learn the challenge of the server.
+func (t *authTransport) probe(req *http.Request) error {
+	probe, err := http.NewRequestWithContext(req.Context(), http.MethodOptions, req.URL.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.base.RoundTrip(probe)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode == http.StatusUnauthorized {
+		t.learn(resp)
+	}
+	return nil
+}
+
+// learn remembers the challenge of the response, it reports whether the request should be sent again.
+func (t *authTransport) learn(resp *http.Response) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		scheme, params, _ := strings.Cut(header, " ")
+		switch {
+		case strings.EqualFold(scheme, "Digest") && t.auth != AuthBasic:
+			challenge := parseDigestChallenge(params)
+			// a second failure with the same nonce means the credentials are wrong.
+			if t.scheme == AuthDigest && t.digest != nil && t.digest.nonce == challenge.nonce &&
+				!strings.EqualFold(challenge.stale, "true") {
+				return false
+			}
+			t.scheme, t.digest, t.nc = AuthDigest, challenge, 0
+			return true
+		case strings.EqualFold(scheme, "Basic") && t.auth == AuthAuto:
+			if t.scheme == AuthBasic {
+				return false
+			}
+			t.scheme = AuthBasic
+			return true
+		}
+	}
+	return false
+}
+
+func (t *authTransport) send(req *http.Request, scheme AuthType) (*http.Response, error) {
+	switch scheme {
+	case AuthBasic:
+		req = req.Clone(req.Context())
+		req.SetBasicAuth(t.username, t.password)
+	case AuthDigest:
+		t.mu.Lock()
+		t.nc++
+		authorization := t.digest.authorization(t.username, t.password, req.Method, req.URL.RequestURI(), t.nc)
+		t.mu.Unlock()
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", authorization)
+	}
+	return t.base.RoundTrip(req)
+}
+
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+	stale     string
+}
+
+func parseDigestChallenge(s string) *digestChallenge {
+	params := parseAuthParams(s)
+	return &digestChallenge{
+		realm:     params["realm"],
+		nonce:     params["nonce"],
+		opaque:    params["opaque"],
+		algorithm: params["algorithm"],
+		qop:       params["qop"],
+		stale:     params["stale"],
+	}
+}
+
+// parseAuthParams parses the comma separated key=value parameters of a challenge, values may be quoted.
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " ,") {
+		k, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		rest = strings.TrimLeft(rest, " ")
+		var v string
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			v, s = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			v, s, _ = strings.Cut(rest, ",")
+			v = strings.TrimSpace(v)
+		}
+		params[k] = v
+	}
+	return params
+}
+
+// authorization answers the challenge, as described by RFC 7616.
+func (c *digestChallenge) authorization(username, password, method, uri string, nc uint32) string {
+	newHash := md5.New
+	algorithm := strings.ToUpper(c.algorithm)
+	if strings.HasPrefix(algorithm, "SHA-256") {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		return hashHex(newHash, s)
+	}
+	cnonce := newCnonce()
+	ha1 := h(username + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	count := fmt.Sprintf("%08x", nc)
+	qop := ""
+	for _, option := range strings.Split(c.qop, ",") {
+		if strings.TrimSpace(option) == "auth" {
+			qop = "auth"
+		}
+	}
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, c.nonce, count, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	}
+	parts := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", c.realm),
+		fmt.Sprintf("nonce=%q", c.nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("response=%q", response),
+	}
+	if c.algorithm != "" {
+		parts = append(parts, "algorithm="+c.algorithm)
+	}
+	if c.opaque != "" {
+		parts = append(parts, fmt.Sprintf("opaque=%q", c.opaque))
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+count, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	return "Digest " + strings.Join(parts, ", ")
+}
+
+func hashHex(newHash func() hash.Hash, s string) string {
+	h := newHash()
+	_, _ = io.WriteString(h, s)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newCnonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
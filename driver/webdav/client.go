@@ -0,0 +1,200 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+)
+
+// StatusError is the error of a request answered with an unexpected status.
+// It wraps os.ErrNotExist for 404 Not Found and 410 Gone, and os.ErrPermission for 401 Unauthorized and 403 Forbidden.
+type StatusError = httpclient.StatusError
+
+func (f *WebDAVFileSystem) request(method, url string, body io.Reader, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range f.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return f.client.Do(req)
+}
+
+// do sends the request and checks that it is answered with one of the statuses,
+// the body of the response is discarded.
+func (f *WebDAVFileSystem) do(method, url string, body io.Reader, header map[string]string, statuses ...int) (int, error) {
+	resp, err := f.request(method, url, body, header)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return status, nil
+		}
+	}
+	return resp.StatusCode, &StatusError{Method: method, URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>` +
+	`<d:propfind xmlns:d="DAV:"><d:prop>` +
+	`<d:resourcetype/><d:getcontentlength/><d:getlastmodified/><d:getcontenttype/><d:getetag/>` +
+	`</d:prop></d:propfind>`
+
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength string `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+				ContentType   string `xml:"DAV: getcontenttype"`
+				ETag          string `xml:"DAV: getetag"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// propfind returns the properties of the resource, and of its members if depth is 1.
+func (f *WebDAVFileSystem) propfind(p string, depth int) ([]*fileInfo, error) {
+	url := httpclient.URL(f.endpoint, p, false)
+	resp, err := f.request("PROPFIND", url, strings.NewReader(propfindBody), map[string]string{
+		"Depth":        strconv.Itoa(depth),
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, &StatusError{Method: "PROPFIND", URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, err
+	}
+	var infos []*fileInfo
+	for _, response := range ms.Responses {
+		p, err := f.hrefPath(response.Href)
+		if err != nil {
+			return nil, err
+		}
+		info := &fileInfo{path: p}
+		for _, propstat := range response.Propstats {
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			prop := propstat.Prop
+			info.dir = info.dir || prop.ResourceType.Collection != nil
+			if prop.ContentLength != "" {
+				info.size, _ = strconv.ParseInt(prop.ContentLength, 10, 64)
+			}
+			if prop.LastModified != "" {
+				info.modTime, _ = http.ParseTime(prop.LastModified)
+			}
+			if prop.ContentType != "" {
+				info.contentType, _, _ = mime.ParseMediaType(prop.ContentType)
+			}
+			if prop.ETag != "" {
+				info.etag = prop.ETag
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// hrefPath converts the href of a response, an absolute path or URL, to the path of the resource.
+func (f *WebDAVFileSystem) hrefPath(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	rel, ok := strings.CutPrefix(u.Path, f.endpoint.Path)
+	if !ok {
+		rel, ok = strings.CutPrefix(u.Path+"/", f.endpoint.Path)
+	}
+	if !ok {
+		return "", fmt.Errorf("webdav: resource %s outside of the endpoint", href)
+	}
+	return filesystem.CleanPath(rel), nil
+}
+
+func (f *WebDAVFileSystem) stat(p string) (*fileInfo, error) {
+	infos, err := f.propfind(p, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, errors.New("webdav: empty PROPFIND response")
+	}
+	return infos[0], nil
+}
+
+// fileInfo is a resource of the server, it implements both [gofs.FileInfo] and [gofs.DirEntry].
+type fileInfo struct {
+	path        string
+	dir         bool
+	size        int64
+	modTime     time.Time
+	contentType string
+	etag        string
+}
+
+func (i *fileInfo) Name() string {
+	return path.Base(i.path)
+}
+
+func (i *fileInfo) Size() int64 {
+	return i.size
+}
+
+func (i *fileInfo) Mode() gofs.FileMode {
+	if i.dir {
+		return gofs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i *fileInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+func (i *fileInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i *fileInfo) Sys() any {
+	return nil
+}
+
+func (i *fileInfo) Type() gofs.FileMode {
+	return i.Mode().Type()
+}
+
+func (i *fileInfo) Info() (gofs.FileInfo, error) {
+	return i, nil
+}
@@ -0,0 +1,150 @@
+package webdav
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/gopi-frame/env"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+)
+
+// AuthType is the authentication scheme used with the server.
+type AuthType string
+
+const (
+	// AuthAuto answers the challenge of the server, basic or digest. It is the default.
+	AuthAuto AuthType = ""
+	// AuthBasic sends the credentials with every request.
+	AuthBasic AuthType = "basic"
+	// AuthDigest answers the digest challenge of the server, the credentials are never sent.
+	AuthDigest AuthType = "digest"
+)
+
+type TLSConfig struct {
+	// CAFile is a PEM file of the certificate authorities trusted in addition to the system ones.
+	CAFile string
+	// CertFile and KeyFile are the client certificate and its key.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name the server certificate is verified against.
+	ServerName string
+	// InsecureSkipVerify disables the verification of the server certificate.
+	InsecureSkipVerify bool
+}
+
+type Config struct {
+	// Endpoint is the URL of the root collection, e.g. https://cloud.example.com/remote.php/dav/files/alice/.
+	Endpoint string
+	Username string
+	Password string
+	Auth     AuthType
+	TLS      *TLSConfig
+	// Timeout bounds every request, it is not set by default so that large transfers are not interrupted.
+	Timeout *time.Duration
+	// Headers are added to every request.
+	Headers map[string]string
+	// Transport is the round tripper of the requests, default is a clone of http.DefaultTransport.
+	Transport http.RoundTripper
+	// Visibility is the visibility reported for every file, default is "public".
+	Visibility string
+}
+
+func (c *Config) Apply(f *WebDAVFileSystem) error {
+	if c.Endpoint == "" {
+		return ErrNoEndpoint
+	}
+	endpoint, err := httpclient.ParseEndpoint(c.Endpoint)
+	if err != nil {
+		return err
+	}
+	f.endpoint = endpoint
+	transport := c.Transport
+	if transport == nil {
+		defaultTransport := http.DefaultTransport.(*http.Transport).Clone()
+		if c.TLS != nil {
+			tlsConfig, err := c.TLS.config()
+			if err != nil {
+				return err
+			}
+			defaultTransport.TLSClientConfig = tlsConfig
+		}
+		transport = defaultTransport
+	}
+	if c.Username != "" || c.Password != "" {
+		transport = &authTransport{
+			base:     transport,
+			auth:     c.Auth,
+			username: c.Username,
+			password: c.Password,
+		}
+	}
+	f.client = &http.Client{Transport: transport}
+	if c.Timeout != nil {
+		f.client.Timeout = *c.Timeout
+	}
+	f.headers = c.Headers
+	if c.Visibility != "" {
+		f.visibility = c.Visibility
+	}
+	return nil
+}
+
+func (c *TLSConfig) config() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in " + c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func ConfigFromMap(configMap map[string]any) (*Config, error) {
+	var cfg Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		Result:           &cfg,
+		MatchName: func(mapKey, fieldName string) bool {
+			return strings.EqualFold(mapKey, fieldName) ||
+				strings.EqualFold(fieldName, strings.NewReplacer("-", "", "_", "").Replace(mapKey))
+		},
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			env.ExpandStringWithEnvHookFunc(),
+			env.ExpandStringKeyMapWithEnvHookFunc(),
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToBasicTypeHookFunc(),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(configMap); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,497 @@
+package webdav
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	gofs "io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+)
+
+var ErrNoEndpoint = errors.New("webdav endpoint is required")
+
+var ErrVisibilityUnsupported = errors.New("webdav does not support visibility")
+
+var ErrAtomicIfMatchUnsupported = errors.New("webdav does not support if_match with atomic writes")
+
+// WebDAVFileSystem is a file system stored on a WebDAV server, e.g. Nextcloud or Apache mod_dav.
+//
+// Files are read with GET and written with PUT, streaming their content;
+// the missing parent collections of a written file are created with MKCOL.
+// WebDAV has no permissions, every file reports the configured visibility.
+type WebDAVFileSystem struct {
+	endpoint         *url.URL
+	client           *http.Client
+	headers          map[string]string
+	visibility       string
+	mimetypeDetector fs.MimeTypeDetector
+}
+
+// NewWebDAVFileSystem creates a file system rooted at the endpoint of the config.
+func NewWebDAVFileSystem(config *Config, opts ...Option) (*WebDAVFileSystem, error) {
+	f := &WebDAVFileSystem{
+		visibility: "public",
+	}
+	if err := config.Apply(f); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(f); err != nil {
+			return nil, err
+		}
+	}
+	if f.mimetypeDetector == nil {
+		f.mimetypeDetector = filesystem.NewMimeTypeDetector()
+	}
+	return f, nil
+}
+
+func (f *WebDAVFileSystem) Exists(path string) (bool, error) {
+	_, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return true, nil
+}
+
+func (f *WebDAVFileSystem) FileExists(path string) (bool, error) {
+	info, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return !info.IsDir(), nil
+}
+
+func (f *WebDAVFileSystem) DirExists(path string) (bool, error) {
+	info, err := f.stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, filesystem.NewUnableToCheckExistence(path, err)
+	}
+	return info.IsDir(), nil
+}
+
+func (f *WebDAVFileSystem) Read(path string) ([]byte, error) {
+	stream, err := f.ReadStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	content, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	return content, nil
+}
+
+// ReadStream returns the body of a GET request, the content is streamed from the server as it is read.
+func (f *WebDAVFileSystem) ReadStream(path string) (io.ReadCloser, error) {
+	url := httpclient.URL(f.endpoint, path, false)
+	resp, err := f.request(http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadFile(path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return nil, filesystem.NewUnableToReadFile(path, &StatusError{Method: http.MethodGet, URL: url, StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+	return resp.Body, nil
+}
+
+func (f *WebDAVFileSystem) ReadDir(path string) ([]os.DirEntry, error) {
+	infos, err := f.propfind(path, 1)
+	if err != nil {
+		return nil, filesystem.NewUnableToReadDirectory(path, err)
+	}
+	dir := filesystem.CleanPath(path)
+	var entries []os.DirEntry
+	for _, info := range infos {
+		if info.path == dir {
+			if !info.IsDir() {
+				return nil, filesystem.NewUnableToReadDirectory(path, filesystem.ErrIsNotDirectory)
+			}
+			continue
+		}
+		entries = append(entries, info)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// WalkDir walks the tree with a PROPFIND request per directory, as servers commonly refuse infinite depth.
+func (f *WebDAVFileSystem) WalkDir(path string, walkFn gofs.WalkDirFunc) error {
+	root, err := f.stat(path)
+	if err != nil {
+		return filesystem.NewUnableToReadDirectory(path, err)
+	}
+	var walkDir func(p string, d gofs.DirEntry) error
+	walkDir = func(p string, d gofs.DirEntry) error {
+		if err := walkFn(p, d, nil); err != nil || !d.IsDir() {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+		entries, err := f.ReadDir(p)
+		if err != nil {
+			err = walkFn(p, d, err)
+			if err != nil {
+				if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+					err = nil
+				}
+				return err
+			}
+		}
+		for _, entry := range entries {
+			if err := walkDir(filepath.ToSlash(filepath.Join(p, entry.Name())), entry); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					break
+				}
+				return err
+			}
+		}
+		return nil
+	}
+	err = walkDir(path, root)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (f *WebDAVFileSystem) LastModified(path string) (time.Time, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return time.Time{}, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info.modTime, nil
+}
+
+func (f *WebDAVFileSystem) FileSize(path string) (int64, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return 0, filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return info.size, nil
+}
+
+// MimeType returns the content type reported by the server, or detects it from the extension of the path.
+func (f *WebDAVFileSystem) MimeType(path string) (string, error) {
+	info, err := f.stat(path)
+	if err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	if info.contentType != "" {
+		return info.contentType, nil
+	}
+	return f.mimetypeDetector.DetectFromPath(path), nil
+}
+
+func (f *WebDAVFileSystem) Visibility(path string) (string, error) {
+	if _, err := f.stat(path); err != nil {
+		return "", filesystem.NewUnableToRetrieveMetadata(path, err)
+	}
+	return f.visibility, nil
+}
+
+func (f *WebDAVFileSystem) Write(path string, content []byte, config map[string]any) error {
+	return f.WriteStream(path, bytes.NewReader(content), config)
+}
+
+// WriteStream uploads the stream with a PUT request, creating the missing parent collections first.
+// The request is sent with a known length when the size of the stream can be known, chunked otherwise.
+//
+// [filesystem.IfAbsentKey] and [filesystem.IfMatchKey] are sent as If-None-Match and If-Match,
+// the server answering 412 Precondition Failed if they do not hold.
+// WebDAV cannot append, with os.O_APPEND the current content is downloaded with GET and uploaded again before the stream;
+// unless a condition is set, the upload is then conditional on the file being unchanged since.
+// With [filesystem.AtomicKey], the content is uploaded to a temporary file moved over the file once complete,
+// with [filesystem.IfAbsentKey] sent as "Overwrite: F"; an atomic write with [filesystem.IfMatchKey] is refused,
+// as MOVE cannot be conditional on the version of its destination.
+func (f *WebDAVFileSystem) WriteStream(path string, stream io.Reader, config map[string]any) error {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return err
+	}
+	atomic := cfg.Atomic != nil && *cfg.Atomic
+	if atomic && cfg.IfMatch != nil {
+		return filesystem.NewUnableToWriteFile(path, ErrAtomicIfMatchUnsupported)
+	}
+	if err := f.mkdirAll(parent(path)); err != nil {
+		return filesystem.NewUnableToWriteFile(path, err)
+	}
+	size := cfg.Size()
+	if size < 0 {
+		size = filesystem.StreamSize(stream)
+	}
+	body := stream
+	tracker := cfg.NewTracker(path, stream)
+	if tracker != nil {
+		body = tracker.ReadCloser(io.NopCloser(stream))
+	}
+	header := make(map[string]string)
+	if cfg.IfAbsent != nil && *cfg.IfAbsent {
+		header["If-None-Match"] = "*"
+	}
+	if cfg.IfMatch != nil {
+		header["If-Match"] = quoteETag(*cfg.IfMatch)
+	}
+	if contentType := f.mimetypeDetector.DetectFromPath(path); contentType != "" {
+		header["Content-Type"] = contentType
+	}
+	if cfg.FileWriteFlag != nil && *cfg.FileWriteFlag&os.O_APPEND != 0 {
+		current, err := f.request(http.MethodGet, httpclient.URL(f.endpoint, path, false), nil, nil)
+		if err != nil {
+			return filesystem.NewUnableToWriteFile(path, err)
+		}
+		defer current.Body.Close()
+		switch current.StatusCode {
+		case http.StatusOK:
+			if _, ok := header["If-None-Match"]; ok {
+				return filesystem.NewPreconditionFailed(path, os.ErrExist)
+			}
+			if _, ok := header["If-Match"]; !ok && current.Header.Get("ETag") != "" {
+				header["If-Match"] = current.Header.Get("ETag")
+			}
+			body = io.MultiReader(current.Body, body)
+			if size >= 0 && current.ContentLength >= 0 {
+				size += current.ContentLength
+			} else {
+				size = -1
+			}
+		case http.StatusNotFound:
+			if _, ok := header["If-Match"]; !ok {
+				header["If-None-Match"] = "*"
+			}
+		default:
+			return filesystem.NewUnableToWriteFile(path, &StatusError{Method: http.MethodGet, URL: httpclient.URL(f.endpoint, path, false), StatusCode: current.StatusCode, Status: current.Status})
+		}
+	}
+	if atomic {
+		if err := f.writeAtomic(path, body, size, header); err != nil {
+			return writeError(path, err)
+		}
+	} else if err := f.put(path, body, size, header); err != nil {
+		return writeError(path, err)
+	}
+	tracker.Done()
+	return nil
+}
+
+// put uploads the body to the path with a PUT request, with the length if it is not negative.
+func (f *WebDAVFileSystem) put(path string, body io.Reader, size int64, header map[string]string) error {
+	url := httpclient.URL(f.endpoint, path, false)
+	req, err := http.NewRequest(http.MethodPut, url, body)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+	}
+	for k, v := range f.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	}
+	return &StatusError{Method: http.MethodPut, URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// writeAtomic uploads the body to a temporary file next to the path, and moves it over the path once complete.
+// The conditions of the header are checked by the MOVE: If-None-Match is sent as "Overwrite: F",
+// the version of the destination cannot be checked.
+// The temporary file is deleted if any step fails.
+func (f *WebDAVFileSystem) writeAtomic(path string, body io.Reader, size int64, header map[string]string) (err error) {
+	temp := filesystem.AtomicTempPath(filesystem.CleanPath(path))
+	defer func() {
+		if err != nil {
+			_, _ = f.do(http.MethodDelete, httpclient.URL(f.endpoint, temp, false), nil, nil, http.StatusOK, http.StatusNoContent)
+		}
+	}()
+	overwrite := header["If-None-Match"] != "*"
+	put := make(map[string]string)
+	if contentType, ok := header["Content-Type"]; ok {
+		put["Content-Type"] = contentType
+	}
+	if err := f.put(temp, body, size, put); err != nil {
+		return err
+	}
+	_, err = f.do("MOVE", httpclient.URL(f.endpoint, temp, false), nil, f.transferHeader(path, false, overwrite), http.StatusCreated, http.StatusNoContent)
+	return err
+}
+
+// quoteETag quotes a version passed with [filesystem.IfMatchKey], unless it is already an entity tag.
+func quoteETag(version string) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, "W/") {
+		return version
+	}
+	return strconv.Quote(version)
+}
+
+// writeError wraps the error of a write, a 412 Precondition Failed answer is a [filesystem.PreconditionFailed] error.
+func writeError(path string, err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusPreconditionFailed {
+		return filesystem.NewPreconditionFailed(path, err)
+	}
+	return filesystem.NewUnableToWriteFile(path, err)
+}
+
+func (f *WebDAVFileSystem) SetVisibility(path string, visibility string) error {
+	return filesystem.NewUnableToSetPermission(path, ErrVisibilityUnsupported)
+}
+
+func (f *WebDAVFileSystem) Delete(path string) error {
+	info, err := f.stat(path)
+	if err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	if info.IsDir() {
+		return filesystem.NewUnableToDeleteFile(path, filesystem.ErrIsNotFile)
+	}
+	if _, err := f.do(http.MethodDelete, httpclient.URL(f.endpoint, path, false), nil, nil, http.StatusOK, http.StatusNoContent); err != nil {
+		return filesystem.NewUnableToDeleteFile(path, err)
+	}
+	return nil
+}
+
+func (f *WebDAVFileSystem) DeleteDir(path string) error {
+	if filesystem.CleanPath(path) == "." {
+		return filesystem.NewUnableToDeleteDirectory(path, errors.New("can't delete the root collection"))
+	}
+	if _, err := f.do(http.MethodDelete, httpclient.URL(f.endpoint, path, true), nil, nil, http.StatusOK, http.StatusNoContent); err != nil {
+		return filesystem.NewUnableToDeleteDirectory(path, err)
+	}
+	return nil
+}
+
+func (f *WebDAVFileSystem) CreateDir(path string, config map[string]any) error {
+	if err := f.mkdirAll(path); err != nil {
+		return filesystem.NewUnableToCreateDirectory(path, err)
+	}
+	return nil
+}
+
+// mkdirAll creates the collection and its missing parents with MKCOL requests.
+func (f *WebDAVFileSystem) mkdirAll(p string) error {
+	p = filesystem.CleanPath(p)
+	if p == "." {
+		return nil
+	}
+	info, err := f.stat(p)
+	if err == nil {
+		if !info.IsDir() {
+			return filesystem.ErrIsNotDirectory
+		}
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := f.mkdirAll(parent(p)); err != nil {
+		return err
+	}
+	// 405 Method Not Allowed is the answer for an existing collection, e.g. created concurrently.
+	_, err = f.do("MKCOL", httpclient.URL(f.endpoint, p, true), nil, nil, http.StatusCreated, http.StatusMethodNotAllowed)
+	return err
+}
+
+func parent(p string) string {
+	return path.Dir(filesystem.CleanPath(p))
+}
+
+// Move moves the file or directory with a MOVE request, replacing the destination
+// unless [filesystem.IfAbsentKey] is set, which is sent as "Overwrite: F".
+func (f *WebDAVFileSystem) Move(src string, dst string, config map[string]any) error {
+	if err := f.transfer("MOVE", src, dst, config); err != nil {
+		if errors.As(err, new(*filesystem.PreconditionFailed)) {
+			return err
+		}
+		return filesystem.NewUnableToMove(src, dst, err)
+	}
+	return nil
+}
+
+// Copy copies the file or directory with a COPY request, replacing the destination
+// unless [filesystem.IfAbsentKey] is set, which is sent as "Overwrite: F".
+func (f *WebDAVFileSystem) Copy(src string, dst string, config map[string]any) error {
+	if err := f.transfer("COPY", src, dst, config); err != nil {
+		if errors.As(err, new(*filesystem.PreconditionFailed)) {
+			return err
+		}
+		return filesystem.NewUnableToCopyFile(src, dst, err)
+	}
+	return nil
+}
+
+func (f *WebDAVFileSystem) transfer(method, src, dst string, config map[string]any) error {
+	cfg, err := filesystem.NewConfig(config)
+	if err != nil {
+		return err
+	}
+	overwrite := cfg.IfAbsent == nil || !*cfg.IfAbsent
+	info, err := f.stat(src)
+	if err != nil {
+		return err
+	}
+	if err := f.mkdirAll(parent(dst)); err != nil {
+		return err
+	}
+	header := f.transferHeader(dst, info.IsDir(), overwrite)
+	if info.IsDir() {
+		header["Depth"] = "infinity"
+	}
+	status, err := f.do(method, httpclient.URL(f.endpoint, src, info.IsDir()), nil, header, http.StatusCreated, http.StatusNoContent)
+	if status == http.StatusPreconditionFailed {
+		return filesystem.NewPreconditionFailed(dst, err)
+	}
+	return err
+}
+
+// transferHeader returns the header of a MOVE or COPY request to dst.
+func (f *WebDAVFileSystem) transferHeader(dst string, collection, overwrite bool) map[string]string {
+	header := map[string]string{
+		"Destination": httpclient.URL(f.endpoint, dst, collection),
+		"Overwrite":   "T",
+	}
+	if !overwrite {
+		header["Overwrite"] = "F"
+	}
+	return header
+}
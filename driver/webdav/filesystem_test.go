@@ -0,0 +1,314 @@
+package webdav
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	gofs "io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem"
+	"github.com/gopi-frame/filesystem/internal/httpclient"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/webdav"
+)
+
+func newServer(t *testing.T, auth func(http.Handler) http.Handler) string {
+	handler := http.Handler(&webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	})
+	if auth != nil {
+		handler = auth(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL + "/dav/"
+}
+
+func basicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "alice" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="dav"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// digestAuth checks the MD5 digest answers of the client, and counts the bodies it refused.
+func digestAuth(refused *int) func(http.Handler) http.Handler {
+	h := func(s string) string {
+		return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, params, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			p := parseAuthParams(params)
+			ha1 := h("alice:dav:secret")
+			ha2 := h(r.Method + ":" + p["uri"])
+			expected := h(strings.Join([]string{ha1, "nonce-1", p["nc"], p["cnonce"], "auth", ha2}, ":"))
+			if scheme != "Digest" || p["username"] != "alice" || p["nonce"] != "nonce-1" || p["response"] != expected {
+				if r.ContentLength != 0 && r.Method == http.MethodPut {
+					*refused++
+				}
+				w.Header().Set("WWW-Authenticate", `Digest realm="dav", nonce="nonce-1", qop="auth", algorithm=MD5, opaque="o"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newFS(t *testing.T, endpoint string, auth AuthType, password string) *WebDAVFileSystem {
+	f, err := NewWebDAVFileSystem(&Config{Endpoint: endpoint, Username: "alice", Password: password, Auth: auth})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return f
+}
+
+func TestWebDAVFileSystem(t *testing.T) {
+	f := newFS(t, newServer(t, basicAuth), AuthAuto, "secret")
+
+	t.Run("write and read", func(t *testing.T) {
+		if err := f.Write("docs/2024/report.txt", []byte("hello"), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := f.Read("docs/2024/report.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "hello", string(content))
+		exists, _ := f.DirExists("docs/2024")
+		assert.True(t, exists)
+		exists, _ = f.FileExists("docs/2024")
+		assert.False(t, exists)
+		size, err := f.FileSize("docs/2024/report.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, int64(5), size)
+		mimeType, err := f.MimeType("docs/2024/report.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "text/plain", mimeType)
+		lastModified, err := f.LastModified("docs/2024/report.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.False(t, lastModified.IsZero())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		err := f.WriteStream("stream.txt", io.MultiReader(strings.NewReader("part 1, "), strings.NewReader("part 2")), nil)
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := f.Read("stream.txt")
+		assert.Equal(t, "part 1, part 2", string(content))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		if err := f.CreateDir("empty/nested", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		entries, err := f.ReadDir("/")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.Equal(t, []string{"docs", "empty", "stream.txt"}, names)
+		var paths []string
+		err = f.WalkDir("docs", func(path string, d gofs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+			return nil
+		})
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, []string{"docs", "docs/2024", "docs/2024/report.txt"}, paths)
+	})
+
+	t.Run("move and copy", func(t *testing.T) {
+		if err := f.Copy("docs", "backup/docs", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Move("stream.txt", "archive/stream.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := f.Read("backup/docs/2024/report.txt")
+		assert.Equal(t, "hello", string(content))
+		exists, _ := f.Exists("stream.txt")
+		assert.False(t, exists)
+		content, _ = f.Read("archive/stream.txt")
+		assert.Equal(t, "part 1, part 2", string(content))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.ErrorIs(t, f.Delete("docs"), filesystem.ErrIsNotFile)
+		if err := f.Delete("archive/stream.txt"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.DeleteDir("backup"); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		exists, _ := f.Exists("backup/docs/2024/report.txt")
+		assert.False(t, exists)
+		_, err := f.Read("archive/stream.txt")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// conditional checks the If-None-Match and If-Match headers of PUT requests, which the webdav package ignores.
+func conditional(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch, ifMatch := r.Header.Get("If-None-Match"), r.Header.Get("If-Match")
+		if r.Method == http.MethodPut && (ifNoneMatch != "" || ifMatch != "") {
+			head := httptest.NewRecorder()
+			next.ServeHTTP(head, httptest.NewRequest(http.MethodHead, r.URL.Path, nil))
+			exists := head.Code == http.StatusOK
+			if (ifNoneMatch == "*" && exists) || (ifMatch != "" && (!exists || ifMatch != head.Header().Get("ETag"))) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestWebDAVFileSystem_WriteConfig(t *testing.T) {
+	f := newFS(t, newServer(t, conditional), AuthAuto, "")
+	if err := f.Write("a.txt", []byte("v1"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	appendFlag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	var precondition *filesystem.PreconditionFailed
+
+	t.Run("conditions", func(t *testing.T) {
+		err := f.Write("a.txt", []byte("v2"), map[string]any{filesystem.IfAbsentKey: true})
+		assert.ErrorAs(t, err, &precondition)
+		err = f.Write("a.txt", []byte("v2"), map[string]any{filesystem.IfMatchKey: "stale"})
+		assert.ErrorAs(t, err, &precondition)
+		if err := f.Write("new.txt", []byte("new"), map[string]any{filesystem.IfAbsentKey: true}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := f.Read("a.txt")
+		assert.Equal(t, "v1", string(content))
+	})
+
+	t.Run("append", func(t *testing.T) {
+		if err := f.Write("a.txt", []byte("v2"), map[string]any{filesystem.FileWriteFlagKey: appendFlag}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		if err := f.Write("b.txt", []byte("b"), map[string]any{filesystem.FileWriteFlagKey: appendFlag}); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := f.Read("a.txt")
+		assert.Equal(t, "v1v2", string(content))
+		content, _ = f.Read("b.txt")
+		assert.Equal(t, "b", string(content))
+		err := f.Write("a.txt", []byte("v3"), map[string]any{filesystem.FileWriteFlagKey: appendFlag, filesystem.IfAbsentKey: true})
+		assert.ErrorAs(t, err, &precondition)
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		config := map[string]any{filesystem.AtomicKey: true}
+		if err := f.Write("c.txt", []byte("c"), config); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		config[filesystem.FileWriteFlagKey] = appendFlag
+		if err := f.Write("c.txt", []byte("d"), config); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ := f.Read("c.txt")
+		assert.Equal(t, "cd", string(content))
+		err := f.Write("c.txt", []byte("e"), map[string]any{filesystem.AtomicKey: true, filesystem.IfAbsentKey: true})
+		assert.ErrorAs(t, err, &precondition)
+		err = f.Write("c.txt", []byte("e"), map[string]any{filesystem.AtomicKey: true, filesystem.IfMatchKey: "v"})
+		assert.ErrorIs(t, err, ErrAtomicIfMatchUnsupported)
+		content, _ = f.Read("c.txt")
+		assert.Equal(t, "cd", string(content))
+		entries, err := f.ReadDir(".")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		for _, entry := range entries {
+			assert.False(t, filesystem.IsAtomicTempFile(entry.Name()), entry.Name())
+		}
+	})
+
+	t.Run("move and copy", func(t *testing.T) {
+		config := map[string]any{filesystem.IfAbsentKey: true}
+		assert.ErrorAs(t, f.Copy("a.txt", "b.txt", config), &precondition)
+		assert.ErrorAs(t, f.Move("a.txt", "b.txt", config), &precondition)
+		content, _ := f.Read("b.txt")
+		assert.Equal(t, "b", string(content))
+		if err := f.Copy("a.txt", "b.txt", nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, _ = f.Read("b.txt")
+		assert.Equal(t, "v1v2", string(content))
+	})
+}
+
+func TestWebDAVFileSystem_Auth(t *testing.T) {
+	t.Run("digest", func(t *testing.T) {
+		var refused int
+		f := newFS(t, newServer(t, digestAuth(&refused)), AuthDigest, "secret")
+		// the body of a plain stream can't be sent twice, the challenge is learnt before it is sent.
+		if err := f.WriteStream("a/b.txt", io.MultiReader(strings.NewReader("digest")), nil); err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		content, err := f.Read("a/b.txt")
+		if err != nil {
+			assert.FailNow(t, err.Error())
+		}
+		assert.Equal(t, "digest", string(content))
+		assert.Equal(t, 0, refused)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		f := newFS(t, newServer(t, basicAuth), AuthAuto, "wrong")
+		_, err := f.Read("a.txt")
+		assert.ErrorIs(t, err, os.ErrPermission)
+		f = newFS(t, newServer(t, digestAuth(new(int))), AuthAuto, "wrong")
+		_, err = f.Exists("a.txt")
+		assert.ErrorIs(t, err, os.ErrPermission)
+	})
+}
+
+func TestConfigFromMap(t *testing.T) {
+	cfg, err := ConfigFromMap(map[string]any{
+		"endpoint": "https://cloud.example.com/remote.php/dav/files/alice",
+		"username": "alice",
+		"password": "secret",
+		"auth":     "digest",
+		"timeout":  "30s",
+		"tls":      map[string]any{"insecure_skip_verify": true},
+	})
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, AuthDigest, cfg.Auth)
+	assert.True(t, cfg.TLS.InsecureSkipVerify)
+	f, err := NewWebDAVFileSystem(cfg)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	assert.Equal(t, "https://cloud.example.com/remote.php/dav/files/alice/a%20b.txt", httpclient.URL(f.endpoint, "a b.txt", false))
+}
@@ -0,0 +1,5 @@
+module github.com/gopi-frame/filesystem/driver/webdav
+
+go 1.22
+
+require golang.org/x/net v0.33.0
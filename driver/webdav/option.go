@@ -0,0 +1,42 @@
+package webdav
+
+import (
+	"net/http"
+
+	"github.com/gopi-frame/contract"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+)
+
+type Option = contract.Option[*WebDAVFileSystem]
+
+type OptionFunc func(f *WebDAVFileSystem) error
+
+func (o OptionFunc) Apply(f *WebDAVFileSystem) error {
+	return o(f)
+}
+
+var noneOption = OptionFunc(func(f *WebDAVFileSystem) error {
+	return nil
+})
+
+func WithMimeTypeDetector(detector fs.MimeTypeDetector) Option {
+	if detector == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *WebDAVFileSystem) error {
+		f.mimetypeDetector = detector
+		return nil
+	})
+}
+
+// WithHTTPClient sets the client sending the requests, replacing the one built from the config.
+func WithHTTPClient(client *http.Client) Option {
+	if client == nil {
+		return noneOption
+	}
+	return OptionFunc(func(f *WebDAVFileSystem) error {
+		f.client = client
+		return nil
+	})
+}
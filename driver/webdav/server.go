@@ -0,0 +1,627 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	gofs "io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gopi-frame/contract"
+	"golang.org/x/net/webdav"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/gopi-frame/filesystem"
+)
+
+// VisibilityProperty is the WebDAV property holding the visibility of a file,
+// read with PROPFIND and changed with PROPPATCH.
+var VisibilityProperty = xml.Name{Space: "urn:gopi-frame:filesystem", Local: "visibility"}
+
+// Handler serves a file system over WebDAV, so that it can be mounted as a network drive.
+//
+// The file system is read-only if set with [WithReadOnly], or if it reports itself as read-only,
+// like the read-only wrapper does: the methods changing it are then refused with 403 Forbidden.
+type Handler struct {
+	handler  *webdav.Handler
+	readOnly bool
+}
+
+type HandlerOption = contract.Option[*Handler]
+
+type HandlerOptionFunc func(h *Handler) error
+
+func (o HandlerOptionFunc) Apply(h *Handler) error {
+	return o(h)
+}
+
+// WithPrefix sets the URL path prefix the file system is served under.
+func WithPrefix(prefix string) HandlerOption {
+	return HandlerOptionFunc(func(h *Handler) error {
+		h.handler.Prefix = prefix
+		return nil
+	})
+}
+
+// WithReadOnly serves the file system read-only.
+func WithReadOnly() HandlerOption {
+	return HandlerOptionFunc(func(h *Handler) error {
+		h.readOnly = true
+		return nil
+	})
+}
+
+// WithLockSystem sets the lock system of the handler, default is an in-memory one,
+// which only holds the locks of a single server.
+func WithLockSystem(lockSystem webdav.LockSystem) HandlerOption {
+	return HandlerOptionFunc(func(h *Handler) error {
+		h.handler.LockSystem = lockSystem
+		return nil
+	})
+}
+
+// WithLogger sets the function called after every request, with the error of the request if any.
+func WithLogger(logger func(r *http.Request, err error)) HandlerOption {
+	return HandlerOptionFunc(func(h *Handler) error {
+		h.handler.Logger = logger
+		return nil
+	})
+}
+
+// NewHandler creates a handler serving the file system over WebDAV.
+func NewHandler(f fs.FileSystem, opts ...HandlerOption) (*Handler, error) {
+	h := &Handler{
+		handler: &webdav.Handler{LockSystem: webdav.NewMemLS()},
+	}
+	if ro, ok := f.(interface{ ReadOnly() bool }); ok && ro.ReadOnly() {
+		h.readOnly = true
+	}
+	for _, opt := range opts {
+		if err := opt.Apply(h); err != nil {
+			return nil, err
+		}
+	}
+	h.handler.FileSystem = &DAVFileSystem{fs: f, readOnly: h.readOnly}
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.readOnly {
+		switch r.Method {
+		case http.MethodPut, http.MethodDelete, "MKCOL", "COPY", "MOVE", "PROPPATCH", "LOCK":
+			http.Error(w, "read-only file system", http.StatusForbidden)
+			return
+		}
+	}
+	h.handler.ServeHTTP(w, r)
+}
+
+// DAVFileSystem adapts a file system to the [webdav.FileSystem] of golang.org/x/net/webdav.
+//
+// Files are read as streams, seeking backwards reopens the stream unless the file system
+// implements [filesystem.RangeReader]; they are written through [filesystem.Creator] when implemented,
+// piped into WriteStream otherwise.
+type DAVFileSystem struct {
+	fs       fs.FileSystem
+	readOnly bool
+}
+
+// NewDAVFileSystem adapts the file system, refusing the writes if readOnly is set.
+func NewDAVFileSystem(f fs.FileSystem, readOnly bool) *DAVFileSystem {
+	return &DAVFileSystem{fs: f, readOnly: readOnly}
+}
+
+// pathError converts an error to the *os.PathError the webdav package checks with os.IsNotExist and os.IsExist.
+func pathError(op, name string, err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	case errors.Is(err, os.ErrExist):
+		return &os.PathError{Op: op, Path: name, Err: os.ErrExist}
+	case errors.Is(err, os.ErrPermission):
+		return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+	}
+	return err
+}
+
+func (d *DAVFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if d.readOnly {
+		return pathError("mkdir", name, os.ErrPermission)
+	}
+	k := filesystem.CleanPath(name)
+	if exists, err := d.fs.Exists(k); err != nil {
+		return err
+	} else if exists {
+		return pathError("mkdir", name, os.ErrExist)
+	}
+	if err := d.checkParent("mkdir", name); err != nil {
+		return err
+	}
+	return d.fs.CreateDir(k, nil)
+}
+
+// checkParent fails with os.ErrNotExist if the parent directory is missing, as WebDAV wants 409 Conflict.
+func (d *DAVFileSystem) checkParent(op, name string) error {
+	parent := path.Dir(filesystem.CleanPath(name))
+	if parent == "." {
+		return nil
+	}
+	exists, err := d.fs.DirExists(parent)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return pathError(op, name, os.ErrNotExist)
+	}
+	return nil
+}
+
+func (d *DAVFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	k := filesystem.CleanPath(name)
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+	if write && d.readOnly {
+		return nil, pathError("open", name, os.ErrPermission)
+	}
+	info, err := d.stat(k)
+	if err != nil && !(write && errors.Is(err, os.ErrNotExist)) {
+		return nil, pathError("open", name, err)
+	}
+	if !write {
+		props := davProps{d: d, path: k}
+		if info.IsDir() {
+			return &davDir{davProps: props, info: info}, nil
+		}
+		return &davReader{davProps: props, info: info}, nil
+	}
+	switch {
+	case info != nil && info.IsDir():
+		return nil, pathError("open", name, filesystem.ErrIsNotFile)
+	case info != nil && flag&os.O_EXCL != 0:
+		return nil, pathError("open", name, os.ErrExist)
+	case info == nil && flag&os.O_CREATE == 0:
+		return nil, pathError("open", name, os.ErrNotExist)
+	}
+	if err := d.checkParent("open", name); err != nil {
+		return nil, err
+	}
+	var config map[string]any
+	if flag&os.O_APPEND != 0 {
+		config = map[string]any{filesystem.FileWriteFlagKey: os.O_APPEND | os.O_CREATE | os.O_WRONLY}
+	}
+	var w filesystem.FileWriter
+	if creator, ok := d.fs.(filesystem.Creator); ok {
+		if w, err = creator.Create(k, config); err != nil {
+			return nil, err
+		}
+	} else {
+		w = filesystem.NewPipeWriter(func(stream io.Reader) error {
+			return d.fs.WriteStream(k, stream, config)
+		}, filesystem.DiscardFunc(nil, info != nil, func() error {
+			return d.fs.Delete(k)
+		}))
+	}
+	return &davWriter{davProps: davProps{d: d, path: k}, w: w}, nil
+}
+
+func (d *DAVFileSystem) RemoveAll(ctx context.Context, name string) error {
+	if d.readOnly {
+		return pathError("remove", name, os.ErrPermission)
+	}
+	k := filesystem.CleanPath(name)
+	if k == "." {
+		return pathError("remove", name, os.ErrPermission)
+	}
+	info, err := d.stat(k)
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	if info.IsDir() {
+		return d.fs.DeleteDir(k)
+	}
+	return d.fs.Delete(k)
+}
+
+func (d *DAVFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	if d.readOnly {
+		return pathError("rename", oldName, os.ErrPermission)
+	}
+	if filesystem.CleanPath(oldName) == "." || filesystem.CleanPath(newName) == "." {
+		return pathError("rename", oldName, os.ErrPermission)
+	}
+	if err := d.checkParent("rename", newName); err != nil {
+		return err
+	}
+	return pathError("rename", oldName, d.fs.Move(filesystem.CleanPath(oldName), filesystem.CleanPath(newName), nil))
+}
+
+func (d *DAVFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	info, err := d.stat(filesystem.CleanPath(name))
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return info, nil
+}
+
+// stat describes the file or directory with the fewest calls the file system allows.
+func (d *DAVFileSystem) stat(k string) (*davInfo, error) {
+	if k == "." {
+		return &davInfo{d: d, path: k, dir: true}, nil
+	}
+	if versioner, ok := d.fs.(filesystem.Versioner); ok {
+		if stat, err := versioner.Stat(k); err == nil {
+			return &davInfo{d: d, path: k, size: stat.Size, modTime: stat.LastModified, version: stat.Version}, nil
+		}
+	} else if linker, ok := d.fs.(filesystem.Symlinker); ok {
+		if info, err := linker.Lstat(k); err == nil && info.Mode()&gofs.ModeSymlink == 0 {
+			return &davInfo{d: d, path: k, dir: info.IsDir(), size: info.Size(), modTime: info.ModTime()}, nil
+		}
+	}
+	isFile, err := d.fs.FileExists(k)
+	if err != nil {
+		return nil, err
+	}
+	if isFile {
+		size, err := d.fs.FileSize(k)
+		if err != nil {
+			return nil, err
+		}
+		modTime, _ := d.fs.LastModified(k)
+		return &davInfo{d: d, path: k, size: size, modTime: modTime}, nil
+	}
+	isDir, err := d.fs.DirExists(k)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, os.ErrNotExist
+	}
+	modTime, _ := d.fs.LastModified(k)
+	return &davInfo{d: d, path: k, dir: true, modTime: modTime}, nil
+}
+
+// davInfo describes a file or directory, with the content type and ETag of the file system.
+type davInfo struct {
+	d       *DAVFileSystem
+	path    string
+	dir     bool
+	size    int64
+	modTime time.Time
+	version string
+}
+
+func (i *davInfo) Name() string {
+	return path.Base(i.path)
+}
+
+func (i *davInfo) Size() int64 {
+	return i.size
+}
+
+func (i *davInfo) Mode() gofs.FileMode {
+	if i.dir {
+		return gofs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i *davInfo) ModTime() time.Time {
+	return i.modTime
+}
+
+func (i *davInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i *davInfo) Sys() any {
+	return nil
+}
+
+// ContentType returns the mime type of the file system, instead of sniffing the content.
+func (i *davInfo) ContentType(ctx context.Context) (string, error) {
+	if i.dir {
+		return "", webdav.ErrNotImplemented
+	}
+	mimeType, err := i.d.fs.MimeType(i.path)
+	if err != nil || mimeType == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return mimeType, nil
+}
+
+// ETag returns the version of the file when the file system has versions.
+func (i *davInfo) ETag(ctx context.Context) (string, error) {
+	if i.version == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	if strings.HasPrefix(i.version, `"`) || strings.HasPrefix(i.version, `W/"`) {
+		return i.version, nil
+	}
+	return `"` + i.version + `"`, nil
+}
+
+// davProps holds the visibility of a file as the [VisibilityProperty] dead property.
+type davProps struct {
+	d    *DAVFileSystem
+	path string
+}
+
+func (p davProps) DeadProps() (map[xml.Name]webdav.Property, error) {
+	visibility, err := p.d.fs.Visibility(p.path)
+	if err != nil || visibility == "" {
+		return map[xml.Name]webdav.Property{}, nil
+	}
+	return map[xml.Name]webdav.Property{VisibilityProperty: visibilityProperty(visibility)}, nil
+}
+
+func visibilityProperty(visibility string) webdav.Property {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(visibility))
+	return webdav.Property{XMLName: VisibilityProperty, InnerXML: buf.Bytes()}
+}
+
+func (p davProps) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	visibility, forbidden, others := parsePatches(patches)
+	if p.d.readOnly && visibility != "" {
+		forbidden = append(forbidden, webdav.Property{XMLName: VisibilityProperty})
+	}
+	if len(forbidden) > 0 {
+		return failedPatch(forbidden, others), nil
+	}
+	if visibility != "" {
+		if err := p.d.fs.SetVisibility(p.path, visibility); err != nil {
+			return nil, err
+		}
+	}
+	return []webdav.Propstat{{Status: http.StatusOK, Props: others}}, nil
+}
+
+// parsePatches returns the visibility set by the patches, the properties which can't be patched,
+// and the names of all the others.
+func parsePatches(patches []webdav.Proppatch) (visibility string, forbidden, others []webdav.Property) {
+	for _, patch := range patches {
+		for _, prop := range patch.Props {
+			value := strings.TrimSpace(string(prop.InnerXML))
+			if prop.XMLName != VisibilityProperty || patch.Remove || (value != "public" && value != "private") {
+				forbidden = append(forbidden, webdav.Property{XMLName: prop.XMLName})
+				continue
+			}
+			visibility = value
+			others = append(others, webdav.Property{XMLName: prop.XMLName})
+		}
+	}
+	return visibility, forbidden, others
+}
+
+// failedPatch reports a patch failing as a whole: the forbidden properties, and the others failing with them.
+func failedPatch(forbidden, others []webdav.Property) []webdav.Propstat {
+	propstats := []webdav.Propstat{{Status: http.StatusForbidden, Props: forbidden}}
+	if len(others) > 0 {
+		propstats = append(propstats, webdav.Propstat{Status: http.StatusFailedDependency, Props: others})
+	}
+	return propstats
+}
+
+type davDir struct {
+	davProps
+	info    *davInfo
+	entries []gofs.FileInfo
+	read    bool
+}
+
+func (f *davDir) Readdir(count int) ([]gofs.FileInfo, error) {
+	if !f.read {
+		entries, err := f.d.fs.ReadDir(f.path)
+		if err != nil {
+			return nil, pathError("readdir", f.path, err)
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			f.entries = append(f.entries, info)
+		}
+		f.read = true
+	}
+	if count <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *davDir) Stat() (gofs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *davDir) Read(p []byte) (int, error) {
+	return 0, pathError("read", f.path, filesystem.ErrIsNotFile)
+}
+
+func (f *davDir) Seek(offset int64, whence int) (int64, error) {
+	return 0, nil
+}
+
+func (f *davDir) Write(p []byte) (int, error) {
+	return 0, pathError("write", f.path, filesystem.ErrIsNotFile)
+}
+
+func (f *davDir) Close() error {
+	return nil
+}
+
+// davReader reads a file, the stream is only opened on the first read at the current offset,
+// so that seeking to find the size or to serve a range costs nothing.
+type davReader struct {
+	davProps
+	info      *davInfo
+	offset    int64
+	stream    io.ReadCloser
+	streamPos int64
+}
+
+func (f *davReader) Readdir(count int) ([]gofs.FileInfo, error) {
+	return nil, pathError("readdir", f.path, filesystem.ErrIsNotDirectory)
+}
+
+func (f *davReader) Stat() (gofs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *davReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	}
+	if offset < 0 {
+		return 0, pathError("seek", f.path, errors.New("negative position"))
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *davReader) Read(p []byte) (int, error) {
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if f.stream == nil || f.streamPos != f.offset {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.stream.Read(p)
+	f.offset += int64(n)
+	f.streamPos = f.offset
+	return n, err
+}
+
+// open positions the stream at the offset: a range is read when the file system can,
+// otherwise the stream is read forward, and reopened to go backwards.
+func (f *davReader) open() error {
+	if reader, ok := f.d.fs.(filesystem.RangeReader); ok {
+		f.closeStream()
+		stream, err := reader.ReadRange(f.path, f.offset, -1)
+		if err != nil {
+			return err
+		}
+		f.stream, f.streamPos = stream, f.offset
+		return nil
+	}
+	if f.stream == nil || f.streamPos > f.offset {
+		f.closeStream()
+		stream, err := f.d.fs.ReadStream(f.path)
+		if err != nil {
+			return err
+		}
+		f.stream, f.streamPos = stream, 0
+	}
+	n, err := io.CopyN(io.Discard, f.stream, f.offset-f.streamPos)
+	f.streamPos += n
+	return err
+}
+
+func (f *davReader) closeStream() {
+	if f.stream != nil {
+		_ = f.stream.Close()
+		f.stream = nil
+	}
+}
+
+func (f *davReader) Write(p []byte) (int, error) {
+	return 0, pathError("write", f.path, os.ErrPermission)
+}
+
+func (f *davReader) Close() error {
+	f.closeStream()
+	return nil
+}
+
+// davWriter writes a file, committed when closed.
+// The visibility patched before, e.g. while copying the properties of a file, is set once committed.
+type davWriter struct {
+	davProps
+	w          filesystem.FileWriter
+	written    int64
+	visibility string
+	// err is the first error of Write, the file is aborted instead of committed on Close
+	err error
+}
+
+func (f *davWriter) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	n, err := f.w.Write(p)
+	f.written += int64(n)
+	if err != nil {
+		f.err = err
+	}
+	return n, err
+}
+
+func (f *davWriter) Stat() (gofs.FileInfo, error) {
+	return &davInfo{d: f.d, path: f.path, size: f.written, modTime: time.Now()}, nil
+}
+
+func (f *davWriter) DeadProps() (map[xml.Name]webdav.Property, error) {
+	if f.visibility == "" {
+		return map[xml.Name]webdav.Property{}, nil
+	}
+	return map[xml.Name]webdav.Property{VisibilityProperty: visibilityProperty(f.visibility)}, nil
+}
+
+func (f *davWriter) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	visibility, forbidden, others := parsePatches(patches)
+	if len(forbidden) > 0 {
+		return failedPatch(forbidden, others), nil
+	}
+	if visibility != "" {
+		f.visibility = visibility
+	}
+	return []webdav.Propstat{{Status: http.StatusOK, Props: others}}, nil
+}
+
+func (f *davWriter) Close() error {
+	if f.err != nil {
+		// the handler closes the file even if copying the body failed, a truncated file must not replace it
+		_ = f.w.Abort()
+		return f.err
+	}
+	if err := f.w.Close(); err != nil {
+		return err
+	}
+	if f.visibility != "" {
+		return f.d.fs.SetVisibility(f.path, f.visibility)
+	}
+	return nil
+}
+
+func (f *davWriter) Read(p []byte) (int, error) {
+	return 0, pathError("read", f.path, os.ErrPermission)
+}
+
+func (f *davWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekCurrent {
+		return f.written, nil
+	}
+	return 0, pathError("seek", f.path, errors.New("seeking a file being written"))
+}
+
+func (f *davWriter) Readdir(count int) ([]gofs.FileInfo, error) {
+	return nil, pathError("readdir", f.path, filesystem.ErrIsNotDirectory)
+}
@@ -0,0 +1,217 @@
+package webdav
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopi-frame/filesystem/driver/memory"
+	"github.com/gopi-frame/filesystem/driver/readonly"
+
+	fs "github.com/gopi-frame/contract/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+// plainFS hides the optional interfaces of a file system, so that the adapter falls back to the contract.
+type plainFS struct {
+	fs.FileSystem
+}
+
+func serve(t *testing.T, f fs.FileSystem, opts ...HandlerOption) string {
+	handler, err := NewHandler(f, append([]HandlerOption{WithPrefix("/dav")}, opts...)...)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server.URL + "/dav/"
+}
+
+func send(t *testing.T, method, url, body string, header map[string]string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	return string(body)
+}
+
+func TestHandler(t *testing.T) {
+	backends := map[string]func() fs.FileSystem{
+		"memory": func() fs.FileSystem { return memory.NewMemoryFileSystem("public", nil) },
+		"plain":  func() fs.FileSystem { return plainFS{memory.NewMemoryFileSystem("public", nil)} },
+	}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			store := backend()
+			endpoint := serve(t, store)
+			client := newFS(t, endpoint, AuthAuto, "")
+
+			if err := client.Write("docs/readme.txt", []byte("0123456789"), nil); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			content, err := store.Read("docs/readme.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "0123456789", string(content))
+			content, err = client.Read("docs/readme.txt")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			assert.Equal(t, "0123456789", string(content))
+
+			resp := send(t, http.MethodGet, endpoint+"docs/readme.txt", "", map[string]string{"Range": "bytes=3-5"})
+			assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+			assert.Equal(t, "345", readBody(t, resp))
+
+			entries, err := client.ReadDir("docs")
+			if err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, "readme.txt", entries[0].Name())
+			}
+			size, _ := client.FileSize("docs/readme.txt")
+			assert.Equal(t, int64(10), size)
+
+			if err := client.Copy("docs", "copy", nil); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			if err := client.Move("copy/readme.txt", "moved.txt", nil); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			content, _ = store.Read("moved.txt")
+			assert.Equal(t, "0123456789", string(content))
+			if err := client.DeleteDir("copy"); err != nil {
+				assert.FailNow(t, err.Error())
+			}
+			exists, _ := store.DirExists("copy")
+			assert.False(t, exists)
+
+			resp = send(t, "MKCOL", endpoint+"missing/child/", "", nil)
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+			resp = send(t, http.MethodPut, endpoint+"missing/file.txt", "x", nil)
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		})
+	}
+}
+
+func TestHandler_Visibility(t *testing.T) {
+	store := memory.NewMemoryFileSystem("public", nil)
+	if err := store.Write("a.txt", []byte("a"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	endpoint := serve(t, store)
+
+	propfind := `<?xml version="1.0"?><d:propfind xmlns:d="DAV:" xmlns:g="urn:gopi-frame:filesystem"><d:prop><g:visibility/></d:prop></d:propfind>`
+	resp := send(t, "PROPFIND", endpoint+"a.txt", propfind, map[string]string{"Depth": "0"})
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), ">public</")
+
+	proppatch := `<?xml version="1.0"?><d:propertyupdate xmlns:d="DAV:" xmlns:g="urn:gopi-frame:filesystem">` +
+		`<d:set><d:prop><g:visibility>private</g:visibility></d:prop></d:set></d:propertyupdate>`
+	resp = send(t, "PROPPATCH", endpoint+"a.txt", proppatch, nil)
+	assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "200 OK")
+	visibility, _ := store.Visibility("a.txt")
+	assert.Equal(t, "private", visibility)
+
+	invalid := strings.Replace(proppatch, "private", "secret", 1)
+	resp = send(t, "PROPPATCH", endpoint+"a.txt", invalid, nil)
+	assert.Contains(t, readBody(t, resp), "403 Forbidden")
+	visibility, _ = store.Visibility("a.txt")
+	assert.Equal(t, "private", visibility)
+}
+
+func TestHandler_Lock(t *testing.T) {
+	store := memory.NewMemoryFileSystem("public", nil)
+	if err := store.Write("locked.txt", []byte("v1"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	endpoint := serve(t, store)
+
+	lock := `<?xml version="1.0"?><d:lockinfo xmlns:d="DAV:"><d:lockscope><d:exclusive/></d:lockscope>` +
+		`<d:locktype><d:write/></d:locktype><d:owner>alice</d:owner></d:lockinfo>`
+	resp := send(t, "LOCK", endpoint+"locked.txt", lock, map[string]string{"Timeout": "Second-60"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	token := resp.Header.Get("Lock-Token")
+	assert.NotEmpty(t, token)
+
+	resp = send(t, http.MethodPut, endpoint+"locked.txt", "v2", nil)
+	assert.Equal(t, http.StatusLocked, resp.StatusCode)
+	resp = send(t, http.MethodPut, endpoint+"locked.txt", "v2", map[string]string{"If": "(" + token + ")"})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	content, _ := store.Read("locked.txt")
+	assert.Equal(t, "v2", string(content))
+
+	resp = send(t, "UNLOCK", endpoint+"locked.txt", "", map[string]string{"Lock-Token": token})
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = send(t, http.MethodPut, endpoint+"locked.txt", "v3", nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+// failingWriter fails every write, and records whether it was committed or aborted.
+type failingWriter struct {
+	closed, aborted bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func (w *failingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func (w *failingWriter) Abort() error {
+	w.aborted = true
+	return nil
+}
+
+func TestDavWriter_Abort(t *testing.T) {
+	w := &failingWriter{}
+	f := &davWriter{davProps: davProps{path: "a.txt"}, w: w}
+	_, err := f.Write([]byte("content"))
+	assert.EqualError(t, err, "disk full")
+	assert.EqualError(t, f.Close(), "disk full")
+	assert.True(t, w.aborted)
+	assert.False(t, w.closed)
+}
+
+func TestHandler_ReadOnly(t *testing.T) {
+	store := memory.NewMemoryFileSystem("public", nil)
+	if err := store.Write("a.txt", []byte("a"), nil); err != nil {
+		assert.FailNow(t, err.Error())
+	}
+	endpoint := serve(t, readonly.NewReadOnlyFileSystem(store))
+
+	resp := send(t, http.MethodGet, endpoint+"a.txt", "", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "a", readBody(t, resp))
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL", "MOVE", "COPY", "PROPPATCH", "LOCK"} {
+		resp = send(t, method, endpoint+"a.txt", "", map[string]string{"Destination": endpoint + "b.txt"})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, method)
+	}
+	content, _ := store.Read("a.txt")
+	assert.Equal(t, "a", string(content))
+}
@@ -279,3 +279,160 @@ func NewInvalidPathError(path string) *InvalidPathError {
 		Throwable: exception.New(fmt.Sprintf("invalid path: %s", path)),
 	}
 }
+
+type PathOutsideRoot struct {
+	path string
+	Throwable
+}
+
+func NewPathOutsideRoot(path string) *PathOutsideRoot {
+	return &PathOutsideRoot{
+		path:      path,
+		Throwable: exception.New(fmt.Sprintf("path outside root: %s", path)),
+	}
+}
+
+type UnableToLock struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewUnableToLock(location string, err error) *UnableToLock {
+	return &UnableToLock{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Unable to lock file at location %s: %s", location, err)),
+	}
+}
+
+func (err *UnableToLock) Unwrap() error {
+	return err.err
+}
+
+type LockHeld struct {
+	path   string
+	holder LockInfo
+	Throwable
+}
+
+func NewLockHeld(path string, holder LockInfo) *LockHeld {
+	return &LockHeld{
+		path:      path,
+		holder:    holder,
+		Throwable: exception.New(fmt.Sprintf("lock of %s is held by %s", path, holder)),
+	}
+}
+
+// Holder returns the ownership metadata of the lock, as recorded by its holder.
+func (err *LockHeld) Holder() LockInfo {
+	return err.holder
+}
+
+type PreconditionFailed struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewPreconditionFailed(location string, err error) *PreconditionFailed {
+	return &PreconditionFailed{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Precondition failed for file at location %s: %s", location, err)),
+	}
+}
+
+func (err *PreconditionFailed) Unwrap() error {
+	return err.err
+}
+
+type UnableToOpenFile struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewUnableToOpenFile(location string, err error) *UnableToOpenFile {
+	return &UnableToOpenFile{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Unable to open file at location %s: %s", location, err)),
+	}
+}
+
+func (err *UnableToOpenFile) Unwrap() error {
+	return err.err
+}
+
+type UnableToCreateSymlink struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewUnableToCreateSymlink(location string, err error) *UnableToCreateSymlink {
+	return &UnableToCreateSymlink{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Unable to create symbolic link at location %s: %s", location, err)),
+	}
+}
+
+func (err *UnableToCreateSymlink) Unwrap() error {
+	return err.err
+}
+
+type UnableToReadSymlink struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewUnableToReadSymlink(location string, err error) *UnableToReadSymlink {
+	return &UnableToReadSymlink{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Unable to read symbolic link at location %s: %s", location, err)),
+	}
+}
+
+func (err *UnableToReadSymlink) Unwrap() error {
+	return err.err
+}
+
+type UnableToSetLastModified struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewUnableToSetLastModified(location string, err error) *UnableToSetLastModified {
+	return &UnableToSetLastModified{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Unable to set last modified time of file at location %s: %s", location, err)),
+	}
+}
+
+func (err *UnableToSetLastModified) Unwrap() error {
+	return err.err
+}
+
+type UnableToSetMetadata struct {
+	location string
+	err      error
+	Throwable
+}
+
+func NewUnableToSetMetadata(location string, err error) *UnableToSetMetadata {
+	return &UnableToSetMetadata{
+		location:  location,
+		err:       err,
+		Throwable: exception.New(fmt.Sprintf("Unable to set metadata of file at location %s: %s", location, err)),
+	}
+}
+
+func (err *UnableToSetMetadata) Unwrap() error {
+	return err.err
+}
@@ -0,0 +1,40 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+var ErrRandomAccessUnsupported = errors.New("file system does not support random access")
+
+// File is a handle for random access to a file, like [os.File].
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	// Name returns the path the file was opened with.
+	Name() string
+	Truncate(size int64) error
+	Stat() (os.FileInfo, error)
+	// Sync commits the content of the file to the storage.
+	Sync() error
+}
+
+// FileOpener is implemented by the file systems which can open files for random access,
+// i.e. reading and writing at any offset.
+type FileOpener interface {
+	// OpenFile opens the file with the flag of [os.OpenFile].
+	// The visibility is applied when the file is created, the default one is used if it is empty.
+	OpenFile(path string, flag int, visibility string) (File, error)
+}
+
+// SupportsRandomAccess reports whether the file system can open files for random access, see [FileOpener].
+// Object stores and FTP cannot write at an offset, so they do not.
+func SupportsRandomAccess(f any) bool {
+	_, ok := f.(FileOpener)
+	return ok
+}
@@ -137,6 +137,66 @@ func (fm *FileSystemManager) ReadStream(path string) (io.ReadCloser, error) {
 	return f.ReadStream(p)
 }
 
+// ReadStreamWithConfig reads the file content as a stream,
+// reporting the progress set in the config if the filesystem implements [ProgressReader].
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if reader, ok := f.(ProgressReader); ok {
+		return reader.ReadStreamWithConfig(p, config)
+	}
+	return f.ReadStream(p)
+}
+
+// ReadRange reads length bytes of the file starting at offset, or the rest of the file if length is negative.
+// Filesystems which do not implement [RangeReader] read the file from the beginning and skip the offset.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) ReadRange(path string, offset, length int64) (io.ReadCloser, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if reader, ok := f.(RangeReader); ok {
+		return reader.ReadRange(p, offset, length)
+	}
+	if err := CheckRange(offset); err != nil {
+		return nil, NewUnableToReadFile(p, err)
+	}
+	stream, err := f.ReadStream(p)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, stream, offset); err != nil && err != io.EOF {
+		_ = stream.Close()
+		return nil, NewUnableToReadFile(p, err)
+	}
+	return LimitReadCloser(stream, length), nil
+}
+
+// OpenFile opens the file for random access, see [FileOpener].
+// It returns an [UnableToOpenFile] error wrapping [ErrRandomAccessUnsupported] if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) OpenFile(path string, flag int, visibility string) (File, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	opener, ok := f.(FileOpener)
+	if !ok {
+		return nil, NewUnableToOpenFile(p, ErrRandomAccessUnsupported)
+	}
+	return opener.OpenFile(p, flag, visibility)
+}
+
 // ReadDir reads the directory content.
 //
 // Path should be in the format of "<fs>://<path>",
@@ -161,6 +221,94 @@ func (fm *FileSystemManager) WalkDir(path string, walkFn fs.WalkDirFunc) error {
 	return f.WalkDir(p, walkFn)
 }
 
+// WalkDirWithConfig walks the directory like WalkDir, handling the symbolic links according to [SymlinksKey],
+// see [SymlinkWalker].
+// If the filesystem does not implement [SymlinkWalker], the links it reports are passed as is or skipped,
+// and following them fails with [ErrSymlinkUnsupported].
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the directory.
+func (fm *FileSystemManager) WalkDirWithConfig(path string, config map[string]any, walkFn fs.WalkDirFunc) error {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return err
+	}
+	if walker, ok := f.(SymlinkWalker); ok {
+		return walker.WalkDirWithConfig(p, config, walkFn)
+	}
+	var cfg *Config
+	if config != nil {
+		if cfg, err = NewConfig(config); err != nil {
+			return err
+		}
+	}
+	mode, err := cfg.SymlinkMode()
+	if err != nil {
+		return NewUnableToReadDirectory(p, err)
+	}
+	switch mode {
+	case FollowSymlinks:
+		return NewUnableToReadDirectory(p, ErrSymlinkUnsupported)
+	case SkipSymlinks:
+		return f.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if d != nil && d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			return walkFn(path, d, err)
+		})
+	}
+	return f.WalkDir(p, walkFn)
+}
+
+// Symlink creates link as a symbolic link to target, see [Symlinker].
+// The target is stored as is, without the filesystem name.
+//
+// Link should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the link.
+func (fm *FileSystemManager) Symlink(target, link string) error {
+	f, p, err := fm.splitFileSystemAndPath(link)
+	if err != nil {
+		return err
+	}
+	symlinker, ok := f.(Symlinker)
+	if !ok {
+		return NewUnableToCreateSymlink(p, ErrSymlinkUnsupported)
+	}
+	return symlinker.Symlink(target, p)
+}
+
+// Readlink returns the target of the symbolic link.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the link.
+func (fm *FileSystemManager) Readlink(path string) (string, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return "", err
+	}
+	symlinker, ok := f.(Symlinker)
+	if !ok {
+		return "", NewUnableToReadSymlink(p, ErrSymlinkUnsupported)
+	}
+	return symlinker.Readlink(p)
+}
+
+// Lstat returns the info of the file, describing the symbolic link itself if it is one.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) Lstat(path string) (os.FileInfo, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	symlinker, ok := f.(Symlinker)
+	if !ok {
+		return nil, NewUnableToRetrieveMetadata(p, ErrSymlinkUnsupported)
+	}
+	return symlinker.Lstat(p)
+}
+
 // LastModified returns the last modified time of the file.
 //
 // Path should be in the format of "<fs>://<path>",
@@ -173,6 +321,114 @@ func (fm *FileSystemManager) LastModified(path string) (time.Time, error) {
 	return f.LastModified(p)
 }
 
+// Touch sets the modification time of the file to now, creating an empty file if it is missing, see [Toucher].
+// It returns an [UnableToSetLastModified] error wrapping [ErrSetLastModifiedUnsupported]
+// if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) Touch(path string) error {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return err
+	}
+	toucher, ok := f.(Toucher)
+	if !ok {
+		return NewUnableToSetLastModified(p, ErrSetLastModifiedUnsupported)
+	}
+	return toucher.Touch(p)
+}
+
+// SetLastModified sets the modification time of the file, see [Toucher].
+// It returns an [UnableToSetLastModified] error wrapping [ErrSetLastModifiedUnsupported]
+// if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) SetLastModified(path string, t time.Time) error {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return err
+	}
+	toucher, ok := f.(Toucher)
+	if !ok {
+		return NewUnableToSetLastModified(p, ErrSetLastModifiedUnsupported)
+	}
+	return toucher.SetLastModified(p, t)
+}
+
+// Metadata returns the custom metadata of the file, see [MetadataStore].
+// It returns an [UnableToRetrieveMetadata] error wrapping [ErrMetadataUnsupported]
+// if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) Metadata(path string) (map[string]string, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := f.(MetadataStore)
+	if !ok {
+		return nil, NewUnableToRetrieveMetadata(p, ErrMetadataUnsupported)
+	}
+	return store.Metadata(p)
+}
+
+// SetMetadata replaces the custom metadata of the file, see [MetadataStore].
+// It returns an [UnableToSetMetadata] error wrapping [ErrMetadataUnsupported]
+// if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) SetMetadata(path string, metadata map[string]string) error {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return err
+	}
+	store, ok := f.(MetadataStore)
+	if !ok {
+		return NewUnableToSetMetadata(p, ErrMetadataUnsupported)
+	}
+	return store.SetMetadata(p, metadata)
+}
+
+// Tags returns the tags of the file, see [MetadataStore].
+// It returns an [UnableToRetrieveMetadata] error wrapping [ErrMetadataUnsupported]
+// if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) Tags(path string) (map[string]string, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := f.(MetadataStore)
+	if !ok {
+		return nil, NewUnableToRetrieveMetadata(p, ErrMetadataUnsupported)
+	}
+	return store.Tags(p)
+}
+
+// SetTags replaces the tags of the file, see [MetadataStore].
+// It returns an [UnableToSetMetadata] error wrapping [ErrMetadataUnsupported]
+// if the filesystem does not support it.
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) SetTags(path string, tags map[string]string) error {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return err
+	}
+	store, ok := f.(MetadataStore)
+	if !ok {
+		return NewUnableToSetMetadata(p, ErrMetadataUnsupported)
+	}
+	return store.SetTags(p, tags)
+}
+
 // FileSize returns the size of the file.
 //
 // Path should be in the format of "<fs>://<path>",
@@ -233,6 +489,37 @@ func (fm *FileSystemManager) WriteStream(path string, stream io.Reader, config m
 	return f.WriteStream(p, stream, config)
 }
 
+// Create returns a writer to the file, see [Creator].
+// If the filesystem does not implement [Creator], the content is piped to its WriteStream,
+// and Abort makes the write fail and deletes the file if the writer created it, see [DiscardFunc].
+//
+// Path should be in the format of "<fs>://<path>",
+// where <fs> is the name of the filesystem and <path> is the path to the file.
+func (fm *FileSystemManager) Create(path string, config map[string]any) (FileWriter, error) {
+	f, p, err := fm.splitFileSystemAndPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if creator, ok := f.(Creator); ok {
+		return creator.Create(p, config)
+	}
+	var cfg *Config
+	if config != nil {
+		if cfg, err = NewConfig(config); err != nil {
+			return nil, err
+		}
+	}
+	existed, err := f.FileExists(p)
+	if err != nil {
+		return nil, err
+	}
+	return NewPipeWriter(func(stream io.Reader) error {
+		return f.WriteStream(p, stream, config)
+	}, DiscardFunc(cfg, existed, func() error {
+		return f.Delete(p)
+	})), nil
+}
+
 // SetVisibility sets the visibility of the file.
 //
 // Path should be in the format of "<fs>://<path>",
@@ -286,9 +573,10 @@ func (fm *FileSystemManager) CreateDir(path string, config map[string]any) error
 // Source path and destination path should be in the format of "<fs>://<path>"
 // where <fs> is the name of the filesystem and <path> is the path to the file or directory.
 //
-// Note:
-//
-//	When the source filesystem and destination filesystem is not the same, moving directory is not supported.
+// When the source filesystem and destination filesystem are not the same,
+// the files are streamed to the destination and deleted from the source once all of them are written.
+// The progress set with [ProgressKey] or [ContextKey] is reported by the destination filesystem,
+// for a directory the reports carry the overall progress as well.
 func (fm *FileSystemManager) Move(src string, dst string, config map[string]any) error {
 	f1, p1, err := fm.splitFileSystemAndPath(src)
 	if err != nil {
@@ -301,18 +589,31 @@ func (fm *FileSystemManager) Move(src string, dst string, config map[string]any)
 	if f1 == f2 {
 		return f1.Move(p1, p2, config)
 	}
-	s1, err := f1.ReadStream(p1)
+	isDir, err := f1.DirExists(p1)
 	if err != nil {
 		return err
 	}
-	defer s1.Close()
-	return f2.WriteStream(p2, s1, nil)
+	// the source is deleted only once everything was copied, so a failure leaves it complete
+	if isDir {
+		if err := fm.copyDir(f1, p1, f2, p2, config); err != nil {
+			return err
+		}
+		return f1.DeleteDir(p1)
+	}
+	if err := fm.transfer(f1, p1, f2, p2, config); err != nil {
+		return err
+	}
+	return f1.Delete(p1)
 }
 
-// Copy copies the source file to the destination location
+// Copy copies the source file or directory to the destination location
 //
 // Source path and destination path should be in the format of "<fs>://<path>"
 // where <fs> is the name of the filesystem and <path> is the path to the file or directory.
+//
+// A directory is copied file by file.
+// The progress set with [ProgressKey] or [ContextKey] is reported by the destination filesystem,
+// for a directory the reports carry the overall progress as well.
 func (fm *FileSystemManager) Copy(src string, dst string, config map[string]any) error {
 	f1, p1, err := fm.splitFileSystemAndPath(src)
 	if err != nil {
@@ -325,10 +626,106 @@ func (fm *FileSystemManager) Copy(src string, dst string, config map[string]any)
 	if f1 == f2 {
 		return f1.Copy(p1, p2, config)
 	}
+	isDir, err := f1.DirExists(p1)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		return fm.copyDir(f1, p1, f2, p2, config)
+	}
+	return fm.transfer(f1, p1, f2, p2, config)
+}
+
+// transfer streams the file from one filesystem to the other,
+// giving the size of the file to the destination so that the progress has a total.
+func (fm *FileSystemManager) transfer(f1 filesystem.FileSystem, p1 string, f2 filesystem.FileSystem, p2 string, config map[string]any) error {
+	cfg, err := NewConfig(config)
+	if err != nil {
+		return err
+	}
+	if cfg.Progress() != nil && cfg.ContentLength == nil {
+		if size, err := f1.FileSize(p1); err == nil {
+			config = withConfig(config, map[string]any{ContentLengthKey: size})
+		}
+	}
 	s1, err := f1.ReadStream(p1)
 	if err != nil {
 		return err
 	}
-	defer s1.Close()
-	return f2.WriteStream(p2, s1, nil)
+	if err := f2.WriteStream(p2, s1, config); err != nil {
+		_ = s1.Close()
+		return err
+	}
+	// a stream which fails on close, e.g. on a checksum mismatch, was not read completely
+	return s1.Close()
+}
+
+// copyDir copies every file of the directory, reporting the progress of each file with the overall progress.
+// The directories are created as they are walked, so that the empty ones are copied as well.
+func (fm *FileSystemManager) copyDir(f1 filesystem.FileSystem, p1 string, f2 filesystem.FileSystem, p2 string, config map[string]any) error {
+	cfg, err := NewConfig(config)
+	if err != nil {
+		return err
+	}
+	progress := cfg.Progress()
+	// the directories only get the visibility, the other keys are meant for the files
+	var dirConfig map[string]any
+	if v, ok := config[DirVisibilityKey]; ok {
+		dirConfig = map[string]any{DirVisibilityKey: v}
+	}
+	var files []string
+	var sizes []int64
+	var total int64
+	err = WalkDirRelative(f1, p1, func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return f2.CreateDir(JoinWalked(p2, rel, true), dirConfig)
+		}
+		var size int64 = -1
+		if progress != nil {
+			if size, err = f1.FileSize(JoinWalked(p1, rel, false)); err != nil {
+				size = -1
+			}
+		}
+		if size < 0 || total < 0 {
+			total = -1
+		} else {
+			total += size
+		}
+		files = append(files, rel)
+		sizes = append(sizes, size)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	aggregate := NewAggregateProgress(len(files), total, progress)
+	for i, rel := range files {
+		file := JoinWalked(p1, rel, false)
+		target := JoinWalked(p2, rel, false)
+		fn, finish := aggregate.File(target, sizes[i])
+		fileConfig := config
+		if fn != nil {
+			fileConfig = withConfig(config, map[string]any{ProgressKey: fn, ContentLengthKey: sizes[i]})
+		}
+		if err := fm.transfer(f1, file, f2, target, fileConfig); err != nil {
+			return err
+		}
+		finish()
+	}
+	return nil
+}
+
+// withConfig returns a copy of the config with the values set.
+func withConfig(config map[string]any, values map[string]any) map[string]any {
+	merged := make(map[string]any, len(config)+len(values))
+	for k, v := range config {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
 }
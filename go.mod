@@ -1,3 +1,8 @@
 module github.com/gopi-frame/filesystem
 
 go 1.22
+
+require (
+	github.com/gabriel-vasile/mimetype v1.4.15
+	github.com/go-viper/mapstructure/v2 v2.5.0
+)
@@ -0,0 +1,66 @@
+// Package httpclient holds the helpers shared by the drivers of the file systems served over HTTP.
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gopi-frame/filesystem"
+)
+
+// StatusError is the error of a request answered with an unexpected status.
+// It wraps os.ErrNotExist for 404 Not Found and 410 Gone, and os.ErrPermission for 401 Unauthorized and 403 Forbidden.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+}
+
+func (e *StatusError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return os.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return os.ErrPermission
+	}
+	return nil
+}
+
+// ParseEndpoint parses the URL of an endpoint, the paths are resolved under it.
+// Its path always ends with a slash, and its query and fragment are dropped.
+func ParseEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u, nil
+}
+
+// URL returns the URL of the resource under the endpoint, directories are addressed with a trailing slash.
+func URL(endpoint *url.URL, p string, dir bool) string {
+	u := *endpoint
+	if k := filesystem.CleanPath(p); k != "." {
+		u.Path += k
+		if dir {
+			u.Path += "/"
+		}
+	}
+	return u.String()
+}
@@ -0,0 +1,146 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultLockTTL is the lease of a lock acquired with a ttl less than or equal to 0.
+const DefaultLockTTL = time.Minute
+
+// LockSuffix is appended to the path of a file to name its lock file, for the drivers storing locks as files.
+const LockSuffix = ".lock"
+
+var ErrLockLost = errors.New("lock is not held anymore")
+
+// Locker is implemented by the file systems supporting advisory locks.
+//
+// Locks are advisory: they only exclude the processes which lock the same path,
+// and never prevent a file from being read or written.
+type Locker interface {
+	// Lock acquires the lock of the path, waiting until it is released, or until its lease expires.
+	Lock(path string, ttl time.Duration) (Lock, error)
+	// LockContext acquires the lock of the path like Lock, or fails with the error of the context once it is done.
+	LockContext(ctx context.Context, path string, ttl time.Duration) (Lock, error)
+	// TryLock acquires the lock of the path, or returns a [LockHeld] error without waiting.
+	TryLock(path string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is an acquired lock.
+//
+// A lock expires when its ttl elapses without being refreshed,
+// so that the lock of a crashed process is recovered by the next one trying to acquire it.
+// Once taken over, Refresh and Unlock return [ErrLockLost].
+type Lock interface {
+	// Path returns the locked path.
+	Path() string
+	// Info returns the ownership metadata of the lock.
+	Info() LockInfo
+	// Refresh extends the lease of the lock to ttl from now.
+	Refresh(ttl time.Duration) error
+	// Unlock releases the lock.
+	Unlock() error
+}
+
+// LockInfo is the ownership metadata stored with a lock.
+type LockInfo struct {
+	// Token identifies the acquisition of the lock.
+	Token string `json:"token"`
+	// Hostname is the host name of the holder.
+	Hostname string `json:"hostname"`
+	// PID is the process id of the holder.
+	PID int `json:"pid"`
+	// AcquiredAt is the time the lock was acquired.
+	AcquiredAt time.Time `json:"acquired_at"`
+	// ExpiresAt is the time the lease of the lock expires.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewLockInfo returns the ownership metadata of a lock acquired now by the current process.
+func NewLockInfo(ttl time.Duration) LockInfo {
+	hostname, _ := os.Hostname()
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+	now := time.Now()
+	return LockInfo{
+		Token:      hex.EncodeToString(token),
+		Hostname:   hostname,
+		PID:        os.Getpid(),
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(lockTTL(ttl)),
+	}
+}
+
+// ParseLockInfo decodes the ownership metadata stored in a lock file.
+//
+// A lock file which cannot be decoded, e.g. because its holder crashed while writing it,
+// is considered acquired at modTime with the [DefaultLockTTL], so that it expires eventually.
+func ParseLockInfo(content []byte, modTime time.Time) LockInfo {
+	var info LockInfo
+	if err := json.Unmarshal(content, &info); err != nil || info.ExpiresAt.IsZero() {
+		return LockInfo{AcquiredAt: modTime, ExpiresAt: modTime.Add(DefaultLockTTL)}
+	}
+	return info
+}
+
+// Renew returns the metadata with the lease extended to ttl from now.
+func (i LockInfo) Renew(ttl time.Duration) LockInfo {
+	i.ExpiresAt = time.Now().Add(lockTTL(ttl))
+	return i
+}
+
+// Expired reports whether the lease of the lock has expired.
+func (i LockInfo) Expired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+// Bytes returns the JSON encoding of the metadata, as stored in lock files.
+func (i LockInfo) Bytes() []byte {
+	content, _ := json.Marshal(i)
+	return content
+}
+
+func (i LockInfo) String() string {
+	if i.Token == "" {
+		return "an unknown owner"
+	}
+	return fmt.Sprintf("%s (pid %d) until %s", i.Hostname, i.PID, i.ExpiresAt.Format(time.RFC3339))
+}
+
+// WaitForLock calls tryLock until it does not return a [LockHeld] error, or until the context is done,
+// in which case the error wraps both the error of the context and the last [LockHeld] error.
+// It is used by the drivers to implement [Locker.LockContext] on top of [Locker.TryLock].
+func WaitForLock(ctx context.Context, tryLock func() (Lock, error)) (Lock, error) {
+	delay := 10 * time.Millisecond
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		lock, err := tryLock()
+		var held *LockHeld
+		if !errors.As(err, &held) {
+			return lock, err
+		}
+		timer.Reset(delay)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+		if delay < time.Second {
+			delay *= 2
+		}
+	}
+}
+
+func lockTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return DefaultLockTTL
+	}
+	return ttl
+}
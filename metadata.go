@@ -0,0 +1,77 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"path"
+	"strings"
+)
+
+var ErrMetadataUnsupported = errors.New("file system does not support custom metadata")
+
+// MetadataStore is implemented by the file systems which keep custom metadata and tags on the files,
+// e.g. the uploader or the retention class of a document.
+// Both are string maps, the metadata describing the file and the tags classifying it,
+// which the object stores keep apart, e.g. to select the files of lifecycle rules by their tags.
+// They can be set when writing the file with [MetadataKey] and [TagsKey].
+type MetadataStore interface {
+	// Metadata returns the custom metadata of the file, empty if it has none.
+	Metadata(path string) (map[string]string, error)
+	// SetMetadata replaces the custom metadata of the file.
+	SetMetadata(path string, metadata map[string]string) error
+	// Tags returns the tags of the file, empty if it has none.
+	Tags(path string) (map[string]string, error)
+	// SetTags replaces the tags of the file.
+	SetTags(path string, tags map[string]string) error
+}
+
+// MetadataSidecar is the content of the sidecar file keeping the metadata and tags of a file,
+// for the backends which can't attach them to the file itself.
+type MetadataSidecar struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+const metadataSidecarSuffix = ".meta.json"
+
+// MetadataSidecarPath returns the path of the sidecar file of the file, a hidden file next to it,
+// e.g. "dir/.report.pdf.meta.json" for "dir/report.pdf".
+func MetadataSidecarPath(p string) string {
+	dir, name := path.Split(p)
+	return dir + "." + name + metadataSidecarSuffix
+}
+
+// IsMetadataSidecar reports whether the file name is the name of a sidecar file,
+// which the drivers using them hide from the listings.
+func IsMetadataSidecar(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, metadataSidecarSuffix) &&
+		len(name) > len("."+metadataSidecarSuffix)
+}
+
+// ParseMetadataSidecar parses the content of a sidecar file.
+func ParseMetadataSidecar(content []byte) (*MetadataSidecar, error) {
+	var sidecar MetadataSidecar
+	if err := json.Unmarshal(content, &sidecar); err != nil {
+		return nil, err
+	}
+	return &sidecar, nil
+}
+
+// Empty reports whether the sidecar keeps nothing, and can be deleted.
+func (s *MetadataSidecar) Empty() bool {
+	return len(s.Metadata) == 0 && len(s.Tags) == 0
+}
+
+// Marshal returns the content of the sidecar file.
+func (s *MetadataSidecar) Marshal() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// CloneMetadata returns a copy of the metadata or tags, never nil.
+func CloneMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return make(map[string]string)
+	}
+	return maps.Clone(metadata)
+}
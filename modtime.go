@@ -0,0 +1,43 @@
+package filesystem
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrSetLastModifiedUnsupported = errors.New("file system does not support setting modification times")
+
+// MtimeMetadataKey is the user metadata in which the object stores keep the modification time set on an object,
+// their own last modified time being the time the object was written.
+const MtimeMetadataKey = "mtime"
+
+// Toucher is implemented by the file systems which can set the modification times of the files,
+// e.g. to preserve them while restoring a backup.
+type Toucher interface {
+	// Touch sets the modification time of the file to now, creating an empty file if it is missing.
+	Touch(path string) error
+	// SetLastModified sets the modification time of the file.
+	SetLastModified(path string, t time.Time) error
+}
+
+// FormatMtime formats the modification time stored in [MtimeMetadataKey].
+func FormatMtime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// MtimeFromMetadata returns the modification time stored in [MtimeMetadataKey] of the user metadata,
+// whose keys are matched case-insensitively since the object stores canonicalize them differently.
+func MtimeFromMetadata(metadata map[string]string) (time.Time, bool) {
+	for key, value := range metadata {
+		if !strings.EqualFold(key, MtimeMetadataKey) {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,359 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressInterval is the minimum interval between two progress reports of the same transfer,
+// the first and the last reports are always sent.
+var ProgressInterval = 100 * time.Millisecond
+
+// Progress is the state of a transfer, passed to a [ProgressFunc].
+type Progress struct {
+	// Path is the path of the file being transferred.
+	Path string
+	// Bytes is the number of bytes transferred so far.
+	Bytes int64
+	// Total is the size of the file, or -1 if it is not known.
+	Total int64
+	// Throughput is the average transfer rate in bytes per second.
+	Throughput float64
+	// Done reports whether the transfer of the file is complete.
+	Done bool
+	// Overall is the progress of the whole operation when the file is part of a directory operation,
+	// it is nil otherwise.
+	Overall *OverallProgress
+}
+
+// OverallProgress is the aggregated progress of a directory operation.
+type OverallProgress struct {
+	// Files is the number of files transferred so far.
+	Files int
+	// TotalFiles is the number of files to transfer.
+	TotalFiles int
+	// Bytes is the number of bytes transferred so far, over all files.
+	Bytes int64
+	// Total is the size of all files, or -1 if it is not known.
+	Total int64
+	// Throughput is the average transfer rate of the operation in bytes per second.
+	Throughput float64
+}
+
+// ProgressFunc receives the progress of a transfer.
+// It is called from the goroutine doing the transfer, so it should return quickly.
+type ProgressFunc func(Progress)
+
+// ProgressReader is implemented by the file systems which can report the progress of reads.
+type ProgressReader interface {
+	// ReadStreamWithConfig is like ReadStream, the progress set in the config is reported as the stream is read.
+	ReadStreamWithConfig(path string, config map[string]any) (io.ReadCloser, error)
+}
+
+type progressContextKey struct{}
+
+// WithProgress returns a copy of the context carrying the progress callback,
+// pass it to a file system with [ContextKey].
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, fn)
+}
+
+// ProgressFromContext returns the progress callback carried by the context, or nil.
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	if ctx == nil {
+		return nil
+	}
+	fn, _ := ctx.Value(progressContextKey{}).(ProgressFunc)
+	return fn
+}
+
+// Progress returns the progress callback set with [ProgressKey],
+// or carried by the context set with [ContextKey], or nil.
+func (cfg *Config) Progress() ProgressFunc {
+	if cfg == nil {
+		return nil
+	}
+	if v, ok := cfg.Get(ProgressKey); ok {
+		switch fn := v.(type) {
+		case ProgressFunc:
+			return fn
+		case func(Progress):
+			return fn
+		}
+	}
+	if v, ok := cfg.Get(ContextKey); ok {
+		if ctx, ok := v.(context.Context); ok {
+			return ProgressFromContext(ctx)
+		}
+	}
+	return nil
+}
+
+// Size returns the size of the content set with [ContentLengthKey], or -1.
+func (cfg *Config) Size() int64 {
+	if cfg == nil || cfg.ContentLength == nil {
+		return -1
+	}
+	return *cfg.ContentLength
+}
+
+// ProgressTracker counts the bytes of a transfer and reports them to a [ProgressFunc].
+//
+// A nil *ProgressTracker is valid and reports nothing,
+// so that drivers can use it whether a progress callback is set or not.
+type ProgressTracker struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	path     string
+	total    int64
+	bytes    int64
+	start    time.Time
+	reported time.Time
+	done     bool
+}
+
+// NewProgressTracker creates a tracker for the transfer of the file, total is -1 if the size is not known.
+// It returns nil if fn is nil.
+func NewProgressTracker(path string, total int64, fn ProgressFunc) *ProgressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &ProgressTracker{
+		fn:    fn,
+		path:  path,
+		total: total,
+		start: time.Now(),
+	}
+}
+
+// Add counts n transferred bytes.
+func (t *ProgressTracker) Add(n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return
+	}
+	t.bytes += n
+	now := time.Now()
+	if !t.reported.IsZero() && now.Sub(t.reported) < ProgressInterval && t.bytes != t.total {
+		return
+	}
+	t.reported = now
+	t.fn(t.progress(now))
+}
+
+// Done sends the last report, further calls do nothing.
+func (t *ProgressTracker) Done() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return
+	}
+	t.done = true
+	t.fn(t.progress(time.Now()))
+}
+
+func (t *ProgressTracker) progress(now time.Time) Progress {
+	var throughput float64
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		throughput = float64(t.bytes) / elapsed
+	}
+	return Progress{
+		Path:       t.path,
+		Bytes:      t.bytes,
+		Total:      t.total,
+		Throughput: throughput,
+		Done:       t.done,
+	}
+}
+
+// ReadCloser returns a stream which reports the bytes read from rc,
+// the last report is sent at the end of the stream or when it is closed.
+// It returns rc as is if t is nil.
+func (t *ProgressTracker) ReadCloser(rc io.ReadCloser) io.ReadCloser {
+	if t == nil {
+		return rc
+	}
+	return &progressReadCloser{rc: rc, tracker: t}
+}
+
+type progressReadCloser struct {
+	rc      io.ReadCloser
+	tracker *ProgressTracker
+}
+
+func (r *progressReadCloser) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.tracker.Add(int64(n))
+	if err == io.EOF {
+		r.tracker.Done()
+	}
+	return n, err
+}
+
+func (r *progressReadCloser) Close() error {
+	r.tracker.Done()
+	return r.rc.Close()
+}
+
+// CopyWithProgress copies src to dst like [io.Copy], counting every written chunk with the tracker.
+// It does not send the last report, call [ProgressTracker.Done] once the transfer is committed.
+func CopyWithProgress(dst io.Writer, src io.Reader, t *ProgressTracker) (int64, error) {
+	return CopyBufferWithProgress(dst, src, nil, t)
+}
+
+// CopyBufferWithProgress is like [CopyWithProgress], using the buffer for the chunks like [io.CopyBuffer],
+// larger chunks let the drivers which pipeline big writes keep doing so.
+// If buf is nil, a 32KiB buffer is allocated.
+func CopyBufferWithProgress(dst io.Writer, src io.Reader, buf []byte, t *ProgressTracker) (int64, error) {
+	if t == nil {
+		return io.CopyBuffer(dst, src, buf)
+	}
+	if buf == nil {
+		buf = make([]byte, 32*1024)
+	}
+	var written int64
+	for {
+		nr, rerr := src.Read(buf)
+		if nr > 0 {
+			nw, werr := dst.Write(buf[:nr])
+			if nw < 0 || nw > nr {
+				nw = 0
+				if werr == nil {
+					werr = io.ErrShortWrite
+				}
+			}
+			written += int64(nw)
+			t.Add(int64(nw))
+			if werr != nil {
+				return written, werr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// StreamSize returns the number of bytes left in the stream when it can be known without reading it,
+// or -1.
+func StreamSize(stream io.Reader) int64 {
+	switch s := stream.(type) {
+	case interface{ Len() int }:
+		return int64(s.Len())
+	case io.Seeker:
+		current, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := s.Seek(current, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - current
+	}
+	return -1
+}
+
+// NewTracker creates a tracker for writing the stream to the file with the config,
+// the size is the one set with [ContentLengthKey], or the size of the stream if it can be known.
+// It returns nil if no progress callback is set.
+func (cfg *Config) NewTracker(path string, stream io.Reader) *ProgressTracker {
+	fn := cfg.Progress()
+	if fn == nil {
+		return nil
+	}
+	total := cfg.Size()
+	if total < 0 && stream != nil {
+		total = StreamSize(stream)
+	}
+	return NewProgressTracker(path, total, fn)
+}
+
+// AggregateProgress aggregates the progress of the files of a directory operation.
+type AggregateProgress struct {
+	mu         sync.Mutex
+	fn         ProgressFunc
+	start      time.Time
+	files      int
+	totalFiles int
+	bytes      int64
+	total      int64
+}
+
+// NewAggregateProgress creates the aggregated progress of an operation on totalFiles files of total bytes,
+// total is -1 if it is not known. It returns nil if fn is nil.
+func NewAggregateProgress(totalFiles int, total int64, fn ProgressFunc) *AggregateProgress {
+	if fn == nil {
+		return nil
+	}
+	return &AggregateProgress{
+		fn:         fn,
+		start:      time.Now(),
+		totalFiles: totalFiles,
+		total:      total,
+	}
+}
+
+// File returns the callback reporting the progress of one file of the operation,
+// the reports are passed on with the overall progress filled in.
+// Call finish once the file is transferred, it sends the last report of the file
+// if the file system did not, e.g. because it does not support progress reporting.
+func (a *AggregateProgress) File(path string, size int64) (fn ProgressFunc, finish func()) {
+	if a == nil {
+		return nil, func() {}
+	}
+	var last int64
+	var done bool
+	fn = func(p Progress) {
+		a.mu.Lock()
+		if done {
+			a.mu.Unlock()
+			return
+		}
+		a.bytes += p.Bytes - last
+		last = p.Bytes
+		if p.Done {
+			done = true
+			a.files++
+		}
+		overall := &OverallProgress{
+			Files:      a.files,
+			TotalFiles: a.totalFiles,
+			Bytes:      a.bytes,
+			Total:      a.total,
+		}
+		if elapsed := time.Since(a.start).Seconds(); elapsed > 0 {
+			overall.Throughput = float64(a.bytes) / elapsed
+		}
+		a.mu.Unlock()
+		p.Overall = overall
+		a.fn(p)
+	}
+	finish = func() {
+		a.mu.Lock()
+		reported := done
+		a.mu.Unlock()
+		if !reported {
+			fn(Progress{Path: path, Bytes: size, Total: size, Done: true})
+		}
+	}
+	return fn, finish
+}
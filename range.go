@@ -0,0 +1,88 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrInvalidRange = errors.New("invalid range")
+
+// RangeReader is implemented by the file systems which can read a part of a file without reading all of it.
+type RangeReader interface {
+	// ReadRange returns a stream of length bytes of the file starting at offset,
+	// or of the rest of the file if length is negative.
+	// The stream is shorter if the file ends before offset+length.
+	ReadRange(path string, offset, length int64) (io.ReadCloser, error)
+	FileSize(path string) (int64, error)
+}
+
+// CheckRange checks the offset of the range passed to [RangeReader.ReadRange].
+func CheckRange(offset int64) error {
+	if offset < 0 {
+		return ErrInvalidRange
+	}
+	return nil
+}
+
+// LimitReadCloser returns a stream reading at most n bytes from rc, closing rc when closed.
+// It returns rc as is if n is negative.
+func LimitReadCloser(rc io.ReadCloser, n int64) io.ReadCloser {
+	if n < 0 {
+		return rc
+	}
+	return &limitReadCloser{Reader: io.LimitReader(rc, n), Closer: rc}
+}
+
+type limitReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ReaderAt is an [io.ReaderAt] view of a file, every ReadAt is a range read,
+// so that e.g. [archive/zip.NewReader] reads only the parts of a remote archive it needs.
+type ReaderAt struct {
+	reader RangeReader
+	path   string
+	size   int64
+}
+
+// NewReaderAt creates a view of the file, its size is read once.
+func NewReaderAt(reader RangeReader, path string) (*ReaderAt, error) {
+	size, err := reader.FileSize(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReaderAt{reader: reader, path: path, size: size}, nil
+}
+
+// Size returns the size of the file when the view was created.
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// ReadAt reads len(p) bytes at offset off, it returns io.EOF if the file ends before.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidRange
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	length := min(int64(len(p)), r.size-off)
+	stream, err := r.reader.ReadRange(r.path, off, length)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	n, err := io.ReadFull(stream, p[:length])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		return n, err
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
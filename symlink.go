@@ -0,0 +1,146 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+)
+
+var ErrSymlinkUnsupported = errors.New("file system does not support symbolic links")
+var ErrIsNotSymlink = errors.New("entry is not a symbolic link")
+
+// SymlinkMode decides how a walk handles the symbolic links, set with [SymlinksKey].
+type SymlinkMode string
+
+const (
+	// ReportSymlinks passes the links to the walk function as links, without following them.
+	// It is the default.
+	ReportSymlinks SymlinkMode = "report"
+	// FollowSymlinks passes the links as the files they point to, and walks the directories they point to,
+	// unless they lead back to a directory being walked.
+	// Dangling links are reported as links.
+	FollowSymlinks SymlinkMode = "follow"
+	// SkipSymlinks does not pass the links to the walk function.
+	SkipSymlinks SymlinkMode = "skip"
+)
+
+// Symlinker is implemented by the file systems which handle symbolic links.
+// The drivers of backends without links implement it with errors wrapping [ErrSymlinkUnsupported].
+type Symlinker interface {
+	// Symlink creates link as a symbolic link to target.
+	// The target is stored as is, a relative one is relative to the directory of the link.
+	Symlink(target, link string) error
+	// Readlink returns the target of the link, as it was stored.
+	Readlink(path string) (string, error)
+	// Lstat returns the info of the file, describing the link itself if it is one.
+	Lstat(path string) (os.FileInfo, error)
+}
+
+// SymlinkWalker is implemented by the file systems which can walk a tree handling the links as configured.
+type SymlinkWalker interface {
+	// WalkDirWithConfig walks like WalkDir, handling the links according to [SymlinksKey] of the config.
+	WalkDirWithConfig(path string, config map[string]any, walkFn fs.WalkDirFunc) error
+}
+
+// SymlinkMode returns the mode set with [SymlinksKey], or [ReportSymlinks] if it is not set.
+func (cfg *Config) SymlinkMode() (SymlinkMode, error) {
+	if cfg == nil || cfg.Symlinks == nil {
+		return ReportSymlinks, nil
+	}
+	switch mode := SymlinkMode(*cfg.Symlinks); mode {
+	case ReportSymlinks, FollowSymlinks, SkipSymlinks:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown symlink mode: %s", mode)
+	}
+}
+
+// SymlinkWalk walks a tree with symbolic links for the drivers, with their own ways to list and resolve them.
+type SymlinkWalk struct {
+	// ReadDir lists the directory, reporting the links as links.
+	ReadDir func(path string) ([]fs.DirEntry, error)
+	// Stat returns the info of the file, following the links.
+	Stat func(path string) (fs.FileInfo, error)
+	// RealPath returns the path with every link resolved, to detect the cycles.
+	RealPath func(path string) (string, error)
+}
+
+// Walk walks the tree rooted at root like [fs.WalkDir], handling the links according to mode.
+// The paths passed to walkFn go through the links, e.g. "dir/link/file.txt" when following them.
+// The root is followed if it is a link.
+func (w *SymlinkWalk) Walk(root string, mode SymlinkMode, walkFn fs.WalkDirFunc) error {
+	info, err := w.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = w.walk(root, fs.FileInfoToDirEntry(info), mode, nil, walkFn)
+	}
+	if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (w *SymlinkWalk) walk(dir string, d fs.DirEntry, mode SymlinkMode, ancestors []string, walkFn fs.WalkDirFunc) error {
+	if err := walkFn(dir, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, fs.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	if mode == FollowSymlinks {
+		real, err := w.RealPath(dir)
+		if err != nil {
+			return skipDir(walkFn(dir, d, err))
+		}
+		if slices.Contains(ancestors, real) {
+			// a link back to a directory being walked, walking it again would never end
+			return nil
+		}
+		ancestors = append(ancestors, real)
+	}
+	entries, err := w.ReadDir(dir)
+	if err != nil {
+		if err := walkFn(dir, d, err); err != nil {
+			return skipDir(err)
+		}
+	}
+	for _, entry := range entries {
+		child := path.Join(dir, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			switch mode {
+			case SkipSymlinks:
+				continue
+			case FollowSymlinks:
+				if info, err := w.Stat(child); err == nil {
+					entry = &followedEntry{DirEntry: fs.FileInfoToDirEntry(info), name: entry.Name()}
+				}
+			}
+		}
+		if err := w.walk(child, entry, mode, ancestors, walkFn); err != nil {
+			return skipDir(err)
+		}
+	}
+	return nil
+}
+
+// skipDir returns nil for [fs.SkipDir], which skips the rest of the directory.
+func skipDir(err error) error {
+	if errors.Is(err, fs.SkipDir) {
+		return nil
+	}
+	return err
+}
+
+// followedEntry is the entry of the target of a link, named after the link.
+type followedEntry struct {
+	fs.DirEntry
+	name string
+}
+
+func (e *followedEntry) Name() string {
+	return e.name
+}
@@ -0,0 +1,67 @@
+package filesystem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+var ErrVersionMismatch = errors.New("version does not match")
+
+// FileStat is the metadata of a file, with its version token.
+type FileStat struct {
+	Path         string
+	Size         int64
+	LastModified time.Time
+	// Version changes whenever the content of the file changes.
+	// It is opaque, and only meant to be passed back with [IfMatchKey].
+	Version string
+}
+
+// Versioner is implemented by the file systems exposing the versions of files,
+// and honoring [IfAbsentKey] and [IfMatchKey] in Write and WriteStream.
+type Versioner interface {
+	// Stat returns the metadata of the file, with its current version.
+	Stat(path string) (*FileStat, error)
+	// WriteVersioned writes like WriteStream, and returns the version of the written file.
+	WriteVersioned(path string, stream io.Reader, config map[string]any) (string, error)
+}
+
+// HasPrecondition reports whether the write is conditional.
+func (cfg *Config) HasPrecondition() bool {
+	return cfg != nil && ((cfg.IfAbsent != nil && *cfg.IfAbsent) || (cfg.IfMatch != nil))
+}
+
+// CheckPrecondition checks the conditions of the write against the current state of the file,
+// for the drivers emulating conditional writes.
+// The version is ignored when the file does not exist.
+func (cfg *Config) CheckPrecondition(location string, exists bool, version string) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.IfAbsent != nil && *cfg.IfAbsent && exists {
+		return NewPreconditionFailed(location, os.ErrExist)
+	}
+	if cfg.IfMatch != nil {
+		if !exists {
+			return NewPreconditionFailed(location, os.ErrNotExist)
+		}
+		if version != *cfg.IfMatch {
+			return NewPreconditionFailed(location, ErrVersionMismatch)
+		}
+	}
+	return nil
+}
+
+// ContentVersion returns a version derived from the content, the SHA-256 of the content in hex,
+// for the drivers without native versions.
+func ContentVersion(content io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
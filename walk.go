@@ -0,0 +1,84 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	fs2 "github.com/gopi-frame/contract/filesystem"
+)
+
+var ErrOutsideWalk = errors.New("walked path is outside of the walked directory")
+
+// WalkDirRelative walks the directory like the WalkDir of the file system,
+// and passes to walkFn the paths relative to dir, with slashes, "." being dir itself.
+//
+// The drivers yield paths in different forms: relative to their root, joined with their root as the local driver,
+// or object keys ending with a slash for the directories as S3.
+// The first path yielded is taken as the form of dir if it is not dir, nor under it,
+// or, if dir is the root, if it is a directory which the file system does not know by that path,
+// and a path which is not under dir is reported to walkFn as an [ErrOutsideWalk] error.
+//
+// The relative paths are joined with dir to address the files in the file system, see [JoinWalked].
+func WalkDirRelative(f fs2.FileSystem, dir string, walkFn fs.WalkDirFunc) error {
+	base := CleanPath(dir)
+	first := true
+	return f.WalkDir(dir, func(walked string, d fs.DirEntry, err error) error {
+		p := CleanPath(walked)
+		if first {
+			first = false
+			switch {
+			case p == base:
+			case base == ".":
+				// the root joined with the root of the file system, unless it is the first key listed by an object store
+				if d != nil && d.IsDir() {
+					if exists, err := f.DirExists(walked); err == nil && !exists {
+						base = p
+					}
+				}
+			case !strings.HasPrefix(p, base+"/"):
+				base = p
+			}
+		}
+		rel, relErr := relativeTo(base, p)
+		if relErr != nil {
+			return walkFn(p, d, relErr)
+		}
+		return walkFn(rel, d, err)
+	})
+}
+
+// JoinWalked returns the path in the file system of the path relative to dir passed by [WalkDirRelative],
+// a directory keeps the trailing slash of dir, which the object stores expect.
+func JoinWalked(dir, rel string, isDir bool) string {
+	p := path.Join(filepath.ToSlash(dir), rel)
+	if isDir && strings.HasSuffix(dir, "/") && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+// CleanPath normalizes a path of a file system, so that "a/b", "./a/b" and "/a/b" name the same file,
+// "." being the root. A path escaping the root is kept inside it.
+func CleanPath(p string) string {
+	p = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+func relativeTo(base, p string) (string, error) {
+	switch {
+	case p == base:
+		return ".", nil
+	case base == ".":
+		return p, nil
+	case strings.HasPrefix(p, base+"/"):
+		return strings.TrimPrefix(p, base+"/"), nil
+	}
+	return "", fmt.Errorf("%w: %s is not under %s", ErrOutsideWalk, p, base)
+}